@@ -1,31 +1,82 @@
 package snowflake
 
-import "sync/atomic"
+import (
+	"math"
+	"sync/atomic"
+)
 
-var lastTime int64
-var lastSeq uint32
+// atomicResolver is the state of an atomic sequence resolver.
+type atomicResolver struct {
+	// lastTime is accessed atomically, keep it as the first field to guarantee 64-bit alignment on 32-bit platforms.
+	lastTime int64
+	lastSeq  uint32
+}
+
+var defaultAtomicResolver atomicResolver
 
 // AtomicResolver define as atomic sequence resolver, base on standard sync/atomic.
+//
+// The sequence is counted up to 2^16-1 so it works with any layout,
+// ErrSequenceExhausted is returned once the sequence of the millisecond is exhausted.
+//
+// AtomicResolver keeps its state in the package, all its users share one sequence stream,
+// use NewAtomicResolver to give a generator its own.
 func AtomicResolver(ms int64) (uint16, error) {
+	return defaultAtomicResolver.resolve(ms)
+}
+
+// NewAtomicResolver create an atomic sequence resolver with its own state, see AtomicResolver.
+// The generators use a private one by default, so they don't interfere with each other.
+func NewAtomicResolver() SequenceResolver {
+	return (&atomicResolver{}).resolve
+}
+
+func (r *atomicResolver) resolve(ms int64) (uint16, error) {
 	var last int64
 	var seq, localSeq uint32
 
 	for {
-		last = atomic.LoadInt64(&lastTime)
-		localSeq = atomic.LoadUint32(&lastSeq)
+		last = atomic.LoadInt64(&r.lastTime)
+		localSeq = atomic.LoadUint32(&r.lastSeq)
 		if last > ms {
-			return MaxSequence, nil
+			return 0, ErrSequenceExhausted
 		}
 
 		if last == ms {
-			seq = uint32(MaxSequence) & (localSeq + 1)
-			if seq == 0 {
-				return MaxSequence, nil
+			if localSeq >= math.MaxUint16 {
+				return 0, ErrSequenceExhausted
 			}
+			seq = localSeq + 1
 		}
 
-		if atomic.CompareAndSwapInt64(&lastTime, last, ms) && atomic.CompareAndSwapUint32(&lastSeq, localSeq, seq) {
+		if atomic.CompareAndSwapInt64(&r.lastTime, last, ms) && atomic.CompareAndSwapUint32(&r.lastSeq, localSeq, seq) {
 			return uint16(seq), nil
 		}
 	}
 }
+
+// resolveBlock claim n consecutive sequences of ms up to max, it returns the first one.
+func (r *atomicResolver) resolveBlock(ms int64, n int, max uint16) (uint16, error) {
+	var last int64
+	var first, localSeq uint32
+
+	for {
+		last = atomic.LoadInt64(&r.lastTime)
+		localSeq = atomic.LoadUint32(&r.lastSeq)
+		if last > ms {
+			return 0, ErrSequenceExhausted
+		}
+
+		first = 0
+		if last == ms {
+			first = localSeq + 1
+		}
+		if first+uint32(n)-1 > uint32(max) {
+			return 0, ErrSequenceExhausted
+		}
+
+		if atomic.CompareAndSwapInt64(&r.lastTime, last, ms) && atomic.CompareAndSwapUint32(&r.lastSeq, localSeq, first+uint32(n)-1) {
+			return uint16(first), nil
+		}
+	}
+}
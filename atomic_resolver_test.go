@@ -1,6 +1,7 @@
 package snowflake_test
 
 import (
+	"math"
 	"testing"
 
 	"github.com/hedwi/go-snowflake"
@@ -27,3 +28,33 @@ func BenchmarkAtomicResolver(b *testing.B) {
 		_, _ = snowflake.AtomicResolver(1)
 	}
 }
+
+func TestAtomicResolver_Exhausted(t *testing.T) {
+	const ms = 1 << 40
+	for i := 0; i <= math.MaxUint16; i++ {
+		seq, err := snowflake.AtomicResolver(ms)
+		if err != nil || int(seq) != i {
+			t.Fatalf("Sequence should be equal %d, got %d, %v", i, seq, err)
+		}
+	}
+
+	if _, err := snowflake.AtomicResolver(ms); err != snowflake.ErrSequenceExhausted {
+		t.Errorf("The error should be ErrSequenceExhausted, got %v", err)
+	}
+	if _, err := snowflake.AtomicResolver(ms - 1); err != snowflake.ErrSequenceExhausted {
+		t.Errorf("An older millisecond should be exhausted, got %v", err)
+	}
+}
+
+func TestNewAtomicResolver(t *testing.T) {
+	r1, r2 := snowflake.NewAtomicResolver(), snowflake.NewAtomicResolver()
+
+	for i := 0; i < 3; i++ {
+		if seq, _ := r1(1); int(seq) != i {
+			t.Fatalf("Sequence should be equal %d, got %d", i, seq)
+		}
+	}
+	if seq, _ := r2(1); seq != 0 {
+		t.Errorf("The resolvers should not share their state, got %d", seq)
+	}
+}
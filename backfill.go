@@ -0,0 +1,118 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBackfillForgotten is returned by NextIDAt and IDsAt for a time before the one passed to ForgetBackfill,
+// the sequences used at that time are forgotten, so new IDs could duplicate the IDs already backfilled.
+var ErrBackfillForgotten = errors.New("snowflake: the backfill state of the time is forgotten")
+
+// NextIDAt generate an ID whose timestamp part is t instead of the current time, to backfill IDs for historical rows,
+// e.g. migrated rows keep their created_at so time range queries on the IDs still work.
+//
+// The IDs at t use a sequence counter dedicated to t, NextIDAt doesn't touch the state of NextID,
+// so backfill from a machineID which didn't generate live IDs at the same time, or the IDs may collide.
+// t cannot be before the start time or after the current time of the generator clock.
+//
+// The generator keeps a counter per tick backfilled, call ForgetBackfill as the backfill moves forward in time,
+// e.g. a migration in created_at order, so the counters of the ticks done are released.
+// This function is thread safe.
+func (g *Generator) NextIDAt(t time.Time) (uint64, error) {
+	ids, err := g.IDsAt(t, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	return ids[0], nil
+}
+
+// IDsAt generate n IDs whose timestamp part is t, see NextIDAt. All the n IDs are generated or none,
+// IDsAt returns an error wrapping ErrSequenceExhausted when the sequence left for the tick of t is less than n.
+// This function is thread safe.
+func (g *Generator) IDsAt(t time.Time, n int) ([]uint64, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("snowflake: invalid IDs count %d, it must be positive", n)
+	}
+
+	// 与 NextID 相同的检查，SwitchMachineID 切换期间暂停生成
+	g.switchMu.RLock()
+	defer g.switchMu.RUnlock()
+	if err := g.checkUsable(context.Background(), false); err != nil {
+		return nil, err
+	}
+
+	tick := toTicks(t, g.timeUnit)
+	df := tick - atomic.LoadInt64(&g.epoch)
+	if df < 0 {
+		return nil, fmt.Errorf("snowflake: the time %s is before the start time %s", t.Format(time.RFC3339Nano), g.StartTime().Format(time.RFC3339Nano))
+	}
+	if now := g.clock.Now(); t.After(now) {
+		return nil, fmt.Errorf("snowflake: the time %s is after the current time %s", t.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano))
+	}
+	if uint64(df) > g.layout.MaxTimestamp() {
+		return nil, fmt.Errorf("%w: the maximum life cycle of the snowflake algorithm is 2^%d-1(%s), please check start-time", ErrEpochExhausted, g.layout.TimestampBits, g.timeUnit)
+	}
+
+	ts := uint64(df)
+	if g.descending {
+		ts = g.layout.MaxTimestamp() - ts
+	}
+	machineID := atomic.LoadUint64(&g.machineID)
+
+	g.backfillMu.Lock()
+	if tick < g.backfillFloor {
+		g.backfillMu.Unlock()
+		return nil, fmt.Errorf("%w: %s is before %s", ErrBackfillForgotten, t.Format(time.RFC3339Nano), time.Unix(0, g.backfillFloor*int64(g.timeUnit)).UTC().Format(time.RFC3339Nano))
+	}
+	next := g.backfill[tick]
+	if next == 0 && ts == 0 && machineID == 0 {
+		// 0 保留为无效 ID，跳过第一个序列号
+		next = 1
+	}
+	if remaining := int(g.layout.MaxSequence()) + 1 - next; n > remaining {
+		g.backfillMu.Unlock()
+		return nil, fmt.Errorf("%w: %d IDs requested at %s, %d left", ErrSequenceExhausted, n, t.Format(time.RFC3339Nano), remaining)
+	}
+	if g.backfill == nil {
+		g.backfill = make(map[int64]int)
+	}
+	g.backfill[tick] = next + n
+	g.backfillMu.Unlock()
+
+	if atomic.LoadInt32(&g.started) == 0 {
+		atomic.StoreInt32(&g.started, 1)
+	}
+
+	ids := make([]uint64, n)
+	for i := range ids {
+		ids[i] = g.layout.compose(ts, machineID, uint16(next+i))
+	}
+
+	return ids, nil
+}
+
+// ForgetBackfill release the sequence counters of the ticks before t, NextIDAt and IDsAt return an error wrapping
+// ErrBackfillForgotten for these times from then on. A backfill in time order calls it periodically with the time
+// of the rows done, so the memory of the generator doesn't grow with the number of ticks backfilled.
+// This function is thread safe.
+func (g *Generator) ForgetBackfill(t time.Time) {
+	tick := toTicks(t, g.timeUnit)
+
+	g.backfillMu.Lock()
+	defer g.backfillMu.Unlock()
+
+	if tick <= g.backfillFloor {
+		return
+	}
+	g.backfillFloor = tick
+	for k := range g.backfill {
+		if k < tick {
+			delete(g.backfill, k)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package snowflake_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestNextIDAt(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	g, err := snowflake.New(
+		snowflake.WithStartTime(start),
+		snowflake.WithMachineID(7),
+		snowflake.WithLayout(snowflake.Layout{TimestampBits: 45, MachineBits: 16, SequenceBits: 2}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live := g.ID()
+	last := snowflake.LastTimestamp(g)
+
+	createdAt := time.Date(2021, 6, 1, 12, 30, 0, 0, time.UTC)
+	id, err := g.NextIDAt(createdAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sid := g.ParseID(id)
+	if !sid.GenerateTime().Equal(createdAt) || sid.MachineID != 7 || sid.Sequence != 0 {
+		t.Errorf("The ID should encode the backfill time, got %+v at %s", sid, sid.GenerateTime())
+	}
+	if snowflake.LastTimestamp(g) != last {
+		t.Error("NextIDAt should not move the lastTimestamp of the generator")
+	}
+	if next := g.ID(); next <= live {
+		t.Errorf("The live IDs should not be affected, got %d after %d", next, live)
+	}
+
+	// 4 sequences per tick, one is used.
+	ids, err := g.IDsAt(createdAt, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range append([]uint64{sid.ID}, ids...) {
+		if s := g.ParseID(id); s.Sequence != uint64(i) || !s.GenerateTime().Equal(createdAt) {
+			t.Errorf("The backfill IDs should use the sequences of the tick in order, got %+v", s)
+		}
+	}
+	if _, err := g.IDsAt(createdAt, 1); !errors.Is(err, snowflake.ErrSequenceExhausted) {
+		t.Errorf("The error should be ErrSequenceExhausted, got %v", err)
+	}
+	if _, err := g.IDsAt(createdAt.Add(time.Millisecond), 5); !errors.Is(err, snowflake.ErrSequenceExhausted) {
+		t.Errorf("IDsAt should reject n beyond the sequence space, got %v", err)
+	}
+	if _, err := g.IDsAt(createdAt.Add(time.Millisecond), 4); err != nil {
+		t.Errorf("A rejected IDsAt should not use the sequence, got %v", err)
+	}
+
+	if _, err := g.NextIDAt(start.Add(-time.Millisecond)); err == nil {
+		t.Error("A time before the start time should be rejected")
+	}
+	if _, err := g.NextIDAt(time.Now().Add(time.Hour)); err == nil {
+		t.Error("A time in the future should be rejected")
+	}
+	if _, err := g.IDsAt(createdAt, 0); err == nil {
+		t.Error("The IDs count must be positive")
+	}
+}
+
+func TestForgetBackfill(t *testing.T) {
+	g, err := snowflake.New(snowflake.WithMachineID(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	first, err := g.NextIDAt(day)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.ForgetBackfill(day.Add(time.Hour))
+	if _, err := g.NextIDAt(day); !errors.Is(err, snowflake.ErrBackfillForgotten) {
+		t.Errorf("A forgotten time should be rejected, its sequences are unknown, got %v", err)
+	}
+	id, err := g.NextIDAt(day.Add(time.Hour))
+	if err != nil || id == first {
+		t.Errorf("The times from the forgotten one should be backfilled, got %d, %v", id, err)
+	}
+
+	// forgetting an earlier time doesn't lower the floor.
+	g.ForgetBackfill(day)
+	if _, err := g.NextIDAt(day); !errors.Is(err, snowflake.ErrBackfillForgotten) {
+		t.Errorf("The floor should not move back, got %v", err)
+	}
+}
+
+func TestIDsAt_Guards(t *testing.T) {
+	createdAt := time.Date(2021, 6, 1, 12, 30, 0, 0, time.UTC)
+
+	g, err := snowflake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.RequireExplicitMachineID()
+	if _, err := g.NextIDAt(createdAt); !errors.Is(err, snowflake.ErrMachineIDNotSet) {
+		t.Errorf("IDsAt should require the machineID as NextID, got %v", err)
+	}
+
+	// without machineID, the backfill uses the random machineID of NextID, not 0.
+	g, err = snowflake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := g.NextIDAt(createdAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !g.Stats().UnmanagedMachineID || g.ParseID(id).MachineID != uint64(g.MachineID()) {
+		t.Errorf("IDsAt should pick the unmanaged machineID, got %+v", g.ParseID(id))
+	}
+
+	// the start time set after New bounds the backfill.
+	g, err = snowflake.New(snowflake.WithMachineID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.TrySetStartTime(createdAt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.NextIDAt(createdAt.Add(-time.Millisecond)); err == nil {
+		t.Error("A time before the start time set by TrySetStartTime should be rejected")
+	}
+	if id, err := g.NextIDAt(createdAt); err != nil || g.ParseID(id).Timestamp != 0 {
+		t.Errorf("The start time should be the first tick, got %d, %v", id, err)
+	}
+}
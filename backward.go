@@ -0,0 +1,67 @@
+package snowflake
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultSlewTolerance is the backward movement absorbed by BackwardPolicyWait by default, see WithSlewTolerance.
+const defaultSlewTolerance = 5 * time.Millisecond
+
+// BackwardPolicy is what NextID does when the clock moved backward, i.e. the current tick is before the last ID.
+// The max backward tolerance bounds every policy, beyond it NextID returns ErrClockMovedBackward.
+type BackwardPolicy int32
+
+const (
+	// BackwardPolicyWait sleep until the clock catches up, it is the default policy.
+	// A backward movement within the slew tolerance is absorbed with a logical clock instead, see WithSlewTolerance.
+	BackwardPolicyWait BackwardPolicy = iota
+	// BackwardPolicyError return ErrClockMovedBackward immediately on any backward movement.
+	BackwardPolicyError
+	// BackwardPolicyLogical keep generating IDs with the last tick as a logical clock until the clock catches up,
+	// when the sequence of the logical tick is exhausted the logical tick advances instead of waiting.
+	BackwardPolicyLogical
+)
+
+// WithSlewTolerance set the backward movement BackwardPolicyWait absorbs by reusing the last tick as a logical clock
+// instead of sleeping, it is 5ms by default and zero disables it. A slewed clock, e.g. during a smeared leap second,
+// moves backward by tiny steps which never block generation, the absorbed drift is reported by Stats and OnClockEvent.
+// The max backward tolerance still applies, with a zero tolerance any backward movement is an error.
+func WithSlewTolerance(d time.Duration) Option {
+	return func(g *Generator) error {
+		if d < 0 {
+			return fmt.Errorf("snowflake: invalid option WithSlewTolerance(%s): the tolerance cannot be negative", d)
+		}
+		g.slewTolerance = d
+
+		return nil
+	}
+}
+
+// WithHybridLogicalClock generate IDs with a hybrid logical clock (HLC): the timestamp is max(physical clock, last timestamp),
+// and it advances by one tick when the sequence is exhausted. The generator never sleeps nor returns an error
+// when the clock moves backward, it just keeps the logical clock ahead, so the IDs are strictly increasing.
+// It suits systems which care about causal ordering more than wall clock accuracy: GenerateTime may run ahead
+// of the wall clock, by the backward movement or the sequence overflow of a burst at most, see Stats.Lead and Stats.MaxLead.
+// The backward policy and tolerance are not used.
+func WithHybridLogicalClock() Option {
+	return func(g *Generator) error {
+		g.hlc = true
+
+		return nil
+	}
+}
+
+// String returns the name of the policy, e.g. wait.
+func (p BackwardPolicy) String() string {
+	switch p {
+	case BackwardPolicyWait:
+		return "wait"
+	case BackwardPolicyError:
+		return "error"
+	case BackwardPolicyLogical:
+		return "logical"
+	default:
+		return fmt.Sprintf("BackwardPolicy(%d)", int32(p))
+	}
+}
@@ -0,0 +1,156 @@
+package snowflake_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/clocktest"
+)
+
+func newBackwardGenerator(t *testing.T, policy snowflake.BackwardPolicy, clock *clocktest.Manual) *snowflake.Generator {
+	t.Helper()
+
+	g, err := snowflake.New(
+		snowflake.WithBackwardPolicy(policy),
+		snowflake.WithLayout(snowflake.Layout{TimestampBits: 45, MachineBits: 16, SequenceBits: 2}),
+		snowflake.WithStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+		snowflake.WithClock(clock),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return g
+}
+
+func TestBackwardPolicyError(t *testing.T) {
+	clock := clocktest.NewManual(time.Now())
+	g := newBackwardGenerator(t, snowflake.BackwardPolicyError, clock)
+
+	if _, err := g.NextID(); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(-10 * time.Millisecond)
+	_, err := g.NextID()
+	if !errors.Is(err, snowflake.ErrClockMovedBackward) {
+		t.Fatalf("The error should be ErrClockMovedBackward, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "by 10ms") {
+		t.Errorf("The error should report how far the clock moved backward, got %v", err)
+	}
+}
+
+func TestBackwardPolicyWait(t *testing.T) {
+	clock := clocktest.NewManual(time.Now())
+	g := newBackwardGenerator(t, snowflake.BackwardPolicyWait, clock)
+
+	first := g.ParseID(g.ID())
+
+	clock.Advance(-3 * time.Millisecond)
+	clock.AutoAdvance(time.Millisecond)
+	id, err := g.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sid := g.ParseID(id); sid.Timestamp < first.Timestamp {
+		t.Errorf("The generator should wait for the clock to catch up, got timestamp %d < %d", sid.Timestamp, first.Timestamp)
+	}
+
+	clock.Advance(-time.Minute)
+	if _, err := g.NextID(); !errors.Is(err, snowflake.ErrClockMovedBackward) {
+		t.Errorf("The error should be ErrClockMovedBackward beyond the tolerance, got %v", err)
+	}
+}
+
+func TestBackwardPolicyLogical(t *testing.T) {
+	clock := clocktest.NewManual(time.Now())
+	g := newBackwardGenerator(t, snowflake.BackwardPolicyLogical, clock)
+
+	last := g.ID()
+	first := g.ParseID(last)
+
+	// 4 IDs per tick, 3 are left in the first tick, so 10 IDs need 2 more logical ticks.
+	clock.Advance(-time.Second)
+	for i := 0; i < 10; i++ {
+		id, err := g.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id <= last {
+			t.Fatalf("The IDs should increase with the logical clock, got %d after %d", id, last)
+		}
+		last = id
+	}
+	if sid := g.ParseID(last); sid.Timestamp != first.Timestamp+2 {
+		t.Errorf("The logical clock should advance on sequence exhaustion, got %d, want %d", sid.Timestamp, first.Timestamp+2)
+	}
+
+	clock.Advance(2 * time.Second)
+	sid := g.ParseID(g.ID())
+	if sid.Timestamp != first.Timestamp+1000 || sid.Sequence != 0 {
+		t.Errorf("The generator should use the real clock once it catches up, got %+v", sid)
+	}
+
+	clock.Advance(-time.Minute)
+	if _, err := g.NextID(); !errors.Is(err, snowflake.ErrClockMovedBackward) {
+		t.Errorf("The error should be ErrClockMovedBackward beyond the tolerance, got %v", err)
+	}
+}
+
+func TestWithBackwardPolicy_Invalid(t *testing.T) {
+	if _, err := snowflake.New(snowflake.WithBackwardPolicy(42)); err == nil {
+		t.Error("An unknown policy should be rejected")
+	}
+}
+
+func TestWithSlewTolerance(t *testing.T) {
+	clock := clocktest.NewManual(time.Now())
+	g, err := snowflake.New(snowflake.WithClock(clock), snowflake.WithSlewTolerance(2*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an ID every millisecond, the slew steps the clock backward by 1ms every 10ms.
+	last := g.ID()
+	for i := 1; i <= 100; i++ {
+		if i%10 == 0 {
+			clock.Advance(-time.Millisecond)
+		} else {
+			clock.Advance(time.Millisecond)
+		}
+
+		start := time.Now()
+		id, err := g.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Millisecond {
+			t.Fatalf("The slew should not block generation, waited %s", elapsed)
+		}
+		if id <= last {
+			t.Fatalf("The IDs should increase with the logical clock, got %d after %d", id, last)
+		}
+		last = id
+	}
+
+	stats := g.Stats()
+	if stats.AbsorbedBackward != 10 || stats.AbsorbedDrift != 10*time.Millisecond {
+		t.Errorf("The absorbed drift should be reported, got %+v", stats)
+	}
+
+	clock.Advance(-10 * time.Millisecond)
+	if _, err := g.NextIDNoWait(); !errors.Is(err, snowflake.ErrClockMovedBackward) {
+		t.Errorf("The error should be ErrClockMovedBackward, got %v", err)
+	}
+	if stats := g.Stats(); stats.AbsorbedBackward != 10 {
+		t.Errorf("A backward movement beyond the slew tolerance should not be absorbed, got %+v", stats)
+	}
+
+	if _, err := snowflake.New(snowflake.WithSlewTolerance(-time.Millisecond)); err == nil {
+		t.Error("A negative slew tolerance should be rejected")
+	}
+}
@@ -0,0 +1,128 @@
+package snowflake
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultBlockSize is the block size of NewBlockLeaseResolver when blockSize < 1.
+const defaultBlockSize = 32
+
+// lease is a block of sequences [next, end) of the tick ms, owned by one caller at a time.
+type lease struct {
+	ms        int64
+	next, end uint32
+}
+
+// BlockLeaseResolver is a sequence resolver which leases blocks of sequences to its callers,
+// the sequences of a block are handed out without synchronization, see NewBlockLeaseResolver.
+type BlockLeaseResolver struct {
+	// leased and wasted are accessed atomically, keep them as the first fields to guarantee 64-bit alignment on 32-bit platforms.
+	leased, wasted uint64
+
+	blockSize uint32
+	// leases caches a lease per P, the goroutines running on a P use it without contention.
+	leases sync.Pool
+
+	// mu guards the sequence of the latest tick, the next block starts at n.
+	mu sync.Mutex
+	ms int64
+	n  uint32
+}
+
+// BlockLeaseStats is a snapshot of the counters of a BlockLeaseResolver.
+type BlockLeaseStats struct {
+	// Leased is the count of blocks leased.
+	Leased uint64
+	// Wasted is the count of leased sequences discarded unused because the tick changed.
+	// The blocks dropped by the garbage collector are not counted.
+	Wasted uint64
+}
+
+// NewBlockLeaseResolver create a sequence resolver which leases blocks of blockSize sequences, 32 when blockSize < 1,
+// a caller takes a sequence from its block and only synchronizes to lease a new one, once the block is used up
+// or the tick changed. The unused sequences of a block from a past tick are discarded, never reused, see Stats.
+//
+//	r := snowflake.NewBlockLeaseResolver(32)
+//	g, err := snowflake.New(snowflake.WithResolver(r))
+//
+// The sequences of a tick are unique but not issued in order, the blocks are leased up to 2^16-1 regardless of the layout,
+// the generator handles the sequences above the max sequence of its layout as exhausted.
+func NewBlockLeaseResolver(blockSize int) *BlockLeaseResolver {
+	if blockSize < 1 {
+		blockSize = defaultBlockSize
+	}
+	if blockSize > math.MaxUint16+1 {
+		blockSize = math.MaxUint16 + 1
+	}
+
+	r := &BlockLeaseResolver{blockSize: uint32(blockSize)}
+	r.leases.New = func() interface{} {
+		return &lease{}
+	}
+
+	return r
+}
+
+// Resolve is r as a Resolver, ctx and machineID are ignored.
+// This function is thread safe.
+func (r *BlockLeaseResolver) Resolve(ctx context.Context, ms int64, machineID uint16) (uint16, error) {
+	l := r.leases.Get().(*lease)
+	defer r.leases.Put(l)
+
+	if l.ms > ms {
+		return 0, ErrSequenceExhausted
+	}
+	if l.ms < ms || l.next >= l.end {
+		if l.ms < ms && l.next < l.end {
+			// 过期的租约：丢弃剩余的序列号，不能复用
+			atomic.AddUint64(&r.wasted, uint64(l.end-l.next))
+			l.next = l.end
+		}
+		if err := r.lease(ms, l); err != nil {
+			return 0, err
+		}
+	}
+
+	seq := l.next
+	l.next++
+
+	return uint16(seq), nil
+}
+
+// Stats returns a snapshot of the counters of r.
+// This function is thread safe.
+func (r *BlockLeaseResolver) Stats() BlockLeaseStats {
+	return BlockLeaseStats{
+		Leased: atomic.LoadUint64(&r.leased),
+		Wasted: atomic.LoadUint64(&r.wasted),
+	}
+}
+
+// lease lease the next block of ms into l.
+func (r *BlockLeaseResolver) lease(ms int64, l *lease) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ms < r.ms {
+		return ErrSequenceExhausted
+	}
+	if ms > r.ms {
+		r.ms, r.n = ms, 0
+	}
+	if r.n > math.MaxUint16 {
+		return ErrSequenceExhausted
+	}
+
+	end := r.n + r.blockSize
+	if end > math.MaxUint16+1 {
+		end = math.MaxUint16 + 1
+	}
+	l.ms, l.next, l.end = ms, r.n, end
+	r.n = end
+	atomic.AddUint64(&r.leased, 1)
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package snowflake_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestBlockLeaseResolver(t *testing.T) {
+	if raceEnabled {
+		t.Skip("The race detector drops the cached blocks randomly")
+	}
+
+	r := snowflake.NewBlockLeaseResolver(32)
+
+	for i := 0; i < 40; i++ {
+		seq, err := r.Resolve(context.Background(), 1, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(seq) != i {
+			t.Fatalf("A single caller should use its blocks in order, got %d, want %d", seq, i)
+		}
+	}
+	if stats := r.Stats(); stats.Leased != 2 || stats.Wasted != 0 {
+		t.Errorf("40 sequences should lease 2 blocks, got %+v", stats)
+	}
+
+	// the 24 sequences left in the second block are discarded on the new tick.
+	seq, err := r.Resolve(context.Background(), 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq != 0 {
+		t.Errorf("The new tick should lease a new block, got %d", seq)
+	}
+	if stats := r.Stats(); stats.Leased != 3 || stats.Wasted != 24 {
+		t.Errorf("The unused sequences of the past tick should be wasted, got %+v", stats)
+	}
+
+	if _, err := r.Resolve(context.Background(), 1, 0); err != snowflake.ErrSequenceExhausted {
+		t.Errorf("A past tick should be exhausted, got %v", err)
+	}
+}
+
+func TestBlockLeaseResolver_Generator(t *testing.T) {
+	r := snowflake.NewBlockLeaseResolver(32)
+	g, err := snowflake.New(snowflake.WithResolver(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const goroutines, n = 64, 2000
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	ids := make(map[uint64]bool, goroutines*n)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				id := g.ID()
+				mu.Lock()
+				if ids[id] {
+					t.Errorf("ID %d should not repeat", id)
+				}
+				ids[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
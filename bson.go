@@ -0,0 +1,157 @@
+package snowflake
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// MarshalBSONValue returns the ID as a BSON int64, see the bson.ValueMarshaler of the mongo driver v2.
+// Its BSON type is a plain byte, so TID implements it without importing the driver, the driver v1 is not supported.
+// The IDs above math.MaxInt64 are an error instead of negative numbers, as TID.Value.
+func (id TID) MarshalBSONValue() (byte, []byte, error) {
+	if id > math.MaxInt64 {
+		return 0, nil, fmt.Errorf("snowflake: the ID %d overflows the BSON int64", uint64(id))
+	}
+
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(id))
+
+	return bsonInt64, b, nil
+}
+
+// UnmarshalBSONValue reads the ID of a BSON int64 or int32, and of the decimal strings and the integral Decimal128
+// of the documents written before MarshalBSONValue, see the bson.ValueUnmarshaler of the mongo driver v2.
+// null, the negative numbers and the doubles are an error, a double may have rounded the ID already.
+func (id *TID) UnmarshalBSONValue(typ byte, data []byte) error {
+	switch typ {
+	case bsonInt64:
+		if len(data) != 8 {
+			return fmt.Errorf("snowflake: invalid BSON int64 of %d bytes", len(data))
+		}
+		return id.Scan(int64(binary.LittleEndian.Uint64(data)))
+	case bsonInt32:
+		if len(data) != 4 {
+			return fmt.Errorf("snowflake: invalid BSON int32 of %d bytes", len(data))
+		}
+		return id.Scan(int64(int32(binary.LittleEndian.Uint32(data))))
+	case bsonString:
+		s, err := bsonStringValue(data)
+		if err != nil {
+			return err
+		}
+		return id.scanString(s)
+	case bsonDecimal128:
+		v, err := bsonDecimal128Value(data)
+		if err != nil {
+			return err
+		}
+		*id = TID(v)
+		return nil
+	case bsonNull:
+		return errors.New("snowflake: cannot decode BSON null into a TID, use a NullTID")
+	default:
+		return fmt.Errorf("snowflake: cannot decode the BSON type 0x%02x into a TID", typ)
+	}
+}
+
+// MarshalBSONValue returns BSON null for NULL, otherwise the value of the ID, see TID.MarshalBSONValue.
+func (n NullTID) MarshalBSONValue() (byte, []byte, error) {
+	if !n.Valid {
+		return bsonNull, nil, nil
+	}
+
+	return n.ID.MarshalBSONValue()
+}
+
+// UnmarshalBSONValue reads BSON null or an ID, see TID.UnmarshalBSONValue.
+func (n *NullTID) UnmarshalBSONValue(typ byte, data []byte) error {
+	if typ == bsonNull {
+		n.ID, n.Valid = 0, false
+		return nil
+	}
+	if err := n.ID.UnmarshalBSONValue(typ, data); err != nil {
+		return err
+	}
+	n.Valid = true
+
+	return nil
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// The BSON types of the IDs, see https://bsonspec.org/spec.html.
+const (
+	bsonString     byte = 0x02
+	bsonNull       byte = 0x0A
+	bsonInt32      byte = 0x10
+	bsonInt64      byte = 0x12
+	bsonDecimal128 byte = 0x13
+)
+
+// bsonStringValue returns the value of a BSON string, an int32 length, the bytes and a trailing 0.
+func bsonStringValue(data []byte) (string, error) {
+	if len(data) < 5 || int64(binary.LittleEndian.Uint32(data)) != int64(len(data)-4) || data[len(data)-1] != 0 {
+		return "", errors.New("snowflake: invalid BSON string")
+	}
+
+	return string(data[4 : len(data)-1]), nil
+}
+
+// bsonDecimal128Value returns the uint64 of a BSON Decimal128, the 8 low bytes then the 8 high bytes, little endian,
+// of an IEEE 754-2008 decimal with a binary integer significand. The value must be an integer, e.g. 1.2E1 but not 1.5.
+func bsonDecimal128Value(data []byte) (uint64, error) {
+	if len(data) != 16 {
+		return 0, fmt.Errorf("snowflake: invalid BSON Decimal128 of %d bytes", len(data))
+	}
+	low, high := binary.LittleEndian.Uint64(data[:8]), binary.LittleEndian.Uint64(data[8:])
+
+	// 5 位组合字段全为 1 是 NaN，11110 是无穷大
+	if high>>58&0x1F >= 0x1E {
+		return 0, errors.New("snowflake: cannot decode a BSON Decimal128 NaN or infinity into a TID")
+	}
+
+	var exp int
+	significand := new(big.Int)
+	if high>>61&3 == 3 {
+		// 有效数字超过 10^34 的编码不合规范，规范规定按 0 处理
+		exp = int(high>>47&(1<<14-1)) - 6176
+	} else {
+		exp = int(high>>49&(1<<14-1)) - 6176
+		significand.SetUint64(high & (1<<49 - 1))
+		significand.Lsh(significand, 64)
+		significand.Or(significand, new(big.Int).SetUint64(low))
+	}
+	if significand.Sign() == 0 {
+		return 0, nil
+	}
+	if high>>63 == 1 {
+		return 0, errors.New("snowflake: cannot decode a negative BSON Decimal128 into a TID")
+	}
+
+	// uint64 最多 20 位十进制数
+	if exp > 20 {
+		return 0, errors.New("snowflake: the BSON Decimal128 overflows the uint64 of a TID")
+	}
+	if exp > 0 {
+		significand.Mul(significand, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil))
+	} else if exp < -34 {
+		// 有效数字小于 10^34，必有小数部分
+		return 0, errors.New("snowflake: cannot decode a fractional BSON Decimal128 into a TID")
+	} else if exp < 0 {
+		var rem big.Int
+		significand.QuoRem(significand, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-exp)), nil), &rem)
+		if rem.Sign() != 0 {
+			return 0, errors.New("snowflake: cannot decode a fractional BSON Decimal128 into a TID")
+		}
+	}
+	if !significand.IsUint64() {
+		return 0, errors.New("snowflake: the BSON Decimal128 overflows the uint64 of a TID")
+	}
+
+	return significand.Uint64(), nil
+}
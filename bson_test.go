@@ -0,0 +1,112 @@
+package snowflake_test
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// bsonDecimal128 returns the BSON Decimal128 of significand * 10^exp, negative when neg is set.
+func bsonDecimal128(significand uint64, exp int, neg bool) []byte {
+	high := uint64(exp+6176) << 49
+	if neg {
+		high |= 1 << 63
+	}
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint64(b, significand)
+	binary.LittleEndian.PutUint64(b[8:], high)
+
+	return b
+}
+
+func bsonString(s string) []byte {
+	b := make([]byte, 4, 5+len(s))
+	binary.LittleEndian.PutUint32(b, uint32(len(s)+1))
+
+	return append(append(b, s...), 0)
+}
+
+func TestTID_MarshalBSONValue(t *testing.T) {
+	for _, id := range []snowflake.TID{0, 850006245121695744, math.MaxInt64} {
+		typ, data, err := id.MarshalBSONValue()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ != 0x12 || len(data) != 8 || binary.LittleEndian.Uint64(data) != uint64(id) {
+			t.Errorf("%d should be a BSON int64, got type 0x%02x %v", id, typ, data)
+		}
+
+		var got snowflake.TID
+		if err := got.UnmarshalBSONValue(typ, data); err != nil || got != id {
+			t.Errorf("%d should round trip, got %d, %v", id, got, err)
+		}
+	}
+
+	if _, _, err := snowflake.TID(math.MaxInt64 + 1).MarshalBSONValue(); err == nil {
+		t.Error("The IDs above math.MaxInt64 should not be encoded")
+	}
+}
+
+func TestTID_UnmarshalBSONValue(t *testing.T) {
+	int32Data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(int32Data, 42)
+	negative := make([]byte, 8)
+	binary.LittleEndian.PutUint64(negative, uint64(1<<64-1))
+
+	tests := []struct {
+		name string
+		typ  byte
+		data []byte
+		want snowflake.TID
+		ok   bool
+	}{
+		{"int32", 0x10, int32Data, 42, true},
+		{"string", 0x02, bsonString("850006245121695744"), 850006245121695744, true},
+		{"decimal128", 0x13, bsonDecimal128(850006245121695744, 0, false), 850006245121695744, true},
+		{"decimal128 exponent", 0x13, bsonDecimal128(8500062451216957, 2, false), 850006245121695700, true},
+		{"decimal128 trailing zero", 0x13, bsonDecimal128(8500062451216957440, -1, false), 850006245121695744, true},
+		{"decimal128 zero", 0x13, bsonDecimal128(0, -3, true), 0, true},
+		{"negative int64", 0x12, negative, 0, false},
+		{"negative decimal128", 0x13, bsonDecimal128(1, 0, true), 0, false},
+		{"fractional decimal128", 0x13, bsonDecimal128(15, -1, false), 0, false},
+		{"overflowing decimal128", 0x13, bsonDecimal128(2, 19, false), 0, false},
+		{"decimal128 NaN", 0x13, append(make([]byte, 15), 0x7C), 0, false},
+		{"invalid string", 0x02, bsonString("b")[:5], 0, false},
+		{"double", 0x01, make([]byte, 8), 0, false},
+		{"null", 0x0A, nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got snowflake.TID
+			err := got.UnmarshalBSONValue(tt.typ, tt.data)
+			if tt.ok && (err != nil || got != tt.want) {
+				t.Errorf("The ID should be %d, got %d, %v", tt.want, got, err)
+			}
+			if !tt.ok && err == nil {
+				t.Errorf("An error should be returned, got %d", got)
+			}
+		})
+	}
+}
+
+func TestNullTID_BSONValue(t *testing.T) {
+	typ, data, err := snowflake.NullTID{}.MarshalBSONValue()
+	if err != nil || typ != 0x0A || data != nil {
+		t.Errorf("An invalid NullTID should be BSON null, got 0x%02x %v, %v", typ, data, err)
+	}
+
+	n := snowflake.NullTID{ID: 42, Valid: true}
+	if err := n.UnmarshalBSONValue(0x0A, nil); err != nil || n.Valid || n.ID != 0 {
+		t.Errorf("BSON null should decode into an invalid NullTID, got %+v, %v", n, err)
+	}
+
+	typ, data, err = snowflake.NullTID{ID: 7, Valid: true}.MarshalBSONValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.UnmarshalBSONValue(typ, data); err != nil || !n.Valid || n.ID != 7 {
+		t.Errorf("A valid NullTID should round trip, got %+v, %v", n, err)
+	}
+}
@@ -0,0 +1,134 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// BufferedGenerator hands out IDs pre-generated by a background goroutine, see Buffered.
+type BufferedGenerator struct {
+	// maxAge, hits, misses and stale are accessed atomically.
+	maxAge int64
+	hits   uint64
+	misses uint64
+	stale  uint64
+
+	g      *Generator
+	ids    chan bufferedID
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// BufferStats is a snapshot of the counters of a BufferedGenerator.
+type BufferStats struct {
+	// Hits is the number of IDs served from the buffer, Misses the number of IDs generated by Next
+	// because the buffer was empty.
+	Hits, Misses uint64
+	// Stale is the number of buffered IDs discarded because they were older than the max age.
+	Stale uint64
+	// Buffered is the number of IDs in the buffer.
+	Buffered int
+}
+
+// bufferedID is an ID of the buffer and the monotonic time it was generated.
+type bufferedID struct {
+	id uint64
+	at int64
+}
+
+// Buffered create a BufferedGenerator which keeps up to size IDs of g pre-generated by a background goroutine,
+// so Next is a channel receive in the common case instead of waiting on a tick rollover or a clock hiccup.
+// Next falls through to g when the buffer is empty.
+//
+// A buffered ID encodes the time it was generated, not the time Next returned it: under a light load
+// the IDs may be older than the callers expect, see SetMaxAge. The IDs returned by concurrent callers
+// are unique but not ordered by the time of the calls. Close stops the background goroutine, it doesn't close g.
+func Buffered(g *Generator, size int) *BufferedGenerator {
+	if size <= 0 {
+		panic("The buffer size must be positive")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &BufferedGenerator{
+		g:      g,
+		ids:    make(chan bufferedID, size),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go b.fill(ctx)
+
+	return b
+}
+
+// SetMaxAge set the max age of a buffered ID, Next discards the older IDs, zero means no limit (the default).
+// This function is thread safe.
+func (b *BufferedGenerator) SetMaxAge(d time.Duration) {
+	atomic.StoreInt64(&b.maxAge, int64(d))
+}
+
+// Next returns a buffered ID, or generates one with the generator when the buffer is empty.
+// This function is thread safe.
+func (b *BufferedGenerator) Next() (uint64, error) {
+	maxAge := atomic.LoadInt64(&b.maxAge)
+	for {
+		select {
+		case e := <-b.ids:
+			if maxAge > 0 && int64(time.Since(monoBase))-e.at > maxAge {
+				atomic.AddUint64(&b.stale, 1)
+				continue
+			}
+			atomic.AddUint64(&b.hits, 1)
+			return e.id, nil
+		default:
+			atomic.AddUint64(&b.misses, 1)
+			return b.g.NextID()
+		}
+	}
+}
+
+// Stats returns a snapshot of the counters of b.
+// This function is thread safe.
+func (b *BufferedGenerator) Stats() BufferStats {
+	return BufferStats{
+		Hits:     atomic.LoadUint64(&b.hits),
+		Misses:   atomic.LoadUint64(&b.misses),
+		Stale:    atomic.LoadUint64(&b.stale),
+		Buffered: len(b.ids),
+	}
+}
+
+// Close stops the background goroutine and waits for it, the IDs left in the buffer are still served by Next.
+// It is safe to call Close more than once.
+func (b *BufferedGenerator) Close() {
+	b.cancel()
+	<-b.done
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// fill keep the buffer topped up until ctx is done or the generator is closed.
+func (b *BufferedGenerator) fill(ctx context.Context) {
+	defer close(b.done)
+
+	for {
+		id, err := b.g.NextIDContext(ctx)
+		if ctx.Err() != nil || errors.Is(err, ErrClosed) {
+			return
+		}
+		if err != nil {
+			// 生成失败（如时钟回拨）：稍后重试，Next 会直接使用生成器
+			b.g.sleep(ctx, b.g.timeUnit)
+			continue
+		}
+
+		select {
+		case b.ids <- bufferedID{id: id, at: int64(time.Since(monoBase))}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package snowflake_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// waitBuffered wait until the buffer holds n IDs.
+func waitBuffered(t *testing.T, b *snowflake.BufferedGenerator, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for b.Stats().Buffered < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("The buffer should be filled, got %+v", b.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBuffered(t *testing.T) {
+	g, err := snowflake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := snowflake.Buffered(g, 64)
+	defer b.Close()
+
+	waitBuffered(t, b, 64)
+
+	seen := make(map[uint64]bool)
+	for i := 0; i < 1000; i++ {
+		id, err := b.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[id] {
+			t.Fatalf("The ID %d should be unique", id)
+		}
+		seen[id] = true
+	}
+
+	if stats := b.Stats(); stats.Hits < 64 || stats.Hits+stats.Misses != 1000 {
+		t.Errorf("The buffered IDs should be counted as hits, got %+v", stats)
+	}
+}
+
+func TestBufferedGenerator_SetMaxAge(t *testing.T) {
+	g, err := snowflake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := snowflake.Buffered(g, 8)
+	defer b.Close()
+
+	waitBuffered(t, b, 8)
+	b.SetMaxAge(5 * time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	id, err := b.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sid := g.ParseID(id)
+	if age := time.Since(sid.GenerateTime()); age > 5*time.Millisecond+time.Millisecond {
+		t.Errorf("The stale IDs should be discarded, got an ID %s old", age)
+	}
+	if stats := b.Stats(); stats.Stale == 0 {
+		t.Errorf("The stale IDs should be counted, got %+v", stats)
+	}
+}
+
+func TestBufferedGenerator_Close(t *testing.T) {
+	g, err := snowflake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := snowflake.Buffered(g, 8)
+	waitBuffered(t, b, 8)
+
+	b.Close()
+	b.Close()
+
+	// the filler is stopped: the buffer is not topped up anymore.
+	for i := 0; i < 8; i++ {
+		if _, err := b.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	if stats := b.Stats(); stats.Buffered != 0 || stats.Hits != 8 {
+		t.Errorf("The buffer should not be filled after Close, got %+v", stats)
+	}
+	if _, err := b.Next(); err != nil {
+		t.Errorf("Next should fall through to the generator, got %v", err)
+	}
+
+	// closing the generator stops the filler too.
+	b = snowflake.Buffered(g, 8)
+	if err := g.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+}
@@ -0,0 +1,62 @@
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/clocktest"
+)
+
+func TestWithBurst(t *testing.T) {
+	clock := clocktest.NewManual(time.Now())
+	g, err := snowflake.New(
+		snowflake.WithClock(clock),
+		snowflake.WithBurst(10*time.Millisecond),
+		snowflake.WithMaxBackwardTolerance(0),
+		snowflake.WithLayout(snowflake.Layout{TimestampBits: 45, MachineBits: 16, SequenceBits: 2}),
+		snowflake.WithStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 4 IDs per tick, the current tick and 10 borrowed ticks.
+	var last uint64
+	for i := 0; i < 44; i++ {
+		id, err := g.NextIDNoWait()
+		if err != nil {
+			t.Fatalf("The burst should borrow the next ticks, got %v at %d", err, i)
+		}
+		if id <= last {
+			t.Fatalf("The IDs should increase, got %d after %d", id, last)
+		}
+		last = id
+	}
+	if _, err := g.NextIDNoWait(); err != snowflake.ErrSequenceExhausted {
+		t.Errorf("The burst should stop at the max lead, got %v", err)
+	}
+	if stats := g.Stats(); stats.Lead != 10*time.Millisecond || stats.MaxLead != 10*time.Millisecond {
+		t.Errorf("The lead should be reported, got %+v", stats)
+	}
+
+	// the lead shrinks as the clock catches up.
+	clock.Advance(5 * time.Millisecond)
+	if stats := g.Stats(); stats.Lead != 5*time.Millisecond {
+		t.Errorf("The lead should shrink, got %s", stats.Lead)
+	}
+	for i := 0; i < 20; i++ {
+		id, err := g.NextIDNoWait()
+		if err != nil {
+			t.Fatalf("The burst should borrow again, got %v at %d", err, i)
+		}
+		if id <= last {
+			t.Fatalf("The IDs should increase, got %d after %d", id, last)
+		}
+		last = id
+	}
+
+	if _, err := snowflake.New(snowflake.WithBurst(0)); err == nil {
+		t.Error("The max lead must be positive")
+	}
+}
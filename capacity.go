@@ -0,0 +1,157 @@
+package snowflake
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// capacityEventQueue is the number of capacity events queued for the hooks, the events are dropped when it is full.
+const capacityEventQueue = 64
+
+// utilizationSeqBits is the width of the ID count in the utilization word, the tick uses the other bits.
+const utilizationSeqBits = 17
+
+// capacityEvent is a sequence exhaustion (waited is set) or a high utilization (hook is set) of the tick ms.
+type capacityEvent struct {
+	ms     int64
+	waited time.Duration
+	used   uint16
+	hook   *utilizationHook
+}
+
+// utilizationHook is a hook of OnHighUtilization, fn is called when a tick reaches mark IDs.
+type utilizationHook struct {
+	mark uint32
+	fn   func(ms int64, used uint16)
+}
+
+// OnSequenceExhausted register fn to be called when NextID exhausted the sequence of the tick ms
+// and waited for the next tick, waited is how long it waited.
+// Routine exhaustion is the signal to add machines before the waits become a latency problem.
+// The hooks are called by a goroutine of the generator like the OnClockEvent hooks, they never block ID generation.
+// This function is thread safe.
+func (g *Generator) OnSequenceExhausted(fn func(ms int64, waited time.Duration)) {
+	g.capacityHooksMu.Lock()
+	defer g.capacityHooksMu.Unlock()
+
+	g.exhaustedHooks = append(g.exhaustedHooks, fn)
+	g.startCapacityHooks()
+}
+
+// OnHighUtilization register fn to be called once per tick, when the tick ms used more than threshold
+// of its sequence space, e.g. 0.8 for 80%, used is the count of IDs of the tick at that point.
+// It panics when threshold is not in (0, 1].
+// The hooks are called by a goroutine of the generator like the OnClockEvent hooks, they never block ID generation.
+// This function is thread safe.
+func (g *Generator) OnHighUtilization(threshold float64, fn func(ms int64, used uint16)) {
+	if threshold <= 0 || threshold > 1 {
+		panic("The threshold must be greater than 0 and not greater than 1")
+	}
+
+	// 超过阈值的第一个 ID
+	space := float64(g.layout.MaxSequence()) + 1
+	mark := uint32(math.Floor(threshold*space)) + 1
+	if mark > uint32(space) {
+		mark = uint32(space)
+	}
+
+	g.capacityHooksMu.Lock()
+	defer g.capacityHooksMu.Unlock()
+
+	g.utilizationHooks = append(g.utilizationHooks, &utilizationHook{mark: mark, fn: fn})
+	g.startCapacityHooks()
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// startCapacityHooks start the dispatcher of the capacity events, capacityHooksMu must be held.
+func (g *Generator) startCapacityHooks() {
+	if g.capacityEvents == nil {
+		g.capacityEvents = make(chan capacityEvent, capacityEventQueue)
+		go g.dispatchCapacityEvents(g.capacityEvents)
+		atomic.StoreInt32(&g.capacityHooked, 1)
+	}
+}
+
+// emitCapacityEvent queue the event for the hooks, it never blocks.
+func (g *Generator) emitCapacityEvent(ev capacityEvent) {
+	g.capacityHooksMu.Lock()
+	events := g.capacityEvents
+	g.capacityHooksMu.Unlock()
+
+	select {
+	case events <- ev:
+	default:
+		// 队列已满，丢弃事件
+	}
+}
+
+func (g *Generator) dispatchCapacityEvents(events chan capacityEvent) {
+	for {
+		select {
+		case ev := <-events:
+			if ev.hook != nil {
+				ev.hook.fn(ev.ms, ev.used)
+				continue
+			}
+
+			g.capacityHooksMu.Lock()
+			hooks := g.exhaustedHooks
+			g.capacityHooksMu.Unlock()
+			for _, fn := range hooks {
+				fn(ev.ms, ev.waited)
+			}
+		case <-g.done:
+			return
+		}
+	}
+}
+
+// sequenceExhausted report the wait for the tick after ms.
+func (g *Generator) sequenceExhausted(ms int64, waited time.Duration) {
+	if atomic.LoadInt32(&g.capacityHooked) == 0 {
+		return
+	}
+
+	g.emitCapacityEvent(capacityEvent{ms: ms, waited: waited})
+}
+
+// countUtilization count an ID of the tick ms, and report the ticks crossing the marks of the utilization hooks.
+func (g *Generator) countUtilization(ms int64) {
+	if atomic.LoadInt32(&g.capacityHooked) == 0 || ms < 0 || ms >= 1<<(64-utilizationSeqBits) {
+		return
+	}
+
+	var n uint32
+	for {
+		old := atomic.LoadUint64(&g.utilization)
+		state := uint64(ms)<<utilizationSeqBits | 1
+		switch tick := int64(old >> utilizationSeqBits); {
+		case ms < tick:
+			// 旧的时间单位，已经不再统计
+			return
+		case ms == tick:
+			state = old + 1
+		}
+		if atomic.CompareAndSwapUint64(&g.utilization, old, state) {
+			n = uint32(state & (1<<utilizationSeqBits - 1))
+			break
+		}
+	}
+
+	g.capacityHooksMu.Lock()
+	hooks := g.utilizationHooks
+	g.capacityHooksMu.Unlock()
+	for _, h := range hooks {
+		if n == h.mark {
+			used := n
+			if used > math.MaxUint16 {
+				used = math.MaxUint16
+			}
+			g.emitCapacityEvent(capacityEvent{ms: ms, used: uint16(used), hook: h})
+		}
+	}
+}
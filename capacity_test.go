@@ -0,0 +1,104 @@
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/clocktest"
+)
+
+func TestOnSequenceExhausted(t *testing.T) {
+	// only sequence 0 and 1 are available in a tick.
+	g, err := snowflake.New(
+		snowflake.WithLayout(snowflake.Layout{TimestampBits: 46, MachineBits: 16, SequenceBits: 1}),
+		snowflake.WithStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+		snowflake.WithTimeUnit(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type exhausted struct {
+		ms     int64
+		waited time.Duration
+	}
+	events := make(chan exhausted, 10)
+	g.OnSequenceExhausted(func(ms int64, waited time.Duration) {
+		events <- exhausted{ms, waited}
+	})
+
+	// 6 IDs need 3 ticks, so the generator waits at least once.
+	ticks := make(map[int64]bool)
+	for i := 0; i < 6; i++ {
+		sid := g.ParseID(g.ID())
+		ticks[sid.GenerateTime().UnixNano()/int64(10*time.Millisecond)] = true
+	}
+
+	select {
+	case ev := <-events:
+		if ev.waited <= 0 || ev.waited > time.Second {
+			t.Errorf("The hook should report the wait, got %s", ev.waited)
+		}
+		if !ticks[ev.ms] {
+			t.Errorf("The hook should report the exhausted tick, got %d", ev.ms)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("The hook should be called")
+	}
+}
+
+func TestOnHighUtilization(t *testing.T) {
+	clock := clocktest.NewManual(time.Now())
+	g, err := snowflake.New(
+		snowflake.WithClock(clock),
+		snowflake.WithLayout(snowflake.Layout{TimestampBits: 45, MachineBits: 16, SequenceBits: 2}),
+		snowflake.WithStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type utilization struct {
+		ms   int64
+		used uint16
+	}
+	events := make(chan utilization, 10)
+	g.OnHighUtilization(0.5, func(ms int64, used uint16) {
+		events <- utilization{ms, used}
+	})
+
+	// 4 IDs per tick, the third one uses more than 50%.
+	for i := 0; i < 2; i++ {
+		g.ID()
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("The hook should not be called below the threshold, got %+v", ev)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	g.ID()
+	tick := snowflake.LastTimestamp(g)
+	g.ID()
+	select {
+	case ev := <-events:
+		if ev.ms != tick || ev.used != 3 {
+			t.Errorf("The hook should report the tick and its used sequences, got %+v, want tick %d", ev, tick)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("The hook should be called")
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("The hook should be called once per tick, got %+v", ev)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("An invalid threshold should panic")
+		}
+	}()
+	g.OnHighUtilization(1.5, func(ms int64, used uint16) {})
+}
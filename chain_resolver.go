@@ -0,0 +1,117 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// defaultChainCooldown is how long a ResolverChain skips a failed resolver by default.
+const defaultChainCooldown = 5 * time.Second
+
+// ResolverChain is a sequence resolver which falls back to the next resolver when one fails, see ChainResolver.
+type ResolverChain struct {
+	resolvers []Resolver
+	cooldown  time.Duration
+
+	// trippedUntil is the unix nano time until which a resolver is skipped, served counts the sequences
+	// each resolver served and trips counts how often each resolver failed, all accessed atomically.
+	trippedUntil []int64
+	served       []uint64
+	trips        []uint64
+}
+
+// ChainStats is a snapshot of the counters of a ResolverChain, the index 0 is the primary resolver.
+type ChainStats struct {
+	// Served is the count of sequences each resolver served.
+	Served []uint64
+	// Trips is the count of failures of each resolver, a failed resolver is skipped during the cooldown.
+	Trips []uint64
+}
+
+// ChainResolver create a sequence resolver which tries primary then the fallbacks in order.
+// A resolver returning an error other than ErrSequenceExhausted is tripped: it is skipped for 5s, see SetCooldown,
+// then it is tried again. ErrSequenceExhausted is returned as is, falling back would not free the tick.
+//
+// A fallback doesn't coordinate with the primary, e.g. a local atomic resolver behind a redis resolver
+// may issue a sequence another process got from redis for the same machineID. Use WithResolverChain
+// to switch to a reserved fallback machineID, unique to the process, while a fallback serves.
+//
+//	chain := snowflake.ChainResolver(snowflake.NewRedisResolver(client), snowflake.NewAtomicResolver())
+//	g, err := snowflake.New(snowflake.WithMachineID(42), snowflake.WithResolverChain(chain, 300+workerIndex))
+func ChainResolver(primary Resolver, fallbacks ...Resolver) *ResolverChain {
+	resolvers := append([]Resolver{primary}, fallbacks...)
+
+	return &ResolverChain{
+		resolvers:    resolvers,
+		cooldown:     defaultChainCooldown,
+		trippedUntil: make([]int64, len(resolvers)),
+		served:       make([]uint64, len(resolvers)),
+		trips:        make([]uint64, len(resolvers)),
+	}
+}
+
+// SetCooldown set how long a failed resolver is skipped, 5s by default.
+// This function is thread-unsafe, recommended you call him before the chain is used.
+func (c *ResolverChain) SetCooldown(d time.Duration) {
+	c.cooldown = d
+}
+
+// Resolve is c as a Resolver, ctx and machineID are passed to every resolver of the chain.
+// This function is thread safe.
+func (c *ResolverChain) Resolve(ctx context.Context, ms int64, machineID uint16) (uint16, error) {
+	seq, _, err := c.resolve(ctx, ms, machineID, machineID)
+
+	return seq, err
+}
+
+// Degraded returns true while the primary resolver is tripped.
+// This function is thread safe.
+func (c *ResolverChain) Degraded() bool {
+	return time.Now().UnixNano() < atomic.LoadInt64(&c.trippedUntil[0])
+}
+
+// Stats returns a snapshot of the counters of c.
+// This function is thread safe.
+func (c *ResolverChain) Stats() ChainStats {
+	stats := ChainStats{Served: make([]uint64, len(c.resolvers)), Trips: make([]uint64, len(c.resolvers))}
+	for i := range c.resolvers {
+		stats.Served[i] = atomic.LoadUint64(&c.served[i])
+		stats.Trips[i] = atomic.LoadUint64(&c.trips[i])
+	}
+
+	return stats
+}
+
+// resolve returns the sequence and the index of the resolver which served it,
+// the primary resolver gets machineID and the fallbacks get fallbackMachineID.
+func (c *ResolverChain) resolve(ctx context.Context, ms int64, machineID, fallbackMachineID uint16) (uint16, int, error) {
+	var err error
+	for i, r := range c.resolvers {
+		now := time.Now().UnixNano()
+		// 熔断期间跳过，最后一个解析器总是尝试
+		if i < len(c.resolvers)-1 && now < atomic.LoadInt64(&c.trippedUntil[i]) {
+			continue
+		}
+
+		id := fallbackMachineID
+		if i == 0 {
+			id = machineID
+		}
+		var seq uint16
+		seq, err = r.Resolve(ctx, ms, id)
+		if err == nil {
+			atomic.AddUint64(&c.served[i], 1)
+			return seq, i, nil
+		}
+		if errors.Is(err, ErrSequenceExhausted) {
+			return 0, i, err
+		}
+
+		atomic.StoreInt64(&c.trippedUntil[i], now+int64(c.cooldown))
+		atomic.AddUint64(&c.trips[i], 1)
+	}
+
+	return 0, len(c.resolvers) - 1, err
+}
@@ -0,0 +1,127 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// flappingResolver is an atomic resolver which fails while down is set.
+type flappingResolver struct {
+	mu      sync.Mutex
+	down    bool
+	calls   int
+	resolve snowflake.SequenceResolver
+}
+
+func newFlappingResolver() *flappingResolver {
+	return &flappingResolver{resolve: snowflake.NewAtomicResolver()}
+}
+
+func (r *flappingResolver) Resolve(ctx context.Context, ms int64, machineID uint16) (uint16, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls++
+	if r.down {
+		return 0, &snowflake.ResolverError{Resolver: "flapping", Err: errors.New("i/o timeout")}
+	}
+
+	return r.resolve(ms)
+}
+
+func (r *flappingResolver) set(down bool) {
+	r.mu.Lock()
+	r.down = down
+	r.mu.Unlock()
+}
+
+func TestChainResolver_Flapping(t *testing.T) {
+	primary := newFlappingResolver()
+	chain := snowflake.ChainResolver(primary, snowflake.NewAtomicResolver())
+	chain.SetCooldown(20 * time.Millisecond)
+
+	if _, err := chain.Resolve(context.Background(), 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// the primary fails, the fallback serves and the primary is skipped during the cooldown.
+	primary.set(true)
+	for i := 0; i < 3; i++ {
+		if _, err := chain.Resolve(context.Background(), 2, 0); err != nil {
+			t.Fatalf("The fallback should serve, got %v", err)
+		}
+	}
+	if !chain.Degraded() {
+		t.Error("The chain should be degraded")
+	}
+	if primary.calls != 2 {
+		t.Errorf("The tripped primary should be skipped, got %d calls", primary.calls)
+	}
+
+	// the primary is back, it is tried again after the cooldown.
+	primary.set(false)
+	time.Sleep(30 * time.Millisecond)
+	if chain.Degraded() {
+		t.Error("The chain should not be degraded after the cooldown")
+	}
+	if _, err := chain.Resolve(context.Background(), 3, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// it flaps again.
+	primary.set(true)
+	if _, err := chain.Resolve(context.Background(), 4, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := chain.Stats()
+	if stats.Served[0] != 2 || stats.Served[1] != 4 || stats.Trips[0] != 2 {
+		t.Errorf("The stats should record which resolver served, got %+v", stats)
+	}
+}
+
+func TestChainResolver_AllDown(t *testing.T) {
+	p1, p2 := newFlappingResolver(), newFlappingResolver()
+	p1.set(true)
+	p2.set(true)
+	chain := snowflake.ChainResolver(p1, p2)
+
+	if _, err := chain.Resolve(context.Background(), 1, 0); !errors.Is(err, snowflake.ErrResolverUnavailable) {
+		t.Errorf("The error of the last resolver should be returned, got %v", err)
+	}
+	// the last resolver is always tried.
+	if _, err := chain.Resolve(context.Background(), 1, 0); !errors.Is(err, snowflake.ErrResolverUnavailable) || p2.calls != 2 {
+		t.Errorf("The last resolver should be tried again, got %v after %d calls", err, p2.calls)
+	}
+}
+
+func TestWithResolverChain(t *testing.T) {
+	primary := newFlappingResolver()
+	fallback := &recordingResolver{resolve: snowflake.NewAtomicResolver()}
+	chain := snowflake.ChainResolver(primary, fallback)
+	g, err := snowflake.New(snowflake.WithMachineID(42), snowflake.WithResolverChain(chain, 300))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sid := g.ParseID(g.ID()); sid.MachineID != 42 {
+		t.Errorf("The primary should use the machineID, got %d", sid.MachineID)
+	}
+
+	primary.set(true)
+	if sid := g.ParseID(g.ID()); sid.MachineID != 300 || fallback.machineID != 300 {
+		t.Errorf("A fallback should use the fallback machineID, got %d and resolved for %d", sid.MachineID, fallback.machineID)
+	}
+	if g.MachineID() != 42 {
+		t.Errorf("The machineID of the generator should not change, got %d", g.MachineID())
+	}
+
+	if _, err := snowflake.New(snowflake.WithResolverChain(chain, 512)); err == nil {
+		t.Error("The fallback machineID should be checked against the layout")
+	}
+}
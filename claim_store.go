@@ -0,0 +1,93 @@
+package snowflake
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// redisClaimScript set the key to the owner with SET PX when it doesn't exist, refresh its ttl when it holds the owner,
+// and return its value, in one round trip.
+const redisClaimScript = `local cur = redis.call('GET', KEYS[1])
+if not cur then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return ARGV[1]
+end
+if cur == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return cur`
+
+// RedisClaimCmdable is the redis client used by NewRedisClaimStore, implement it with any redis library supporting EVAL.
+// With go-redis:
+//
+//	func (r goRedis) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+//		return r.c.Eval(ctx, script, keys, args...).Result()
+//	}
+type RedisClaimCmdable interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// EtcdKV is the etcd client used by NewEtcdClaimStore, implement it with clientv3:
+//
+// PutIfAbsent must put key = value attached to a lease of ttl in a transaction comparing the create revision of key to 0,
+// and return the value of key when the comparison fails. KeepAliveOnce must refresh the lease of key.
+type EtcdKV interface {
+	PutIfAbsent(ctx context.Context, key, value string, ttl time.Duration) (current string, created bool, err error)
+	KeepAliveOnce(ctx context.Context, key string) error
+}
+
+// NewRedisClaimStore create a ClaimStore backed by redis, a claim runs GET, SET PX and PEXPIRE in one script.
+func NewRedisClaimStore(client RedisClaimCmdable) ClaimStore {
+	return redisClaimStore{client}
+}
+
+// NewEtcdClaimStore create a ClaimStore backed by etcd, the claims are attached to leases.
+func NewEtcdClaimStore(kv EtcdKV) ClaimStore {
+	return etcdClaimStore{kv}
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+type redisClaimStore struct {
+	c RedisClaimCmdable
+}
+
+func (s redisClaimStore) Claim(ctx context.Context, key, owner string, ttl time.Duration) (string, error) {
+	res, err := s.c.Eval(ctx, redisClaimScript, []string{key}, owner, ttl.Milliseconds())
+	if err != nil {
+		return "", err
+	}
+
+	switch got := res.(type) {
+	case string:
+		return got, nil
+	case []byte:
+		return string(got), nil
+	default:
+		return "", fmt.Errorf("snowflake: unexpected reply %T of the claim script", res)
+	}
+}
+
+type etcdClaimStore struct {
+	kv EtcdKV
+}
+
+func (s etcdClaimStore) Claim(ctx context.Context, key, owner string, ttl time.Duration) (string, error) {
+	got, created, err := s.kv.PutIfAbsent(ctx, key, owner, ttl)
+	if err != nil {
+		return "", err
+	}
+	if created {
+		return owner, nil
+	}
+	if got == owner {
+		if err := s.kv.KeepAliveOnce(ctx, key); err != nil {
+			return "", err
+		}
+	}
+
+	return got, nil
+}
@@ -0,0 +1,18 @@
+package snowflake
+
+import "time"
+
+// Clock is the time source of a generator, inject one with WithClock to control time in tests,
+// see the clocktest package. It returns a time.Time so the generator keeps the precision of any time unit.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the default Clock, it returns time.Now.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
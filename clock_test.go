@@ -0,0 +1,106 @@
+package snowflake_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/clocktest"
+)
+
+func TestWithClock_SequenceOverflow(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	g, err := snowflake.New(
+		snowflake.WithClock(clock),
+		snowflake.WithLayout(snowflake.Layout{TimestampBits: 45, MachineBits: 16, SequenceBits: 2}),
+		snowflake.WithStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sequence 0, 1, 2, 3 are available in a tick.
+	var first snowflake.SID
+	for i := 0; i < 4; i++ {
+		sid := g.ParseID(g.ID())
+		if i == 0 {
+			first = sid
+		}
+		if sid.Timestamp != first.Timestamp || sid.Sequence != uint64(i) {
+			t.Fatalf("The IDs should share the tick, got %+v", sid)
+		}
+	}
+
+	ids := make(chan uint64)
+	go func() {
+		ids <- g.ID()
+	}()
+
+	select {
+	case id := <-ids:
+		t.Fatalf("NextID should wait for the next tick on sequence overflow, got %d", id)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(time.Millisecond)
+	sid := g.ParseID(<-ids)
+	if sid.Timestamp != first.Timestamp+1 || sid.Sequence != 0 {
+		t.Errorf("The ID should use the next tick, got %+v", sid)
+	}
+}
+
+func TestWithClock_EpochOverflow(t *testing.T) {
+	layout := snowflake.Layout{TimestampBits: 31, MachineBits: 16, SequenceBits: 16}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := clocktest.NewManual(now)
+
+	// 2^31 ms is about 24.8 days.
+	start := now.Add(-24 * 24 * time.Hour)
+	g, err := snowflake.New(snowflake.WithClock(clock), snowflake.WithLayout(layout), snowflake.WithStartTime(start))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.NextID(); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(2 * 24 * time.Hour)
+	if _, err := g.NextID(); err == nil {
+		t.Error("NextID should fail once the timestamp overflows the layout")
+	}
+
+	if _, err := snowflake.New(snowflake.WithClock(clock), snowflake.WithLayout(layout), snowflake.WithStartTime(start)); !errors.Is(err, snowflake.ErrStartTimeTooEarly) {
+		t.Errorf("The start time should be validated against the clock, got %v", err)
+	}
+}
+
+func TestWithClock_FutureStartTime(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	g, err := snowflake.New(snowflake.WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.TrySetStartTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)); !errors.Is(err, snowflake.ErrFutureStartTime) {
+		t.Errorf("The start time should be in the future of the clock, got %v", err)
+	}
+
+	if _, err := snowflake.New(snowflake.WithClock(nil)); err == nil {
+		t.Error("A nil clock should be rejected")
+	}
+}
+
+func TestSetClock(t *testing.T) {
+	defer snowflake.Reset()
+	snowflake.Reset()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	snowflake.SetClock(clocktest.NewManual(now))
+
+	sid := snowflake.ParseID(snowflake.ID())
+	if gt := sid.GenerateTime(); !gt.Equal(now) {
+		t.Errorf("The ID should be generated at the clock time, got %s", gt)
+	}
+}
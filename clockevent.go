@@ -0,0 +1,137 @@
+package snowflake
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// defaultForwardJumpThreshold is the wall clock jump beyond the monotonic clock reported as ClockForwardJump by default.
+const defaultForwardJumpThreshold = 10 * time.Second
+
+// clockEventQueue is the number of clock events queued for the hooks, the events are dropped when it is full.
+const clockEventQueue = 64
+
+// monoBase is the reference of the monotonic readings used to detect the forward jumps.
+var monoBase = time.Now()
+
+// ClockEventKind is the kind of a ClockEvent.
+type ClockEventKind uint8
+
+const (
+	// ClockBackward is reported when the clock moved backward behind the last ID.
+	ClockBackward ClockEventKind = iota
+	// ClockForwardJump is reported when the wall clock jumped ahead of the monotonic clock by more than the threshold,
+	// e.g. after a VM was paused or live migrated.
+	ClockForwardJump
+)
+
+// String returns the name of the kind, e.g. backward.
+func (k ClockEventKind) String() string {
+	switch k {
+	case ClockBackward:
+		return "backward"
+	case ClockForwardJump:
+		return "forward-jump"
+	default:
+		return fmt.Sprintf("ClockEventKind(%d)", uint8(k))
+	}
+}
+
+// ClockEvent is a clock anomaly detected by the generator, see OnClockEvent.
+type ClockEvent struct {
+	Kind ClockEventKind
+	// Delta is how far the clock moved, backward or forward.
+	Delta time.Duration
+	// Action is what NextID did on ClockBackward: waited, returned an error or used a logical clock.
+	// It is not set on ClockForwardJump.
+	Action BackwardPolicy
+	// Time is the generator clock time of the detection.
+	Time time.Time
+}
+
+// WithForwardJumpThreshold set the wall clock jump beyond the monotonic clock reported as ClockForwardJump,
+// it is 10 seconds by default.
+func WithForwardJumpThreshold(d time.Duration) Option {
+	return func(g *Generator) error {
+		if d <= 0 {
+			return fmt.Errorf("snowflake: invalid option WithForwardJumpThreshold(%s): the threshold must be positive", d)
+		}
+		g.forwardJumpThreshold = d
+
+		return nil
+	}
+}
+
+// OnClockEvent register fn to be called when the generator detects a clock anomaly.
+// The hooks are called one event at a time by a goroutine of the generator, outside any lock,
+// a slow hook never blocks ID generation: the events are dropped when too many are queued.
+// This function is thread safe.
+func (g *Generator) OnClockEvent(fn func(ev ClockEvent)) {
+	g.clockHooksMu.Lock()
+	defer g.clockHooksMu.Unlock()
+
+	g.clockHooks = append(g.clockHooks, fn)
+	if g.clockEvents == nil {
+		g.clockEvents = make(chan ClockEvent, clockEventQueue)
+		go g.dispatchClockEvents(g.clockEvents)
+		atomic.StoreInt32(&g.clockHooked, 1)
+	}
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// emitClockEvent queue the event for the hooks, it never blocks.
+func (g *Generator) emitClockEvent(ev ClockEvent) {
+	if atomic.LoadInt32(&g.clockHooked) == 0 {
+		return
+	}
+
+	g.clockHooksMu.Lock()
+	events := g.clockEvents
+	g.clockHooksMu.Unlock()
+
+	select {
+	case events <- ev:
+	default:
+		// 队列已满，丢弃事件
+	}
+}
+
+func (g *Generator) dispatchClockEvents(events chan ClockEvent) {
+	for {
+		select {
+		case ev := <-events:
+			g.clockHooksMu.Lock()
+			hooks := g.clockHooks
+			g.clockHooksMu.Unlock()
+
+			for _, fn := range hooks {
+				fn(ev)
+			}
+		case <-g.done:
+			return
+		}
+	}
+}
+
+// detectForwardJump compare the wall clock and the monotonic clock elapsed since the previous call.
+func (g *Generator) detectForwardJump() {
+	if atomic.LoadInt32(&g.clockHooked) == 0 {
+		return
+	}
+
+	now := g.clock.Now()
+	wall, mono := now.UnixNano(), int64(time.Since(monoBase))
+	prevWall := atomic.SwapInt64(&g.lastWall, wall)
+	prevMono := atomic.SwapInt64(&g.lastMono, mono)
+	if prevWall == 0 {
+		return
+	}
+
+	if jump := time.Duration((wall - prevWall) - (mono - prevMono)); jump > g.forwardJumpThreshold {
+		g.emitClockEvent(ClockEvent{Kind: ClockForwardJump, Delta: jump, Time: now})
+	}
+}
@@ -0,0 +1,106 @@
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/clocktest"
+)
+
+func newClockEventGenerator(t *testing.T, clock *clocktest.Manual, opts ...snowflake.Option) (*snowflake.Generator, chan snowflake.ClockEvent) {
+	t.Helper()
+
+	g, err := snowflake.New(append([]snowflake.Option{snowflake.WithClock(clock)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan snowflake.ClockEvent, 10)
+	g.OnClockEvent(func(ev snowflake.ClockEvent) {
+		events <- ev
+	})
+
+	return g, events
+}
+
+func receiveClockEvent(t *testing.T, events chan snowflake.ClockEvent) snowflake.ClockEvent {
+	t.Helper()
+
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("The clock event should be reported")
+		return snowflake.ClockEvent{}
+	}
+}
+
+func TestOnClockEvent_Backward(t *testing.T) {
+	clock := clocktest.NewManual(time.Now())
+	g, events := newClockEventGenerator(t, clock, snowflake.WithMaxBackwardTolerance(0))
+
+	g.ID()
+	clock.Advance(-10 * time.Millisecond)
+	if _, err := g.NextID(); err == nil {
+		t.Fatal("NextID should refuse the backward clock")
+	}
+
+	ev := receiveClockEvent(t, events)
+	if ev.Kind != snowflake.ClockBackward || ev.Delta != 10*time.Millisecond || ev.Action != snowflake.BackwardPolicyError {
+		t.Errorf("The backward event should report the delta and the action, got %+v", ev)
+	}
+
+	g, events = newClockEventGenerator(t, clock, snowflake.WithBackwardPolicy(snowflake.BackwardPolicyLogical))
+	g.ID()
+	clock.Advance(-time.Millisecond)
+	g.ID()
+	if ev := receiveClockEvent(t, events); ev.Action != snowflake.BackwardPolicyLogical {
+		t.Errorf("The backward event should report the logical clock, got %+v", ev)
+	}
+}
+
+func TestOnClockEvent_ForwardJump(t *testing.T) {
+	clock := clocktest.NewManual(time.Now())
+	g, events := newClockEventGenerator(t, clock, snowflake.WithForwardJumpThreshold(time.Second))
+
+	g.ID()
+	clock.Advance(500 * time.Millisecond)
+	g.ID()
+	clock.Advance(time.Minute)
+	g.ID()
+
+	ev := receiveClockEvent(t, events)
+	if ev.Kind != snowflake.ClockForwardJump || ev.Delta < 59*time.Second || ev.Delta > time.Minute {
+		t.Errorf("The forward jump should be reported, got %+v", ev)
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("Only the jump beyond the threshold should be reported, got %+v", ev)
+	default:
+	}
+}
+
+func TestOnClockEvent_SlowHook(t *testing.T) {
+	clock := clocktest.NewManual(time.Now())
+	g, err := snowflake.New(snowflake.WithClock(clock), snowflake.WithMaxBackwardTolerance(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	g.OnClockEvent(func(ev snowflake.ClockEvent) {
+		<-release
+	})
+
+	g.ID()
+	clock.Advance(-time.Millisecond)
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		_, _ = g.NextID()
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("A slow hook should not block ID generation, took %s", elapsed)
+	}
+}
@@ -0,0 +1,193 @@
+package snowflake
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ClockSyncNTPServer is the NTP server queried by CheckClockSync when the kernel sync status is not available.
+var ClockSyncNTPServer = "pool.ntp.org:123"
+
+// ClockSyncMaxOffset is the max offset from the NTP server CheckClockSync accepts.
+var ClockSyncMaxOffset = 100 * time.Millisecond
+
+// errSyncStatusUnavailable is returned by kernelClockSynced when the platform has no kernel sync status.
+var errSyncStatusUnavailable = errors.New("the kernel clock sync status is not available")
+
+// clockSyncTimeout bounds a check of WithRequireSyncedClock, which doesn't run under the context of a caller.
+const clockSyncTimeout = 5 * time.Second
+
+// ntpEpochOffset is the number of seconds from 1900-01-01 (NTP epoch) to 1970-01-01 (unix epoch).
+const ntpEpochOffset = 2208988800
+
+// CheckClockSync check the system clock is synchronized, it returns an error wrapping ErrClockUnsynchronized when it is not.
+// On linux it reads the kernel status with adjtimex, on the other platforms it queries ClockSyncNTPServer once
+// and requires the offset to be within ClockSyncMaxOffset.
+func CheckClockSync(ctx context.Context) error {
+	synced, err := kernelClockSynced()
+	if err == nil {
+		if !synced {
+			return fmt.Errorf("%w: the kernel reports the clock is not synchronized", ErrClockUnsynchronized)
+		}
+		return nil
+	}
+	if err != errSyncStatusUnavailable {
+		return err
+	}
+
+	offset, err := queryNTPOffset(ctx, ClockSyncNTPServer)
+	if err != nil {
+		return fmt.Errorf("snowflake: cannot query the NTP server %s: %w", ClockSyncNTPServer, err)
+	}
+	if offset > ClockSyncMaxOffset || offset < -ClockSyncMaxOffset {
+		return fmt.Errorf("%w: the clock is off by %s from %s", ErrClockUnsynchronized, offset, ClockSyncNTPServer)
+	}
+
+	return nil
+}
+
+// WithRequireSyncedClock make NextID fail with ErrClockUnsynchronized until CheckClockSync passes,
+// the result of the check is cached for ttl so the check doesn't run on every ID.
+// The check runs once for all the callers in the background, bounded by 5 seconds, a caller whose context is done
+// stops waiting for it. A check which timed out is not cached, the next NextID runs it again.
+func WithRequireSyncedClock(ttl time.Duration) Option {
+	return func(g *Generator) error {
+		if ttl <= 0 {
+			return fmt.Errorf("snowflake: invalid option WithRequireSyncedClock(%s): the ttl must be positive", ttl)
+		}
+		g.clockSync = &clockSync{check: CheckClockSync, ttl: ttl}
+
+		return nil
+	}
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// clockSync caches the result of the clock sync check.
+type clockSync struct {
+	check func(ctx context.Context) error
+	ttl   time.Duration
+
+	// expires is the unix nanoseconds when the cached result expires, accessed atomically.
+	expires int64
+	// cached is the clockSyncResult of the last check.
+	cached atomic.Value
+
+	// mu guards inflight, the running check, nil when no check runs.
+	mu       sync.Mutex
+	inflight *clockSyncCall
+}
+
+type clockSyncResult struct {
+	err error
+}
+
+// clockSyncCall is a running check, done is closed once err is set.
+type clockSyncCall struct {
+	done chan struct{}
+	err  error
+}
+
+// result returns the cached result of the check, it starts the check once the result expired,
+// or waits for the running one, until ctx is done.
+func (c *clockSync) result(ctx context.Context, now time.Time) error {
+	if now.UnixNano() < atomic.LoadInt64(&c.expires) {
+		return c.cached.Load().(clockSyncResult).err
+	}
+
+	c.mu.Lock()
+	// 其它 goroutine 可能已经完成检查
+	if now.UnixNano() < atomic.LoadInt64(&c.expires) {
+		c.mu.Unlock()
+		return c.cached.Load().(clockSyncResult).err
+	}
+	call := c.inflight
+	if call == nil {
+		call = &clockSyncCall{done: make(chan struct{})}
+		c.inflight = call
+		go c.run(call, now)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run the check under its own timeout, so a caller giving up doesn't fail the check for the others.
+func (c *clockSync) run(call *clockSyncCall, now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), clockSyncTimeout)
+	err := c.check(ctx)
+	cancel()
+
+	c.mu.Lock()
+	// 超时不缓存，下次调用重新检查
+	if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		c.cached.Store(clockSyncResult{err})
+		atomic.StoreInt64(&c.expires, now.Add(c.ttl).UnixNano())
+	}
+	c.inflight = nil
+	c.mu.Unlock()
+
+	call.err = err
+	close(call.done)
+}
+
+// queryNTPOffset returns the offset of the local clock from the NTP server, positive when the local clock is behind.
+func queryNTPOffset(ctx context.Context, server string) (time.Duration, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return 0, err
+	}
+
+	// LI = 0, VN = 4, Mode = 3 (client)
+	req := make([]byte, 48)
+	req[0] = 0x23
+	t1 := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+
+	resp := make([]byte, 48)
+	if _, err := conn.Read(resp); err != nil {
+		return 0, err
+	}
+	t4 := time.Now()
+
+	if resp[0]>>6 == 3 || resp[1] == 0 {
+		return 0, fmt.Errorf("%w: the NTP server is not synchronized", ErrClockUnsynchronized)
+	}
+
+	t2, t3 := ntpTime(resp[32:40]), ntpTime(resp[40:48])
+
+	return (t2.Sub(t1) + t3.Sub(t4)) / 2, nil
+}
+
+// ntpTime decode a 64 bits NTP timestamp.
+func ntpTime(b []byte) time.Time {
+	sec := int64(binary.BigEndian.Uint32(b[:4])) - ntpEpochOffset
+	frac := int64(binary.BigEndian.Uint32(b[4:]))
+
+	return time.Unix(sec, frac*int64(time.Second)>>32)
+}
@@ -0,0 +1,20 @@
+package snowflake
+
+import "syscall"
+
+// timeError is the adjtimex state of an unsynchronized clock, STA_UNSYNC is its status flag.
+const (
+	timeError = 5
+	staUnsync = 0x0040
+)
+
+// kernelClockSynced read the kernel clock sync status with adjtimex.
+func kernelClockSynced() (bool, error) {
+	var tx syscall.Timex
+	state, err := syscall.Adjtimex(&tx)
+	if err != nil {
+		return false, err
+	}
+
+	return state != timeError && tx.Status&staUnsync == 0, nil
+}
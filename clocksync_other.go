@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package snowflake
+
+// kernelClockSynced returns errSyncStatusUnavailable, only linux exposes the kernel clock sync status.
+func kernelClockSynced() (bool, error) {
+	return false, errSyncStatusUnavailable
+}
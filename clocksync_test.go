@@ -0,0 +1,183 @@
+package snowflake_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/clocktest"
+)
+
+// fakeNTPServer answers NTP requests with the current time moved by offset.
+func fakeNTPServer(t *testing.T, offset time.Duration) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 48)
+		for {
+			_, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			now := time.Now().Add(offset)
+			sec := uint32(now.Unix() + 2208988800)
+			frac := uint32(uint64(now.Nanosecond()) << 32 / uint64(time.Second))
+
+			resp := make([]byte, 48)
+			resp[0], resp[1] = 0x24, 2 // LI = 0, VN = 4, Mode = 4 (server), stratum 2
+			for _, off := range []int{32, 40} {
+				binary.BigEndian.PutUint32(resp[off:], sec)
+				binary.BigEndian.PutUint32(resp[off+4:], frac)
+			}
+			_, _ = conn.WriteTo(resp, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestQueryNTPOffset(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	offset, err := snowflake.QueryNTPOffset(ctx, fakeNTPServer(t, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset < -10*time.Millisecond || offset > 10*time.Millisecond {
+		t.Errorf("The offset should be about 0, got %s", offset)
+	}
+
+	offset, err = snowflake.QueryNTPOffset(ctx, fakeNTPServer(t, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset < time.Hour-10*time.Millisecond || offset > time.Hour+10*time.Millisecond {
+		t.Errorf("The offset should be about 1h, got %s", offset)
+	}
+}
+
+func TestWithRequireSyncedClock(t *testing.T) {
+	clock := clocktest.NewManual(time.Now())
+	g, err := snowflake.New(snowflake.WithClock(clock), snowflake.WithRequireSyncedClock(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := 0
+	synced := false
+	snowflake.SetClockSyncCheck(g, func(ctx context.Context) error {
+		checks++
+		if !synced {
+			return snowflake.ErrClockUnsynchronized
+		}
+		return nil
+	})
+
+	if _, err := g.NextID(); !errors.Is(err, snowflake.ErrClockUnsynchronized) {
+		t.Fatalf("NextID should fail until the clock is synchronized, got %v", err)
+	}
+
+	// the failure is cached for the ttl.
+	synced = true
+	if _, err := g.NextID(); !errors.Is(err, snowflake.ErrClockUnsynchronized) {
+		t.Errorf("The check result should be cached, got %v", err)
+	}
+
+	clock.Advance(time.Minute)
+	for i := 0; i < 10; i++ {
+		if _, err := g.NextID(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if checks != 2 {
+		t.Errorf("The check should run once per ttl, ran %d times", checks)
+	}
+
+	if _, err := snowflake.New(snowflake.WithRequireSyncedClock(0)); err == nil {
+		t.Error("The ttl must be positive")
+	}
+}
+
+func TestWithRequireSyncedClock_Cancel(t *testing.T) {
+	g, err := snowflake.New(snowflake.WithMachineID(1), snowflake.WithRequireSyncedClock(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var checks int32
+	release := make(chan struct{})
+	snowflake.SetClockSyncCheck(g, func(ctx context.Context) error {
+		atomic.AddInt32(&checks, 1)
+		<-release
+		return nil
+	})
+
+	// the caller gives up, the check keeps running for the others.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := g.NextIDContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("NextIDContext should return the error of its context, got %v", err)
+	}
+
+	// the callers waiting for the running check share it.
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := g.NextID()
+			errs <- err
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("The cancellation of another caller should not be cached, got %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&checks); n != 1 {
+		t.Errorf("The check should run once for all the callers, ran %d times", n)
+	}
+}
+
+func TestWithRequireSyncedClock_Timeout(t *testing.T) {
+	g, err := snowflake.New(snowflake.WithMachineID(1), snowflake.WithRequireSyncedClock(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := 0
+	snowflake.SetClockSyncCheck(g, func(ctx context.Context) error {
+		checks++
+		if checks == 1 {
+			return fmt.Errorf("cannot query the NTP server: %w", context.DeadlineExceeded)
+		}
+		return nil
+	})
+
+	if _, err := g.NextID(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("NextID should return the error of the check, got %v", err)
+	}
+	if _, err := g.NextID(); err != nil || checks != 2 {
+		t.Errorf("A timed out check should not be cached, got %v after %d checks", err, checks)
+	}
+}
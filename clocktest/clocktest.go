@@ -0,0 +1,56 @@
+// Package clocktest provides a manual snowflake.Clock for deterministic tests of clock backward,
+// sequence overflow and epoch overflow, without sleeping.
+package clocktest
+
+import (
+	"sync"
+	"time"
+)
+
+// Manual is a snowflake.Clock which only moves when told to, it is safe for concurrent use.
+type Manual struct {
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+// NewManual create a Manual clock at now.
+func NewManual(now time.Time) *Manual {
+	return &Manual{now: now}
+}
+
+// Now returns the current time of the clock, then advances it by the auto-advance step.
+func (m *Manual) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now
+	m.now = m.now.Add(m.step)
+
+	return now
+}
+
+// Advance move the clock by d, a negative d moves it backward.
+func (m *Manual) Advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = m.now.Add(d)
+}
+
+// Set move the clock to t.
+func (m *Manual) Set(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = t
+}
+
+// AutoAdvance make every Now advance the clock by step, so code waiting for the clock makes progress,
+// zero stops auto-advancing.
+func (m *Manual) AutoAdvance(step time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.step = step
+}
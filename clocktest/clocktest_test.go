@@ -0,0 +1,31 @@
+package clocktest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/clocktest"
+)
+
+var _ snowflake.Clock = (*clocktest.Manual)(nil)
+
+func TestManual(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := clocktest.NewManual(now)
+
+	if !m.Now().Equal(now) || !m.Now().Equal(now) {
+		t.Error("The clock should not move by itself")
+	}
+
+	m.Advance(-time.Second)
+	if !m.Now().Equal(now.Add(-time.Second)) {
+		t.Error("Advance should move the clock")
+	}
+
+	m.Set(now)
+	m.AutoAdvance(time.Millisecond)
+	if !m.Now().Equal(now) || !m.Now().Equal(now.Add(time.Millisecond)) {
+		t.Error("AutoAdvance should move the clock after every Now")
+	}
+}
@@ -0,0 +1,82 @@
+package cloudid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// ErrNotAzure is returned by the Azure sources when the instance metadata service cannot be reached,
+// i.e. the process doesn't run on an Azure VM, so the callers can fall back to another source.
+var ErrNotAzure = errors.New("cloudid: not running on Azure, the instance metadata service is unreachable")
+
+// AzureMetadataEndpoint is the endpoint of the instance metadata service, replace it e.g. with the address of a local emulator.
+// It is read by each call, set it before the machineID is resolved.
+var AzureMetadataEndpoint = "http://169.254.169.254"
+
+// azureInstance is the part of the instance metadata used by the Azure sources.
+type azureInstance struct {
+	Compute struct {
+		VMID string `json:"vmId"`
+	} `json:"compute"`
+	Network struct {
+		Interface []struct {
+			IPv4 struct {
+				IPAddress []struct {
+					PrivateIPAddress string `json:"privateIpAddress"`
+				} `json:"ipAddress"`
+			} `json:"ipv4"`
+		} `json:"interface"`
+	} `json:"network"`
+}
+
+// AzureVMID returns a machineID hashed from the unique ID of the Azure VM, see snowflake.HashMachineID.
+// The hashes of different VMs may collide, see snowflake.ClaimMachineID.
+func AzureVMID(ctx context.Context) (uint16, error) {
+	inst, err := azureMetadata(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if inst.Compute.VMID == "" {
+		return 0, errors.New("cloudid: the Azure instance metadata has no vmId")
+	}
+
+	return snowflake.HashMachineID([]byte(inst.Compute.VMID), snowflake.MachineIDLength), nil
+}
+
+// AzurePrivateIP returns the low bits of the first private IPv4 address of the first network interface of the Azure VM,
+// the machineIDs are unique as long as the VMs are in a subnet of 2^MachineIDLength addresses at most.
+func AzurePrivateIP(ctx context.Context) (uint16, error) {
+	inst, err := azureMetadata(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(inst.Network.Interface) == 0 || len(inst.Network.Interface[0].IPv4.IPAddress) == 0 {
+		return 0, errors.New("cloudid: the Azure instance metadata has no private IPv4 address")
+	}
+
+	return lowBits(inst.Network.Interface[0].IPv4.IPAddress[0].PrivateIPAddress)
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+func azureMetadata(ctx context.Context) (azureInstance, error) {
+	var inst azureInstance
+
+	header := http.Header{"Metadata": []string{"true"}}
+	body, err := get(ctx, AzureMetadataEndpoint+"/metadata/instance?api-version=2021-02-01", header, ErrNotAzure)
+	if err != nil {
+		return inst, err
+	}
+	if err := json.Unmarshal([]byte(body), &inst); err != nil {
+		return inst, fmt.Errorf("cloudid: decode the Azure instance metadata: %w", err)
+	}
+
+	return inst, nil
+}
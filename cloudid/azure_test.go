@@ -0,0 +1,69 @@
+package cloudid_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/cloudid"
+)
+
+const azureInstance = `{
+	"compute": {"vmId": "02aab8a4-74ef-476e-8182-f6d2ba4166a6", "location": "westeurope"},
+	"network": {"interface": [{"ipv4": {"ipAddress": [{"privateIpAddress": "10.1.2.9", "publicIpAddress": ""}]}}]}
+}`
+
+func newAzureMetadata(t *testing.T, instance string) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" || r.URL.Query().Get("api-version") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(instance))
+	}))
+	t.Cleanup(srv.Close)
+	cloudid.SetAzureEndpoint(t, srv.URL)
+}
+
+func TestAzure(t *testing.T) {
+	newAzureMetadata(t, azureInstance)
+
+	m, err := cloudid.AzurePrivateIP(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10.1.2.9 & 511 = 9
+	if m != 9 {
+		t.Errorf("The machineID should be the low bits of the private IP, got %d", m)
+	}
+
+	m, err = cloudid.AzureVMID(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := snowflake.HashMachineID([]byte("02aab8a4-74ef-476e-8182-f6d2ba4166a6"), snowflake.MachineIDLength); m != want {
+		t.Errorf("The machineID should be the hash of the vmId, got %d, want %d", m, want)
+	}
+}
+
+func TestAzure_NoAddress(t *testing.T) {
+	newAzureMetadata(t, `{"compute": {}, "network": {"interface": []}}`)
+
+	if _, err := cloudid.AzurePrivateIP(context.Background()); err == nil {
+		t.Error("The metadata without a private IP should be rejected")
+	}
+	if _, err := cloudid.AzureVMID(context.Background()); err == nil {
+		t.Error("The metadata without a vmId should be rejected")
+	}
+}
+
+func TestAzure_NotAzure(t *testing.T) {
+	cloudid.SetAzureEndpoint(t, closedEndpoint(t))
+
+	if _, err := cloudid.AzureVMID(context.Background()); !errors.Is(err, cloudid.ErrNotAzure) {
+		t.Errorf("The error should be ErrNotAzure, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+// Package cloudid derives snowflake machineIDs from the metadata services of the cloud providers.
+// The sources have the signature of snowflake.MachineIDFromEC2, they fold the machineID into
+// snowflake.MachineIDLength bits, the machine bits of the default layout:
+//
+//	m, err := cloudid.GCEInternalIP(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	g, err := snowflake.New(snowflake.WithMachineID(m))
+//
+// Each call to a metadata service times out after 1s, or at the deadline of the context if it is earlier.
+package cloudid
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// callTimeout is the timeout of each call to a metadata service, they answer within milliseconds in the cloud.
+const callTimeout = time.Second
+
+// client is the client of the metadata services. They are link-local, so it never goes through the proxy
+// of HTTP_PROXY or HTTPS_PROXY, which would see the metadata.
+var client = &http.Client{
+	Transport: &http.Transport{
+		Proxy:       nil,
+		DialContext: (&net.Dialer{Timeout: callTimeout}).DialContext,
+	},
+	Timeout: callTimeout,
+}
+
+// ErrNoSource is returned by FirstAvailable when every source failed.
+var ErrNoSource = errors.New("cloudid: no machineID source is available")
+
+// Source returns a machineID of the host, e.g. GCEInstanceID or snowflake.MachineIDFromEC2.
+type Source func(ctx context.Context) (uint16, error)
+
+// FirstAvailable returns the machineID of the first source which succeeds, so a binary deployed on several clouds
+// can find its machineID wherever it runs:
+//
+//	m, err := cloudid.FirstAvailable(ctx, snowflake.MachineIDFromEC2, cloudid.GCEInternalIP, cloudid.AzurePrivateIP)
+//
+// The sources are tried in order, each one fails fast outside its cloud. When every source failed,
+// the error wraps ErrNoSource and lists the error of each source.
+func FirstAvailable(ctx context.Context, sources ...Source) (uint16, error) {
+	errs := make([]string, 0, len(sources))
+	for _, src := range sources {
+		m, err := src(ctx)
+		if err == nil {
+			return m, nil
+		}
+		errs = append(errs, err.Error())
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %s", ErrNoSource, strings.Join(errs, "; "))
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// get the metadata at url with the headers, a transport error is wrapped in notAvailable.
+func get(ctx context.Context, url string, header http.Header, notAvailable error) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header = header
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// 连接失败或超时，视为不在该云上运行
+		return "", fmt.Errorf("%w: %v", notAvailable, err)
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("cloudid: read %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("cloudid: GET %s returned %s", url, resp.Status)
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// lowBits returns the low MachineIDLength bits of the IPv4 address ip.
+func lowBits(ip string) (uint16, error) {
+	v4 := net.ParseIP(ip).To4()
+	if v4 == nil {
+		return 0, fmt.Errorf("cloudid: invalid IPv4 address %q", ip)
+	}
+
+	return uint16(binary.BigEndian.Uint32(v4) & (1<<snowflake.MachineIDLength - 1)), nil
+}
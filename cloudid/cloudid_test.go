@@ -0,0 +1,55 @@
+package cloudid_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hedwi/go-snowflake/cloudid"
+)
+
+func TestFirstAvailable(t *testing.T) {
+	cloudid.SetGCEEndpoint(t, closedEndpoint(t))
+	newAzureMetadata(t, azureInstance)
+
+	m, err := cloudid.FirstAvailable(context.Background(), cloudid.GCEInternalIP, cloudid.AzurePrivateIP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != 9 {
+		t.Errorf("The machineID of the first available source should be returned, got %d", m)
+	}
+
+	cloudid.SetAzureEndpoint(t, closedEndpoint(t))
+	_, err = cloudid.FirstAvailable(context.Background(), cloudid.GCEInternalIP, cloudid.AzurePrivateIP)
+	if !errors.Is(err, cloudid.ErrNoSource) {
+		t.Fatalf("The error should be ErrNoSource, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "GCE") || !strings.Contains(err.Error(), "Azure") {
+		t.Errorf("The error should list the error of each source, got %v", err)
+	}
+}
+
+func TestFirstAvailable_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	src := func(ctx context.Context) (uint16, error) {
+		calls++
+		return 0, ctx.Err()
+	}
+	if _, err := cloudid.FirstAvailable(ctx, src, src); !errors.Is(err, cloudid.ErrNoSource) {
+		t.Errorf("The error should be ErrNoSource, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("The sources should not be tried once the context is done, got %d calls", calls)
+	}
+}
+
+func TestClient_NoProxy(t *testing.T) {
+	if cloudid.ClientProxied() {
+		t.Error("The metadata requests should not go through the proxy of the environment, and should time out")
+	}
+}
@@ -0,0 +1,31 @@
+package cloudid
+
+import (
+	"net/http"
+	"testing"
+)
+
+// SetGCEEndpoint replace the endpoint of the GCE metadata server until the test ends.
+func SetGCEEndpoint(t *testing.T, endpoint string) {
+	old := GCEMetadataEndpoint
+	GCEMetadataEndpoint = endpoint
+	t.Cleanup(func() {
+		GCEMetadataEndpoint = old
+	})
+}
+
+// SetAzureEndpoint replace the endpoint of the Azure instance metadata service until the test ends.
+func SetAzureEndpoint(t *testing.T, endpoint string) {
+	old := AzureMetadataEndpoint
+	AzureMetadataEndpoint = endpoint
+	t.Cleanup(func() {
+		AzureMetadataEndpoint = old
+	})
+}
+
+// ClientProxied reports whether the client of the metadata services may use a proxy.
+func ClientProxied() bool {
+	t, ok := client.Transport.(*http.Transport)
+
+	return !ok || t.Proxy != nil || client.Timeout == 0
+}
@@ -0,0 +1,49 @@
+package cloudid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// ErrNotGCE is returned by the GCE sources when the metadata server cannot be reached,
+// i.e. the process doesn't run on GCE or GKE, so the callers can fall back to another source.
+var ErrNotGCE = errors.New("cloudid: not running on GCE, the metadata server is unreachable")
+
+// GCEMetadataEndpoint is the endpoint of the metadata server, replace it e.g. with the address of a local emulator.
+// It is read by each call, set it before the machineID is resolved.
+var GCEMetadataEndpoint = "http://metadata.google.internal"
+
+// GCEInstanceID returns a machineID hashed from the numeric ID of the GCE instance, see snowflake.HashMachineID.
+// The hashes of different instances may collide, see snowflake.ClaimMachineID.
+func GCEInstanceID(ctx context.Context) (uint16, error) {
+	id, err := gceMetadata(ctx, "instance/id")
+	if err != nil {
+		return 0, err
+	}
+
+	return snowflake.HashMachineID([]byte(id), snowflake.MachineIDLength), nil
+}
+
+// GCEInternalIP returns the low bits of the internal IPv4 address of the first network interface of the GCE instance,
+// the machineIDs are unique as long as the instances are in a subnet of 2^MachineIDLength addresses at most.
+func GCEInternalIP(ctx context.Context) (uint16, error) {
+	ip, err := gceMetadata(ctx, "instance/network-interfaces/0/ip")
+	if err != nil {
+		return 0, err
+	}
+
+	return lowBits(ip)
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+func gceMetadata(ctx context.Context, path string) (string, error) {
+	header := http.Header{"Metadata-Flavor": []string{"Google"}}
+
+	return get(ctx, GCEMetadataEndpoint+"/computeMetadata/v1/"+path, header, ErrNotGCE)
+}
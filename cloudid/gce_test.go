@@ -0,0 +1,96 @@
+package cloudid_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/cloudid"
+)
+
+// closedEndpoint returns the endpoint of a closed port, like a metadata service outside its cloud.
+func closedEndpoint(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	return "http://" + addr
+}
+
+func newGCEMetadata(t *testing.T, metadata map[string]string) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		v, ok := metadata[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(v))
+	}))
+	t.Cleanup(srv.Close)
+	cloudid.SetGCEEndpoint(t, srv.URL)
+}
+
+func TestGCE(t *testing.T) {
+	newGCEMetadata(t, map[string]string{
+		"/computeMetadata/v1/instance/id":                      "4520031799277581759",
+		"/computeMetadata/v1/instance/network-interfaces/0/ip": "10.128.1.5",
+	})
+
+	m, err := cloudid.GCEInternalIP(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10.128.1.5 & 511 = 256 + 5
+	if m != 261 {
+		t.Errorf("The machineID should be the low bits of the internal IP, got %d", m)
+	}
+
+	m, err = cloudid.GCEInstanceID(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := snowflake.HashMachineID([]byte("4520031799277581759"), snowflake.MachineIDLength); m != want {
+		t.Errorf("The machineID should be the hash of the instance ID, got %d, want %d", m, want)
+	}
+}
+
+func TestGCE_NotGCE(t *testing.T) {
+	cloudid.SetGCEEndpoint(t, closedEndpoint(t))
+
+	if _, err := cloudid.GCEInstanceID(context.Background()); !errors.Is(err, cloudid.ErrNotGCE) {
+		t.Errorf("The error should be ErrNotGCE, got %v", err)
+	}
+}
+
+func TestGCE_Deadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+	cloudid.SetGCEEndpoint(t, srv.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := cloudid.GCEInternalIP(ctx); !errors.Is(err, cloudid.ErrNotGCE) {
+		t.Errorf("A timeout should be ErrNotGCE, got %v", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("The deadline of the context should be respected, took %s", d)
+	}
+}
@@ -0,0 +1,224 @@
+package snowflake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Config is the generator configuration, it can be loaded from a JSON file with LoadConfig.
+// The zero value of every field means the default, the yaml tags allow loading it with any YAML library.
+// The IDs are pointers, nil means unset and 0 is a valid ID.
+//
+//	{
+//		"machine_id": 42,
+//		"epoch": "2020-01-01T00:00:00Z",
+//		"layout": {"timestamp_bits": 41, "machine_bits": 10, "sequence_bits": 12},
+//		"backward_tolerance": "500ms"
+//	}
+type Config struct {
+	// MachineID is the machine ID, it cannot be used with DatacenterID or WorkerID.
+	MachineID    *uint16 `json:"machine_id,omitempty" yaml:"machine_id,omitempty"`
+	DatacenterID *uint16 `json:"datacenter_id,omitempty" yaml:"datacenter_id,omitempty"`
+	WorkerID     *uint16 `json:"worker_id,omitempty" yaml:"worker_id,omitempty"`
+
+	// Epoch is the start time in RFC3339 format, e.g. 2020-01-01T00:00:00Z.
+	Epoch string `json:"epoch,omitempty" yaml:"epoch,omitempty"`
+
+	// Layout is the bit layout, all widths zero means DefaultLayout.
+	Layout LayoutConfig `json:"layout,omitempty" yaml:"layout,omitempty"`
+
+	// TimeUnit is the tick duration, e.g. "10ms".
+	TimeUnit Duration `json:"time_unit,omitempty" yaml:"time_unit,omitempty"`
+
+	// BackwardTolerance is the max clock backward the generator waits for, e.g. "500ms".
+	BackwardTolerance *Duration `json:"backward_tolerance,omitempty" yaml:"backward_tolerance,omitempty"`
+
+	// Descending stores MaxTimestamp - elapsed in the timestamp part, see WithDescending.
+	Descending bool `json:"descending,omitempty" yaml:"descending,omitempty"`
+}
+
+// LayoutConfig is the Layout part of Config.
+type LayoutConfig struct {
+	TimestampBits  uint8      `json:"timestamp_bits,omitempty" yaml:"timestamp_bits,omitempty"`
+	MachineBits    uint8      `json:"machine_bits,omitempty" yaml:"machine_bits,omitempty"`
+	SequenceBits   uint8      `json:"sequence_bits,omitempty" yaml:"sequence_bits,omitempty"`
+	DatacenterBits uint8      `json:"datacenter_bits,omitempty" yaml:"datacenter_bits,omitempty"`
+	Order          FieldOrder `json:"order,omitempty" yaml:"order,omitempty"`
+}
+
+// Duration is a time.Duration which is encoded as a string like "500ms" in JSON,
+// a JSON number is accepted as nanoseconds.
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case float64:
+		*d = Duration(v)
+	case string:
+		p, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(p)
+	default:
+		return fmt.Errorf("invalid duration %s", b)
+	}
+
+	return nil
+}
+
+// String implements flag.Value.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// Set implements flag.Value, it accepts a duration like "500ms".
+func (d *Duration) Set(s string) error {
+	p, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(p)
+
+	return nil
+}
+
+// LoadConfig read a JSON config from r, unknown fields are rejected to catch typos.
+func LoadConfig(r io.Reader) (Config, error) {
+	var c Config
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return Config{}, fmt.Errorf("snowflake: invalid config: %w", err)
+	}
+
+	return c, nil
+}
+
+// NewFromConfig create a Generator from the config, a validation error names the offending field.
+func NewFromConfig(c Config) (*Generator, error) {
+	opts, err := c.options()
+	if err != nil {
+		return nil, err
+	}
+
+	return New(opts...)
+}
+
+// Build validate the config and create a Generator, it is NewFromConfig(c).
+func (c Config) Build() (*Generator, error) {
+	return NewFromConfig(c)
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+func (c Config) options() ([]Option, error) {
+	var opts []Option
+
+	layout := DefaultLayout
+	if c.Layout != (LayoutConfig{}) {
+		layout = Layout{
+			TimestampBits:  c.Layout.TimestampBits,
+			MachineBits:    c.Layout.MachineBits,
+			SequenceBits:   c.Layout.SequenceBits,
+			DatacenterBits: c.Layout.DatacenterBits,
+			Order:          c.Layout.Order,
+		}
+		if err := layout.Validate(); err != nil {
+			return nil, configFieldError("layout", err)
+		}
+		opts = append(opts, WithLayout(layout))
+	}
+
+	unit := time.Millisecond
+	if c.TimeUnit != 0 {
+		unit = time.Duration(c.TimeUnit)
+		if unit <= 0 {
+			return nil, configFieldError("time_unit", fmt.Errorf("the time unit must be positive, got %s", unit))
+		}
+		opts = append(opts, WithTimeUnit(unit))
+	}
+
+	if c.Epoch != "" {
+		epoch, err := time.Parse(time.RFC3339, c.Epoch)
+		if err != nil {
+			return nil, configFieldError("epoch", err)
+		}
+		if err := checkStartTime(epoch, layout, unit, time.Now()); err != nil {
+			return nil, configFieldError("epoch", err)
+		}
+		opts = append(opts, WithStartTime(epoch))
+	}
+
+	if c.MachineID != nil {
+		if c.DatacenterID != nil || c.WorkerID != nil {
+			return nil, configFieldError("machine_id", fmt.Errorf("cannot be used with datacenter_id or worker_id"))
+		}
+		if err := layout.checkMachineID(*c.MachineID); err != nil {
+			return nil, configFieldError("machine_id", err)
+		}
+		opts = append(opts, WithMachineID(*c.MachineID))
+	}
+
+	if c.DatacenterID != nil {
+		if err := layout.checkDatacenterID(*c.DatacenterID); err != nil {
+			return nil, configFieldError("datacenter_id", err)
+		}
+		opts = append(opts, WithDatacenterID(*c.DatacenterID))
+	}
+
+	if c.WorkerID != nil {
+		if err := layout.checkWorkerID(*c.WorkerID); err != nil {
+			return nil, configFieldError("worker_id", err)
+		}
+		opts = append(opts, WithWorkerID(*c.WorkerID))
+	}
+
+	if c.BackwardTolerance != nil {
+		d := time.Duration(*c.BackwardTolerance)
+		if d < 0 {
+			return nil, configFieldError("backward_tolerance", fmt.Errorf("the tolerance cannot be negative, got %s", d))
+		}
+		opts = append(opts, WithMaxBackwardTolerance(d))
+	}
+
+	if c.Descending {
+		opts = append(opts, WithDescending())
+	}
+
+	return opts, nil
+}
+
+// fieldError is a validation error of a config field.
+type fieldError struct {
+	field string
+	err   error
+}
+
+func (e *fieldError) Error() string {
+	return fmt.Sprintf("snowflake: invalid config field %s: %v", e.field, e.err)
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+func configFieldError(field string, err error) error {
+	return &fieldError{field: field, err: err}
+}
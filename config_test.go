@@ -0,0 +1,124 @@
+package snowflake_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestLoadConfig(t *testing.T) {
+	c, err := snowflake.LoadConfig(strings.NewReader(`{
+		"machine_id": 1000,
+		"epoch": "2020-01-01T00:00:00Z",
+		"layout": {"timestamp_bits": 41, "machine_bits": 10, "sequence_bits": 12, "order": "timestamp-sequence-machine"},
+		"time_unit": "1ms",
+		"backward_tolerance": "500ms",
+		"descending": true
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.MachineID == nil || *c.MachineID != 1000 || c.Epoch != "2020-01-01T00:00:00Z" || c.Layout.Order != snowflake.OrderTimestampSequenceMachine ||
+		time.Duration(*c.BackwardTolerance) != 500*time.Millisecond || !c.Descending {
+		t.Errorf("The config should be loaded, got %+v", c)
+	}
+
+	g, err := snowflake.NewFromConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layout := snowflake.Layout{TimestampBits: 41, MachineBits: 10, SequenceBits: 12, Order: snowflake.OrderTimestampSequenceMachine}
+	if g.Layout() != layout {
+		t.Error("The layout should be equal", layout)
+	}
+
+	sid := g.ParseID(g.ID())
+	if sid.MachineID != 1000 {
+		t.Error("MachineID should be equal 1000")
+	}
+	if d := time.Since(sid.GenerateTime()); d < 0 || d > time.Second {
+		t.Error("The id generate time should be equal current time", sid.GenerateTime())
+	}
+}
+
+// checkExplicitMachineIDZero check the generator uses the machineID 0 as an explicit machineID, not a random one.
+func checkExplicitMachineIDZero(t *testing.T, g *snowflake.Generator, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.RequireExplicitMachineID()
+	id, err := g.NextID()
+	if err != nil {
+		t.Fatalf("The machineID 0 should be explicit, got %v", err)
+	}
+	if sid := g.ParseID(id); sid.MachineID != 0 || g.Stats().UnmanagedMachineID {
+		t.Errorf("The machineID should be 0, got %d, unmanaged %v", sid.MachineID, g.Stats().UnmanagedMachineID)
+	}
+}
+
+func TestLoadConfig_MachineIDZero(t *testing.T) {
+	c, err := snowflake.LoadConfig(strings.NewReader(`{"machine_id": 0}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MachineID == nil || *c.MachineID != 0 {
+		t.Fatalf("The machineID 0 should be set, got %v", c.MachineID)
+	}
+
+	g, err := snowflake.NewFromConfig(c)
+	checkExplicitMachineIDZero(t, g, err)
+}
+
+func TestLoadConfig_Duration(t *testing.T) {
+	c, err := snowflake.LoadConfig(strings.NewReader(`{"time_unit": 10000000, "backward_tolerance": "0s"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Duration(c.TimeUnit) != 10*time.Millisecond || time.Duration(*c.BackwardTolerance) != 0 {
+		t.Errorf("The durations should be loaded, got %+v", c)
+	}
+
+	if _, err := snowflake.LoadConfig(strings.NewReader(`{"time_unit": "10 parsecs"}`)); err == nil {
+		t.Error("An invalid duration should be rejected")
+	}
+}
+
+func TestNewFromConfig_Invalid(t *testing.T) {
+	cases := []struct {
+		json  string
+		field string
+	}{
+		{`{"machine_idd": 1}`, "machine_idd"},
+		{`{"machine_id": 512}`, "machine_id"},
+		{`{"machine_id": 1000, "layout": {"timestamp_bits": 42, "machine_bits": 9, "sequence_bits": 12}}`, "machine_id"},
+		{`{"machine_id": 1, "worker_id": 1}`, "machine_id"},
+		{`{"datacenter_id": 1}`, "datacenter_id"},
+		{`{"worker_id": 128, "layout": {"timestamp_bits": 42, "machine_bits": 9, "sequence_bits": 12, "datacenter_bits": 2}}`, "worker_id"},
+		{`{"epoch": "2020-01-01"}`, "epoch"},
+		{`{"epoch": "2999-01-01T00:00:00Z"}`, "epoch"},
+		{`{"layout": {"timestamp_bits": 43, "machine_bits": 9, "sequence_bits": 12}}`, "layout"},
+		{`{"layout": {"timestamp_bits": 42, "machine_bits": 9, "sequence_bits": 12, "order": "sequence-first"}}`, "order"},
+		{`{"time_unit": "-1ms"}`, "time_unit"},
+		{`{"backward_tolerance": "-1s"}`, "backward_tolerance"},
+	}
+
+	for _, c := range cases {
+		cfg, err := snowflake.LoadConfig(strings.NewReader(c.json))
+		if err == nil {
+			_, err = snowflake.NewFromConfig(cfg)
+		}
+		if err == nil {
+			t.Errorf("%s: should return an error", c.json)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.field) {
+			t.Errorf("%s: the error should name the field %s, got %v", c.json, c.field, err)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Package consullease leases snowflake machineIDs from consul, so the processes get a unique machineID at startup
+// without a static assignment:
+//
+//	lease, err := consullease.Allocate(ctx, consulClient{client}, consullease.Options{})
+//	if err != nil {
+//		return err
+//	}
+//	g, err := snowflake.New(snowflake.WithMachineIDLease(lease))
+//
+// The lease is a key per machineID, snowflake/machine-ids/{machineID}, acquired by a consul session with a TTL,
+// the session is renewed in the background and destroyed by Close, which deletes the key.
+package consullease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/internal/leasekeeper"
+)
+
+const (
+	defaultPrefix = "snowflake/machine-ids/"
+	defaultTTL    = 15 * time.Second
+)
+
+// ErrNoFreeMachineID is returned by Allocate when every machineID up to Options.MaxMachineID is leased.
+var ErrNoFreeMachineID = errors.New("consullease: no free machineID")
+
+// ErrSessionNotFound must be returned, or wrapped, by Client.RenewSession when the session was invalidated,
+// e.g. when consul returns no session entry.
+var ErrSessionNotFound = errors.New("consullease: the consul session is not found")
+
+// Client is the consul client used by Allocate, implement it with github.com/hashicorp/consul/api.
+// The sessions must be created with the delete behavior, so the key of an invalidated session is deleted:
+//
+//	type consulClient struct{ c *api.Client }
+//
+//	func (c consulClient) CreateSession(ctx context.Context, ttl time.Duration) (string, error) {
+//		id, _, err := c.c.Session().Create(&api.SessionEntry{
+//			TTL:      ttl.String(),
+//			Behavior: api.SessionBehaviorDelete,
+//		}, (&api.WriteOptions{}).WithContext(ctx))
+//		return id, err
+//	}
+//
+//	func (c consulClient) RenewSession(ctx context.Context, session string) error {
+//		entry, _, err := c.c.Session().Renew(session, (&api.WriteOptions{}).WithContext(ctx))
+//		if err == nil && entry == nil {
+//			return consullease.ErrSessionNotFound
+//		}
+//		return err
+//	}
+//
+//	func (c consulClient) DestroySession(ctx context.Context, session string) error {
+//		_, err := c.c.Session().Destroy(session, (&api.WriteOptions{}).WithContext(ctx))
+//		return err
+//	}
+//
+//	func (c consulClient) Keys(ctx context.Context, prefix string) ([]string, error) {
+//		keys, _, err := c.c.KV().Keys(prefix, "", (&api.QueryOptions{}).WithContext(ctx))
+//		return keys, err
+//	}
+//
+//	func (c consulClient) Acquire(ctx context.Context, key, value, session string) (bool, error) {
+//		ok, _, err := c.c.KV().Acquire(&api.KVPair{Key: key, Value: []byte(value), Session: session},
+//			(&api.WriteOptions{}).WithContext(ctx))
+//		return ok, err
+//	}
+type Client interface {
+	CreateSession(ctx context.Context, ttl time.Duration) (string, error)
+	RenewSession(ctx context.Context, session string) error
+	DestroySession(ctx context.Context, session string) error
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	Acquire(ctx context.Context, key, value, session string) (bool, error)
+}
+
+// Options configure Allocate, the zero value uses the defaults.
+type Options struct {
+	// Prefix is the prefix of the keys, "snowflake/machine-ids/" by default.
+	Prefix string
+	// MaxMachineID is the max machineID leased, snowflake.MaxMachineID by default.
+	MaxMachineID uint16
+	// TTL is the TTL of the session, 15s by default, consul accepts 10s to 24h.
+	TTL time.Duration
+	// RenewInterval is how often the session is renewed, TTL/3 by default.
+	RenewInterval time.Duration
+	// SafetyMargin is how long before the TTL elapses the lease ends when the renewals fail, so the generator stops
+	// before the lease expires in consul, RenewInterval/2 by default, less when the TTL is under twice the RenewInterval.
+	SafetyMargin time.Duration
+	// Owner is the value of the key, the host name by default.
+	Owner string
+}
+
+// Allocate lease the lowest free machineID in [0, MaxMachineID] and renew its session in the background until Close.
+//
+// The lease is lost when the session was invalidated, or SafetyMargin before TTL elapsed since the last successful
+// renewal was sent, e.g. the consul agent is down: Done is closed and Err returns why, see snowflake.WithMachineIDLease.
+// Consul invalidates the session no sooner than TTL after it received the renewal, so the generator stops before
+// the key is released; consul may wait up to twice the TTL, the margin doesn't rely on it.
+func Allocate(ctx context.Context, client Client, opts Options) (snowflake.MachineIDLease, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	acquired := time.Now()
+	session, err := client.CreateSession(ctx, opts.TTL)
+	if err != nil {
+		return nil, fmt.Errorf("consullease: create a session: %w", err)
+	}
+
+	id, err := opts.acquire(ctx, client, session)
+	if err != nil {
+		destroyCtx, cancel := context.WithTimeout(context.Background(), opts.RenewInterval)
+		defer cancel()
+		_ = client.DestroySession(destroyCtx, session)
+
+		if errors.Is(err, ErrNoFreeMachineID) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("consullease: acquire a machineID: %w", err)
+	}
+
+	renew := func(ctx context.Context) error {
+		err := client.RenewSession(ctx, session)
+		if errors.Is(err, ErrSessionNotFound) {
+			return fmt.Errorf("%v: %w", err, leasekeeper.ErrNotOwned)
+		}
+		return err
+	}
+	release := func(ctx context.Context) error {
+		return client.DestroySession(ctx, session)
+	}
+
+	return leasekeeper.Start(uint16(id), acquired, opts.RenewInterval, opts.TTL, opts.SafetyMargin, renew, release), nil
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+func (o Options) withDefaults() (Options, error) {
+	if o.Prefix == "" {
+		o.Prefix = defaultPrefix
+	}
+	if o.MaxMachineID == 0 {
+		o.MaxMachineID = snowflake.MaxMachineID
+	}
+	if o.TTL == 0 {
+		o.TTL = defaultTTL
+	}
+	if o.TTL < 10*time.Second || o.TTL > 24*time.Hour {
+		return o, fmt.Errorf("consullease: invalid TTL %s, it must be between 10s and 24h", o.TTL)
+	}
+	if o.RenewInterval == 0 {
+		o.RenewInterval = o.TTL / 3
+	}
+	if o.RenewInterval <= 0 || o.RenewInterval >= o.TTL {
+		return o, fmt.Errorf("consullease: invalid renew interval %s, it must be positive and less than the TTL %s", o.RenewInterval, o.TTL)
+	}
+	if o.SafetyMargin == 0 {
+		o.SafetyMargin = leasekeeper.DefaultMargin(o.RenewInterval, o.TTL)
+	}
+	if o.SafetyMargin <= 0 || o.SafetyMargin >= o.TTL-o.RenewInterval {
+		return o, fmt.Errorf("consullease: invalid safety margin %s, it must be positive and less than the TTL minus the renew interval %s", o.SafetyMargin, o.TTL-o.RenewInterval)
+	}
+	if o.Owner == "" {
+		o.Owner, _ = os.Hostname()
+	}
+
+	return o, nil
+}
+
+// acquire the key of the lowest free machineID with the session.
+func (o Options) acquire(ctx context.Context, client Client, session string) (int, error) {
+	keys, err := client.Keys(ctx, o.Prefix)
+	if err != nil {
+		return 0, err
+	}
+	leased := make(map[int]bool, len(keys))
+	for _, k := range keys {
+		if id, err := strconv.Atoi(strings.TrimPrefix(k, o.Prefix)); err == nil {
+			leased[id] = true
+		}
+	}
+
+	// 其它进程可能同时抢占同一个 ID，失败时尝试下一个
+	for id := 0; id <= int(o.MaxMachineID); id++ {
+		if leased[id] {
+			continue
+		}
+		ok, err := client.Acquire(ctx, o.Prefix+strconv.Itoa(id), o.Owner, session)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return id, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: 0-%d are leased", ErrNoFreeMachineID, o.MaxMachineID)
+}
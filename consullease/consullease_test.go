@@ -0,0 +1,208 @@
+package consullease_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/consullease"
+)
+
+// fakeConsul keeps the sessions and the acquired keys in maps, the TTLs are ignored.
+type fakeConsul struct {
+	mu       sync.Mutex
+	keys     map[string]string
+	sessions map[string]bool
+	next     int
+	renews   int
+}
+
+func newFakeConsul() *fakeConsul {
+	return &fakeConsul{keys: make(map[string]string), sessions: make(map[string]bool)}
+}
+
+func (c *fakeConsul) CreateSession(ctx context.Context, ttl time.Duration) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.next++
+	id := strconv.Itoa(c.next)
+	c.sessions[id] = true
+	return id, nil
+}
+
+func (c *fakeConsul) RenewSession(ctx context.Context, session string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.sessions[session] {
+		return consullease.ErrSessionNotFound
+	}
+	c.renews++
+	return nil
+}
+
+// DestroySession deletes the keys of the session, like the delete behavior.
+func (c *fakeConsul) DestroySession(ctx context.Context, session string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.sessions, session)
+	for k, s := range c.keys {
+		if s == session {
+			delete(c.keys, k)
+		}
+	}
+	return nil
+}
+
+func (c *fakeConsul) Keys(ctx context.Context, prefix string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var keys []string
+	for k := range c.keys {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (c *fakeConsul) Acquire(ctx context.Context, key, value, session string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.keys[key]; ok || !c.sessions[session] {
+		return false, nil
+	}
+	c.keys[key] = session
+	return true, nil
+}
+
+func (c *fakeConsul) invalidate(session string) {
+	_ = c.DestroySession(context.Background(), session)
+}
+
+func TestAllocate(t *testing.T) {
+	client := newFakeConsul()
+
+	leases := make([]snowflake.MachineIDLease, 20)
+	var wg sync.WaitGroup
+	for i := range leases {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l, err := consullease.Allocate(context.Background(), client, consullease.Options{Owner: fmt.Sprintf("pod-%d", i)})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			leases[i] = l
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[uint16]bool)
+	for _, l := range leases {
+		if l == nil {
+			t.FailNow()
+		}
+		if seen[l.MachineID()] || l.MachineID() >= 20 {
+			t.Errorf("The machineIDs should be unique and the lowest, got %d", l.MachineID())
+		}
+		seen[l.MachineID()] = true
+	}
+
+	// a released machineID is leased again.
+	released := leases[5].MachineID()
+	if err := leases[5].Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	l, err := consullease.Allocate(context.Background(), client, consullease.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.MachineID() != released {
+		t.Errorf("The lowest free machineID should be leased, got %d, want %d", l.MachineID(), released)
+	}
+
+	for _, l := range append(leases, l) {
+		_ = l.Close(context.Background())
+	}
+}
+
+func TestAllocate_NoFreeMachineID(t *testing.T) {
+	client := newFakeConsul()
+	opts := consullease.Options{MaxMachineID: 1}
+	for i := 0; i < 2; i++ {
+		l, err := consullease.Allocate(context.Background(), client, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close(context.Background())
+	}
+
+	if _, err := consullease.Allocate(context.Background(), client, opts); !errors.Is(err, consullease.ErrNoFreeMachineID) {
+		t.Errorf("The error should be ErrNoFreeMachineID, got %v", err)
+	}
+	client.mu.Lock()
+	sessions := len(client.sessions)
+	client.mu.Unlock()
+	if sessions != 2 {
+		t.Errorf("The session of a failed allocation should be destroyed, got %d sessions", sessions)
+	}
+}
+
+func TestAllocate_Lost(t *testing.T) {
+	client := newFakeConsul()
+	l, err := consullease.Allocate(context.Background(), client, consullease.Options{TTL: 10 * time.Second, RenewInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := snowflake.New(snowflake.WithMachineIDLease(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close(context.Background())
+
+	time.Sleep(30 * time.Millisecond)
+	client.mu.Lock()
+	renews := client.renews
+	client.mu.Unlock()
+	if renews == 0 {
+		t.Error("The session should be renewed")
+	}
+
+	// the session was invalidated, e.g. its node failed the health checks.
+	client.invalidate("1")
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Fatal("The lost lease should be done")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := g.NextID()
+		if errors.Is(err, snowflake.ErrLeaseLost) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("The generator should stop once the lease is lost, got %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAllocate_InvalidTTL(t *testing.T) {
+	if _, err := consullease.Allocate(context.Background(), newFakeConsul(), consullease.Options{TTL: time.Second}); err == nil {
+		t.Error("A TTL below the minimum of consul should be rejected")
+	}
+}
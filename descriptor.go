@@ -0,0 +1,83 @@
+package snowflake
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Descriptor describes how the IDs of a generator are encoded, it is enough to parse them in another language.
+// It has a stable JSON encoding, e.g.
+//
+//	{"timestamp_bits":42,"machine_bits":9,"sequence_bits":12,"datacenter_bits":0,
+//	 "order":"timestamp-machine-sequence","epoch_ms":1226358000000,"time_unit_ns":1000000,"descending":false}
+//
+// The machineID is not part of the descriptor, Descriptors of two generators are equal (==) when their IDs are compatible,
+// so services can compare them at startup to detect mismatched configs.
+type Descriptor struct {
+	TimestampBits  uint8      `json:"timestamp_bits"`
+	MachineBits    uint8      `json:"machine_bits"`
+	SequenceBits   uint8      `json:"sequence_bits"`
+	DatacenterBits uint8      `json:"datacenter_bits"`
+	Order          FieldOrder `json:"order"`
+
+	// EpochMillis is the start time in milliseconds since the unix epoch.
+	EpochMillis int64 `json:"epoch_ms"`
+
+	// TimeUnit is the tick duration, it is encoded as nanoseconds.
+	TimeUnit time.Duration `json:"time_unit_ns"`
+
+	// Descending means the timestamp part stores MaxTimestamp - elapsed, see WithDescending.
+	Descending bool `json:"descending"`
+}
+
+// Layout returns the bit layout of the descriptor.
+func (d Descriptor) Layout() Layout {
+	return Layout{
+		TimestampBits:  d.TimestampBits,
+		MachineBits:    d.MachineBits,
+		SequenceBits:   d.SequenceBits,
+		Order:          d.Order,
+		DatacenterBits: d.DatacenterBits,
+	}
+}
+
+// Descriptor returns the descriptor of the generator.
+func (g *Generator) Descriptor() Descriptor {
+	l := g.layout
+
+	return Descriptor{
+		TimestampBits:  l.TimestampBits,
+		MachineBits:    l.MachineBits,
+		SequenceBits:   l.SequenceBits,
+		DatacenterBits: l.DatacenterBits,
+		Order:          l.Order,
+		EpochMillis:    atomic.LoadInt64(&g.epoch) * int64(g.timeUnit) / int64(time.Millisecond),
+		TimeUnit:       g.timeUnit,
+		Descending:     g.descending,
+	}
+}
+
+// FromDescriptor create a Generator which generates IDs matching the descriptor,
+// the extra options are applied after the descriptor, e.g. WithMachineID.
+func FromDescriptor(d Descriptor, opts ...Option) (*Generator, error) {
+	if d.TimeUnit <= 0 {
+		return nil, fmt.Errorf("snowflake: invalid descriptor: the time unit must be positive, got %s", d.TimeUnit)
+	}
+
+	preset := []Option{
+		WithLayout(d.Layout()),
+		WithStartTime(time.Unix(0, d.EpochMillis*int64(time.Millisecond)).UTC()),
+		WithTimeUnit(d.TimeUnit),
+	}
+	if d.Descending {
+		preset = append(preset, WithDescending())
+	}
+
+	g, err := New(append(preset, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("snowflake: invalid descriptor: %w", err)
+	}
+
+	return g, nil
+}
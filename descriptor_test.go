@@ -0,0 +1,87 @@
+package snowflake_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestDescriptor_RoundTrip(t *testing.T) {
+	tests := map[string][]snowflake.Option{
+		"default": nil,
+		"custom": {
+			snowflake.WithLayout(snowflake.Layout{TimestampBits: 41, MachineBits: 10, SequenceBits: 12, DatacenterBits: 3}),
+			snowflake.WithStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+			snowflake.WithDescending(),
+		},
+		"sonyflake": {
+			snowflake.WithLayout(snowflake.SonyflakeLayout),
+			snowflake.WithStartTime(snowflake.SonyflakeEpoch),
+			snowflake.WithTimeUnit(snowflake.SonyflakeTimeUnit),
+		},
+	}
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			g, err := snowflake.New(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := g.Descriptor()
+
+			b, err := json.Marshal(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded snowflake.Descriptor
+			if err := json.Unmarshal(b, &decoded); err != nil {
+				t.Fatal(err)
+			}
+
+			g2, err := snowflake.FromDescriptor(decoded, snowflake.WithMachineID(7))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g2.Descriptor() != d {
+				t.Errorf("The descriptor should round trip, got %+v, want %+v", g2.Descriptor(), d)
+			}
+
+			id := g2.ID()
+			sid, sid2 := g.ParseID(id), g2.ParseID(id)
+			if got, want := sid.GenerateTime(), sid2.GenerateTime(); !got.Equal(want) {
+				t.Errorf("The generators should parse the same time, got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestDescriptor_JSON(t *testing.T) {
+	g, err := snowflake.NewTwitterCompatible(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(g.Descriptor())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"timestamp_bits":41,"machine_bits":10,"sequence_bits":12,"datacenter_bits":0,` +
+		`"order":"timestamp-machine-sequence","epoch_ms":1288834974657,"time_unit_ns":1000000,"descending":false}`
+	if string(b) != want {
+		t.Errorf("The JSON should be stable, got %s", b)
+	}
+}
+
+func TestFromDescriptor_Invalid(t *testing.T) {
+	d := snowflake.Descriptor{TimestampBits: 42, MachineBits: 10, SequenceBits: 12, TimeUnit: time.Millisecond}
+	if _, err := snowflake.FromDescriptor(d); err == nil {
+		t.Error("The layout should be validated")
+	}
+
+	d = snowflake.Descriptor{TimestampBits: 42, MachineBits: 9, SequenceBits: 12}
+	if _, err := snowflake.FromDescriptor(d); err == nil {
+		t.Error("The time unit should be validated")
+	}
+}
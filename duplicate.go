@@ -0,0 +1,179 @@
+package snowflake
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// defaultDuplicateProbeInterval is the interval of the duplicate machineID probes by default.
+const defaultDuplicateProbeInterval = 10 * time.Second
+
+// ErrDuplicateMachineID is returned by NextID with WithHaltOnDuplicate while another live instance holds the machineID,
+// see WithDuplicateDetection.
+var ErrDuplicateMachineID = errors.New("snowflake: the machineID is used by another instance")
+
+// duplicateDetector is the state of WithDuplicateDetection.
+type duplicateDetector struct {
+	// detected is 1 while the generator halts on a duplicate, err holds the error in an errorBox.
+	detected int32
+	err      atomic.Value
+
+	store    ClaimStore
+	interval time.Duration
+	// token identifies the generator in the claims, it is unique per generator even on the same host.
+	token string
+	// reported is the last duplicate reported to the hooks, so a duplicate is reported once until it is resolved.
+	reported string
+}
+
+// WithDuplicateDetection probe every interval whether another live instance uses the machineID of the generator,
+// e.g. two hosts configured with the same static machineID, whose IDs would collide silently.
+// Each probe claims the machineID in store for the token of the generator, see ClaimMachineID, the claim lives
+// three intervals so the claim of a stopped instance expires. Another token holding the claim is a duplicate:
+// the OnDuplicateMachineID hooks are called, and the generator fails over to its standby machineID, see WithStandbyMachineID,
+// or halts with WithHaltOnDuplicate.
+//
+// The probes run in the background from New and never block ID generation, a failing store is ignored until
+// it recovers. interval is 10 seconds when it is zero. The detection is disabled without this option.
+func WithDuplicateDetection(store ClaimStore, interval time.Duration) Option {
+	return func(g *Generator) error {
+		if store == nil {
+			return errors.New("snowflake: invalid option WithDuplicateDetection: the store cannot be nil")
+		}
+		if interval < 0 {
+			return fmt.Errorf("snowflake: invalid option WithDuplicateDetection: the interval %s must be positive", interval)
+		}
+		if interval == 0 {
+			interval = defaultDuplicateProbeInterval
+		}
+		g.duplicates = &duplicateDetector{store: store, interval: interval}
+
+		return nil
+	}
+}
+
+// WithHaltOnDuplicate make NextID return an error wrapping ErrDuplicateMachineID while another live instance
+// holds the machineID, see WithDuplicateDetection. The generator resumes once a probe claims the machineID again,
+// i.e. the other instance stopped.
+func WithHaltOnDuplicate() Option {
+	return func(g *Generator) error {
+		g.haltOnDuplicate = true
+
+		return nil
+	}
+}
+
+// OnDuplicateMachineID register fn to be called with the machineID and the token of the other instance
+// when a probe detects a duplicate, see WithDuplicateDetection. The hooks are called by the goroutine of the probes.
+// This function is thread safe.
+func (g *Generator) OnDuplicateMachineID(fn func(machineID uint16, owner string)) {
+	g.duplicateHooksMu.Lock()
+	defer g.duplicateHooksMu.Unlock()
+
+	g.duplicateHooks = append(g.duplicateHooks, fn)
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// detectDuplicates probe the machineID every interval until the generator is closed.
+func (g *Generator) detectDuplicates() error {
+	d := g.duplicates
+	token, err := instanceToken()
+	if err != nil {
+		return err
+	}
+	d.token = token
+
+	go func() {
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+
+		for {
+			g.probeDuplicate()
+			select {
+			case <-ticker.C:
+			case <-g.done:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// probeDuplicate claim the machineID once and handle a duplicate.
+func (g *Generator) probeDuplicate() {
+	d := g.duplicates
+	if g.provider != nil && atomic.LoadInt32(&g.provider.provided) == 0 {
+		return
+	}
+
+	m := g.MachineID()
+	timeout := d.interval
+	if timeout > claimTimeout {
+		timeout = claimTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	owner, err := d.store.Claim(ctx, claimKey(m), d.token, 3*d.interval)
+	cancel()
+	if err != nil {
+		// 存储故障：保持当前状态，等待下一次探测
+		return
+	}
+	if owner == d.token {
+		d.reported = ""
+		atomic.StoreInt32(&d.detected, 0)
+		return
+	}
+
+	err = fmt.Errorf("%w: machineID %d is held by %s", ErrDuplicateMachineID, m, owner)
+	if dup := fmt.Sprintf("%d/%s", m, owner); dup != d.reported {
+		d.reported = dup
+		g.duplicateHooksMu.Lock()
+		hooks := g.duplicateHooks
+		g.duplicateHooksMu.Unlock()
+
+		for _, fn := range hooks {
+			fn(m, owner)
+		}
+	}
+
+	if g.standby != nil && g.failover(err) == nil {
+		return
+	}
+	if g.haltOnDuplicate {
+		d.err.Store(errorBox{err})
+		atomic.StoreInt32(&d.detected, 1)
+	}
+}
+
+// checkDuplicate returns the duplicate the generator halts on.
+func (g *Generator) checkDuplicate() error {
+	d := g.duplicates
+	if d == nil || atomic.LoadInt32(&d.detected) == 0 {
+		return nil
+	}
+
+	return d.err.Load().(errorBox).err
+}
+
+// instanceToken returns the host name with a random suffix, unique per generator.
+func instanceToken() (string, error) {
+	name, err := hostname()
+	if err != nil {
+		return "", fmt.Errorf("snowflake: hostname: %w", err)
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("snowflake: instance token: %w", err)
+	}
+
+	return name + "-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,137 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// failingClaimStore is a ClaimStore during an outage.
+type failingClaimStore struct{}
+
+func (failingClaimStore) Claim(ctx context.Context, key, owner string, ttl time.Duration) (string, error) {
+	return "", errors.New("connection refused")
+}
+
+// waitFor poll cond until it is true or the deadline.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWithDuplicateDetection(t *testing.T) {
+	snowflake.SetHostname(t, "web-1")
+	kv := newFakeClaimKV()
+	store := snowflake.NewRedisClaimStore(kv)
+
+	first, err := snowflake.New(snowflake.WithMachineID(5), snowflake.WithDuplicateDetection(store, 5*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close(context.Background())
+	waitFor(t, "the claim of the first instance", func() bool {
+		v := kv.get("snowflake:machine:5")
+		return v != ""
+	})
+
+	// the second instance is misconfigured with the same machineID.
+	second, err := snowflake.New(
+		snowflake.WithMachineID(5),
+		snowflake.WithDuplicateDetection(store, 5*time.Millisecond),
+		snowflake.WithHaltOnDuplicate(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close(context.Background())
+	var reports int32
+	second.OnDuplicateMachineID(func(machineID uint16, owner string) {
+		if machineID != 5 || !strings.HasPrefix(owner, "web-1-") {
+			t.Errorf("Unexpected duplicate %d held by %s", machineID, owner)
+		}
+		atomic.AddInt32(&reports, 1)
+	})
+
+	waitFor(t, "the halt of the second instance", func() bool {
+		_, err := second.NextID()
+		return errors.Is(err, snowflake.ErrDuplicateMachineID)
+	})
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&reports); n != 1 {
+		t.Errorf("The duplicate should be reported once, got %d", n)
+	}
+	if _, err := first.NextID(); err != nil {
+		t.Errorf("The instance holding the claim should keep generating, got %v", err)
+	}
+
+	// the second instance resumes once the first one stopped and its claim expired.
+	_ = first.Close(context.Background())
+	kv.mu.Lock()
+	delete(kv.values, "snowflake:machine:5")
+	kv.mu.Unlock()
+	waitFor(t, "the second instance to resume", func() bool {
+		_, err := second.NextID()
+		return err == nil
+	})
+}
+
+func TestWithDuplicateDetection_Standby(t *testing.T) {
+	kv := newFakeClaimKV()
+	kv.set("snowflake:machine:5", "other-instance")
+
+	g, err := snowflake.New(
+		snowflake.WithMachineID(5),
+		snowflake.WithStandbyMachineID(6),
+		snowflake.WithDuplicateDetection(snowflake.NewEtcdClaimStore(kv), 5*time.Millisecond),
+		snowflake.WithHaltOnDuplicate(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close(context.Background())
+
+	waitFor(t, "the failover", func() bool {
+		return g.MachineID() == 6
+	})
+	if _, err := g.NextID(); err != nil {
+		t.Errorf("The generator should fail over instead of halting, got %v", err)
+	}
+	waitFor(t, "the claim of the standby machineID", func() bool {
+		v := kv.get("snowflake:machine:6")
+		return v != ""
+	})
+}
+
+func TestWithDuplicateDetection_StoreOutage(t *testing.T) {
+	g, err := snowflake.New(
+		snowflake.WithDuplicateDetection(failingClaimStore{}, time.Millisecond),
+		snowflake.WithHaltOnDuplicate(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close(context.Background())
+
+	time.Sleep(10 * time.Millisecond)
+	if _, err := g.NextID(); err != nil {
+		t.Errorf("A store outage should not halt the generator, got %v", err)
+	}
+
+	if _, err := snowflake.New(snowflake.WithHaltOnDuplicate()); err == nil {
+		t.Error("WithHaltOnDuplicate should need WithDuplicateDetection")
+	}
+	if _, err := snowflake.New(snowflake.WithDuplicateDetection(nil, 0)); err == nil {
+		t.Error("A nil store should be rejected")
+	}
+}
@@ -0,0 +1,212 @@
+// Package dynamolease leases snowflake machineIDs from a dynamodb table, so the processes get a unique machineID
+// at startup without a static assignment, e.g. on lambda or fargate:
+//
+//	lease, err := dynamolease.Allocate(ctx, dynamoClient{client}, dynamolease.Options{Table: "snowflake-machine-ids"})
+//	if err != nil {
+//		return err
+//	}
+//	g, err := snowflake.New(snowflake.WithMachineIDLease(lease))
+//
+// The lease is an item per machineID, keyed by the number attribute machine_id, holding the owner and
+// the unix milliseconds expires_at. The expiry is refreshed in the background, the item is deleted by Close,
+// and an expired item is claimed again by another process.
+package dynamolease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/internal/leasekeeper"
+)
+
+const (
+	defaultTTL          = 30 * time.Second
+	defaultRetries      = 3
+	defaultRetryBackoff = 200 * time.Millisecond
+)
+
+// ErrNoFreeMachineID is returned by Allocate when every machineID of the range is leased.
+var ErrNoFreeMachineID = errors.New("dynamolease: no free machineID")
+
+// ErrConditionFailed must be returned, or wrapped, by the Client when the condition of a write failed,
+// i.e. for a ConditionalCheckFailedException.
+var ErrConditionFailed = errors.New("dynamolease: the condition failed")
+
+// Client is the dynamodb client used by Allocate, implement it with the aws sdk:
+//
+//   - Claim puts the item of machineID if it doesn't exist or expired:
+//     PutItem with the condition "attribute_not_exists(machine_id) OR expires_at < :now".
+//   - Refresh updates expires_at if the item is still owned:
+//     UpdateItem "SET expires_at = :expires" with the condition "owner = :owner".
+//   - Release deletes the item if it is still owned: DeleteItem with the condition "owner = :owner".
+//
+// With aws-sdk-go-v2, Claim is:
+//
+//	func (c dynamoClient) Claim(ctx context.Context, table string, machineID uint16, owner string, expiresAt, now time.Time) error {
+//		_, err := c.c.PutItem(ctx, &dynamodb.PutItemInput{
+//			TableName: aws.String(table),
+//			Item: map[string]types.AttributeValue{
+//				"machine_id": &types.AttributeValueMemberN{Value: strconv.Itoa(int(machineID))},
+//				"owner":      &types.AttributeValueMemberS{Value: owner},
+//				"expires_at": &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt.UnixNano()/1e6, 10)},
+//			},
+//			ConditionExpression: aws.String("attribute_not_exists(machine_id) OR expires_at < :now"),
+//			ExpressionAttributeValues: map[string]types.AttributeValue{
+//				":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.UnixNano()/1e6, 10)},
+//			},
+//		})
+//		var ccf *types.ConditionalCheckFailedException
+//		if errors.As(err, &ccf) {
+//			return dynamolease.ErrConditionFailed
+//		}
+//		return err
+//	}
+type Client interface {
+	Claim(ctx context.Context, table string, machineID uint16, owner string, expiresAt, now time.Time) error
+	Refresh(ctx context.Context, table string, machineID uint16, owner string, expiresAt time.Time) error
+	Release(ctx context.Context, table string, machineID uint16, owner string) error
+}
+
+// Options configure Allocate, Table is required.
+type Options struct {
+	// Table is the name of the table, its partition key is the number attribute machine_id.
+	Table string
+	// MachineBits is the machine bits of the generator layout, MachineIDLength by default,
+	// the machineIDs leased are 0 to 2^MachineBits-1.
+	MachineBits uint8
+	// TTL is how long a lease lives without a refresh, 30s by default.
+	// The expiry is compared with the clock of the claiming process, keep the clocks synchronized.
+	TTL time.Duration
+	// RenewInterval is how often the expiry is refreshed, TTL/3 by default.
+	RenewInterval time.Duration
+	// SafetyMargin is how long before the TTL elapses the lease ends when the renewals fail, so the generator stops
+	// before the lease expires in dynamodb, RenewInterval/2 by default, less when the TTL is under twice the RenewInterval.
+	SafetyMargin time.Duration
+	// Owner identifies the process in the items, hostname:pid by default.
+	Owner string
+	// Retries is the count of scans retried when every machineID was leased, 3 by default, with a doubling
+	// backoff from RetryBackoff, 200ms by default, the leases of the stopped processes expire meanwhile.
+	Retries      int
+	RetryBackoff time.Duration
+}
+
+// Allocate claim a free or expired machineID and refresh its expiry in the background until Close.
+// The scan starts at a random machineID, so the processes starting together, e.g. a burst of lambdas,
+// rarely try the same items; a machineID whose claim failed the condition is skipped.
+//
+// The lease is lost when a refresh finds the item owned by another process, or SafetyMargin before TTL elapsed
+// since the last successful refresh was sent:
+// Done is closed and Err returns why, see snowflake.WithMachineIDLease.
+func Allocate(ctx context.Context, client Client, opts Options) (snowflake.MachineIDLease, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	id, acquired, err := opts.claim(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	renew := func(ctx context.Context) error {
+		err := client.Refresh(ctx, opts.Table, id, opts.Owner, time.Now().Add(opts.TTL))
+		if errors.Is(err, ErrConditionFailed) {
+			return fmt.Errorf("dynamolease: the item of machineID %d is not owned by %s: %w", id, opts.Owner, leasekeeper.ErrNotOwned)
+		}
+		return err
+	}
+	release := func(ctx context.Context) error {
+		err := client.Release(ctx, opts.Table, id, opts.Owner)
+		if errors.Is(err, ErrConditionFailed) {
+			// 条目已经属于其它进程，不需要删除
+			return nil
+		}
+		return err
+	}
+
+	return leasekeeper.Start(id, acquired, opts.RenewInterval, opts.TTL, opts.SafetyMargin, renew, release), nil
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+func (o Options) withDefaults() (Options, error) {
+	if o.Table == "" {
+		return o, errors.New("dynamolease: the table is required")
+	}
+	if o.MachineBits == 0 {
+		o.MachineBits = snowflake.MachineIDLength
+	}
+	if o.MachineBits > 16 {
+		return o, fmt.Errorf("dynamolease: invalid machine bits %d, it must be between 1 and 16", o.MachineBits)
+	}
+	if o.TTL == 0 {
+		o.TTL = defaultTTL
+	}
+	if o.TTL < time.Millisecond {
+		return o, fmt.Errorf("dynamolease: invalid TTL %s, it must be at least 1ms", o.TTL)
+	}
+	if o.RenewInterval == 0 {
+		o.RenewInterval = o.TTL / 3
+	}
+	if o.RenewInterval <= 0 || o.RenewInterval >= o.TTL {
+		return o, fmt.Errorf("dynamolease: invalid renew interval %s, it must be positive and less than the TTL %s", o.RenewInterval, o.TTL)
+	}
+	if o.SafetyMargin == 0 {
+		o.SafetyMargin = leasekeeper.DefaultMargin(o.RenewInterval, o.TTL)
+	}
+	if o.SafetyMargin <= 0 || o.SafetyMargin >= o.TTL-o.RenewInterval {
+		return o, fmt.Errorf("dynamolease: invalid safety margin %s, it must be positive and less than the TTL minus the renew interval %s", o.SafetyMargin, o.TTL-o.RenewInterval)
+	}
+	if o.Owner == "" {
+		host, _ := os.Hostname()
+		o.Owner = fmt.Sprintf("%s:%d", host, os.Getpid())
+	}
+	if o.Retries == 0 {
+		o.Retries = defaultRetries
+	}
+	if o.RetryBackoff <= 0 {
+		o.RetryBackoff = defaultRetryBackoff
+	}
+
+	return o, nil
+}
+
+// claim scan the machineIDs from a random one until a claim succeeds, backing off between the scans.
+// It returns the machineID and the time its expiry is counted from.
+func (o Options) claim(ctx context.Context, client Client) (uint16, time.Time, error) {
+	slots := 1 << o.MachineBits
+	backoff := o.RetryBackoff
+	for scan := 0; ; scan++ {
+		start := rand.Intn(slots)
+		for i := 0; i < slots; i++ {
+			id := uint16((start + i) % slots)
+			now := time.Now()
+			err := client.Claim(ctx, o.Table, id, o.Owner, now.Add(o.TTL), now)
+			if err == nil {
+				return id, now, nil
+			}
+			if !errors.Is(err, ErrConditionFailed) {
+				return 0, time.Time{}, fmt.Errorf("dynamolease: claim machineID %d: %w", id, err)
+			}
+		}
+		if scan >= o.Retries {
+			return 0, time.Time{}, fmt.Errorf("%w: 0-%d are leased", ErrNoFreeMachineID, slots-1)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+			backoff *= 2
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, time.Time{}, fmt.Errorf("%w: 0-%d are leased: %v", ErrNoFreeMachineID, slots-1, ctx.Err())
+		}
+	}
+}
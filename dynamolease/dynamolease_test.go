@@ -0,0 +1,178 @@
+package dynamolease_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/dynamolease"
+)
+
+type item struct {
+	owner     string
+	expiresAt time.Time
+}
+
+// fakeDynamo evaluates the conditions of dynamolease on a map, like dynamodb local.
+type fakeDynamo struct {
+	mu        sync.Mutex
+	items     map[uint16]item
+	refreshes int
+}
+
+func newFakeDynamo() *fakeDynamo {
+	return &fakeDynamo{items: make(map[uint16]item)}
+}
+
+func (d *fakeDynamo) Claim(ctx context.Context, table string, machineID uint16, owner string, expiresAt, now time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if it, ok := d.items[machineID]; ok && !it.expiresAt.Before(now) {
+		return fmt.Errorf("ConditionalCheckFailedException: %w", dynamolease.ErrConditionFailed)
+	}
+	d.items[machineID] = item{owner: owner, expiresAt: expiresAt}
+	return nil
+}
+
+func (d *fakeDynamo) Refresh(ctx context.Context, table string, machineID uint16, owner string, expiresAt time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.items[machineID].owner != owner {
+		return dynamolease.ErrConditionFailed
+	}
+	d.items[machineID] = item{owner: owner, expiresAt: expiresAt}
+	d.refreshes++
+	return nil
+}
+
+func (d *fakeDynamo) Release(ctx context.Context, table string, machineID uint16, owner string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.items[machineID].owner != owner {
+		return dynamolease.ErrConditionFailed
+	}
+	delete(d.items, machineID)
+	return nil
+}
+
+func (d *fakeDynamo) set(machineID uint16, it item) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.items[machineID] = it
+}
+
+func TestAllocate(t *testing.T) {
+	client := newFakeDynamo()
+	opts := dynamolease.Options{Table: "ids", MachineBits: 3, RetryBackoff: time.Millisecond}
+
+	seen := make(map[uint16]bool)
+	var leases []snowflake.MachineIDLease
+	for i := 0; i < 8; i++ {
+		opts.Owner = fmt.Sprintf("lambda-%d", i)
+		l, err := dynamolease.Allocate(context.Background(), client, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[l.MachineID()] || l.MachineID() >= 8 {
+			t.Errorf("The machineIDs should be unique and fit the machine bits, got %d", l.MachineID())
+		}
+		seen[l.MachineID()] = true
+		leases = append(leases, l)
+	}
+
+	opts.Owner = "lambda-8"
+	if _, err := dynamolease.Allocate(context.Background(), client, opts); !errors.Is(err, dynamolease.ErrNoFreeMachineID) {
+		t.Errorf("The error should be ErrNoFreeMachineID, got %v", err)
+	}
+
+	released := leases[0].MachineID()
+	if err := leases[0].Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	l, err := dynamolease.Allocate(context.Background(), client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.MachineID() != released {
+		t.Errorf("The released machineID should be claimed, got %d, want %d", l.MachineID(), released)
+	}
+
+	for _, l := range append(leases, l) {
+		_ = l.Close(context.Background())
+	}
+}
+
+func TestAllocate_ReclaimExpired(t *testing.T) {
+	client := newFakeDynamo()
+	// machineID 1 was leased by a crashed process, its item expired.
+	client.set(0, item{owner: "alive", expiresAt: time.Now().Add(time.Hour)})
+	client.set(1, item{owner: "crashed", expiresAt: time.Now().Add(-time.Second)})
+
+	l, err := dynamolease.Allocate(context.Background(), client, dynamolease.Options{Table: "ids", MachineBits: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close(context.Background())
+	if l.MachineID() != 1 {
+		t.Errorf("The expired machineID should be claimed, got %d", l.MachineID())
+	}
+}
+
+func TestAllocate_Lost(t *testing.T) {
+	client := newFakeDynamo()
+	l, err := dynamolease.Allocate(context.Background(), client, dynamolease.Options{Table: "ids", TTL: 30 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := snowflake.New(snowflake.WithMachineIDLease(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close(context.Background())
+
+	time.Sleep(30 * time.Millisecond)
+	client.mu.Lock()
+	refreshes := client.refreshes
+	client.mu.Unlock()
+	if refreshes == 0 {
+		t.Error("The expiry should be refreshed")
+	}
+
+	// the item expired during a freeze of the lambda and another process claimed it.
+	client.set(l.MachineID(), item{owner: "other", expiresAt: time.Now().Add(time.Hour)})
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Fatal("The lost lease should be done")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := g.NextID()
+		if errors.Is(err, snowflake.ErrLeaseLost) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("The generator should stop once the lease is lost, got %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAllocate_Options(t *testing.T) {
+	client := newFakeDynamo()
+	if _, err := dynamolease.Allocate(context.Background(), client, dynamolease.Options{}); err == nil {
+		t.Error("The table should be required")
+	}
+	if _, err := dynamolease.Allocate(context.Background(), client, dynamolease.Options{Table: "ids", MachineBits: 17}); err == nil {
+		t.Error("More than 16 machine bits should be rejected")
+	}
+}
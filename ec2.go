@@ -0,0 +1,128 @@
+package snowflake
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ec2Timeout is the timeout of each call to the instance metadata service, it answers within milliseconds on EC2.
+const ec2Timeout = time.Second
+
+// ErrNotEC2 is returned by MachineIDFromEC2 when the instance metadata service cannot be reached,
+// i.e. the process doesn't run on EC2, so the callers can fall back to another source.
+var ErrNotEC2 = errors.New("snowflake: not running on EC2, the instance metadata service is unreachable")
+
+// EC2MetadataEndpoint is the endpoint of the instance metadata service, replace it e.g. with the address
+// of a local IMDS emulator. It is read by each call, set it before the machineID is resolved.
+var EC2MetadataEndpoint = "http://169.254.169.254"
+
+// metadataClient is the client of the metadata services of the instance. They are link-local,
+// so it never goes through the proxy of HTTP_PROXY or HTTPS_PROXY, which would see the tokens and the metadata.
+var metadataClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:       nil,
+		DialContext: (&net.Dialer{Timeout: ec2Timeout}).DialContext,
+	},
+	Timeout: ec2Timeout,
+}
+
+// MachineIDFromEC2 returns the low MachineIDLength bits of the primary private IPv4 address of the EC2 instance,
+// read from the instance metadata service with IMDSv2. Like MachineIDFromPrivateIP, the machineIDs are unique
+// as long as the instances are in a subnet of 2^MachineIDLength addresses at most.
+//
+// Each call to the service times out after 1s, it returns an error wrapping ErrNotEC2 when the service is unreachable.
+// In a container, the hop limit of the IMDSv2 token must be at least 2.
+func MachineIDFromEC2(ctx context.Context) (uint16, error) {
+	return ec2PrivateIP(ctx, MachineIDLength)
+}
+
+// MachineIDFromEC2InstanceID returns a machineID hashed from the instance ID of the EC2 instance, e.g. i-0abc123def4567890,
+// folded into MachineIDLength bits, for the instances spread over large subnets. The hashes of different instances may collide,
+// see ClaimMachineID.
+func MachineIDFromEC2InstanceID(ctx context.Context) (uint16, error) {
+	id, err := ec2Metadata(ctx, "instance-id")
+	if err != nil {
+		return 0, err
+	}
+
+	return hashMachineID([]byte(id), MachineIDLength), nil
+}
+
+// EC2MachineID set the machineID of the generator from the primary private IPv4 address of the EC2 instance,
+// with the machine bits of its layout, see MachineIDFromEC2.
+func EC2MachineID() Option {
+	return func(g *Generator) error {
+		g.derivedMachineID = func(bits uint8) (uint16, error) {
+			return ec2PrivateIP(context.Background(), bits)
+		}
+		g.derivedBy = "EC2MachineID"
+
+		return nil
+	}
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+func ec2PrivateIP(ctx context.Context, bits uint8) (uint16, error) {
+	if bits == 0 || bits > 16 {
+		return 0, fmt.Errorf("snowflake: invalid machine bits %d, it must be between 1 and 16", bits)
+	}
+
+	v, err := ec2Metadata(ctx, "local-ipv4")
+	if err != nil {
+		return 0, err
+	}
+	ip := net.ParseIP(v).To4()
+	if ip == nil {
+		return 0, fmt.Errorf("snowflake: invalid local-ipv4 %q from the instance metadata service", v)
+	}
+
+	return uint16(binary.BigEndian.Uint32(ip) & (1<<bits - 1)), nil
+}
+
+// ec2Metadata get a token and read the metadata category with it, see the IMDSv2 documentation.
+func ec2Metadata(ctx context.Context, category string) (string, error) {
+	token, err := ec2Call(ctx, http.MethodPut, "/latest/api/token", "X-aws-ec2-metadata-token-ttl-seconds", "60")
+	if err != nil {
+		return "", err
+	}
+
+	return ec2Call(ctx, http.MethodGet, "/latest/meta-data/"+category, "X-aws-ec2-metadata-token", token)
+}
+
+func ec2Call(ctx context.Context, method, path, header, value string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, ec2Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, EC2MetadataEndpoint+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(header, value)
+
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		// 连接失败或超时，视为不在 EC2 上运行
+		return "", fmt.Errorf("%w: %v", ErrNotEC2, err)
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("snowflake: read %s from the instance metadata service: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("snowflake: %s %s of the instance metadata service returned %s", method, path, resp.Status)
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,101 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// newIMDS serves the metadata of an EC2 instance with IMDSv2.
+func newIMDS(t *testing.T, metadata map[string]string) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			if r.Method != http.MethodPut || r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte("token"))
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		v, ok := metadata[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(v))
+	}))
+	t.Cleanup(srv.Close)
+	snowflake.SetEC2Endpoint(t, srv.URL)
+}
+
+func TestMachineIDFromEC2(t *testing.T) {
+	newIMDS(t, map[string]string{
+		"/latest/meta-data/local-ipv4":  "10.0.3.17",
+		"/latest/meta-data/instance-id": "i-0abc123def4567890",
+	})
+
+	m, err := snowflake.MachineIDFromEC2(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10.0.3.17 & 511 = 256 + 17
+	if m != 273 {
+		t.Errorf("The machineID should be the low bits of the private IP, got %d", m)
+	}
+
+	m, err = snowflake.MachineIDFromEC2InstanceID(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m > snowflake.MaxMachineID {
+		t.Errorf("The machineID should fit the machine bits, got %d", m)
+	}
+
+	layout := snowflake.Layout{TimestampBits: 41, MachineBits: 8, SequenceBits: 14}
+	g, err := snowflake.New(snowflake.WithLayout(layout), snowflake.EC2MachineID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.MachineID() != 17 {
+		t.Errorf("The machineID should be the low bits of the layout, got %d", g.MachineID())
+	}
+}
+
+func TestMachineIDFromEC2_NotEC2(t *testing.T) {
+	// a closed port, like 169.254.169.254 outside EC2.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	snowflake.SetEC2Endpoint(t, "http://"+addr)
+
+	if _, err := snowflake.MachineIDFromEC2(context.Background()); !errors.Is(err, snowflake.ErrNotEC2) {
+		t.Errorf("The error should be ErrNotEC2, got %v", err)
+	}
+}
+
+func TestMachineIDFromEC2_MissingMetadata(t *testing.T) {
+	newIMDS(t, nil)
+
+	_, err := snowflake.MachineIDFromEC2(context.Background())
+	if err == nil || errors.Is(err, snowflake.ErrNotEC2) {
+		t.Errorf("A metadata error should not be ErrNotEC2, got %v", err)
+	}
+}
+
+func TestMachineIDFromEC2_NoProxy(t *testing.T) {
+	if snowflake.MetadataClientProxied() {
+		t.Error("The metadata requests should not go through the proxy of the environment, and should time out")
+	}
+}
@@ -0,0 +1,125 @@
+package snowflake
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ecsTimeout is the timeout of each call to the task metadata endpoint, served by the agent on the host.
+const ecsTimeout = time.Second
+
+// EnvECSMetadataURI is the environment variable set by the ECS agent to the task metadata endpoint v4.
+const EnvECSMetadataURI = "ECS_CONTAINER_METADATA_URI_V4"
+
+// ErrNotECS is returned by MachineIDFromECS when the ECS_CONTAINER_METADATA_URI_V4 environment variable is not set,
+// i.e. the process doesn't run in an ECS task, or its agent is older than 1.39.0.
+var ErrNotECS = errors.New("snowflake: not running on ECS, " + EnvECSMetadataURI + " is not set")
+
+// ecsMetadata is the part of the task and container metadata used by the ECS sources.
+type ecsMetadata struct {
+	TaskARN  string `json:"TaskARN"`
+	Networks []struct {
+		IPv4Addresses []string `json:"IPv4Addresses"`
+	} `json:"Networks"`
+}
+
+// MachineIDFromECS returns the low MachineIDLength bits of the private IPv4 address of the container, read from the
+// task metadata endpoint v4. The tasks of the awsvpc network mode, e.g. on Fargate, have their own address,
+// the machineIDs are unique as long as the tasks are in a subnet of 2^MachineIDLength addresses at most.
+//
+// Prefer it to MachineIDFromECSTaskARN for the services scaling to many tasks.
+func MachineIDFromECS(ctx context.Context) (uint16, error) {
+	return ecsPrivateIP(ctx, MachineIDLength)
+}
+
+// MachineIDFromECSTaskARN returns a machineID hashed from the ARN of the ECS task, for the tasks sharing the address
+// of the host in the bridge or host network mode.
+//
+// The hashes of different tasks collide with the probability about n²/2^(MachineIDLength+1) for n tasks:
+// with the 9 machine bits of the default layout, 10 tasks collide with 9% probability, 30 tasks with 57%,
+// and 100 tasks almost surely. Check the machineIDs of the tasks are unique, see ClaimMachineID.
+func MachineIDFromECSTaskARN(ctx context.Context) (uint16, error) {
+	var task ecsMetadata
+	if err := getECSMetadata(ctx, "/task", &task); err != nil {
+		return 0, err
+	}
+	if task.TaskARN == "" {
+		return 0, errors.New("snowflake: the ECS task metadata has no TaskARN")
+	}
+
+	return hashMachineID([]byte(task.TaskARN), MachineIDLength), nil
+}
+
+// ECSMachineID set the machineID of the generator from the private IPv4 address of the ECS container,
+// with the machine bits of its layout, see MachineIDFromECS.
+func ECSMachineID() Option {
+	return func(g *Generator) error {
+		g.derivedMachineID = func(bits uint8) (uint16, error) {
+			return ecsPrivateIP(context.Background(), bits)
+		}
+		g.derivedBy = "ECSMachineID"
+
+		return nil
+	}
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+func ecsPrivateIP(ctx context.Context, bits uint8) (uint16, error) {
+	if bits == 0 || bits > 16 {
+		return 0, fmt.Errorf("snowflake: invalid machine bits %d, it must be between 1 and 16", bits)
+	}
+
+	var container ecsMetadata
+	if err := getECSMetadata(ctx, "", &container); err != nil {
+		return 0, err
+	}
+	for _, n := range container.Networks {
+		for _, a := range n.IPv4Addresses {
+			if ip := net.ParseIP(a).To4(); ip != nil {
+				return uint16(binary.BigEndian.Uint32(ip) & (1<<bits - 1)), nil
+			}
+		}
+	}
+
+	return 0, errors.New("snowflake: the ECS container metadata has no IPv4 address")
+}
+
+// getECSMetadata decode the metadata at path of the endpoint into v, "" is the metadata of the container.
+func getECSMetadata(ctx context.Context, path string, v interface{}) error {
+	uri := os.Getenv(EnvECSMetadataURI)
+	if uri == "" {
+		return ErrNotECS
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ecsTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri+path, nil)
+	if err != nil {
+		return fmt.Errorf("snowflake: invalid %s: %w", EnvECSMetadataURI, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("snowflake: get the ECS metadata: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("snowflake: GET %s of the ECS metadata endpoint returned %s", uri+path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("snowflake: decode the ECS metadata: %w", err)
+	}
+
+	return nil
+}
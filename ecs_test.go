@@ -0,0 +1,70 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func newECSMetadata(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4/abc":
+			_, _ = w.Write([]byte(`{"Name": "app", "Networks": [{"NetworkMode": "awsvpc", "IPv4Addresses": ["10.0.1.44"]}]}`))
+		case "/v4/abc/task":
+			_, _ = w.Write([]byte(`{"TaskARN": "arn:aws:ecs:us-west-2:111122223333:task/default/158d1c8083dd49d6b527399fd6414f5c"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	setenv(t, snowflake.EnvECSMetadataURI, srv.URL+"/v4/abc")
+}
+
+func TestMachineIDFromECS(t *testing.T) {
+	newECSMetadata(t)
+
+	m, err := snowflake.MachineIDFromECS(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10.0.1.44 & 511 = 256 + 44
+	if m != 300 {
+		t.Errorf("The machineID should be the low bits of the container IP, got %d", m)
+	}
+
+	m, err = snowflake.MachineIDFromECSTaskARN(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	arn := "arn:aws:ecs:us-west-2:111122223333:task/default/158d1c8083dd49d6b527399fd6414f5c"
+	if want := snowflake.HashMachineID([]byte(arn), snowflake.MachineIDLength); m != want {
+		t.Errorf("The machineID should be the hash of the task ARN, got %d, want %d", m, want)
+	}
+
+	layout := snowflake.Layout{TimestampBits: 41, MachineBits: 8, SequenceBits: 14}
+	g, err := snowflake.New(snowflake.WithLayout(layout), snowflake.ECSMachineID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.MachineID() != 44 {
+		t.Errorf("The machineID should be the low bits of the layout, got %d", g.MachineID())
+	}
+}
+
+func TestMachineIDFromECS_NotECS(t *testing.T) {
+	setenv(t, snowflake.EnvECSMetadataURI, "")
+	os.Unsetenv(snowflake.EnvECSMetadataURI)
+
+	if _, err := snowflake.MachineIDFromECS(context.Background()); !errors.Is(err, snowflake.ErrNotECS) {
+		t.Errorf("The error should be ErrNotECS, got %v", err)
+	}
+	if _, err := snowflake.New(snowflake.ECSMachineID()); !errors.Is(err, snowflake.ErrNotECS) {
+		t.Errorf("New should fail outside ECS, got %v", err)
+	}
+}
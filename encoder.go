@@ -0,0 +1,140 @@
+package snowflake
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// Encoder encodes the IDs as strings in the base of its alphabet, e.g. a legacy 32-character alphabet:
+//
+//	e, err := snowflake.NewEncoder("ybndrfg8ejkmcpqxot1uwisza345h769")
+//	s := e.Encode(id)
+//	id, err := e.Decode(s)
+//
+// The built-in encodings, e.g. FormatBase62, are Encoders. An Encoder is safe for concurrent use.
+type Encoder struct {
+	name     string
+	alphabet string
+	// digits maps a byte to its digit, or -1 outside the alphabet.
+	digits [256]int16
+	// maxLen is the length of math.MaxUint64.
+	maxLen int
+}
+
+// NewEncoder create an Encoder with alphabet, its bytes are the digits from 0, the first one pads the strings
+// of EncodePadded. It returns an error unless alphabet has 2 to 256 distinct bytes.
+func NewEncoder(alphabet string) (*Encoder, error) {
+	if len(alphabet) < 2 || len(alphabet) > 256 {
+		return nil, fmt.Errorf("snowflake: invalid alphabet %q: it must have 2 to 256 characters, got %d", alphabet, len(alphabet))
+	}
+	for i := 0; i < len(alphabet); i++ {
+		if j := strings.IndexByte(alphabet[:i], alphabet[i]); j >= 0 {
+			return nil, fmt.Errorf("snowflake: invalid alphabet %q: %q is duplicated at %d and %d", alphabet, alphabet[i], j, i)
+		}
+	}
+
+	return newEncoder("custom", alphabet), nil
+}
+
+// Encode returns id in the base of the alphabet, without padding.
+func (e *Encoder) Encode(id uint64) string {
+	return e.format(id, 0)
+}
+
+// EncodePadded returns id in the base of the alphabet, left padded with its first character to MaxLen,
+// so the strings sort in ID order when the alphabet is in ASCII order.
+func (e *Encoder) EncodePadded(id uint64) string {
+	return e.format(id, e.maxLen)
+}
+
+// Append appends id in the base of the alphabet, as Encode, to b and returns the extended buffer.
+// It doesn't allocate when b has the capacity, e.g. for the hot paths formatting the IDs into a reused buffer.
+func (e *Encoder) Append(b []byte, id uint64) []byte {
+	return e.appendFormat(b, id, 0)
+}
+
+// Decode parse a string of Encode or EncodePadded. It returns an error wrapping ErrEmptyString, ErrInvalidCharacter,
+// ErrStringTooLong when s is longer than MaxLen, or ErrValueOverflow.
+func (e *Encoder) Decode(s string) (uint64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("%w: %s", ErrEmptyString, e.name)
+	}
+	if len(s) > e.maxLen {
+		return 0, fmt.Errorf("%w: %s %q has %d characters, the max is %d", ErrStringTooLong, e.name, s, len(s), e.maxLen)
+	}
+
+	base := uint64(len(e.alphabet))
+	var id uint64
+	for i := 0; i < len(s); i++ {
+		d := e.digits[s[i]]
+		if d < 0 {
+			return 0, fmt.Errorf("%w: %s %q has %q at %d", ErrInvalidCharacter, e.name, s, s[i], i)
+		}
+		// 检测乘法和加法溢出
+		hi, lo := bits.Mul64(id, base)
+		sum, carry := bits.Add64(lo, uint64(d), 0)
+		if hi != 0 || carry != 0 {
+			return 0, fmt.Errorf("%w: %s %q", ErrValueOverflow, e.name, s)
+		}
+		id = sum
+	}
+
+	return id, nil
+}
+
+// MaxLen returns the length of the largest ID, math.MaxUint64, the length of the strings of EncodePadded.
+func (e *Encoder) MaxLen() int {
+	return e.maxLen
+}
+
+// Alphabet returns the alphabet of the encoder.
+func (e *Encoder) Alphabet() string {
+	return e.alphabet
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// newEncoder create an Encoder named name in the errors, alphabet must be valid.
+func newEncoder(name, alphabet string) *Encoder {
+	e := &Encoder{name: name, alphabet: alphabet}
+	for i := range e.digits {
+		e.digits[i] = -1
+	}
+	for i := 0; i < len(alphabet); i++ {
+		e.digits[alphabet[i]] = int16(i)
+	}
+	e.maxLen = len(e.format(math.MaxUint64, 0))
+
+	return e
+}
+
+// format returns id in the base of the alphabet, left padded with the zero digit to width.
+func (e *Encoder) format(id uint64, width int) string {
+	var b [64]byte
+
+	return string(e.appendFormat(b[:0], id, width))
+}
+
+// appendFormat appends id in the base of the alphabet, left padded with the zero digit to width, to dst.
+func (e *Encoder) appendFormat(dst []byte, id uint64, width int) []byte {
+	var buf [64]byte
+	base := uint64(len(e.alphabet))
+	i := len(buf)
+	for {
+		i--
+		buf[i] = e.alphabet[id%base]
+		if id /= base; id == 0 {
+			break
+		}
+	}
+	for len(buf)-i < width {
+		i--
+		buf[i] = e.alphabet[0]
+	}
+
+	return append(dst, buf[i:]...)
+}
@@ -0,0 +1,97 @@
+package snowflake_test
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestNewEncoder(t *testing.T) {
+	e, err := snowflake.NewEncoder("ybndrfg8ejkmcpqxot1uwisza345h769")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Encode(0); got != "y" {
+		t.Errorf("0 should be the first character, got %s", got)
+	}
+	if got := e.Encode(33); got != "bb" {
+		t.Errorf("33 should be bb, got %s", got)
+	}
+	if got := e.EncodePadded(33); got != "yyyyyyyyyyybb" || e.MaxLen() != 13 {
+		t.Errorf("The padding should use the first character up to 13 characters, got %s", got)
+	}
+	if got, err := e.Decode("yyyyyyyyyyybb"); err != nil || got != 33 {
+		t.Errorf("The padded string should decode, got %d, %v", got, err)
+	}
+	if _, err := e.Decode("yyb0"); !errors.Is(err, snowflake.ErrInvalidCharacter) {
+		t.Errorf("The error should be ErrInvalidCharacter, got %v", err)
+	}
+
+	// the built-in encodings are Encoders.
+	base62, _ := snowflake.NewEncoder("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")
+	if got := base62.Encode(math.MaxUint64); got != snowflake.FormatBase62(math.MaxUint64) {
+		t.Errorf("The encoder should match FormatBase62, got %s", got)
+	}
+
+	for _, alphabet := range []string{"", "0", "0120", strings.Repeat("ab", 129)} {
+		if _, err := snowflake.NewEncoder(alphabet); err == nil {
+			t.Errorf("The alphabet %q should be rejected", alphabet)
+		}
+	}
+}
+
+func TestEncoder_Bases(t *testing.T) {
+	var all []byte
+	for i := 0; i < 256; i++ {
+		all = append(all, byte(i))
+	}
+	for _, alphabet := range []string{"01", "012", string(all)} {
+		e, err := snowflake.NewEncoder(alphabet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roundTrip := func(id uint64) bool {
+			got, err := e.Decode(e.Encode(id))
+			padded, perr := e.Decode(e.EncodePadded(id))
+			appended := string(e.Append([]byte("x"), id))
+			return err == nil && perr == nil && got == id && padded == id && appended == "x"+e.Encode(id)
+		}
+		if err := quick.Check(roundTrip, nil); err != nil {
+			t.Errorf("base %d: %v", len(alphabet), err)
+		}
+		if _, err := e.Decode(e.Encode(math.MaxUint64) + alphabet[:1]); !errors.Is(err, snowflake.ErrStringTooLong) {
+			t.Errorf("base %d: the error should be ErrStringTooLong, got %v", len(alphabet), err)
+		}
+	}
+}
+
+func TestEncoder_DecodeFuzz(t *testing.T) {
+	e, _ := snowflake.NewEncoder("0123456789abcdefghijklmnopqrstuv")
+	rng := rand.New(rand.NewSource(1))
+	const chars = "0123456789abcdefghijklmnopqrstuvwxyzV-_ \x00\xff"
+	for i := 0; i < 100000; i++ {
+		b := make([]byte, rng.Intn(16))
+		for j := range b {
+			b[j] = chars[rng.Intn(len(chars))]
+		}
+		s := string(b)
+
+		id, err := e.Decode(s)
+		if err != nil {
+			if !errors.Is(err, snowflake.ErrEmptyString) && !errors.Is(err, snowflake.ErrInvalidCharacter) &&
+				!errors.Is(err, snowflake.ErrStringTooLong) && !errors.Is(err, snowflake.ErrValueOverflow) {
+				t.Fatalf("Decode(%q) failed with an untyped error %v", s, err)
+			}
+			continue
+		}
+		// a decoded string is the encoding of its ID, up to the leading zeros.
+		if want := strings.TrimLeft(s, "0"); e.Encode(id) != want && !(want == "" && id == 0) {
+			t.Fatalf("Decode(%q) = %d, which encodes to %s", s, id, e.Encode(id))
+		}
+	}
+}
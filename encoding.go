@@ -0,0 +1,258 @@
+package snowflake
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The errors of the string decoders, e.g. ParseBase62, wrapped with the input.
+var (
+	ErrEmptyString      = errors.New("snowflake: the encoded ID is empty")
+	ErrInvalidCharacter = errors.New("snowflake: the encoded ID has a character outside the alphabet")
+	ErrStringTooLong    = errors.New("snowflake: the encoded ID is longer than any uint64")
+	ErrValueOverflow    = errors.New("snowflake: the encoded ID overflows uint64")
+	ErrInvalidChecksum  = errors.New("snowflake: the check symbol of the encoded ID doesn't match")
+	ErrInvalidLength    = errors.New("snowflake: the encoded ID doesn't have the fixed length of the encoding")
+)
+
+// base62 is the 0-9A-Za-z alphabet, the IDs are at most 11 characters long.
+var base62 = newEncoder("base62", "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")
+
+// base58 is the Bitcoin alphabet, without the ambiguous 0, O, I and l, the IDs are at most 11 characters long.
+var base58 = newEncoder("base58", "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz")
+
+// crockford is the Crockford base32 alphabet, decoded case-insensitively with I and L read as 1 and O as 0,
+// the IDs are 13 characters long.
+var crockford = newCrockford()
+
+// crockfordCheckSymbols are the check symbols of the Crockford base32, the values modulo 37.
+const crockfordCheckSymbols = "0123456789ABCDEFGHJKMNPQRSTVWXYZ*~$=U"
+
+// base16 is the lowercase hexadecimal alphabet, decoded case-insensitively, the IDs are 16 characters long.
+var base16 = newHex()
+
+// decimal is the decimal alphabet of FormatPadded, the IDs are 20 digits long.
+var decimal = newEncoder("decimal", "0123456789")
+
+// FormatBase62 returns id in base62 with the 0-9A-Za-z alphabet, 11 characters at most instead of 19 digits,
+// e.g. for the IDs exposed in URLs. The strings of the IDs don't sort in ID order unless they have the same length.
+func FormatBase62(id uint64) string {
+	return base62.Encode(id)
+}
+
+// AppendBase62 appends id in base62, as FormatBase62, to b and returns the extended buffer.
+func AppendBase62(b []byte, id uint64) []byte {
+	return base62.Append(b, id)
+}
+
+// ParseBase62 parse a string of FormatBase62. It returns an error wrapping ErrEmptyString, ErrInvalidCharacter,
+// ErrStringTooLong when s is longer than 11 characters, or ErrValueOverflow.
+func ParseBase62(s string) (uint64, error) {
+	return base62.Decode(s)
+}
+
+// Base62 returns the ID in base62, see FormatBase62.
+func (id *SID) Base62() string {
+	return FormatBase62(id.ID)
+}
+
+// FormatBase58 returns id in base58 with the Bitcoin alphabet, 11 characters at most, e.g. for the codes read
+// by customers: the alphabet has no 0, O, I or l. The integer is encoded, 0 is "1".
+func FormatBase58(id uint64) string {
+	return base58.Encode(id)
+}
+
+// ParseBase58 parse a string of FormatBase58. It returns an error wrapping ErrEmptyString, ErrInvalidCharacter,
+// e.g. for 0 or l, ErrStringTooLong when s is longer than 11 characters, or ErrValueOverflow.
+func ParseBase58(s string) (uint64, error) {
+	return base58.Decode(s)
+}
+
+// Base58 returns the ID in base58, see FormatBase58.
+func (id *SID) Base58() string {
+	return FormatBase58(id.ID)
+}
+
+// FormatBase32Crockford returns id in the Crockford base32, e.g. for the IDs read over the phone, padded to 13 characters
+// so the strings sort in ID order. withCheck appends the check symbol, id modulo 37, which detects a mistyped character.
+func FormatBase32Crockford(id uint64, withCheck bool) string {
+	s := crockford.EncodePadded(id)
+	if withCheck {
+		s += string(crockfordCheckSymbols[id%37])
+	}
+
+	return s
+}
+
+// ParseBase32Crockford parse a string of FormatBase32Crockford, the padding is optional. The decoding is case-insensitive,
+// I and L are read as 1, O as 0, and the hyphens are ignored. With withCheck, the last character of s is the check symbol
+// and an error wrapping ErrInvalidChecksum is returned when it doesn't match.
+// The other errors wrap ErrEmptyString, ErrInvalidCharacter, ErrStringTooLong or ErrValueOverflow.
+func ParseBase32Crockford(s string, withCheck bool) (uint64, error) {
+	s = strings.ReplaceAll(s, "-", "")
+	if !withCheck {
+		return crockford.Decode(s)
+	}
+	if s == "" {
+		return 0, fmt.Errorf("%w: %s", ErrEmptyString, crockford.name)
+	}
+
+	id, err := crockford.Decode(s[:len(s)-1])
+	if err != nil {
+		return 0, err
+	}
+	check := s[len(s)-1]
+	c := int(crockford.digits[check])
+	if c < 0 {
+		// 校验符号 32-36 不在字母表中
+		if check == 'u' {
+			check = 'U'
+		}
+		if c = strings.IndexByte(crockfordCheckSymbols[32:], check); c < 0 {
+			return 0, fmt.Errorf("%w: %s check symbol %q", ErrInvalidCharacter, crockford.name, check)
+		}
+		c += 32
+	}
+	if uint64(c) != id%37 {
+		return 0, fmt.Errorf("%w: %s %q", ErrInvalidChecksum, crockford.name, s)
+	}
+
+	return id, nil
+}
+
+// Base32Crockford returns the ID in the Crockford base32 without check symbol, see FormatBase32Crockford.
+func (id *SID) Base32Crockford() string {
+	return FormatBase32Crockford(id.ID, false)
+}
+
+// FormatHex returns id in lowercase hexadecimal, zero padded to 16 characters, so the IDs line up in the logs
+// and the strings sort in ID order.
+func FormatHex(id uint64) string {
+	return base16.EncodePadded(id)
+}
+
+// ParseHex parse a string of FormatHex, the padding is optional. The 0x prefix is accepted, and the digits in either case.
+// The errors wrap ErrEmptyString, ErrInvalidCharacter, ErrStringTooLong or ErrValueOverflow.
+func ParseHex(s string) (uint64, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+
+	return base16.Decode(s)
+}
+
+// Hex returns the ID in hexadecimal, see FormatHex.
+func (id *SID) Hex() string {
+	return FormatHex(id.ID)
+}
+
+// FormatBase64URL returns the 8 big-endian bytes of id in unpadded base64url, always 11 characters,
+// e.g. for the IDs embedded in tokens. The strings don't sort in ID order, the alphabet is not in ASCII order.
+func FormatBase64URL(id uint64) string {
+	b := Bytes(id)
+	var out [11]byte
+	base64.RawURLEncoding.Encode(out[:], b[:])
+
+	return string(out[:])
+}
+
+// ParseBase64URL parse a string of FormatBase64URL. It returns an error wrapping ErrInvalidLength unless s has 11 characters,
+// ErrInvalidCharacter for the padding and the characters outside the base64url alphabet, e.g. + or /,
+// or ErrValueOverflow when the last character has bits beyond the 64 bits of an ID.
+func ParseBase64URL(s string) (uint64, error) {
+	if len(s) != 11 {
+		return 0, fmt.Errorf("%w: base64url %q has %d characters, not 11", ErrInvalidLength, s, len(s))
+	}
+
+	var b [8]byte
+	if _, err := base64.RawURLEncoding.Strict().Decode(b[:], []byte(s)); err != nil {
+		// 最后一个字符的低位不为 0 时 Strict 解码失败
+		if i := strings.IndexFunc(s, func(r rune) bool { return !isBase64URL(r) }); i >= 0 {
+			return 0, fmt.Errorf("%w: base64url %q has %q at %d", ErrInvalidCharacter, s, s[i], i)
+		}
+		return 0, fmt.Errorf("%w: base64url %q", ErrValueOverflow, s)
+	}
+
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
+// FormatPadded returns id in decimal, zero padded to 20 digits, so the strings sort in ID order,
+// e.g. for the systems storing the IDs as strings. strconv.FormatUint doesn't, the IDs have different lengths.
+func FormatPadded(id uint64) string {
+	return decimal.EncodePadded(id)
+}
+
+// ParsePadded parse a string of FormatPadded. It returns an error wrapping ErrInvalidLength unless s has 20 digits,
+// ErrInvalidCharacter for a non-digit, or ErrValueOverflow.
+func ParsePadded(s string) (uint64, error) {
+	if len(s) != decimal.MaxLen() {
+		return 0, fmt.Errorf("%w: padded decimal %q has %d characters, not %d", ErrInvalidLength, s, len(s), decimal.MaxLen())
+	}
+
+	return decimal.Decode(s)
+}
+
+// Padded returns the ID in zero padded decimal, see FormatPadded.
+func (id *SID) Padded() string {
+	return FormatPadded(id.ID)
+}
+
+// Bytes returns the 8 big-endian bytes of id, e.g. for the binary wire formats and the cache keys.
+// The bytes compare in ID order, bytes.Compare(Bytes(a), Bytes(b)) is the order of a and b,
+// so the IDs used as keys of LSM stores or B-trees sort by generation time.
+func Bytes(id uint64) [8]byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], id)
+
+	return b
+}
+
+// FromBytes returns the ID of the 8 big-endian bytes of Bytes.
+// It returns an error wrapping ErrInvalidLength unless b has 8 bytes.
+func FromBytes(b []byte) (uint64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("%w: binary ID has %d bytes, not 8", ErrInvalidLength, len(b))
+	}
+
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// Bytes returns the 8 big-endian bytes of the ID, see Bytes.
+func (id *SID) Bytes() [8]byte {
+	return Bytes(id.ID)
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+func newCrockford() *Encoder {
+	e := newEncoder("base32", "0123456789ABCDEFGHJKMNPQRSTVWXYZ")
+	// 不区分大小写，I、L 读作 1，O 读作 0
+	for i := 0; i < len(e.alphabet); i++ {
+		if c := e.alphabet[i]; c >= 'A' && c <= 'Z' {
+			e.digits[c|0x20] = int16(i)
+		}
+	}
+	e.digits['I'], e.digits['i'], e.digits['L'], e.digits['l'] = 1, 1, 1, 1
+	e.digits['O'], e.digits['o'] = 0, 0
+
+	return e
+}
+
+func newHex() *Encoder {
+	e := newEncoder("hex", "0123456789abcdef")
+	for c := byte('A'); c <= 'F'; c++ {
+		e.digits[c] = e.digits[c|0x20]
+	}
+
+	return e
+}
+
+// isBase64URL reports whether r is in the base64url alphabet.
+func isBase64URL(r rune) bool {
+	return r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_'
+}
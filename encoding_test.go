@@ -0,0 +1,413 @@
+package snowflake_test
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"testing/quick"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestFormatBase62(t *testing.T) {
+	golden := map[uint64]string{
+		0:              "0",
+		61:             "z",
+		62:             "10",
+		3843:           "zz",
+		math.MaxUint64: "LygHa16AHYF",
+	}
+	for id, want := range golden {
+		if got := snowflake.FormatBase62(id); got != want {
+			t.Errorf("FormatBase62(%d) should be %s, got %s", id, want, got)
+		}
+		if got, err := snowflake.ParseBase62(want); err != nil || got != id {
+			t.Errorf("ParseBase62(%s) should be %d, got %d, %v", want, id, got, err)
+		}
+	}
+
+	sid := snowflake.ParseID(snowflake.ID())
+	if got, _ := snowflake.ParseBase62(sid.Base62()); got != sid.ID {
+		t.Errorf("The base62 of the SID should decode to its ID, got %d", got)
+	}
+}
+
+func TestParseBase62_RoundTrip(t *testing.T) {
+	roundTrip := func(id uint64) bool {
+		got, err := snowflake.ParseBase62(snowflake.FormatBase62(id))
+		return err == nil && got == id
+	}
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 10000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseBase62_Invalid(t *testing.T) {
+	cases := map[string]error{
+		"":             snowflake.ErrEmptyString,
+		"abc-":         snowflake.ErrInvalidCharacter,
+		"ab c":         snowflake.ErrInvalidCharacter,
+		"é":            snowflake.ErrInvalidCharacter,
+		"000000000000": snowflake.ErrStringTooLong,
+		"LygHa16AHYG":  snowflake.ErrValueOverflow,
+		"zzzzzzzzzzz":  snowflake.ErrValueOverflow,
+	}
+	for s, want := range cases {
+		if _, err := snowflake.ParseBase62(s); !errors.Is(err, want) {
+			t.Errorf("ParseBase62(%q) should fail with %v, got %v", s, want, err)
+		}
+	}
+}
+
+func TestFormatBase58(t *testing.T) {
+	// the golden vectors pin the Bitcoin alphabet.
+	golden := map[uint64]string{
+		0:                   "1",
+		57:                  "z",
+		58:                  "21",
+		3363:                "zz",
+		1234567890:          "2t6V2H",
+		1790392102848512000: "5A3Yoa9zsgT",
+		math.MaxUint64:      "jpXCZedGfVQ",
+	}
+	for id, want := range golden {
+		if got := snowflake.FormatBase58(id); got != want {
+			t.Errorf("FormatBase58(%d) should be %s, got %s", id, want, got)
+		}
+		if got, err := snowflake.ParseBase58(want); err != nil || got != id {
+			t.Errorf("ParseBase58(%s) should be %d, got %d, %v", want, id, got, err)
+		}
+	}
+
+	roundTrip := func(id uint64) bool {
+		got, err := snowflake.ParseBase58(snowflake.FormatBase58(id))
+		return err == nil && got == id
+	}
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 10000}); err != nil {
+		t.Error(err)
+	}
+
+	sid := snowflake.ParseID(snowflake.ID())
+	if got, _ := snowflake.ParseBase58(sid.Base58()); got != sid.ID {
+		t.Errorf("The base58 of the SID should decode to its ID, got %d", got)
+	}
+}
+
+func TestParseBase58_Invalid(t *testing.T) {
+	cases := map[string]error{
+		"":             snowflake.ErrEmptyString,
+		"0":            snowflake.ErrInvalidCharacter,
+		"O":            snowflake.ErrInvalidCharacter,
+		"I":            snowflake.ErrInvalidCharacter,
+		"l":            snowflake.ErrInvalidCharacter,
+		"111111111111": snowflake.ErrStringTooLong,
+		"jpXCZedGfVR":  snowflake.ErrValueOverflow,
+	}
+	for s, want := range cases {
+		if _, err := snowflake.ParseBase58(s); !errors.Is(err, want) {
+			t.Errorf("ParseBase58(%q) should fail with %v, got %v", s, want, err)
+		}
+	}
+}
+
+func TestFormatBase32Crockford(t *testing.T) {
+	golden := []struct {
+		id    uint64
+		plain string
+		check string
+	}{
+		{0, "0000000000000", "0"},
+		{32, "0000000000010", "*"},
+		{33, "0000000000011", "~"},
+		{34, "0000000000012", "$"},
+		{35, "0000000000013", "="},
+		{36, "0000000000014", "U"},
+		{1234, "000000000016J", "D"},
+		{math.MaxUint64, "FZZZZZZZZZZZZ", "B"},
+	}
+	for _, g := range golden {
+		if got := snowflake.FormatBase32Crockford(g.id, false); got != g.plain {
+			t.Errorf("FormatBase32Crockford(%d) should be %s, got %s", g.id, g.plain, got)
+		}
+		if got := snowflake.FormatBase32Crockford(g.id, true); got != g.plain+g.check {
+			t.Errorf("FormatBase32Crockford(%d) with check should be %s, got %s", g.id, g.plain+g.check, got)
+		}
+		if got, err := snowflake.ParseBase32Crockford(g.plain+g.check, true); err != nil || got != g.id {
+			t.Errorf("ParseBase32Crockford(%s) should be %d, got %d, %v", g.plain+g.check, g.id, got, err)
+		}
+	}
+
+	// the padded strings sort in ID order.
+	if a, b := snowflake.FormatBase32Crockford(31, false), snowflake.FormatBase32Crockford(32, false); a >= b {
+		t.Errorf("%s should sort before %s", a, b)
+	}
+
+	roundTrip := func(id uint64, withCheck bool) bool {
+		got, err := snowflake.ParseBase32Crockford(snowflake.FormatBase32Crockford(id, withCheck), withCheck)
+		return err == nil && got == id
+	}
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 10000}); err != nil {
+		t.Error(err)
+	}
+
+	sid := snowflake.ParseID(snowflake.ID())
+	if got, _ := snowflake.ParseBase32Crockford(sid.Base32Crockford(), false); got != sid.ID {
+		t.Errorf("The base32 of the SID should decode to its ID, got %d", got)
+	}
+}
+
+func TestParseBase32Crockford_Normalization(t *testing.T) {
+	// the decoding rules of the Crockford base32 specification.
+	cases := map[string]uint64{
+		"16J":     1234,
+		"16j":     1234,
+		"1-6-J":   1234,
+		"0O0o16J": 1234,
+		"I":       1,
+		"i":       1,
+		"L":       1,
+		"l":       1,
+		"O":       0,
+		"o":       0,
+		"zz":      1023,
+	}
+	for s, want := range cases {
+		if got, err := snowflake.ParseBase32Crockford(s, false); err != nil || got != want {
+			t.Errorf("ParseBase32Crockford(%q) should be %d, got %d, %v", s, want, got, err)
+		}
+	}
+	if got, err := snowflake.ParseBase32Crockford("16jd", true); err != nil || got != 1234 {
+		t.Errorf("The check symbol should be case-insensitive, got %d, %v", got, err)
+	}
+	if got, err := snowflake.ParseBase32Crockford("14u", true); err != nil || got != 36 {
+		t.Errorf("The check symbol u should be read as U, got %d, %v", got, err)
+	}
+}
+
+func TestParseBase32Crockford_Invalid(t *testing.T) {
+	cases := []struct {
+		s         string
+		withCheck bool
+		want      error
+	}{
+		{"", false, snowflake.ErrEmptyString},
+		{"", true, snowflake.ErrEmptyString},
+		{"U", false, snowflake.ErrInvalidCharacter},
+		{"16J*", false, snowflake.ErrInvalidCharacter},
+		{"16J#", true, snowflake.ErrInvalidCharacter},
+		{"16JE", true, snowflake.ErrInvalidChecksum},
+		{"16KD", true, snowflake.ErrInvalidChecksum},
+		{"00000000000000", false, snowflake.ErrStringTooLong},
+		{"G000000000000", false, snowflake.ErrValueOverflow},
+	}
+	for _, c := range cases {
+		if _, err := snowflake.ParseBase32Crockford(c.s, c.withCheck); !errors.Is(err, c.want) {
+			t.Errorf("ParseBase32Crockford(%q, %v) should fail with %v, got %v", c.s, c.withCheck, c.want, err)
+		}
+	}
+}
+
+func TestFormatHex(t *testing.T) {
+	golden := map[uint64]string{
+		0:                  "0000000000000000",
+		255:                "00000000000000ff",
+		0x18d8a1c2b3e4f500: "18d8a1c2b3e4f500",
+		math.MaxUint64:     "ffffffffffffffff",
+	}
+	for id, want := range golden {
+		if got := snowflake.FormatHex(id); got != want {
+			t.Errorf("FormatHex(%d) should be %s, got %s", id, want, got)
+		}
+	}
+	for s, want := range map[string]uint64{"00000000000000ff": 255, "0xFF": 255, "0X00ff": 255, "Ab": 0xab} {
+		if got, err := snowflake.ParseHex(s); err != nil || got != want {
+			t.Errorf("ParseHex(%q) should be %d, got %d, %v", s, want, got, err)
+		}
+	}
+
+	// the strings sort in ID order.
+	sorted := func(a, b uint64) bool {
+		if a > b {
+			a, b = b, a
+		}
+		return a == b || snowflake.FormatHex(a) < snowflake.FormatHex(b)
+	}
+	if err := quick.Check(sorted, &quick.Config{MaxCount: 10000}); err != nil {
+		t.Error(err)
+	}
+
+	sid := snowflake.ParseID(snowflake.ID())
+	if got, _ := snowflake.ParseHex(sid.Hex()); got != sid.ID || len(sid.Hex()) != 16 {
+		t.Errorf("The hex of the SID should decode to its ID, got %d", got)
+	}
+}
+
+func TestParseHex_Invalid(t *testing.T) {
+	cases := map[string]error{
+		"":                  snowflake.ErrEmptyString,
+		"0x":                snowflake.ErrEmptyString,
+		"0xg":               snowflake.ErrInvalidCharacter,
+		" ff":               snowflake.ErrInvalidCharacter,
+		"-1":                snowflake.ErrInvalidCharacter,
+		"x10":               snowflake.ErrInvalidCharacter,
+		"00000000000000000": snowflake.ErrStringTooLong,
+	}
+	for s, want := range cases {
+		if _, err := snowflake.ParseHex(s); !errors.Is(err, want) {
+			t.Errorf("ParseHex(%q) should fail with %v, got %v", s, want, err)
+		}
+	}
+}
+
+func TestFormatBase64URL(t *testing.T) {
+	golden := map[uint64]string{
+		0:                  "AAAAAAAAAAA",
+		1:                  "AAAAAAAAAAE",
+		0x18d8a1c2b3e4f500: "GNihwrPk9QA",
+		math.MaxUint64:     "__________8",
+	}
+	for id, want := range golden {
+		if got := snowflake.FormatBase64URL(id); got != want {
+			t.Errorf("FormatBase64URL(%d) should be %s, got %s", id, want, got)
+		}
+	}
+
+	roundTrip := func(id uint64) bool {
+		got, err := snowflake.ParseBase64URL(snowflake.FormatBase64URL(id))
+		return err == nil && got == id
+	}
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 10000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseBase64URL_Invalid(t *testing.T) {
+	cases := map[string]error{
+		"":             snowflake.ErrInvalidLength,
+		"AAAAAAAAAA":   snowflake.ErrInvalidLength,
+		"AAAAAAAAAAAA": snowflake.ErrInvalidLength,
+		"AAAAAAAAAAE=": snowflake.ErrInvalidLength,
+		"AAAAAAAAAA=":  snowflake.ErrInvalidCharacter,
+		"AAAAAAAAA+E":  snowflake.ErrInvalidCharacter,
+		"AAAAAAAAA/E":  snowflake.ErrInvalidCharacter,
+		"AAAAAAAAA E":  snowflake.ErrInvalidCharacter,
+		"AAAAAAAAAAB":  snowflake.ErrValueOverflow,
+		"___________":  snowflake.ErrValueOverflow,
+	}
+	for s, want := range cases {
+		if _, err := snowflake.ParseBase64URL(s); !errors.Is(err, want) {
+			t.Errorf("ParseBase64URL(%q) should fail with %v, got %v", s, want, err)
+		}
+	}
+}
+
+func BenchmarkFormatBase64URL(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = snowflake.FormatBase64URL(uint64(i))
+	}
+}
+
+func TestFormatPadded(t *testing.T) {
+	golden := map[uint64]string{
+		0:              "00000000000000000000",
+		42:             "00000000000000000042",
+		math.MaxUint64: "18446744073709551615",
+	}
+	for id, want := range golden {
+		if got := snowflake.FormatPadded(id); got != want {
+			t.Errorf("FormatPadded(%d) should be %s, got %s", id, want, got)
+		}
+		if got, err := snowflake.ParsePadded(want); err != nil || got != id {
+			t.Errorf("ParsePadded(%s) should be %d, got %d, %v", want, id, got, err)
+		}
+	}
+
+	// for random pairs a < b, FormatPadded(a) < FormatPadded(b).
+	sorted := func(a, b uint64) bool {
+		if a > b {
+			a, b = b, a
+		}
+		return a == b || snowflake.FormatPadded(a) < snowflake.FormatPadded(b)
+	}
+	if err := quick.Check(sorted, &quick.Config{MaxCount: 10000}); err != nil {
+		t.Error(err)
+	}
+
+	sid := snowflake.ParseID(snowflake.ID())
+	if got, _ := snowflake.ParsePadded(sid.Padded()); got != sid.ID {
+		t.Errorf("The padded decimal of the SID should decode to its ID, got %d", got)
+	}
+}
+
+func TestParsePadded_Invalid(t *testing.T) {
+	cases := map[string]error{
+		"":                      snowflake.ErrInvalidLength,
+		"42":                    snowflake.ErrInvalidLength,
+		"000000000000000000042": snowflake.ErrInvalidLength,
+		"0000000000000000004a":  snowflake.ErrInvalidCharacter,
+		"+0000000000000000042":  snowflake.ErrInvalidCharacter,
+		"18446744073709551616":  snowflake.ErrValueOverflow,
+		"99999999999999999999":  snowflake.ErrValueOverflow,
+	}
+	for s, want := range cases {
+		if _, err := snowflake.ParsePadded(s); !errors.Is(err, want) {
+			t.Errorf("ParsePadded(%q) should fail with %v, got %v", s, want, err)
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	b := snowflake.Bytes(0x0102030405060708)
+	if want := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}; b != want {
+		t.Errorf("Bytes should be big-endian, got %v", b)
+	}
+
+	// for random pairs, the bytes compare in the order of the IDs and decode to the IDs.
+	ordered := func(x, y uint64) bool {
+		bx, by := snowflake.Bytes(x), snowflake.Bytes(y)
+		id, err := snowflake.FromBytes(bx[:])
+		if err != nil || id != x {
+			return false
+		}
+		switch c := bytes.Compare(bx[:], by[:]); {
+		case x < y:
+			return c < 0
+		case x > y:
+			return c > 0
+		default:
+			return c == 0
+		}
+	}
+	if err := quick.Check(ordered, &quick.Config{MaxCount: 10000}); err != nil {
+		t.Error(err)
+	}
+
+	for _, n := range []int{0, 7, 9} {
+		if _, err := snowflake.FromBytes(make([]byte, n)); !errors.Is(err, snowflake.ErrInvalidLength) {
+			t.Errorf("FromBytes of %d bytes should fail with ErrInvalidLength, got %v", n, err)
+		}
+	}
+
+	sid := snowflake.ParseID(snowflake.ID())
+	if got := sid.Bytes(); got != snowflake.Bytes(sid.ID) {
+		t.Errorf("The bytes of the SID should be the bytes of its ID, got %v", got)
+	}
+}
+
+func TestTID_MarshalBinary(t *testing.T) {
+	id := snowflake.TID(math.MaxUint64 - 1)
+	b, err := id.MarshalBinary()
+	if err != nil || len(b) != 8 {
+		t.Fatalf("MarshalBinary should return 8 bytes, got %v, %v", b, err)
+	}
+
+	var got snowflake.TID
+	if err := got.UnmarshalBinary(b); err != nil || got != id {
+		t.Errorf("The ID should round-trip, got %d, %v", got, err)
+	}
+	if err := got.UnmarshalBinary(b[:4]); !errors.Is(err, snowflake.ErrInvalidLength) {
+		t.Errorf("UnmarshalBinary of 4 bytes should fail with ErrInvalidLength, got %v", err)
+	}
+}
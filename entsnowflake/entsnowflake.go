@@ -0,0 +1,124 @@
+// Package entsnowflake generates the snowflake IDs of the ent schemas. Declare the id field with Mixin in the schemas:
+//
+//	func (Order) Mixin() []ent.Mixin {
+//		return []ent.Mixin{entsnowflake.Mixin{}}
+//	}
+//
+// and wire the generator when the client is constructed, the hook of Mixin is registered by the generated runtime package:
+//
+//	import _ "<project>/ent/runtime"
+//
+//	client, err := ent.Open(dialect.Postgres, dsn)
+//	client.Use(entsnowflake.Hook(g))
+//
+// The creates without an id get an ID of the generator, a failed ID generation fails the create.
+// The ids set by the caller are kept, but they must be positive and their time no later than a minute from now,
+// the IDs of another generator, e.g. of an older start time, look generated in the future.
+// It is a separate module, so the snowflake module doesn't depend on ent.
+package entsnowflake
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/mixin"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// maxClockSkew is how far in the future the time of an id set by the caller can be, the clocks of the machines differ.
+const maxClockSkew = time.Minute
+
+// Mixin declares the immutable int64 id field of the snowflake IDs, a BIGINT column,
+// and the hook filling it on create with the generator of Hook.
+type Mixin struct {
+	mixin.Schema
+}
+
+// Fields of the Mixin.
+func (Mixin) Fields() []ent.Field {
+	return []ent.Field{
+		field.Int64("id").Immutable(),
+	}
+}
+
+// Hooks of the Mixin.
+func (Mixin) Hooks() []ent.Hook {
+	return []ent.Hook{fillID}
+}
+
+// Hook returns the client hook wiring g into the creates of the schemas declared with Mixin,
+// register it on the client with client.Use. The creates fail without it.
+func Hook(g *snowflake.Generator) ent.Hook {
+	return func(next ent.Mutator) ent.Mutator {
+		return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+			if m.Op().Is(ent.OpCreate) {
+				ctx = context.WithValue(ctx, generatorKey{}, g)
+			}
+			return next.Mutate(ctx, m)
+		})
+	}
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// generatorKey is the context key of the generator set by Hook.
+type generatorKey struct{}
+
+// idMutation is the generated mutation of a schema declared with Mixin.
+type idMutation interface {
+	ID() (int64, bool)
+	SetID(id int64)
+}
+
+// fillID set the id of a create from the generator of Hook, or validate the id set by the caller.
+func fillID(next ent.Mutator) ent.Mutator {
+	return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+		if !m.Op().Is(ent.OpCreate) {
+			return next.Mutate(ctx, m)
+		}
+
+		im, ok := m.(idMutation)
+		if !ok {
+			return nil, fmt.Errorf("entsnowflake: unexpected mutation %T, the id of %s must be an int64", m, m.Type())
+		}
+		g, ok := ctx.Value(generatorKey{}).(*snowflake.Generator)
+		if !ok || g == nil {
+			return nil, fmt.Errorf("entsnowflake: no generator for the %s create, register entsnowflake.Hook on the client", m.Type())
+		}
+
+		if id, ok := im.ID(); ok {
+			if err := validate(g, id); err != nil {
+				return nil, fmt.Errorf("entsnowflake: invalid %s id: %w", m.Type(), err)
+			}
+			return next.Mutate(ctx, m)
+		}
+
+		id, err := g.NextInt64()
+		if err != nil {
+			return nil, err
+		}
+		im.SetID(id)
+
+		return next.Mutate(ctx, m)
+	})
+}
+
+// validate check that id is positive and its time, decoded by g, is no later than maxClockSkew from now.
+func validate(g *snowflake.Generator, id int64) error {
+	if id <= 0 {
+		return fmt.Errorf("%d is not positive", id)
+	}
+
+	sid := g.ParseID(uint64(id))
+	if t, latest := sid.GenerateTime(), time.Now().Add(maxClockSkew); t.After(latest) {
+		return fmt.Errorf("the time of %d is %s, after %s, it was not generated by this generator", id, t.Format(time.RFC3339Nano), latest.Format(time.RFC3339Nano))
+	}
+
+	return nil
+}
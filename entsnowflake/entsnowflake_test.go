@@ -0,0 +1,145 @@
+package entsnowflake_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/entsnowflake"
+)
+
+// orderMutation is the part of a generated mutation used by the hooks, for an Order schema declared with Mixin.
+// The other methods of ent.Mutation are not implemented.
+type orderMutation struct {
+	ent.Mutation
+	op ent.Op
+	id *int64
+}
+
+func (m *orderMutation) Op() ent.Op     { return m.op }
+func (m *orderMutation) Type() string   { return "Order" }
+func (m *orderMutation) SetID(id int64) { m.id = &id }
+
+func (m *orderMutation) ID() (int64, bool) {
+	if m.id == nil {
+		return 0, false
+	}
+	return *m.id, true
+}
+
+// create runs m through the client hooks and the hooks of Mixin, as the generated code does,
+// and returns the id seen by the insert.
+func create(ctx context.Context, m *orderMutation, clientHooks ...ent.Hook) (int64, error) {
+	var inserted int64
+	var mutator ent.Mutator = ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+		inserted, _ = m.(*orderMutation).ID()
+		return nil, nil
+	})
+
+	// 客户端的 hooks 在 schema 的 hooks 之外
+	hooks := append(clientHooks, entsnowflake.Mixin{}.Hooks()...)
+	for i := len(hooks) - 1; i >= 0; i-- {
+		mutator = hooks[i](mutator)
+	}
+	_, err := mutator.Mutate(ctx, m)
+
+	return inserted, err
+}
+
+func newGenerator(t *testing.T) *snowflake.Generator {
+	t.Helper()
+
+	g, err := snowflake.New(snowflake.WithMachineID(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return g
+}
+
+func TestMixin_Fields(t *testing.T) {
+	fields := entsnowflake.Mixin{}.Fields()
+	if len(fields) != 1 {
+		t.Fatalf("The mixin should declare the id field only, got %d fields", len(fields))
+	}
+	d := fields[0].Descriptor()
+	if d.Name != "id" || d.Info.Type != field.TypeInt64 || !d.Immutable {
+		t.Errorf("The id should be an immutable int64, got %s %s immutable %t", d.Name, d.Info.Type, d.Immutable)
+	}
+}
+
+func TestHook_Create(t *testing.T) {
+	ctx := context.Background()
+	g := newGenerator(t)
+	hook := entsnowflake.Hook(g)
+
+	var last int64
+	for i := 0; i < 100; i++ {
+		id, err := create(ctx, &orderMutation{op: ent.OpCreate}, hook)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id <= last {
+			t.Fatalf("The IDs should be unique and increase, got %d after %d", id, last)
+		}
+		if sid := g.ParseID(uint64(id)); sid.MachineID != 7 {
+			t.Errorf("The ID %d should come from the generator, got machineID %d", id, sid.MachineID)
+		}
+		last = id
+	}
+
+	set, err := g.NextInt64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &orderMutation{op: ent.OpCreate}
+	m.SetID(set)
+	if id, err := create(ctx, m, hook); err != nil || id != set {
+		t.Errorf("The id set should be kept, got %d, %v", id, err)
+	}
+
+	// 更新不生成 ID
+	if id, err := create(ctx, &orderMutation{op: ent.OpUpdate}, hook); err != nil || id != 0 {
+		t.Errorf("An update should not get an ID, got %d, %v", id, err)
+	}
+}
+
+func TestHook_Validate(t *testing.T) {
+	hook := entsnowflake.Hook(newGenerator(t))
+
+	for _, id := range []int64{0, -1, math.MaxInt64} {
+		m := &orderMutation{op: ent.OpCreate}
+		m.SetID(id)
+		if _, err := create(context.Background(), m, hook); err == nil || !strings.Contains(err.Error(), "entsnowflake: invalid Order id") {
+			t.Errorf("The id %d should be rejected, got %v", id, err)
+		}
+	}
+}
+
+func TestHook_Missing(t *testing.T) {
+	_, err := create(context.Background(), &orderMutation{op: ent.OpCreate})
+	if err == nil || !strings.Contains(err.Error(), "entsnowflake.Hook") {
+		t.Errorf("The create should fail without the hook, got %v", err)
+	}
+}
+
+func TestHook_Error(t *testing.T) {
+	// machineID 不可用时无法生成 ID
+	g, err := snowflake.New(snowflake.WithMachineIDProvider(func(ctx context.Context) (uint16, error) {
+		return 0, errors.New("no machineID")
+	}), snowflake.WithMachineIDProviderNoWait())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close(context.Background())
+
+	if _, err := create(context.Background(), &orderMutation{op: ent.OpCreate}, entsnowflake.Hook(g)); !errors.Is(err, snowflake.ErrMachineIDUnavailable) {
+		t.Errorf("The error of the generator should be returned, got %v", err)
+	}
+}
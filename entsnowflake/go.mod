@@ -0,0 +1,10 @@
+module github.com/hedwi/go-snowflake/entsnowflake
+
+go 1.25.0
+
+require (
+	entgo.io/ent v0.14.6
+	github.com/hedwi/go-snowflake v0.0.0-20261015084624-fec5baa67a53
+)
+
+require github.com/google/uuid v1.6.0 // indirect
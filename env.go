@@ -0,0 +1,200 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// These are the environment variables read by InitFromEnv and NewFromEnv.
+const (
+	EnvMachineID         = "SNOWFLAKE_MACHINE_ID"
+	EnvDatacenterID      = "SNOWFLAKE_DATACENTER_ID"
+	EnvWorkerID          = "SNOWFLAKE_WORKER_ID"
+	EnvEpoch             = "SNOWFLAKE_EPOCH"
+	EnvTimestampBits     = "SNOWFLAKE_TIMESTAMP_BITS"
+	EnvMachineIDBits     = "SNOWFLAKE_MACHINE_ID_BITS"
+	EnvSequenceBits      = "SNOWFLAKE_SEQUENCE_BITS"
+	EnvDatacenterBits    = "SNOWFLAKE_DATACENTER_BITS"
+	EnvTimeUnit          = "SNOWFLAKE_TIME_UNIT"
+	EnvBackwardTolerance = "SNOWFLAKE_BACKWARD_TOLERANCE"
+)
+
+// envFields maps the config fields to the environment variables, so the validation errors name the variable.
+var envFields = map[string]string{
+	"machine_id":         EnvMachineID,
+	"datacenter_id":      EnvDatacenterID,
+	"worker_id":          EnvWorkerID,
+	"epoch":              EnvEpoch,
+	"layout":             "SNOWFLAKE_*_BITS",
+	"time_unit":          EnvTimeUnit,
+	"backward_tolerance": EnvBackwardTolerance,
+}
+
+// ConfigFromEnv read the SNOWFLAKE_* environment variables into a Config, missing variables keep the defaults:
+//
+//	SNOWFLAKE_MACHINE_ID, SNOWFLAKE_DATACENTER_ID, SNOWFLAKE_WORKER_ID: decimal numbers.
+//	SNOWFLAKE_EPOCH: RFC3339 time or unix milliseconds.
+//	SNOWFLAKE_TIMESTAMP_BITS, SNOWFLAKE_MACHINE_ID_BITS, SNOWFLAKE_SEQUENCE_BITS, SNOWFLAKE_DATACENTER_BITS:
+//		override the widths of DefaultLayout, they must still sum to 63.
+//	SNOWFLAKE_TIME_UNIT, SNOWFLAKE_BACKWARD_TOLERANCE: durations, e.g. 10ms.
+//
+// A malformed value returns an error naming the variable.
+func ConfigFromEnv() (Config, error) {
+	var c Config
+
+	for _, id := range []struct {
+		name string
+		dst  **uint16
+	}{
+		{EnvMachineID, &c.MachineID},
+		{EnvDatacenterID, &c.DatacenterID},
+		{EnvWorkerID, &c.WorkerID},
+	} {
+		if v, ok := os.LookupEnv(id.name); ok {
+			n, err := parseEnvUint16(id.name, v)
+			if err != nil {
+				return Config{}, err
+			}
+			*id.dst = &n
+		}
+	}
+
+	if v, ok := os.LookupEnv(EnvEpoch); ok {
+		epoch, err := parseEpoch(v)
+		if err != nil {
+			return Config{}, envError(EnvEpoch, err)
+		}
+		c.Epoch = epoch
+	}
+
+	layout := LayoutConfig{
+		TimestampBits:  DefaultLayout.TimestampBits,
+		MachineBits:    DefaultLayout.MachineBits,
+		SequenceBits:   DefaultLayout.SequenceBits,
+		DatacenterBits: DefaultLayout.DatacenterBits,
+	}
+	customLayout := false
+	for _, bits := range []struct {
+		name string
+		dst  *uint8
+	}{
+		{EnvTimestampBits, &layout.TimestampBits},
+		{EnvMachineIDBits, &layout.MachineBits},
+		{EnvSequenceBits, &layout.SequenceBits},
+		{EnvDatacenterBits, &layout.DatacenterBits},
+	} {
+		if v, ok := os.LookupEnv(bits.name); ok {
+			n, err := strconv.ParseUint(v, 10, 8)
+			if err != nil {
+				return Config{}, envError(bits.name, err)
+			}
+			*bits.dst = uint8(n)
+			customLayout = true
+		}
+	}
+	if customLayout {
+		c.Layout = layout
+	}
+
+	if v, ok := os.LookupEnv(EnvTimeUnit); ok {
+		p, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, envError(EnvTimeUnit, err)
+		}
+		c.TimeUnit = Duration(p)
+	}
+
+	if v, ok := os.LookupEnv(EnvBackwardTolerance); ok {
+		p, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, envError(EnvBackwardTolerance, err)
+		}
+		d := Duration(p)
+		c.BackwardTolerance = &d
+	}
+
+	return c, nil
+}
+
+// NewFromEnv create a Generator from the SNOWFLAKE_* environment variables, see ConfigFromEnv.
+// A validation error names the offending variable.
+func NewFromEnv() (*Generator, error) {
+	c, err := ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := NewFromConfig(c)
+	if err != nil {
+		var fe *fieldError
+		if errors.As(err, &fe) {
+			return nil, envError(envFields[fe.field], fe.err)
+		}
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// InitFromEnv configure the default generator from the SNOWFLAKE_* environment variables, see ConfigFromEnv.
+// The default generator is configured in place: the machineID, start time, layout, time unit, backward tolerance
+// and order are replaced by the ones of NewFromEnv, the variables not set reset them to the defaults.
+// The hooks, the clock, the resolver and the other settings are kept, and Default keeps returning the same generator.
+// It returns ErrConfigFrozen when the default generator has already generated an ID.
+// This function is thread-unsafe, recommended you call him in the main function.
+func InitFromEnv() error {
+	if err := defaultGenerator.checkNotStarted(); err != nil {
+		return err
+	}
+
+	env, err := NewFromEnv()
+	if err != nil {
+		return err
+	}
+	defaultGenerator.applyConfig(env)
+
+	return nil
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// applyConfig copy the settings of a Config from c, a generator created by NewFromConfig, to g.
+func (g *Generator) applyConfig(c *Generator) {
+	g.layout, g.timeUnit, g.startTime, g.descending = c.layout, c.timeUnit, c.startTime, c.descending
+	atomic.StoreInt64(&g.epoch, c.epoch)
+	atomic.StoreUint64(&g.machineID, c.machineID)
+	atomic.StoreInt32(&g.machineIDSet, c.machineIDSet)
+	atomic.StoreInt64(&g.maxBackward, c.maxBackward)
+}
+
+func parseEnvUint16(name, v string) (uint16, error) {
+	n, err := strconv.ParseUint(v, 10, 16)
+	if err != nil {
+		return 0, envError(name, err)
+	}
+
+	return uint16(n), nil
+}
+
+// parseEpoch accept RFC3339 time or unix milliseconds, and returns it as a RFC3339 time.
+func parseEpoch(v string) (string, error) {
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(0, ms*int64(time.Millisecond)).UTC().Format(time.RFC3339Nano), nil
+	}
+
+	if _, err := time.Parse(time.RFC3339, v); err != nil {
+		return "", fmt.Errorf("neither RFC3339 nor unix milliseconds: %w", err)
+	}
+
+	return v, nil
+}
+
+func envError(name string, err error) error {
+	return fmt.Errorf("snowflake: invalid environment variable %s: %w", name, err)
+}
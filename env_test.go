@@ -0,0 +1,192 @@
+package snowflake_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// setenv set an environment variable for the test, t.Setenv requires go1.17.
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+
+	old, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	setenv(t, snowflake.EnvMachineID, "1000")
+	setenv(t, snowflake.EnvEpoch, "2020-01-01T00:00:00Z")
+	setenv(t, snowflake.EnvMachineIDBits, "10")
+	setenv(t, snowflake.EnvTimestampBits, "41")
+	setenv(t, snowflake.EnvTimeUnit, "1ms")
+	setenv(t, snowflake.EnvBackwardTolerance, "500ms")
+
+	c, err := snowflake.ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.MachineID == nil || *c.MachineID != 1000 || c.Epoch != "2020-01-01T00:00:00Z" || c.Layout.TimestampBits != 41 || c.Layout.MachineBits != 10 ||
+		c.Layout.SequenceBits != snowflake.SequenceLength || time.Duration(*c.BackwardTolerance) != 500*time.Millisecond {
+		t.Errorf("The config should be read from the environment, got %+v", c)
+	}
+
+	g, err := snowflake.NewFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sid := g.ParseID(g.ID())
+	if sid.MachineID != 1000 {
+		t.Errorf("The machineID should be 1000, got %d", sid.MachineID)
+	}
+}
+
+func TestConfigFromEnv_MachineIDZero(t *testing.T) {
+	setenv(t, snowflake.EnvMachineID, "0")
+
+	g, err := snowflake.NewFromEnv()
+	checkExplicitMachineIDZero(t, g, err)
+}
+
+func TestConfigFromEnv_Defaults(t *testing.T) {
+	g, err := snowflake.NewFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Layout() != snowflake.DefaultLayout {
+		t.Errorf("The layout should be the default, got %s", g.Layout())
+	}
+}
+
+func TestConfigFromEnv_EpochMillis(t *testing.T) {
+	setenv(t, snowflake.EnvEpoch, "1577836800123")
+
+	c, err := snowflake.ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Epoch != "2020-01-01T00:00:00.123Z" {
+		t.Errorf("The epoch should be converted to RFC3339, got %s", c.Epoch)
+	}
+}
+
+func TestConfigFromEnv_Malformed(t *testing.T) {
+	tests := map[string]string{
+		snowflake.EnvMachineID:         "abc",
+		snowflake.EnvDatacenterID:      "-1",
+		snowflake.EnvWorkerID:          "70000",
+		snowflake.EnvEpoch:             "yesterday",
+		snowflake.EnvSequenceBits:      "300",
+		snowflake.EnvTimeUnit:          "fast",
+		snowflake.EnvBackwardTolerance: "1",
+	}
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			setenv(t, name, value)
+
+			_, err := snowflake.NewFromEnv()
+			if err == nil || !strings.Contains(err.Error(), name) {
+				t.Errorf("The error should name %s, got %v", name, err)
+			}
+		})
+	}
+}
+
+func TestNewFromEnv_Invalid(t *testing.T) {
+	tests := map[string]string{
+		snowflake.EnvMachineID:     "1000",
+		snowflake.EnvEpoch:         "2999-01-01T00:00:00Z",
+		snowflake.EnvMachineIDBits: "10",
+	}
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			setenv(t, name, value)
+
+			_, err := snowflake.NewFromEnv()
+			if err == nil || !strings.Contains(err.Error(), "SNOWFLAKE_") {
+				t.Errorf("The error should name the environment variable, got %v", err)
+			}
+		})
+	}
+}
+
+func TestInitFromEnv(t *testing.T) {
+	t.Cleanup(func() {
+		os.Unsetenv(snowflake.EnvMachineID)
+		snowflake.ResetForTesting()
+		if err := snowflake.InitFromEnv(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	setenv(t, snowflake.EnvMachineID, "300")
+	snowflake.ResetForTesting()
+	if err := snowflake.InitFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	sid := snowflake.ParseID(snowflake.ID())
+	if sid.MachineID != 300 {
+		t.Errorf("The machineID should be 300, got %d", sid.MachineID)
+	}
+
+	if err := snowflake.InitFromEnv(); err != snowflake.ErrConfigFrozen {
+		t.Errorf("The error should be ErrConfigFrozen, got %v", err)
+	}
+}
+
+func TestInitFromEnv_InPlace(t *testing.T) {
+	t.Cleanup(func() {
+		os.Unsetenv(snowflake.EnvEpoch)
+		snowflake.Reset()
+		if err := snowflake.InitFromEnv(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	setenv(t, snowflake.EnvEpoch, "2020-01-01T00:00:00Z")
+	snowflake.Reset()
+	g := snowflake.Default()
+	got := make(chan error, 1)
+	snowflake.OnError(func(err error) {
+		got <- err
+	})
+	if err := snowflake.InitFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	if snowflake.Default() != g {
+		t.Error("InitFromEnv should configure the default generator in place")
+	}
+	if want := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC); !snowflake.StartTime().Equal(want) {
+		t.Errorf("The start time should be %s, got %s", want, snowflake.StartTime())
+	}
+
+	// without machineID, the first ID passes the warning to the hook registered before InitFromEnv.
+	snowflake.ID()
+	select {
+	case err := <-got:
+		if !errors.Is(err, snowflake.ErrUnmanagedMachineID) {
+			t.Errorf("The warning should wrap ErrUnmanagedMachineID, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("The hook should be kept")
+	}
+}
@@ -0,0 +1,87 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrClosed is returned by NextID once the generator is closed.
+var ErrClosed = errors.New("snowflake: generator is closed")
+
+// ErrClockMovedBackward is returned by NextID when the clock moved backward by more than the max backward tolerance,
+// and by NextIDNoWait on any backward movement it would wait for. The error message reports how far the clock moved backward.
+var ErrClockMovedBackward = errors.New("snowflake: the clock moved backward")
+
+// ErrSequenceExhausted is returned by NextIDNoWait when the sequence of the current tick is exhausted,
+// NextID waits for the next tick instead.
+var ErrSequenceExhausted = errors.New("snowflake: the sequence of the current tick is exhausted")
+
+// ErrRateLimited is returned by NextIDNoWait when the generator exceeds the rate of WithMaxRate,
+// NextID waits for its turn instead.
+var ErrRateLimited = errors.New("snowflake: the rate limit of the generator is exceeded")
+
+// ErrEpochExhausted is returned by NextID once the time since the start time exceeds the timestamp part of the layout,
+// see Generator.LifetimeEnd.
+var ErrEpochExhausted = errors.New("snowflake: the timestamp part of the layout is exhausted")
+
+// ErrClockUnsynchronized is returned by CheckClockSync, and by NextID with WithRequireSyncedClock,
+// when the system clock is not synchronized.
+var ErrClockUnsynchronized = errors.New("snowflake: the system clock is not synchronized")
+
+// ErrResolverUnavailable is matched by the errors of the sequence resolvers backed by an external store
+// when the store cannot be reached, see ResolverError.
+var ErrResolverUnavailable = errors.New("snowflake: the sequence resolver is unavailable")
+
+// ErrMachineIDNotSet is returned by NextID after RequireExplicitMachineID until the machineID is set,
+// by an option, a setter or an allocator, so a health check can detect the replicas without a machineID configured.
+var ErrMachineIDNotSet = errors.New("snowflake: the machineID is not set explicitly")
+
+// ErrConfigFrozen is returned by the TrySetXXX methods (and is the reason of the SetXXX panic) once the generator has generated an ID,
+// changing the configuration afterwards silently breaks ordering and uniqueness.
+var ErrConfigFrozen = errors.New("snowflake: the configuration cannot be changed after the first ID was generated")
+
+// These errors are returned by the configuration validation, use errors.Is to check them.
+var (
+	ErrZeroStartTime        = errors.New("snowflake: the start time is zero")
+	ErrFutureStartTime      = errors.New("snowflake: the start time is in the future")
+	ErrStartTimeTooEarly    = errors.New("snowflake: the start time exceeds the life cycle of the layout")
+	ErrMachineIDTooLarge    = errors.New("snowflake: the machineID exceeds the machine bits of the layout")
+	ErrDatacenterIDTooLarge = errors.New("snowflake: the datacenterID exceeds the datacenter bits of the layout")
+	ErrWorkerIDTooLarge     = errors.New("snowflake: the workerID exceeds the worker bits of the layout")
+)
+
+// configError is a validation error, its message is the panic message of the SetXXX functions
+// and it wraps one of the sentinel errors above.
+type configError struct {
+	msg string
+	err error
+}
+
+func (e *configError) Error() string {
+	return e.msg
+}
+
+func (e *configError) Unwrap() error {
+	return e.err
+}
+
+// ResolverError is returned by the sequence resolvers backed by an external store when the store fails,
+// it wraps the cause and errors.Is(err, ErrResolverUnavailable) reports true, so the callers can fall back.
+type ResolverError struct {
+	// Resolver is the kind of the resolver, e.g. redis.
+	Resolver string
+	Err      error
+}
+
+func (e *ResolverError) Error() string {
+	return fmt.Sprintf("snowflake: the %s sequence resolver is unavailable: %v", e.Resolver, e.Err)
+}
+
+func (e *ResolverError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports true for ErrResolverUnavailable.
+func (e *ResolverError) Is(target error) bool {
+	return target == ErrResolverUnavailable
+}
@@ -0,0 +1,287 @@
+// Package etcdlease leases snowflake machineIDs from etcd, so the processes get a unique machineID at startup
+// without a static assignment:
+//
+//	lease, err := etcdlease.Allocate(ctx, etcdClient{cli}, etcdlease.Options{HintFile: "/var/lib/app/machine-id"})
+//	if err != nil {
+//		return err
+//	}
+//	g, err := snowflake.New(snowflake.WithMachineIDLease(lease))
+//
+// The lease is a key per machineID, /snowflake/machines/{machineID}, attached to an etcd lease
+// kept alive in the background and revoked by Close.
+package etcdlease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/internal/leasekeeper"
+)
+
+const (
+	defaultPrefix       = "/snowflake/machines/"
+	defaultTTL          = 10 * time.Second
+	defaultRetries      = 5
+	defaultRetryBackoff = 100 * time.Millisecond
+)
+
+// ErrNoFreeMachineID is returned by Allocate when every machineID up to Options.MaxMachineID is leased.
+var ErrNoFreeMachineID = errors.New("etcdlease: no free machineID")
+
+// ErrLeaseNotFound must be returned, or wrapped, by Client.KeepAliveOnce when the etcd lease expired or was revoked,
+// e.g. for rpctypes.ErrLeaseNotFound.
+var ErrLeaseNotFound = errors.New("etcdlease: the etcd lease is not found")
+
+// Client is the etcd client used by Allocate, implement it with clientv3. The package doesn't depend on clientv3
+// so the snowflake module stays dependency-free:
+//
+//	type etcdClient struct{ c *clientv3.Client }
+//
+//	func (e etcdClient) Grant(ctx context.Context, ttl time.Duration) (int64, error) {
+//		resp, err := e.c.Grant(ctx, int64(ttl/time.Second))
+//		if err != nil {
+//			return 0, err
+//		}
+//		return int64(resp.ID), nil
+//	}
+//
+//	func (e etcdClient) Keys(ctx context.Context, prefix string) ([]string, error) {
+//		resp, err := e.c.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+//		...
+//	}
+//
+//	func (e etcdClient) PutIfAbsent(ctx context.Context, key, value string, lease int64) (bool, error) {
+//		resp, err := e.c.Txn(ctx).
+//			If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+//			Then(clientv3.OpPut(key, value, clientv3.WithLease(clientv3.LeaseID(lease)))).
+//			Commit()
+//		if err != nil {
+//			return false, err
+//		}
+//		return resp.Succeeded, nil
+//	}
+//
+//	func (e etcdClient) KeepAliveOnce(ctx context.Context, lease int64) error {
+//		_, err := e.c.KeepAliveOnce(ctx, clientv3.LeaseID(lease))
+//		if errors.Is(err, rpctypes.ErrLeaseNotFound) {
+//			return etcdlease.ErrLeaseNotFound
+//		}
+//		return err
+//	}
+//
+//	func (e etcdClient) Revoke(ctx context.Context, lease int64) error {
+//		_, err := e.c.Revoke(ctx, clientv3.LeaseID(lease))
+//		return err
+//	}
+type Client interface {
+	Grant(ctx context.Context, ttl time.Duration) (int64, error)
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	PutIfAbsent(ctx context.Context, key, value string, lease int64) (bool, error)
+	KeepAliveOnce(ctx context.Context, lease int64) error
+	Revoke(ctx context.Context, lease int64) error
+}
+
+// Options configure Allocate, the zero value uses the defaults.
+type Options struct {
+	// Prefix is the prefix of the keys, "/snowflake/machines/" by default.
+	Prefix string
+	// MaxMachineID is the max machineID leased, snowflake.MaxMachineID by default.
+	MaxMachineID uint16
+	// TTL is the ttl of the etcd lease, 10s by default, etcd rounds it to seconds.
+	TTL time.Duration
+	// RenewInterval is how often the etcd lease is kept alive, TTL/3 by default.
+	RenewInterval time.Duration
+	// SafetyMargin is how long before the TTL elapses the lease ends when the renewals fail, so the generator stops
+	// before the lease expires in etcd, RenewInterval/2 by default, less when the TTL is under twice the RenewInterval.
+	SafetyMargin time.Duration
+	// Owner is the value of the key, the host name by default.
+	Owner string
+	// HintFile is where the leased machineID is saved, Allocate prefers it the next time so the dashboards stay stable.
+	// It is not used when empty.
+	HintFile string
+	// Retries is the count of retries when etcd is unavailable at startup, 5 by default, with a doubling backoff
+	// from RetryBackoff, 100ms by default.
+	Retries      int
+	RetryBackoff time.Duration
+}
+
+// Allocate lease a free machineID in [0, MaxMachineID] and keep it alive in the background until Close:
+// the machineID of the hint file when it is free, or the lowest free one. The errors of etcd are retried
+// with a bounded backoff, etcd may be briefly unavailable when the processes start.
+//
+// The lease is lost when the etcd lease expired, or SafetyMargin before TTL elapsed since the last successful keep-alive was sent:
+// Done is closed and Err returns why, see snowflake.WithMachineIDLease.
+func Allocate(ctx context.Context, client Client, opts Options) (snowflake.MachineIDLease, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	var lease int64
+	var acquired time.Time
+	err = opts.retry(ctx, func() error {
+		acquired = time.Now()
+		lease, err = client.Grant(ctx, opts.TTL)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("etcdlease: grant a lease: %w", err)
+	}
+
+	var id int
+	err = opts.retry(ctx, func() error {
+		id, err = opts.claim(ctx, client, lease)
+		return err
+	})
+	if err != nil {
+		revokeCtx, cancel := context.WithTimeout(context.Background(), opts.RenewInterval)
+		defer cancel()
+		_ = client.Revoke(revokeCtx, lease)
+
+		if errors.Is(err, ErrNoFreeMachineID) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("etcdlease: claim a machineID: %w", err)
+	}
+
+	if opts.HintFile != "" {
+		// 提示文件只影响下次的偏好，写入失败不影响租约
+		_ = ioutil.WriteFile(opts.HintFile, []byte(strconv.Itoa(id)+"\n"), 0o644)
+	}
+
+	renew := func(ctx context.Context) error {
+		err := client.KeepAliveOnce(ctx, lease)
+		if errors.Is(err, ErrLeaseNotFound) {
+			return fmt.Errorf("%v: %w", err, leasekeeper.ErrNotOwned)
+		}
+		return err
+	}
+	release := func(ctx context.Context) error {
+		return client.Revoke(ctx, lease)
+	}
+
+	return leasekeeper.Start(uint16(id), acquired, opts.RenewInterval, opts.TTL, opts.SafetyMargin, renew, release), nil
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+func (o Options) withDefaults() (Options, error) {
+	if o.Prefix == "" {
+		o.Prefix = defaultPrefix
+	}
+	if o.MaxMachineID == 0 {
+		o.MaxMachineID = snowflake.MaxMachineID
+	}
+	if o.TTL == 0 {
+		o.TTL = defaultTTL
+	}
+	if o.TTL < time.Second {
+		return o, fmt.Errorf("etcdlease: invalid TTL %s, it must be at least 1s", o.TTL)
+	}
+	if o.RenewInterval == 0 {
+		o.RenewInterval = o.TTL / 3
+	}
+	if o.RenewInterval <= 0 || o.RenewInterval >= o.TTL {
+		return o, fmt.Errorf("etcdlease: invalid renew interval %s, it must be positive and less than the TTL %s", o.RenewInterval, o.TTL)
+	}
+	if o.SafetyMargin == 0 {
+		o.SafetyMargin = leasekeeper.DefaultMargin(o.RenewInterval, o.TTL)
+	}
+	if o.SafetyMargin <= 0 || o.SafetyMargin >= o.TTL-o.RenewInterval {
+		return o, fmt.Errorf("etcdlease: invalid safety margin %s, it must be positive and less than the TTL minus the renew interval %s", o.SafetyMargin, o.TTL-o.RenewInterval)
+	}
+	if o.Owner == "" {
+		o.Owner, _ = os.Hostname()
+	}
+	if o.Retries == 0 {
+		o.Retries = defaultRetries
+	}
+	if o.RetryBackoff <= 0 {
+		o.RetryBackoff = defaultRetryBackoff
+	}
+
+	return o, nil
+}
+
+// retry call fn until it succeeds, at most Retries more times, ErrNoFreeMachineID is not retried.
+func (o Options) retry(ctx context.Context, fn func() error) error {
+	backoff := o.RetryBackoff
+	for i := 0; ; i++ {
+		err := fn()
+		if err == nil || errors.Is(err, ErrNoFreeMachineID) || i >= o.Retries {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+			backoff *= 2
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%v: %w", err, ctx.Err())
+		}
+	}
+}
+
+// claim put the key of the hint machineID, or of the lowest free machineID, with the lease.
+func (o Options) claim(ctx context.Context, client Client, lease int64) (int, error) {
+	keys, err := client.Keys(ctx, o.Prefix)
+	if err != nil {
+		return 0, err
+	}
+	leased := make(map[int]bool, len(keys))
+	for _, k := range keys {
+		if id, err := strconv.Atoi(strings.TrimPrefix(k, o.Prefix)); err == nil {
+			leased[id] = true
+		}
+	}
+
+	candidates := make([]int, 0, int(o.MaxMachineID)+2)
+	if hint, ok := o.hint(); ok && !leased[hint] {
+		candidates = append(candidates, hint)
+	}
+	for id := 0; id <= int(o.MaxMachineID); id++ {
+		if !leased[id] {
+			candidates = append(candidates, id)
+		}
+	}
+
+	// 其它进程可能同时抢占同一个 ID，失败时尝试下一个
+	for _, id := range candidates {
+		ok, err := client.PutIfAbsent(ctx, o.Prefix+strconv.Itoa(id), o.Owner, lease)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return id, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: 0-%d are leased", ErrNoFreeMachineID, o.MaxMachineID)
+}
+
+// hint returns the machineID of the hint file.
+func (o Options) hint() (int, bool) {
+	if o.HintFile == "" {
+		return 0, false
+	}
+	b, err := ioutil.ReadFile(o.HintFile)
+	if err != nil {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || id < 0 || id > int(o.MaxMachineID) {
+		return 0, false
+	}
+
+	return id, true
+}
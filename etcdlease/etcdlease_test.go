@@ -0,0 +1,253 @@
+package etcdlease_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/etcdlease"
+)
+
+// fakeEtcd keeps the keys and the leases of etcdlease in maps, the ttls are ignored.
+type fakeEtcd struct {
+	mu          sync.Mutex
+	keys        map[string]int64
+	leases      map[int64]bool
+	nextLease   int64
+	unavailable int
+	keepAlives  int
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{keys: make(map[string]int64), leases: make(map[int64]bool)}
+}
+
+func (e *fakeEtcd) Grant(ctx context.Context, ttl time.Duration) (int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.unavailable > 0 {
+		e.unavailable--
+		return 0, errors.New("etcdserver: request timed out")
+	}
+	e.nextLease++
+	e.leases[e.nextLease] = true
+	return e.nextLease, nil
+}
+
+func (e *fakeEtcd) Keys(ctx context.Context, prefix string) ([]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var keys []string
+	for k := range e.keys {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (e *fakeEtcd) PutIfAbsent(ctx context.Context, key, value string, lease int64) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.keys[key]; ok {
+		return false, nil
+	}
+	e.keys[key] = lease
+	return true, nil
+}
+
+func (e *fakeEtcd) KeepAliveOnce(ctx context.Context, lease int64) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.leases[lease] {
+		return etcdlease.ErrLeaseNotFound
+	}
+	e.keepAlives++
+	return nil
+}
+
+func (e *fakeEtcd) Revoke(ctx context.Context, lease int64) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.leases, lease)
+	for k, l := range e.keys {
+		if l == lease {
+			delete(e.keys, k)
+		}
+	}
+	return nil
+}
+
+func (e *fakeEtcd) has(key string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, ok := e.keys[key]
+	return ok
+}
+
+func TestAllocate(t *testing.T) {
+	client := newFakeEtcd()
+
+	leases := make([]snowflake.MachineIDLease, 20)
+	var wg sync.WaitGroup
+	for i := range leases {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l, err := etcdlease.Allocate(context.Background(), client, etcdlease.Options{Owner: fmt.Sprintf("pod-%d", i)})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			leases[i] = l
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[uint16]bool)
+	for _, l := range leases {
+		if l == nil {
+			t.FailNow()
+		}
+		if seen[l.MachineID()] || l.MachineID() >= 20 {
+			t.Errorf("The machineIDs should be unique and the lowest, got %d", l.MachineID())
+		}
+		seen[l.MachineID()] = true
+	}
+
+	released := leases[3].MachineID()
+	if err := leases[3].Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if client.has(fmt.Sprintf("/snowflake/machines/%d", released)) {
+		t.Error("Close should revoke the lease and delete the key")
+	}
+
+	for _, l := range leases {
+		_ = l.Close(context.Background())
+	}
+}
+
+func TestAllocate_HintFile(t *testing.T) {
+	client := newFakeEtcd()
+	hint := filepath.Join(t.TempDir(), "machine-id")
+	if err := ioutil.WriteFile(hint, []byte("17\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := etcdlease.Allocate(context.Background(), client, etcdlease.Options{HintFile: hint})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.MachineID() != 17 {
+		t.Errorf("The machineID of the hint file should be preferred, got %d", l.MachineID())
+	}
+
+	// the hinted machineID is leased, the lowest free one is leased and saved.
+	l2, err := etcdlease.Allocate(context.Background(), client, etcdlease.Options{HintFile: hint})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l2.MachineID() != 0 {
+		t.Errorf("The lowest free machineID should be leased, got %d", l2.MachineID())
+	}
+	if b, _ := ioutil.ReadFile(hint); strings.TrimSpace(string(b)) != "0" {
+		t.Errorf("The leased machineID should be saved in the hint file, got %q", b)
+	}
+
+	_ = l.Close(context.Background())
+	_ = l2.Close(context.Background())
+}
+
+func TestAllocate_Retries(t *testing.T) {
+	client := newFakeEtcd()
+	client.unavailable = 2
+	l, err := etcdlease.Allocate(context.Background(), client, etcdlease.Options{Retries: 2, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Allocate should retry while etcd is unavailable, got %v", err)
+	}
+	_ = l.Close(context.Background())
+
+	client.unavailable = 3
+	if _, err := etcdlease.Allocate(context.Background(), client, etcdlease.Options{Retries: 2, RetryBackoff: time.Millisecond}); err == nil {
+		t.Error("Allocate should fail once the retries are exhausted")
+	}
+}
+
+func TestAllocate_NoFreeMachineID(t *testing.T) {
+	client := newFakeEtcd()
+	opts := etcdlease.Options{MaxMachineID: 1}
+	for i := 0; i < 2; i++ {
+		l, err := etcdlease.Allocate(context.Background(), client, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close(context.Background())
+	}
+
+	if _, err := etcdlease.Allocate(context.Background(), client, opts); !errors.Is(err, etcdlease.ErrNoFreeMachineID) {
+		t.Errorf("The error should be ErrNoFreeMachineID, got %v", err)
+	}
+	client.mu.Lock()
+	leases := len(client.leases)
+	client.mu.Unlock()
+	if leases != 2 {
+		t.Errorf("The etcd lease of a failed allocation should be revoked, got %d leases", leases)
+	}
+}
+
+func TestAllocate_Lost(t *testing.T) {
+	client := newFakeEtcd()
+	l, err := etcdlease.Allocate(context.Background(), client, etcdlease.Options{TTL: time.Second, RenewInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := snowflake.New(snowflake.WithMachineIDLease(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close(context.Background())
+
+	time.Sleep(30 * time.Millisecond)
+	client.mu.Lock()
+	keepAlives := client.keepAlives
+	client.mu.Unlock()
+	if keepAlives == 0 {
+		t.Error("The etcd lease should be kept alive")
+	}
+
+	// the etcd lease expired during a partition.
+	client.mu.Lock()
+	client.leases = make(map[int64]bool)
+	client.mu.Unlock()
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Fatal("The lost lease should be done")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := g.NextID()
+		if errors.Is(err, snowflake.ErrLeaseLost) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("The generator should stop once the lease is lost, got %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -10,7 +10,9 @@ import (
 func main() {
 	// set starttime and machineID for the first time if you wan't to use the default value
 	snowflake.SetStartTime(time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC))
-	snowflake.SetMachineID(snowflake.PrivateIPToMachineID()) // testing, not to be used in production
+	if m, err := snowflake.MachineIDFromPrivateIP(snowflake.MachineIDLength); err == nil {
+		snowflake.SetMachineID(m)
+	}
 
 	id := snowflake.ID()
 	fmt.Println(id) // 1537200202186752
@@ -0,0 +1,150 @@
+package snowflake
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// MoveClockBackward make the generator believe its last ID was generated d in the future,
+// so the next NextID sees the clock moved backward by d.
+func MoveClockBackward(g *Generator, d time.Duration) {
+	atomic.StoreInt64(&g.lastTimestamp, g.currentTicks()+int64(d/g.timeUnit))
+}
+
+// NewMonotonicClock create the clock used by WithMonotonicClockReanchor.
+var NewMonotonicClock = newMonotonicClock
+
+// Anchor returns the anchor of the monotonic clock.
+func (c *monotonicClock) Anchor() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.anchor
+}
+
+// LastTimestamp returns the tick of the last ID of the generator.
+func LastTimestamp(g *Generator) int64 {
+	return atomic.LoadInt64(&g.lastTimestamp)
+}
+
+// DefaultWaitStrategy is the default wait strategy of the platform.
+const DefaultWaitStrategy = defaultWaitStrategy
+
+// SpinThreshold returns the remaining duration below which the generator spins instead of sleeping.
+func SpinThreshold(g *Generator) time.Duration {
+	return g.spinThreshold()
+}
+
+// SetClockSyncCheck replace the clock sync check of a generator created with WithRequireSyncedClock.
+func SetClockSyncCheck(g *Generator, check func(ctx context.Context) error) {
+	g.clockSync.check = check
+}
+
+// QueryNTPOffset returns the offset of the local clock from the NTP server.
+var QueryNTPOffset = queryNTPOffset
+
+// ExpireFallbackProbe make a generator created with WithResolverFallback probe its resolver on the next NextID.
+func ExpireFallbackProbe(g *Generator) {
+	atomic.StoreInt64(&g.fallback.nextProbe, 0)
+}
+
+// TestInterface is a network interface returned by SetInterfaces.
+type TestInterface struct {
+	Name  string
+	Down  bool
+	MAC   string
+	Addrs []string
+}
+
+// SetInterfaces replace the network interfaces of the host until the test ends, the addresses are CIDRs.
+func SetInterfaces(t *testing.T, ifaces ...TestInterface) {
+	list := make([]netInterface, 0, len(ifaces))
+	for _, i := range ifaces {
+		ni := netInterface{name: i.Name, flags: net.FlagUp}
+		if i.Down {
+			ni.flags = 0
+		}
+		if i.MAC != "" {
+			mac, err := net.ParseMAC(i.MAC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ni.hwAddr = mac
+		}
+		for _, a := range i.Addrs {
+			ip, ipnet, err := net.ParseCIDR(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ipnet.IP = ip
+			ni.addrs = append(ni.addrs, ipnet)
+		}
+		list = append(list, ni)
+	}
+
+	old := listInterfaces
+	listInterfaces = func() ([]netInterface, error) {
+		return append([]netInterface(nil), list...), nil
+	}
+	t.Cleanup(func() {
+		listInterfaces = old
+	})
+}
+
+// SetHostname replace the host name until the test ends.
+func SetHostname(t *testing.T, name string) {
+	old := hostname
+	hostname = func() (string, error) {
+		return name, nil
+	}
+	t.Cleanup(func() {
+		hostname = old
+	})
+}
+
+// SetClaimTTL replace the ttl of the claims of HostnameMachineID until the test ends.
+func SetClaimTTL(t *testing.T, ttl time.Duration) {
+	old := claimTTL
+	claimTTL = ttl
+	t.Cleanup(func() {
+		claimTTL = old
+	})
+}
+
+// SetMachineIDFiles replace the machine-id files until the test ends.
+func SetMachineIDFiles(t *testing.T, files ...string) {
+	old := machineIDFiles
+	machineIDFiles = files
+	t.Cleanup(func() {
+		machineIDFiles = old
+	})
+}
+
+// SetMachineIDFilePoll replace how often MachineIDFile re-reads the file until the test ends.
+func SetMachineIDFilePoll(t *testing.T, d time.Duration) {
+	old := machineIDFilePoll
+	machineIDFilePoll = d
+	t.Cleanup(func() {
+		machineIDFilePoll = old
+	})
+}
+
+// SetEC2Endpoint replace the endpoint of the instance metadata service until the test ends.
+func SetEC2Endpoint(t *testing.T, endpoint string) {
+	old := EC2MetadataEndpoint
+	EC2MetadataEndpoint = endpoint
+	t.Cleanup(func() {
+		EC2MetadataEndpoint = old
+	})
+}
+
+// MetadataClientProxied reports whether the client of the metadata services may use a proxy.
+func MetadataClientProxied() bool {
+	t, ok := metadataClient.Transport.(*http.Transport)
+
+	return !ok || t.Proxy != nil || metadataClient.Timeout == 0
+}
@@ -0,0 +1,181 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// defaultFallbackProbe is how often the primary resolver is probed while the fallback serves.
+const defaultFallbackProbe = time.Second
+
+// resolverEventQueue is the number of resolver events queued for the hooks, the events are dropped when it is full.
+const resolverEventQueue = 64
+
+// ResolverEventKind is the kind of a ResolverEvent.
+type ResolverEventKind uint8
+
+const (
+	// ResolverFallback is reported when the resolver failed and the generator switched to the fallback resolver.
+	ResolverFallback ResolverEventKind = iota
+	// ResolverRecovered is reported when a probe of the resolver succeeded and the generator switched back to it.
+	ResolverRecovered
+)
+
+// String returns the name of the kind, e.g. fallback.
+func (k ResolverEventKind) String() string {
+	switch k {
+	case ResolverFallback:
+		return "fallback"
+	case ResolverRecovered:
+		return "recovered"
+	default:
+		return fmt.Sprintf("ResolverEventKind(%d)", uint8(k))
+	}
+}
+
+// ResolverEvent is a switch between the resolver and the fallback resolver, see WithResolverFallback.
+type ResolverEvent struct {
+	Kind ResolverEventKind
+	// Err is the error of the resolver on ResolverFallback, it is nil on ResolverRecovered.
+	Err error
+	// Time is the generator clock time of the switch.
+	Time time.Time
+}
+
+// resolverFallback is the state of WithResolverFallback.
+type resolverFallback struct {
+	// active is 1 while the fallback serves, nextProbe is the unix nano time of the next probe of the resolver,
+	// fallbacks, recoveries and served are the counters of Stats, all accessed atomically.
+	active     int32
+	nextProbe  int64
+	fallbacks  uint64
+	recoveries uint64
+	served     uint64
+
+	local     SequenceResolver
+	machineID uint16
+	probe     time.Duration
+}
+
+// WithResolverFallback switch to the local resolver when the sequence resolver fails, e.g. a redis resolver during a network blip,
+// instead of returning the error. The IDs served by local use fallbackMachineID instead of the machineID of the generator,
+// so they cannot collide with the IDs of the other processes still using the shared resolver,
+// fallbackMachineID must be unique to the process. While local serves, the resolver is probed once per second,
+// the generator switches back once a probe succeeds. ErrSequenceExhausted and the errors of a done context don't switch.
+//
+// The switches are reported to the OnResolverEvent hooks and counted in Stats.
+// It cannot be used with WithResolverChain.
+//
+//	g, err := snowflake.New(
+//		snowflake.WithMachineID(42),
+//		snowflake.WithResolver(snowflake.NewRedisResolver(client)),
+//		snowflake.WithResolverFallback(snowflake.NewAtomicResolver(), 300+workerIndex),
+//	)
+func WithResolverFallback(local SequenceResolver, fallbackMachineID uint16) Option {
+	return func(g *Generator) error {
+		if local == nil {
+			return fmt.Errorf("snowflake: invalid option WithResolverFallback(%d): the local resolver cannot be nil", fallbackMachineID)
+		}
+		g.fallback = &resolverFallback{local: local, machineID: fallbackMachineID, probe: defaultFallbackProbe}
+
+		return nil
+	}
+}
+
+// OnResolverEvent register fn to be called when the generator switches to the fallback resolver or back, see WithResolverFallback.
+// The hooks are called by a goroutine of the generator like the OnClockEvent hooks, they never block ID generation.
+// This function is thread safe.
+func (g *Generator) OnResolverEvent(fn func(ev ResolverEvent)) {
+	g.resolverHooksMu.Lock()
+	defer g.resolverHooksMu.Unlock()
+
+	g.resolverHooks = append(g.resolverHooks, fn)
+	if g.resolverEvents == nil {
+		g.resolverEvents = make(chan ResolverEvent, resolverEventQueue)
+		go g.dispatchResolverEvents(g.resolverEvents)
+	}
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// resolve resolve the sequence of ms with primary, or with the local resolver while the fallback is active.
+// It returns whether local served the sequence.
+func (f *resolverFallback) resolve(g *Generator, ms int64, primary func(ms int64) (uint16, error)) (uint16, bool, error) {
+	if atomic.LoadInt32(&f.active) == 1 {
+		now := time.Now().UnixNano()
+		next := atomic.LoadInt64(&f.nextProbe)
+		// 只有一个调用者探测主解析器，其余的继续使用本地解析器
+		if now < next || !atomic.CompareAndSwapInt64(&f.nextProbe, next, now+int64(f.probe)) {
+			return f.resolveLocal(ms)
+		}
+
+		seq, err := primary(ms)
+		if err != nil && !errors.Is(err, ErrSequenceExhausted) {
+			return f.resolveLocal(ms)
+		}
+		if atomic.CompareAndSwapInt32(&f.active, 1, 0) {
+			atomic.AddUint64(&f.recoveries, 1)
+			g.emitResolverEvent(ResolverEvent{Kind: ResolverRecovered, Time: g.clock.Now()})
+		}
+
+		return seq, false, err
+	}
+
+	seq, err := primary(ms)
+	if err == nil || errors.Is(err, ErrSequenceExhausted) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return seq, false, err
+	}
+
+	// 解析器故障：切换到本地解析器和备用的 machineID
+	atomic.StoreInt64(&f.nextProbe, time.Now().UnixNano()+int64(f.probe))
+	if atomic.CompareAndSwapInt32(&f.active, 0, 1) {
+		atomic.AddUint64(&f.fallbacks, 1)
+		g.emitResolverEvent(ResolverEvent{Kind: ResolverFallback, Err: err, Time: g.clock.Now()})
+	}
+
+	return f.resolveLocal(ms)
+}
+
+func (f *resolverFallback) resolveLocal(ms int64) (uint16, bool, error) {
+	seq, err := f.local(ms)
+	if err == nil {
+		atomic.AddUint64(&f.served, 1)
+	}
+
+	return seq, true, err
+}
+
+// emitResolverEvent queue the event for the hooks, it never blocks.
+func (g *Generator) emitResolverEvent(ev ResolverEvent) {
+	g.resolverHooksMu.Lock()
+	events := g.resolverEvents
+	g.resolverHooksMu.Unlock()
+
+	select {
+	case events <- ev:
+	default:
+		// 队列已满或没有钩子，丢弃事件
+	}
+}
+
+func (g *Generator) dispatchResolverEvents(events chan ResolverEvent) {
+	for {
+		select {
+		case ev := <-events:
+			g.resolverHooksMu.Lock()
+			hooks := g.resolverHooks
+			g.resolverHooksMu.Unlock()
+
+			for _, fn := range hooks {
+				fn(ev)
+			}
+		case <-g.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package snowflake_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/clocktest"
+)
+
+func TestWithResolverFallback(t *testing.T) {
+	// the shared resolver stands for redis, a and b are sibling processes with the same machineID,
+	// the network of a blips while b keeps using the shared resolver.
+	shared := snowflake.NewAtomicResolver()
+	view := &flappingResolver{resolve: shared}
+
+	clock := clocktest.NewManual(time.Now())
+	a, err := snowflake.New(
+		snowflake.WithClock(clock),
+		snowflake.WithMachineID(42),
+		snowflake.WithResolver(view),
+		snowflake.WithResolverFallback(snowflake.NewAtomicResolver(), 300),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := snowflake.New(snowflake.WithClock(clock), snowflake.WithMachineID(42), snowflake.WithSequenceResolver(shared))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan snowflake.ResolverEvent, 2)
+	a.OnResolverEvent(func(ev snowflake.ResolverEvent) {
+		events <- ev
+	})
+
+	// the clock is frozen, every switch happens in the same millisecond.
+	seen := make(map[uint64]bool)
+	generate := func(g *snowflake.Generator, machineID uint64) {
+		t.Helper()
+		for i := 0; i < 3; i++ {
+			id, err := g.NextID()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if seen[id] {
+				t.Fatalf("The ID %d should be unique across the switchover", id)
+			}
+			seen[id] = true
+			if sid := g.ParseID(id); sid.MachineID != machineID {
+				t.Errorf("The machineID should be %d, got %d", machineID, sid.MachineID)
+			}
+		}
+	}
+
+	generate(a, 42)
+	generate(b, 42)
+
+	view.set(true)
+	generate(a, 300)
+	generate(b, 42)
+	if stats := a.Stats(); !stats.ResolverDegraded || stats.ResolverFallbacks != 1 || stats.FallbackServed != 3 {
+		t.Errorf("The fallback should be reported, got %+v", stats)
+	}
+
+	// the resolver is back, it is not probed before the probe interval.
+	view.set(false)
+	generate(a, 300)
+
+	snowflake.ExpireFallbackProbe(a)
+	generate(a, 42)
+	generate(b, 42)
+	if stats := a.Stats(); stats.ResolverDegraded || stats.ResolverRecoveries != 1 || stats.FallbackServed != 6 {
+		t.Errorf("The recovery should be reported, got %+v", stats)
+	}
+
+	for _, kind := range []snowflake.ResolverEventKind{snowflake.ResolverFallback, snowflake.ResolverRecovered} {
+		select {
+		case ev := <-events:
+			if ev.Kind != kind {
+				t.Errorf("The event should be %s, got %s", kind, ev.Kind)
+			}
+			if kind == snowflake.ResolverFallback && ev.Err == nil {
+				t.Error("The fallback event should carry the error of the resolver")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("The %s event should be reported", kind)
+		}
+	}
+}
+
+func TestWithResolverFallback_Exhausted(t *testing.T) {
+	g, err := snowflake.New(
+		snowflake.WithMachineID(1),
+		snowflake.WithSequenceResolver(func(ms int64) (uint16, error) {
+			return 0, snowflake.ErrSequenceExhausted
+		}),
+		snowflake.WithResolverFallback(snowflake.NewAtomicResolver(), 2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.NextIDNoWait(); !errors.Is(err, snowflake.ErrSequenceExhausted) {
+		t.Errorf("The exhaustion should not switch to the fallback, got %v", err)
+	}
+	if stats := g.Stats(); stats.ResolverFallbacks != 0 {
+		t.Errorf("The exhaustion should not be counted as a fallback, got %+v", stats)
+	}
+}
+
+func TestWithResolverFallback_Invalid(t *testing.T) {
+	chain := snowflake.ChainResolver(snowflake.NewAtomicResolver())
+	cases := map[string][]snowflake.Option{
+		"nil":        {snowflake.WithResolverFallback(nil, 2)},
+		"same":       {snowflake.WithMachineID(2), snowflake.WithResolverFallback(snowflake.NewAtomicResolver(), 2)},
+		"too large":  {snowflake.WithResolverFallback(snowflake.NewAtomicResolver(), 1024)},
+		"with chain": {snowflake.WithResolverChain(chain, 3), snowflake.WithResolverFallback(snowflake.NewAtomicResolver(), 2)},
+	}
+	for name, opts := range cases {
+		if _, err := snowflake.New(opts...); err == nil {
+			t.Errorf("%s: the fallback should be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package filelease leases snowflake machineIDs with lock files, so the processes of a host embedding the package
+// get different machineIDs without any external service:
+//
+//	lease, err := filelease.Allocate(filelease.Options{Min: 8, Max: 15})
+//	if err != nil {
+//		return err
+//	}
+//	g, err := snowflake.New(snowflake.WithMachineIDLease(lease))
+//
+// The lease is the lock of a file per machineID, /var/run/snowflake/ids/{machineID}.lock, held with flock until Close.
+// Give each host its own range, e.g. from the host part of its address, the lock files are only unique on the host.
+package filelease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/internal/leasekeeper"
+)
+
+const defaultDir = "/var/run/snowflake/ids"
+
+// ErrNoFreeMachineID is returned by Allocate when every machineID of the range is locked by another process.
+var ErrNoFreeMachineID = errors.New("filelease: no free machineID")
+
+// errLocked is returned by tryLock when another process holds the lock.
+var errLocked = errors.New("filelease: locked by another process")
+
+// Options configure Allocate, the zero value leases 0 to snowflake.MaxMachineID in /var/run/snowflake/ids.
+type Options struct {
+	// Dir is the directory of the lock files, it is created when missing.
+	Dir string
+	// Min and Max are the range of the machineIDs leased, Max is snowflake.MaxMachineID by default.
+	Min, Max uint16
+}
+
+// Allocate lock the file of the lowest free machineID in [Min, Max] and hold the lock until Close.
+//
+// The kernel releases the lock when the process exits, so the machineID of a crashed process is leased again
+// by the next Allocate, the stale lock files are reused and never need a cleanup. The lease is never lost
+// while the process lives, Done is closed by Close only. The locks need flock, Allocate fails without it, e.g. on windows.
+func Allocate(opts Options) (snowflake.MachineIDLease, error) {
+	if opts.Dir == "" {
+		opts.Dir = defaultDir
+	}
+	if opts.Max == 0 {
+		opts.Max = snowflake.MaxMachineID
+	}
+	if opts.Min > opts.Max {
+		return nil, fmt.Errorf("filelease: invalid range %d-%d", opts.Min, opts.Max)
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("filelease: %w", err)
+	}
+
+	for id := int(opts.Min); id <= int(opts.Max); id++ {
+		f, err := lockFile(filepath.Join(opts.Dir, fmt.Sprintf("%03d.lock", id)))
+		if errors.Is(err, errLocked) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		release := func(ctx context.Context) error {
+			err := unlock(f)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			return err
+		}
+		return leasekeeper.Watch(uint16(id), nil, release), nil
+	}
+
+	return nil, fmt.Errorf("%w: %d-%d are locked in %s", ErrNoFreeMachineID, opts.Min, opts.Max, opts.Dir)
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// lockFile open and lock the file at path, the pid of the process is written in it for the operators.
+func lockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("filelease: %w", err)
+	}
+	if err := tryLock(f); err != nil {
+		f.Close()
+		if errors.Is(err, errLocked) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("filelease: lock %s: %w", path, err)
+	}
+
+	// pid 只用于排查，写入失败不影响锁
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	return f, nil
+}
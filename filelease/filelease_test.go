@@ -0,0 +1,99 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package filelease_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/filelease"
+)
+
+// crashChildEnv is set for the child process of TestAllocate_Crashed, it is the directory of the lock files.
+const crashChildEnv = "SNOWFLAKE_FILELEASE_CHILD"
+
+func TestAllocate(t *testing.T) {
+	opts := filelease.Options{Dir: filepath.Join(t.TempDir(), "ids"), Min: 8, Max: 10}
+
+	var leases []snowflake.MachineIDLease
+	for want := uint16(8); want <= 10; want++ {
+		l, err := filelease.Allocate(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.MachineID() != want {
+			t.Errorf("The lowest free machineID of the range should be leased, got %d, want %d", l.MachineID(), want)
+		}
+		leases = append(leases, l)
+	}
+	if _, err := os.Stat(filepath.Join(opts.Dir, "009.lock")); err != nil {
+		t.Errorf("The lock file should exist, got %v", err)
+	}
+
+	if _, err := filelease.Allocate(opts); !errors.Is(err, filelease.ErrNoFreeMachineID) {
+		t.Errorf("The error should be ErrNoFreeMachineID, got %v", err)
+	}
+
+	// a released machineID is leased again.
+	if err := leases[1].Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-leases[1].Done():
+	default:
+		t.Error("Close should close Done")
+	}
+	l, err := filelease.Allocate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.MachineID() != 9 {
+		t.Errorf("The released machineID should be leased, got %d", l.MachineID())
+	}
+
+	for _, l := range append(leases, l) {
+		_ = l.Close(context.Background())
+	}
+
+	if _, err := filelease.Allocate(filelease.Options{Dir: opts.Dir, Min: 3, Max: 2}); err == nil {
+		t.Error("An invalid range should be rejected")
+	}
+}
+
+func TestAllocate_Crashed(t *testing.T) {
+	if dir := os.Getenv(crashChildEnv); dir != "" {
+		l, err := filelease.Allocate(filelease.Options{Dir: dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Printf("leased %d\n", l.MachineID())
+		// the process crashes without closing the lease.
+		os.Exit(3)
+	}
+
+	dir := t.TempDir()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestAllocate_Crashed$")
+	cmd.Env = append(os.Environ(), crashChildEnv+"="+dir)
+	out, _ := cmd.Output()
+	if !strings.Contains(string(out), "leased 0") {
+		t.Fatalf("The child process should lease machineID 0, got %q", out)
+	}
+
+	// the stale lock file of the crashed process is reclaimed.
+	l, err := filelease.Allocate(filelease.Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close(context.Background())
+	if l.MachineID() != 0 {
+		t.Errorf("The machineID of the crashed process should be leased again, got %d", l.MachineID())
+	}
+}
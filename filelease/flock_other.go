@@ -0,0 +1,19 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package filelease
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// tryLock returns an error, flock is not available on this platform.
+func tryLock(f *os.File) error {
+	return fmt.Errorf("filelease: file locks are not supported on %s", runtime.GOOS)
+}
+
+func unlock(f *os.File) error {
+	return fmt.Errorf("filelease: file locks are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,25 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package filelease
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// tryLock take the exclusive lock of f without blocking, it returns errLocked when another process holds it.
+// The kernel releases the lock when the process exits, even on a crash.
+func tryLock(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errLocked
+	}
+
+	return err
+}
+
+func unlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
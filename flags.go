@@ -0,0 +1,135 @@
+package snowflake
+
+import (
+	"flag"
+	"strconv"
+)
+
+// RegisterFlags register the generator settings on fs, the flag names are prefixed by prefix and a dot,
+// e.g. -snowflake.machine-id, -snowflake.epoch, -snowflake.backward-tolerance with the prefix snowflake.
+// The epoch flag accepts RFC3339 time or unix milliseconds.
+//
+// The returned Config is filled by fs.Parse, call its Build to create the Generator:
+//
+//	cfg := snowflake.RegisterFlags(flag.CommandLine, "snowflake")
+//	flag.Parse()
+//	g, err := cfg.Build()
+func RegisterFlags(fs *flag.FlagSet, prefix string) *Config {
+	c := &Config{
+		Layout: LayoutConfig{
+			TimestampBits:  DefaultLayout.TimestampBits,
+			MachineBits:    DefaultLayout.MachineBits,
+			SequenceBits:   DefaultLayout.SequenceBits,
+			DatacenterBits: DefaultLayout.DatacenterBits,
+		},
+	}
+
+	name := func(n string) string {
+		if prefix == "" {
+			return n
+		}
+		return prefix + "." + n
+	}
+
+	fs.Var(&optionalUint16{&c.MachineID}, name("machine-id"), "the machine ID, it cannot be used with datacenter-id or worker-id")
+	fs.Var(&optionalUint16{&c.DatacenterID}, name("datacenter-id"), "the datacenter ID")
+	fs.Var(&optionalUint16{&c.WorkerID}, name("worker-id"), "the worker ID")
+	fs.Var((*epochValue)(&c.Epoch), name("epoch"), "the start time, RFC3339 time or unix milliseconds")
+	fs.Var((*uint8Value)(&c.Layout.TimestampBits), name("timestamp-bits"), "the timestamp bits of the layout")
+	fs.Var((*uint8Value)(&c.Layout.MachineBits), name("machine-id-bits"), "the machine ID bits of the layout")
+	fs.Var((*uint8Value)(&c.Layout.SequenceBits), name("sequence-bits"), "the sequence bits of the layout")
+	fs.Var((*uint8Value)(&c.Layout.DatacenterBits), name("datacenter-bits"), "the datacenter bits of the layout")
+	fs.Var(&c.TimeUnit, name("time-unit"), "the tick duration, e.g. 10ms (default 1ms)")
+	fs.Var(&optionalDuration{&c.BackwardTolerance}, name("backward-tolerance"), "the max clock backward to wait for, e.g. 500ms (default 5s)")
+	fs.BoolVar(&c.Descending, name("descending"), false, "generate IDs in descending order")
+
+	return c
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+type uint8Value uint8
+
+func (v *uint8Value) String() string {
+	if v == nil {
+		return "0"
+	}
+	return strconv.FormatUint(uint64(*v), 10)
+}
+
+func (v *uint8Value) Set(s string) error {
+	n, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return err
+	}
+	*v = uint8Value(n)
+
+	return nil
+}
+
+// optionalUint16 set the pointer only when the flag is given.
+type optionalUint16 struct {
+	p **uint16
+}
+
+func (v *optionalUint16) String() string {
+	if v.p == nil || *v.p == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(**v.p), 10)
+}
+
+func (v *optionalUint16) Set(s string) error {
+	n, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return err
+	}
+	u := uint16(n)
+	*v.p = &u
+
+	return nil
+}
+
+// optionalDuration set the pointer only when the flag is given.
+type optionalDuration struct {
+	p **Duration
+}
+
+func (v *optionalDuration) String() string {
+	if v.p == nil || *v.p == nil {
+		return ""
+	}
+	return (*v.p).String()
+}
+
+func (v *optionalDuration) Set(s string) error {
+	var d Duration
+	if err := d.Set(s); err != nil {
+		return err
+	}
+	*v.p = &d
+
+	return nil
+}
+
+// epochValue store the epoch flag as RFC3339 time.
+type epochValue string
+
+func (v *epochValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return string(*v)
+}
+
+func (v *epochValue) Set(s string) error {
+	epoch, err := parseEpoch(s)
+	if err != nil {
+		return err
+	}
+	*v = epochValue(epoch)
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package snowflake_test
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestRegisterFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := snowflake.RegisterFlags(fs, "snowflake")
+
+	err := fs.Parse([]string{
+		"-snowflake.machine-id", "1000",
+		"-snowflake.epoch", "1577836800000",
+		"-snowflake.timestamp-bits", "41",
+		"-snowflake.machine-id-bits", "10",
+		"-snowflake.backward-tolerance", "500ms",
+		"-snowflake.descending",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.MachineID == nil || *cfg.MachineID != 1000 || cfg.Epoch != "2020-01-01T00:00:00Z" || time.Duration(*cfg.BackwardTolerance) != 500*time.Millisecond || !cfg.Descending {
+		t.Errorf("The flags should be parsed into the config, got %+v", cfg)
+	}
+
+	g, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layout := snowflake.Layout{TimestampBits: 41, MachineBits: 10, SequenceBits: 12}
+	if g.Layout() != layout {
+		t.Errorf("The layout should be %s, got %s", layout, g.Layout())
+	}
+
+	sid := g.ParseID(g.ID())
+	if sid.MachineID != 1000 {
+		t.Errorf("The machineID should be 1000, got %d", sid.MachineID)
+	}
+}
+
+func TestRegisterFlags_MachineIDZero(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := snowflake.RegisterFlags(fs, "snowflake")
+	if err := fs.Parse([]string{"-snowflake.machine-id", "0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := cfg.Build()
+	checkExplicitMachineIDZero(t, g, err)
+}
+
+func TestRegisterFlags_Defaults(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := snowflake.RegisterFlags(fs, "")
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Layout() != snowflake.DefaultLayout {
+		t.Errorf("The layout should be the default, got %s", g.Layout())
+	}
+}
+
+func TestRegisterFlags_Invalid(t *testing.T) {
+	tests := [][]string{
+		{"-machine-id", "70000"},
+		{"-epoch", "yesterday"},
+		{"-backward-tolerance", "1"},
+		{"-sequence-bits", "300"},
+	}
+	for _, args := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		snowflake.RegisterFlags(fs, "")
+		if err := fs.Parse(args); err == nil {
+			t.Errorf("Parsing %v should fail", args)
+		}
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := snowflake.RegisterFlags(fs, "")
+	if err := fs.Parse([]string{"-machine-id", "1000"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.Build(); err == nil {
+		t.Error("The machineID 1000 should be rejected by the default layout")
+	}
+}
+
+func ExampleRegisterFlags() {
+	// in a main function, use flag.CommandLine and flag.Parse().
+	fs := flag.NewFlagSet("example", flag.ExitOnError)
+	cfg := snowflake.RegisterFlags(fs, "snowflake")
+	_ = fs.Parse([]string{"-snowflake.machine-id", "42", "-snowflake.epoch", "2020-01-01T00:00:00Z"})
+
+	g, err := cfg.Build()
+	if err != nil {
+		panic(err)
+	}
+
+	sid := g.ParseID(g.ID())
+	fmt.Println(sid.MachineID)
+	// Output: 42
+}
@@ -0,0 +1,1048 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultMaxBackward is the max clock backward the generator waits for by default, it refuses to generate ID beyond it.
+const defaultMaxBackward = 5 * time.Second
+
+// Generator is a snowflake ID generator with its own machineID, start time, sequence resolver and clock state.
+//
+// The package-level functions (ID, NextID, SetMachineID...) use a default Generator,
+// use New when you need several independently configured generators in one process,
+// e.g. one for orders with a 2020 epoch and one for events with a 2023 epoch.
+type Generator struct {
+	// lastTimestamp, epoch, machineID and maxBackward are accessed atomically,
+	// keep them as the first fields to guarantee 64-bit alignment on 32-bit platforms.
+	lastTimestamp int64
+	// epoch is the start time in ticks since the unix epoch, precomputed so NextID doesn't convert the start time every call.
+	epoch     int64
+	machineID uint64
+	// maxBackward is the max clock backward (a time.Duration) the generator waits for.
+	maxBackward int64
+	// persistReserved is the last tick covered by the persisted state.
+	persistReserved int64
+	// maxLead is the max ticks the logical clock ran ahead of the physical clock.
+	maxLead int64
+	// absorbedBackward and absorbedDrift count the backward movements absorbed within the slew tolerance.
+	absorbedBackward uint64
+	absorbedDrift    int64
+	// lastWall and lastMono are the wall clock and monotonic readings of the last NextID, used to detect the forward jumps.
+	lastWall, lastMono int64
+	// utilization is the latest tick and its count of IDs, counted once a capacity hook is registered.
+	utilization uint64
+	// strictFloor is the latest tick WithStrictMonotonic advanced to ahead of the clock.
+	strictFloor int64
+
+	// leaseLost is set to 1 once the machineID lease is lost, leaseErr holds the error in an errorBox.
+	leaseLost int32
+
+	// panicOnError is set to 1 by WithPanicOnError, ID panics instead of returning 0.
+	panicOnError int32
+
+	// started is set to 1 by the first NextID, the configuration is frozen afterwards.
+	started int32
+
+	// requireMachineID is set to 1 by RequireExplicitMachineID, machineIDSet once the machineID is set explicitly.
+	requireMachineID int32
+	machineIDSet     int32
+	// unmanaged is set to 1 once the generator picked a random machineID, see ErrUnmanagedMachineID.
+	unmanaged     int32
+	unmanagedOnce sync.Once
+
+	// backwardPolicy is a BackwardPolicy, accessed atomically.
+	backwardPolicy int32
+
+	// closed is set to 1 by Close, done is closed at the same time to stop background goroutines.
+	closed  int32
+	done    chan struct{}
+	closeMu sync.Mutex
+	closers []func(ctx context.Context) error
+
+	// backwardWait is closed when the shared clock backward wait is over, it is nil when nobody waits.
+	backwardMu   sync.Mutex
+	backwardWait chan struct{}
+
+	// startTime is the start time set by the options, New converts it to epoch.
+	startTime time.Time
+	clock     Clock
+	layout    Layout
+	timeUnit  time.Duration
+
+	// resolver holds the custom Resolver in a resolverBox, it is empty when the generator uses its atomic resolver.
+	// It is an atomic.Value so SetSequenceResolver and ResolverName don't race with NextID.
+	resolver atomic.Value
+
+	// hlc generates IDs with a hybrid logical clock, see WithHybridLogicalClock.
+	hlc bool
+	// burstLead is the max lead of the burst mode, zero disables it, see WithBurst.
+	burstLead time.Duration
+
+	// slewTolerance is the backward movement the wait policy absorbs with a logical clock instead of sleeping.
+	slewTolerance time.Duration
+
+	// waitStrategy is how the generator waits for the next tick on sequence exhaustion.
+	waitStrategy WaitStrategy
+	// timerGranularity is the timer granularity set by WithTimerGranularity, zero means measured.
+	timerGranularity time.Duration
+
+	// clockSync is set by WithRequireSyncedClock, it is nil when the clock sync is not checked.
+	clockSync *clockSync
+
+	// clockHooked is set to 1 once a clock event hook is registered, the events are only detected afterwards.
+	clockHooked          int32
+	clockHooksMu         sync.Mutex
+	clockHooks           []func(ev ClockEvent)
+	clockEvents          chan ClockEvent
+	forwardJumpThreshold time.Duration
+
+	// capacityHooked is set to 1 once a capacity hook is registered, see OnSequenceExhausted and OnHighUtilization.
+	capacityHooked   int32
+	capacityHooksMu  sync.Mutex
+	exhaustedHooks   []func(ms int64, waited time.Duration)
+	utilizationHooks []*utilizationHook
+	capacityEvents   chan capacityEvent
+
+	// chain is set by WithResolverChain, the IDs served by its fallbacks use fallbackMachineID.
+	chain             *ResolverChain
+	fallbackMachineID uint16
+
+	// fallback is set by WithResolverFallback, the IDs served by its local resolver use its machineID.
+	fallback        *resolverFallback
+	resolverHooksMu sync.Mutex
+	resolverHooks   []func(ev ResolverEvent)
+	resolverEvents  chan ResolverEvent
+
+	// strict is set by WithStrictMonotonic, strictMu serializes the IDs and guards lastID and lastTick, the tick of lastID.
+	strict   bool
+	strictMu sync.Mutex
+	lastID   uint64
+	lastTick int64
+
+	// lease is set by WithMachineIDLease, NextID fails once it is lost unless leaseLossTolerated.
+	lease              MachineIDLease
+	leaseLossTolerated bool
+	leaseErr           atomic.Value
+	// pool is set by WithMachineIDPool, the generator rotates between its machineIDs on sequence exhaustion.
+	pool *machineIDPool
+
+	// standby is set by WithStandbyMachineID, the generator fails over to it once the lease is lost or a duplicate is detected.
+	standby *standbyMachineID
+
+	// duplicates is set by WithDuplicateDetection, it is nil when the duplicates are not detected.
+	duplicates       *duplicateDetector
+	haltOnDuplicate  bool
+	duplicateHooksMu sync.Mutex
+	duplicateHooks   []func(machineID uint16, owner string)
+
+	// switchMu is locked by SwitchMachineID to block the generation while the machineID changes.
+	switchMu         sync.RWMutex
+	machineIDHooksMu sync.Mutex
+	machineIDHooks   []func(ev MachineIDEvent)
+	machineIDEvents  chan MachineIDEvent
+
+	// limiter is set by WithMaxRate, it is nil when the rate is not limited.
+	limiter *rateLimiter
+
+	// errorHooks are called with the errors ignored by ID, lastError holds the last one in an errorBox.
+	errorHooksMu sync.Mutex
+	errorHooks   []func(err error)
+	lastError    atomic.Value
+
+	// descending stores MaxTimestamp - elapsed in the timestamp part, so later IDs compare smaller.
+	descending bool
+
+	// derivedMachineID is set by the options deriving the machineID, e.g. AutoMachineID, named derivedBy,
+	// New derives machineID with the machine bits once the layout is known.
+	derivedMachineID func(bits uint8) (uint16, error)
+	derivedBy        string
+	// claims is set by HostnameMachineID, New claims machineID for the host, it is nil when the claim is skipped.
+	claims ClaimStore
+	// machineIDFile is set by MachineIDFile, machineIDFileHook is called when the file changed after New.
+	machineIDFile     string
+	machineIDFileHook func(err error)
+	// provider is set by WithMachineIDProvider, it provides machineID on the first NextID.
+	provider       *machineIDProvider
+	providerNoWait bool
+
+	// datacenterID and workerID are set by the options, New composes them into machineID once the layout is known.
+	datacenterID, workerID *uint16
+
+	// persister saves the state every persistInterval, it is nil when the state is not persisted.
+	persister       StatePersister
+	persistInterval time.Duration
+	persistMu       sync.Mutex
+
+	// backfill counts the IDs generated by NextIDAt per tick, from the tick backfillFloor set by ForgetBackfill.
+	backfillMu    sync.Mutex
+	backfill      map[int64]int
+	backfillFloor int64
+
+	// atomic is the default resolver, every generator has its own so generators with different time units don't interfere.
+	atomic *atomicResolver
+}
+
+// New create a Generator, the options are applied in order and validated eagerly,
+// New returns the error of the first invalid option instead of panicking.
+//
+// Without options the generator use the default configuration:
+// start time is 2008-11-10 23:00:00 UTC, machineID is random, see ErrUnmanagedMachineID, and resolver is an atomic resolver owned by the generator.
+//
+//	g, err := snowflake.New(
+//		snowflake.WithMachineID(42),
+//		snowflake.WithStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+//	)
+func New(opts ...Option) (*Generator, error) {
+	g := newGenerator()
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(g); err != nil {
+			return nil, err
+		}
+	}
+
+	// machineID and start time limits depend on the layout, so they are checked after all options applied.
+	if err := g.composeMachineID(); err != nil {
+		return nil, err
+	}
+	if g.derivedMachineID != nil {
+		// machineIDSet rather than machineID, WithMachineID(0) is explicit too
+		if g.machineIDSet == 1 || g.lease != nil {
+			return nil, fmt.Errorf("snowflake: conflicting options, %s cannot be used with WithMachineID, WithMachineIDLease, WithDatacenterID or WithWorkerID", g.derivedBy)
+		}
+		m, err := g.derivedMachineID(g.layout.MachineBits)
+		if err != nil {
+			return nil, fmt.Errorf("snowflake: invalid option %s: %w", g.derivedBy, err)
+		}
+		g.machineID, g.machineIDSet = uint64(m), 1
+	}
+	if g.provider != nil && (g.machineIDSet == 1 || g.lease != nil || g.derivedMachineID != nil) {
+		return nil, errors.New("snowflake: conflicting options, WithMachineIDProvider cannot be used with the other machineID options")
+	}
+	if g.pool != nil {
+		if err := g.checkPool(); err != nil {
+			return nil, err
+		}
+	}
+	if err := g.layout.checkMachineID(uint16(g.machineID)); err != nil {
+		return nil, fmt.Errorf("snowflake: invalid machineID %d for layout %s: %w", g.machineID, g.layout, err)
+	}
+	if g.standby != nil {
+		if err := g.layout.checkMachineID(g.standby.id); err != nil {
+			return nil, fmt.Errorf("snowflake: invalid standby machineID %d for layout %s: %w", g.standby.id, g.layout, err)
+		}
+		if uint64(g.standby.id) == g.machineID || g.fallback != nil && g.standby.id == g.fallback.machineID {
+			return nil, fmt.Errorf("snowflake: invalid standby machineID %d: it must differ from the machineID and the fallback machineID", g.standby.id)
+		}
+	}
+	if g.haltOnDuplicate && g.duplicates == nil {
+		return nil, errors.New("snowflake: invalid option WithHaltOnDuplicate: it needs WithDuplicateDetection")
+	}
+	if g.strict && g.descending {
+		return nil, errors.New("snowflake: conflicting options, WithStrictMonotonic cannot be used with WithDescending")
+	}
+	if err := g.layout.checkMachineID(g.fallbackMachineID); g.chain != nil && err != nil {
+		return nil, fmt.Errorf("snowflake: invalid fallback machineID %d for layout %s: %w", g.fallbackMachineID, g.layout, err)
+	}
+	if g.fallback != nil {
+		if g.chain != nil {
+			return nil, errors.New("snowflake: conflicting options, WithResolverFallback cannot be used with WithResolverChain")
+		}
+		if err := g.layout.checkMachineID(g.fallback.machineID); err != nil {
+			return nil, fmt.Errorf("snowflake: invalid fallback machineID %d for layout %s: %w", g.fallback.machineID, g.layout, err)
+		}
+		if uint64(g.fallback.machineID) == g.machineID {
+			return nil, fmt.Errorf("snowflake: invalid fallback machineID %d: it must differ from the machineID", g.fallback.machineID)
+		}
+	}
+	if err := checkStartTime(g.startTime, g.layout, g.timeUnit, g.clock.Now()); err != nil {
+		return nil, fmt.Errorf("snowflake: invalid start time %s for layout %s: %w", g.startTime.Format(time.RFC3339), g.layout, err)
+	}
+	g.epoch = toTicks(g.startTime, g.timeUnit)
+
+	if g.persister != nil {
+		if err := g.loadState(); err != nil {
+			return nil, err
+		}
+	}
+	if g.claims != nil {
+		if err := g.claimMachineID(); err != nil {
+			return nil, err
+		}
+	}
+	if g.lease != nil {
+		if err := g.watchLease(); err != nil {
+			return nil, err
+		}
+	}
+	if g.machineIDFileHook != nil {
+		g.watchMachineIDFile()
+	}
+	if g.duplicates != nil {
+		if err := g.detectDuplicates(); err != nil {
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+// ID use ID to generate snowflake id, and it will ignore error. if you want error info, you need use NextID method.
+// ID returns 0 on error, a successful generation never produces 0, see OnError, LastError and WithPanicOnError.
+// This function is thread safe.
+func (g *Generator) ID() uint64 {
+	id, err := g.NextID()
+	if err != nil {
+		g.ignoreError(err)
+	}
+	return id
+}
+
+// NextID use NextID to generate snowflake id and return an error.
+// This function is thread safe.
+func (g *Generator) NextID() (uint64, error) {
+	return g.NextIDContext(context.Background())
+}
+
+// NextIDContext is NextID, the waits for the clock (clock backward, sequence exhaustion) return ctx.Err() once ctx is done.
+// This function is thread safe.
+func (g *Generator) NextIDContext(ctx context.Context) (uint64, error) {
+	return g.nextID(ctx, false)
+}
+
+// NextIDNoWait is NextID without waiting for the clock, it returns ErrSequenceExhausted when the sequence of the
+// current tick is exhausted, and ErrClockMovedBackward instead of sleeping when the clock moved backward.
+// The callers must handle the retry themselves, e.g. retry in the next tick, shed load or fall back to NextID.
+// This function is thread safe.
+func (g *Generator) NextIDNoWait() (uint64, error) {
+	return g.nextID(context.Background(), true)
+}
+
+// nextID generate snowflake id, noWait returns an error instead of waiting for the clock.
+func (g *Generator) nextID(ctx context.Context, noWait bool) (uint64, error) {
+	return g.nextBlock(ctx, noWait, 1)
+}
+
+// nextBlock generate n consecutive snowflake ids in one tick and returns the first one.
+// With WithStrictMonotonic the IDs are generated one block at a time, a block not greater than the last one
+// is generated again from the tick after the last one.
+func (g *Generator) nextBlock(ctx context.Context, noWait bool, n int) (uint64, error) {
+	if g.limiter != nil {
+		if err := g.limiter.wait(ctx, g, noWait, n); err != nil {
+			return 0, err
+		}
+	}
+
+	// SwitchMachineID 切换期间暂停生成
+	g.switchMu.RLock()
+	defer g.switchMu.RUnlock()
+
+	if !g.strict {
+		return g.generate(ctx, noWait, 0, n)
+	}
+
+	g.strictMu.Lock()
+	defer g.strictMu.Unlock()
+
+	id, err := g.generate(ctx, noWait, 0, n)
+	if err == nil && g.lastTick != 0 && id <= g.lastID {
+		// 严格递增：从上一个 ID 的下一个时间单位重新生成
+		floor := g.lastTick + 1
+		atomic.StoreInt64(&g.strictFloor, floor)
+		id, err = g.generate(ctx, noWait, floor, n)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	last := id + uint64(n-1)
+	g.lastID, g.lastTick = last, int64(g.layout.parse(last).Timestamp)+atomic.LoadInt64(&g.epoch)
+
+	return id, nil
+}
+
+// generate generate n consecutive snowflake ids from the tick floor at least and returns the first one,
+// noWait returns an error instead of waiting for the clock.
+func (g *Generator) generate(ctx context.Context, noWait bool, floor int64, n int) (uint64, error) {
+	if err := g.checkUsable(ctx, noWait); err != nil {
+		return 0, err
+	}
+
+	// 时钟未同步时拒绝生成 ID
+	if g.clockSync != nil {
+		if err := g.clockSync.result(ctx, g.clock.Now()); err != nil {
+			return 0, err
+		}
+	}
+
+	g.detectForwardJump()
+	now := g.currentTicks()
+	last := atomic.LoadInt64(&g.lastTimestamp)
+
+	physical := now
+	logical, reported := false, false
+	if g.hlc {
+		// 混合逻辑时钟：时间戳取 max(物理时钟, lastTimestamp)，从不休眠也不报错
+		if now < last {
+			g.emitClockEvent(ClockEvent{Kind: ClockBackward, Delta: time.Duration(last-now) * g.timeUnit, Action: BackwardPolicyLogical, Time: g.clock.Now()})
+			now = last
+		}
+		logical = true
+	}
+
+	if g.burstLead > 0 && now < last && time.Duration(last-now)*g.timeUnit <= g.burstLead {
+		// 突发模式：仍在借用的时间范围内，不是时钟回拨
+		now = last
+	}
+	if strictFloor := atomic.LoadInt64(&g.strictFloor); now < last && last <= strictFloor {
+		// 严格递增模式：仍在推进的时间范围内，不是时钟回拨
+		now = last
+	}
+
+	// ⏰ 时钟回拨检测
+	policy := BackwardPolicy(atomic.LoadInt32(&g.backwardPolicy))
+	for now < last {
+		backward := time.Duration(last-now) * g.timeUnit
+		// 🛡️ 最大容忍回拨：默认 5000 毫秒（5秒），0 表示不等待
+		tolerance := time.Duration(atomic.LoadInt64(&g.maxBackward))
+		absorb := policy == BackwardPolicyWait && backward <= g.slewTolerance && backward <= tolerance
+		refuse := !absorb && (backward > tolerance || policy == BackwardPolicyError || (noWait && policy == BackwardPolicyWait))
+		if !reported {
+			action := policy
+			switch {
+			case refuse:
+				action = BackwardPolicyError
+			case absorb:
+				action = BackwardPolicyLogical
+			}
+			g.emitClockEvent(ClockEvent{Kind: ClockBackward, Delta: backward, Action: action, Time: g.clock.Now()})
+			reported = true
+		}
+		if refuse {
+			return 0, fmt.Errorf("%w by %s (tolerance %s, policy %s), refusing to generate ID", ErrClockMovedBackward, backward, tolerance, policy)
+		}
+		if absorb {
+			// 微小回拨（如闰秒平滑）：吸收为逻辑时钟，不休眠
+			atomic.AddUint64(&g.absorbedBackward, 1)
+			atomic.AddInt64(&g.absorbedDrift, int64(backward))
+			now, logical = last, true
+			break
+		}
+		if policy == BackwardPolicyLogical {
+			// 使用 lastTimestamp 作为逻辑时钟
+			now, logical = last, true
+			break
+		}
+		// 在容忍范围内，等待时间追上（并发的调用者共享同一次等待）
+		if err := g.waitBackward(ctx, backward); err != nil {
+			return 0, err
+		}
+		now, last = g.currentTicks(), atomic.LoadInt64(&g.lastTimestamp)
+		physical = now
+	}
+
+	if now < floor {
+		now = floor
+	}
+
+	// 获取序列号
+	machineID := atomic.LoadUint64(&g.machineID)
+	primaryID, resolver := machineID, g.sequenceResolver()
+	seqResolver := func(ms int64) (uint16, error) {
+		return resolver.Resolve(ctx, ms, uint16(primaryID))
+	}
+	if g.chain != nil {
+		// 降级时使用备用的 machineID，避免与共享主解析器的其它进程冲突
+		seqResolver = func(ms int64) (uint16, error) {
+			seq, served, err := g.chain.resolve(ctx, ms, uint16(primaryID), g.fallbackMachineID)
+			machineID = primaryID
+			if served > 0 {
+				machineID = uint64(g.fallbackMachineID)
+			}
+			return seq, err
+		}
+	} else if g.fallback != nil {
+		// 主解析器故障时使用本地解析器和备用的 machineID
+		primary := seqResolver
+		seqResolver = func(ms int64) (uint16, error) {
+			seq, local, err := g.fallback.resolve(g, ms, primary)
+			machineID = primaryID
+			if local {
+				machineID = uint64(g.fallback.machineID)
+			}
+			return seq, err
+		}
+	}
+	if n > 1 {
+		// 连续的 ID 块：一次性占用同一时间单位内的 n 个序列号
+		seqResolver = func(ms int64) (uint16, error) {
+			return g.atomic.resolveBlock(ms, n, g.layout.MaxSequence())
+		}
+	}
+	if g.pool != nil {
+		// machineID 池：当前 machineID 的序列号用完时轮换到下一个
+		seqResolver = func(ms int64) (uint16, error) {
+			seq, m, err := g.pool.resolve(ms, n, g.layout.MaxSequence())
+			machineID = uint64(m)
+			return seq, err
+		}
+	}
+	seq, err := seqResolver(now)
+
+	// 序列号溢出：等待下一个时间单位（MaxSequence 本身是有效的序列号）
+	maxSequence := g.layout.MaxSequence()
+	for errors.Is(err, ErrSequenceExhausted) || err == nil && seq > maxSequence {
+		if logical || g.canBorrow(now) {
+			// 逻辑时钟或突发模式：推进到下一个时间单位，不等待
+			now++
+		} else if noWait {
+			return 0, ErrSequenceExhausted
+		} else {
+			exhausted, start := now, time.Now()
+			if now, err = g.waitForNextTick(ctx, now); err != nil {
+				return 0, err
+			}
+			g.sequenceExhausted(exhausted, time.Since(start))
+			physical = now
+		}
+		seq, err = seqResolver(now)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if now > physical {
+		g.recordLead(now - physical)
+	}
+	g.countUtilization(now)
+
+	// 持久化状态必须先覆盖当前时间
+	if g.persister != nil {
+		if err := g.reserve(now); err != nil {
+			return 0, err
+		}
+	}
+
+	if atomic.LoadInt32(&g.started) == 0 {
+		atomic.StoreInt32(&g.started, 1)
+	}
+
+	// 更新 lastTimestamp（必须在生成 ID 前完成），CAS 保证它只会前进
+	g.advanceLastTimestamp(now)
+
+	// 计算相对于 startTime 的偏移
+	df := now - atomic.LoadInt64(&g.epoch)
+	if df < 0 || uint64(df) > g.layout.MaxTimestamp() {
+		return 0, fmt.Errorf("%w: the maximum life cycle of the snowflake algorithm is 2^%d-1(%s), please check start-time", ErrEpochExhausted, g.layout.TimestampBits, g.timeUnit)
+	}
+
+	ts := uint64(df)
+	if g.descending {
+		ts = g.layout.MaxTimestamp() - ts
+	}
+
+	id := g.layout.compose(ts, machineID, seq)
+	if id > math.MaxInt64 {
+		return 0, fmt.Errorf("the id %d overflows int64, please check the layout %s", id, g.layout)
+	}
+	if id == 0 {
+		// 0 保留为无效 ID（ID 出错时返回 0），使用下一个序列号
+		return g.generate(ctx, noWait, floor, n)
+	}
+
+	return id, nil
+}
+
+// checkUsable check the generator can generate IDs with its machineID: it is not closed, the lease is not lost,
+// no duplicate is detected, and the machineID is provided or set, a random one is picked when it is not required.
+func (g *Generator) checkUsable(ctx context.Context, noWait bool) error {
+	if atomic.LoadInt32(&g.closed) == 1 {
+		return ErrClosed
+	}
+	if err := g.checkLease(); err != nil {
+		return err
+	}
+	if err := g.checkDuplicate(); err != nil {
+		return err
+	}
+	if g.provider != nil {
+		if err := g.waitMachineID(ctx, noWait); err != nil {
+			return err
+		}
+	}
+	if atomic.LoadInt32(&g.machineIDSet) == 0 && atomic.LoadInt32(&g.unmanaged) == 0 {
+		// 未配置 machineID：严格模式拒绝生成，否则随机选择一个
+		if atomic.LoadInt32(&g.requireMachineID) == 1 {
+			return ErrMachineIDNotSet
+		}
+		g.pickUnmanagedMachineID()
+	}
+
+	return nil
+}
+
+// NextInt64 use NextInt64 to generate snowflake id as int64 and return an error,
+// the id is never negative so it can be stored as a BIGINT or a Java long.
+// This function is thread safe.
+func (g *Generator) NextInt64() (int64, error) {
+	id, err := g.NextID()
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(id), nil
+}
+
+// WaitUntilSafe block until the generator clock is strictly past the timestamp of lastKnownID, or ctx is done.
+// Call it at startup with the last ID stored before shutdown, it protects against restoring onto a machine
+// whose clock is behind the old one. The timestamp is decoded with the layout, start time and time unit of the generator.
+// This function is thread safe.
+func (g *Generator) WaitUntilSafe(ctx context.Context, lastKnownID uint64) error {
+	sid := g.ParseID(lastKnownID)
+	last := sid.epoch + int64(sid.Timestamp)
+
+	for {
+		now := g.currentTicks()
+		if now > last {
+			// 之后的 ID 不能回到 lastKnownID 之前
+			g.advanceLastTimestamp(last)
+			return nil
+		}
+
+		timer := time.NewTimer(time.Duration(last-now+1) * g.timeUnit)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Close stops the background goroutines of the generator, flushes its persisted state and releases its machineID lease,
+// in the reverse order they were set up. After Close, NextID returns ErrClosed.
+//
+// ctx bounds the time spent on releasing external resources, e.g. returning a lease quickly during a graceful shutdown.
+// It is safe to call Close more than once, only the first call does the work.
+func (g *Generator) Close(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&g.closed, 0, 1) {
+		return nil
+	}
+	close(g.done)
+
+	g.closeMu.Lock()
+	closers := g.closers
+	g.closers = nil
+	g.closeMu.Unlock()
+
+	var err error
+	for i := len(closers) - 1; i >= 0; i-- {
+		if e := closers[i](ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// OnClose register fn to be called by Close, e.g. to stop a background goroutine or release a machineID lease.
+// The functions are called in the reverse order they were registered,
+// fn is called immediately when the generator is already closed.
+// This function is thread safe.
+func (g *Generator) OnClose(fn func(ctx context.Context) error) error {
+	g.closeMu.Lock()
+	if atomic.LoadInt32(&g.closed) == 0 {
+		g.closers = append(g.closers, fn)
+		g.closeMu.Unlock()
+
+		return nil
+	}
+	g.closeMu.Unlock()
+
+	return fn(context.Background())
+}
+
+// TrySetStartTime set the start time for the generator, it returns an error instead of panicking:
+//
+//	ErrZeroStartTime: s IsZero,
+//	ErrFutureStartTime: s > current millisecond,
+//	ErrStartTimeTooEarly: current millisecond - s > the max timestamp of the layout,
+//	ErrConfigFrozen: the generator has already generated an ID.
+//
+// This function is thread-unsafe, recommended you call him right after New.
+func (g *Generator) TrySetStartTime(s time.Time) error {
+	if err := g.checkNotStarted(); err != nil {
+		return err
+	}
+	if err := checkStartTime(s, g.layout, g.timeUnit, g.clock.Now()); err != nil {
+		return err
+	}
+	atomic.StoreInt64(&g.epoch, toTicks(s, g.timeUnit))
+
+	return nil
+}
+
+// SetStartTime set the start time for the generator, it will panic when TrySetStartTime returns an error.
+// This function is thread-unsafe, recommended you call him right after New.
+func (g *Generator) SetStartTime(s time.Time) {
+	must(g.TrySetStartTime(s))
+}
+
+// TrySetMachineID specify the machine ID, it returns ErrMachineIDTooLarge when m > the max machineID of the layout,
+// or ErrConfigFrozen when the generator has already generated an ID.
+// This function is thread-unsafe, recommended you call him right after New.
+func (g *Generator) TrySetMachineID(m uint16) error {
+	if err := g.checkNotStarted(); err != nil {
+		return err
+	}
+	if err := g.layout.checkMachineID(m); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&g.machineID, uint64(m))
+	atomic.StoreInt32(&g.machineIDSet, 1)
+
+	return nil
+}
+
+// SetMachineID specify the machine ID, it will panic when TrySetMachineID returns an error.
+// This function is thread-unsafe, recommended you call him right after New.
+func (g *Generator) SetMachineID(m uint16) {
+	must(g.TrySetMachineID(m))
+}
+
+// RequireExplicitMachineID make NextID return ErrMachineIDNotSet until the machineID is set explicitly:
+// by WithMachineID, WithDatacenterID, WithWorkerID, an option deriving or leasing the machineID, or a TrySetXXX setter.
+// The replicas started without a machineID pick random machineIDs which may collide, see ErrUnmanagedMachineID,
+// the generators requiring an explicit machineID refuse to generate instead.
+// This function is thread safe.
+func (g *Generator) RequireExplicitMachineID() {
+	atomic.StoreInt32(&g.requireMachineID, 1)
+}
+
+// TrySetDatacenterID specify the datacenterID part of the machine ID, see Layout.DatacenterBits.
+// It returns ErrDatacenterIDTooLarge when d > the max datacenterID of the layout,
+// or ErrConfigFrozen when the generator has already generated an ID.
+// This function is thread-unsafe, recommended you call him right after New.
+func (g *Generator) TrySetDatacenterID(d uint16) error {
+	if err := g.checkNotStarted(); err != nil {
+		return err
+	}
+	if err := g.layout.checkDatacenterID(d); err != nil {
+		return err
+	}
+	m := uint16(atomic.LoadUint64(&g.machineID))
+	atomic.StoreUint64(&g.machineID, uint64(g.layout.machineID(d, m&g.layout.MaxWorkerID())))
+	atomic.StoreInt32(&g.machineIDSet, 1)
+
+	return nil
+}
+
+// SetDatacenterID specify the datacenterID part of the machine ID, it will panic when TrySetDatacenterID returns an error.
+// This function is thread-unsafe, recommended you call him right after New.
+func (g *Generator) SetDatacenterID(d uint16) {
+	must(g.TrySetDatacenterID(d))
+}
+
+// TrySetWorkerID specify the workerID part of the machine ID, see Layout.DatacenterBits.
+// It returns ErrWorkerIDTooLarge when w > the max workerID of the layout,
+// or ErrConfigFrozen when the generator has already generated an ID.
+// This function is thread-unsafe, recommended you call him right after New.
+func (g *Generator) TrySetWorkerID(w uint16) error {
+	if err := g.checkNotStarted(); err != nil {
+		return err
+	}
+	if err := g.layout.checkWorkerID(w); err != nil {
+		return err
+	}
+	m := uint16(atomic.LoadUint64(&g.machineID))
+	atomic.StoreUint64(&g.machineID, uint64(g.layout.machineID(m>>g.layout.WorkerBits(), w)))
+	atomic.StoreInt32(&g.machineIDSet, 1)
+
+	return nil
+}
+
+// SetWorkerID specify the workerID part of the machine ID, it will panic when TrySetWorkerID returns an error.
+// This function is thread-unsafe, recommended you call him right after New.
+func (g *Generator) SetWorkerID(w uint16) {
+	must(g.TrySetWorkerID(w))
+}
+
+// SetMaxBackwardTolerance set the max clock backward NextID waits for, it is 5 seconds by default.
+// When the clock moves backward by more than d NextID returns ErrClockMovedBackward immediately,
+// otherwise it sleeps until the clock catches up, so d is also the longest sleep.
+// Zero means never wait, any backward movement is an error. It will panic when d is negative.
+// This function is thread safe.
+func (g *Generator) SetMaxBackwardTolerance(d time.Duration) {
+	if d < 0 {
+		panic("The max backward tolerance cannot be negative")
+	}
+	atomic.StoreInt64(&g.maxBackward, int64(d))
+}
+
+// SetBackwardPolicy set what NextID does when the clock moved backward, it is BackwardPolicyWait by default.
+// This function is thread safe.
+func (g *Generator) SetBackwardPolicy(p BackwardPolicy) {
+	if p < BackwardPolicyWait || p > BackwardPolicyLogical {
+		panic(fmt.Sprintf("Unknown backward policy %s", p))
+	}
+	atomic.StoreInt32(&g.backwardPolicy, int32(p))
+}
+
+// SetClock set the time source of the generator, a nil clock is ignored.
+// It will panic when the generator has already generated an ID.
+// This function is thread-unsafe, recommended you call him right after New.
+func (g *Generator) SetClock(c Clock) {
+	must(g.checkNotStarted())
+	if c != nil {
+		g.clock = c
+	}
+}
+
+// SetSequenceResolver set a custom sequence resolver, a nil resolver is ignored.
+// It will panic when the generator has already generated an ID.
+// This function is thread-unsafe, recommended you call him right after New.
+func (g *Generator) SetSequenceResolver(seq SequenceResolver) {
+	must(g.checkNotStarted())
+	if seq != nil {
+		g.resolver.Store(resolverBox{seq})
+		g.chain = nil
+	}
+}
+
+// ResetForTesting unfreeze the configuration so the setters can be called again after IDs were generated.
+// It is an escape hatch for tests and will panic when it is not called from a test binary.
+func (g *Generator) ResetForTesting() {
+	if flag.Lookup("test.v") == nil {
+		panic("snowflake: ResetForTesting can only be called in tests")
+	}
+	atomic.StoreInt32(&g.started, 0)
+}
+
+// Layout returns the bit layout of the generator.
+func (g *Generator) Layout() Layout {
+	return g.layout
+}
+
+// MachineID returns the machine ID of the generator.
+// This function is thread safe.
+func (g *Generator) MachineID() uint16 {
+	return uint16(atomic.LoadUint64(&g.machineID))
+}
+
+// StartTime returns the start time of the generator, truncated to the time unit, as a UTC time.
+// This function is thread safe.
+func (g *Generator) StartTime() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&g.epoch)*int64(g.timeUnit)).UTC()
+}
+
+// LifetimeEnd returns the time the timestamp part of the generator runs out, as a UTC time,
+// NextID returns ErrEpochExhausted from then on. It depends on the start time, the layout and the time unit.
+// This function is thread safe.
+func (g *Generator) LifetimeEnd() time.Time {
+	end := g.StartTime()
+	// the lifetime of wide layouts overflows a time.Duration, add it in chunks.
+	ticks := g.layout.MaxTimestamp() + 1
+	chunk := uint64(math.MaxInt64 / int64(g.timeUnit))
+	for ticks > 0 {
+		n := ticks
+		if n > chunk {
+			n = chunk
+		}
+		end = end.Add(time.Duration(n) * g.timeUnit)
+		ticks -= n
+	}
+
+	return end
+}
+
+// Remaining returns how long the generator can still generate IDs, see LifetimeEnd,
+// alert on it years ahead instead of discovering the exhaustion in production.
+// This function is thread safe.
+func (g *Generator) Remaining() time.Duration {
+	return g.LifetimeEnd().Sub(g.clock.Now())
+}
+
+// ResolverName returns the name of the sequence resolver, "atomic" for the default resolver of the generator,
+// the function name of a SequenceResolver, e.g. github.com/hedwi/go-snowflake.AtomicResolver,
+// otherwise the type of the Resolver, e.g. *main.redisResolver.
+// This function is thread safe.
+func (g *Generator) ResolverName() string {
+	b, ok := g.resolver.Load().(resolverBox)
+	if !ok {
+		return "atomic"
+	}
+	seq, ok := b.r.(SequenceResolver)
+	if !ok {
+		return fmt.Sprintf("%T", b.r)
+	}
+
+	if fn := runtime.FuncForPC(reflect.ValueOf(seq).Pointer()); fn != nil {
+		return fn.Name()
+	}
+
+	return "unknown"
+}
+
+// ParseID parse snowflake id to SID struct with the layout of this generator,
+// SID.GenerateTime will use the start time and time unit of this generator.
+// In descending mode the timestamp part is inverted back, SID.Timestamp is always the elapsed ticks since the start time.
+func (g *Generator) ParseID(id uint64) SID {
+	sid := g.layout.parse(id)
+	if g.descending {
+		sid.Timestamp = g.layout.MaxTimestamp() - sid.Timestamp
+	}
+	sid.epoch = atomic.LoadInt64(&g.epoch)
+	sid.timeUnit = g.timeUnit
+
+	return sid
+}
+
+// ParseInt64 parse snowflake id stored as int64 to SID struct, see ParseID.
+// A negative value is not a valid snowflake id, its sign bit is ignored.
+func (g *Generator) ParseInt64(id int64) SID {
+	return g.ParseID(uint64(id) & math.MaxInt64)
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+func newGenerator() *Generator {
+	return &Generator{
+		epoch:                toTicks(defaultStartTime, time.Millisecond),
+		startTime:            defaultStartTime,
+		clock:                SystemClock,
+		waitStrategy:         defaultWaitStrategy,
+		forwardJumpThreshold: defaultForwardJumpThreshold,
+		slewTolerance:        defaultSlewTolerance,
+		layout:               DefaultLayout,
+		timeUnit:             time.Millisecond,
+		maxBackward:          int64(defaultMaxBackward),
+		atomic:               &atomicResolver{},
+		done:                 make(chan struct{}),
+	}
+}
+
+// composeMachineID compose the datacenterID and workerID set by the options into machineID.
+func (g *Generator) composeMachineID() error {
+	if g.datacenterID == nil && g.workerID == nil {
+		return nil
+	}
+
+	// machineIDSet rather than machineID, WithMachineID(0) is explicit too
+	if g.machineIDSet == 1 {
+		return errors.New("snowflake: conflicting options, WithMachineID and WithMachineIDLease cannot be used with WithDatacenterID or WithWorkerID")
+	}
+
+	var d, w uint16
+	if g.datacenterID != nil {
+		d = *g.datacenterID
+		if err := g.layout.checkDatacenterID(d); err != nil {
+			return fmt.Errorf("snowflake: invalid datacenterID %d for layout %s: %w", d, g.layout, err)
+		}
+	}
+	if g.workerID != nil {
+		w = *g.workerID
+		if err := g.layout.checkWorkerID(w); err != nil {
+			return fmt.Errorf("snowflake: invalid workerID %d for layout %s: %w", w, g.layout, err)
+		}
+	}
+	g.machineID, g.machineIDSet = uint64(g.layout.machineID(d, w)), 1
+
+	return nil
+}
+
+// waitBackward wait d for the clock to catch up after it moved backward.
+// The first caller sleeps while the concurrent callers block on its channel, then they all resume together,
+// Close interrupts the wait and waitBackward returns ErrClosed, a done ctx stops the wait of its caller only.
+func (g *Generator) waitBackward(ctx context.Context, d time.Duration) error {
+	g.backwardMu.Lock()
+	wait := g.backwardWait
+	leader := wait == nil
+	if leader {
+		wait = make(chan struct{})
+		g.backwardWait = wait
+	}
+	g.backwardMu.Unlock()
+
+	if leader {
+		// ctx 结束时唤醒其它等待者，其中一个会接替等待
+		g.sleep(ctx, d)
+
+		g.backwardMu.Lock()
+		g.backwardWait = nil
+		g.backwardMu.Unlock()
+		close(wait)
+	} else {
+		select {
+		case <-wait:
+		case <-g.done:
+		case <-ctx.Done():
+		}
+	}
+
+	if atomic.LoadInt32(&g.closed) == 1 {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// canBorrow returns true when the burst mode can borrow the tick after now without exceeding the max lead.
+func (g *Generator) canBorrow(now int64) bool {
+	return g.burstLead > 0 && time.Duration(now+1-g.currentTicks())*g.timeUnit <= g.burstLead
+}
+
+// advanceLastTimestamp set lastTimestamp to now unless another goroutine has already advanced it further,
+// a slower goroutine never moves it backward.
+func (g *Generator) advanceLastTimestamp(now int64) {
+	for {
+		last := atomic.LoadInt64(&g.lastTimestamp)
+		if now <= last || atomic.CompareAndSwapInt64(&g.lastTimestamp, last, now) {
+			return
+		}
+	}
+}
+
+// currentTicks get the current tick of the generator clock.
+func (g *Generator) currentTicks() int64 {
+	return toTicks(g.clock.Now(), g.timeUnit)
+}
+
+func (g *Generator) checkNotStarted() error {
+	if atomic.LoadInt32(&g.started) == 1 {
+		return ErrConfigFrozen
+	}
+
+	return nil
+}
+
+// must panic with the error message, the SetXXX functions panic with plain strings.
+func must(err error) {
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+func (g *Generator) sequenceResolver() Resolver {
+	if b, ok := g.resolver.Load().(resolverBox); ok {
+		return b.r
+	}
+
+	return g.atomic
+}
@@ -0,0 +1,830 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/clocktest"
+)
+
+func TestNew(t *testing.T) {
+	g, err := snowflake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := g.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sid := g.ParseID(id)
+	if sid.MachineID != uint64(g.MachineID()) || !g.Stats().UnmanagedMachineID {
+		t.Error("MachineID should be the random machineID picked without configuration")
+	}
+
+	if d := time.Since(sid.GenerateTime()); d < 0 || d > time.Second {
+		t.Error("The id generate time should be equal current time", sid.GenerateTime())
+	}
+}
+
+func TestGenerator_Independent(t *testing.T) {
+	orderEpoch := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	eventEpoch := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	orders, err := snowflake.New(snowflake.WithStartTime(orderEpoch), snowflake.WithMachineID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := snowflake.New(snowflake.WithStartTime(eventEpoch), snowflake.WithMachineID(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	le := 10000
+	ch := make(chan uint64, le*2)
+	var wg sync.WaitGroup
+	for _, g := range []*snowflake.Generator{orders, events} {
+		wg.Add(1)
+		go func(g *snowflake.Generator) {
+			defer wg.Done()
+			for i := 0; i < le; i++ {
+				id, err := g.NextID()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				ch <- id
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(ch)
+
+	mp := make(map[uint64]bool)
+	for id := range ch {
+		if mp[id] {
+			t.Fatal("ID should't repeat", id)
+		}
+		mp[id] = true
+	}
+
+	for _, c := range []struct {
+		g         *snowflake.Generator
+		machineID uint64
+	}{
+		{orders, 1},
+		{events, 2},
+	} {
+		sid := c.g.ParseID(c.g.ID())
+		if sid.MachineID != c.machineID {
+			t.Errorf("MachineID should be equal %d, got %d", c.machineID, sid.MachineID)
+		}
+
+		if d := time.Since(sid.GenerateTime()); d < 0 || d > time.Second {
+			t.Error("The id generate time should be equal current time", sid.GenerateTime())
+		}
+	}
+
+	// the same id decodes to a different timestamp offset under each epoch.
+	id := orders.ID()
+	a, b := orders.ParseID(id), events.ParseID(id)
+	if a.GenerateTime().Equal(b.GenerateTime()) {
+		t.Error("The generate time should depend on the generator start time")
+	}
+}
+
+func TestGenerator_DatacenterWorker(t *testing.T) {
+	layout := snowflake.Layout{TimestampBits: 42, MachineBits: 9, SequenceBits: 12, DatacenterBits: 2}
+	g, err := snowflake.New(snowflake.WithLayout(layout), snowflake.WithDatacenterID(3), snowflake.WithWorkerID(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sid := g.ParseID(g.ID())
+	if sid.DatacenterID != 3 || sid.WorkerID != 100 || sid.MachineID != 3<<7|100 {
+		t.Error("The machineID should be composed of datacenterID and workerID", sid)
+	}
+
+	// all the datacenterID and workerID values must round-trip.
+	for d := uint16(0); d <= layout.MaxDatacenterID(); d++ {
+		for w := uint16(0); w <= layout.MaxWorkerID(); w++ {
+			g.ResetForTesting()
+			g.SetDatacenterID(d)
+			g.SetWorkerID(w)
+
+			sid := g.ParseID(g.ID())
+			if sid.DatacenterID != uint64(d) || sid.WorkerID != uint64(w) {
+				t.Fatalf("datacenterID %d and workerID %d should round-trip, got %+v", d, w, sid)
+			}
+		}
+	}
+
+	t.Run("Panic", func(tt *testing.T) {
+		for _, c := range []struct {
+			set func()
+			msg string
+		}{
+			{func() { g.SetDatacenterID(4) }, "The datacenterID cannot be greater than 3"},
+			{func() { g.SetWorkerID(128) }, "The workerID cannot be greater than 127"},
+		} {
+			func() {
+				g.ResetForTesting()
+				defer func() {
+					if err := recover(); err == nil {
+						tt.Error("Should throw a error")
+					} else if err.(string) != c.msg {
+						tt.Errorf("The error message should be eq 「%s」, got 「%s」", c.msg, err)
+					}
+				}()
+				c.set()
+			}()
+		}
+	})
+
+	t.Run("Invalid", func(tt *testing.T) {
+		if _, err := snowflake.New(snowflake.WithLayout(layout), snowflake.WithDatacenterID(4)); err == nil {
+			tt.Error("The datacenterID should be checked against the layout")
+		}
+		if _, err := snowflake.New(snowflake.WithLayout(layout), snowflake.WithWorkerID(128)); err == nil {
+			tt.Error("The workerID should be checked against the layout")
+		}
+		if _, err := snowflake.New(snowflake.WithDatacenterID(1)); err == nil {
+			tt.Error("The default layout has no datacenter bits")
+		}
+		if _, err := snowflake.New(snowflake.WithLayout(layout), snowflake.WithMachineID(1), snowflake.WithWorkerID(1)); err == nil {
+			tt.Error("WithMachineID and WithWorkerID are conflicting")
+		}
+		if _, err := snowflake.New(snowflake.WithLayout(layout), snowflake.WithMachineID(0), snowflake.WithWorkerID(1)); err == nil {
+			tt.Error("WithMachineID(0) and WithWorkerID are conflicting")
+		}
+		if _, err := snowflake.New(snowflake.WithLayout(layout), snowflake.WithWorkerID(1), snowflake.WithMachineID(0)); err == nil {
+			tt.Error("WithWorkerID and WithMachineID(0) are conflicting")
+		}
+	})
+}
+
+func TestGenerator_Close(t *testing.T) {
+	g, err := snowflake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []int
+	for i := 0; i < 3; i++ {
+		i := i
+		if err := g.OnClose(func(ctx context.Context) error {
+			calls = append(calls, i)
+			if i == 1 {
+				return errors.New("release lease")
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := g.NextID(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.Close(context.Background()); err == nil || err.Error() != "release lease" {
+		t.Error("Close should return the error of the closers, got", err)
+	}
+	if len(calls) != 3 || calls[0] != 2 || calls[1] != 1 || calls[2] != 0 {
+		t.Error("The closers should be called in the reverse order", calls)
+	}
+
+	if _, err := g.NextID(); !errors.Is(err, snowflake.ErrClosed) {
+		t.Error("NextID should return ErrClosed after Close, got", err)
+	}
+	if g.ID() != 0 {
+		t.Error("ID should return 0 after Close")
+	}
+
+	// closing twice is safe and does nothing.
+	if err := g.Close(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if len(calls) != 3 {
+		t.Error("The closers should be called only once")
+	}
+
+	// a closer registered after Close is called immediately.
+	called := false
+	_ = g.OnClose(func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if !called {
+		t.Error("OnClose should call fn immediately when the generator is closed")
+	}
+}
+
+func TestGenerator_Freeze(t *testing.T) {
+	g, err := snowflake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the configuration can be changed before the first id.
+	g.SetMachineID(1)
+	g.SetStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	g.ID()
+
+	setters := map[string]func(){
+		"SetStartTime":        func() { g.SetStartTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)) },
+		"SetMachineID":        func() { g.SetMachineID(2) },
+		"SetDatacenterID":     func() { g.SetDatacenterID(0) },
+		"SetWorkerID":         func() { g.SetWorkerID(2) },
+		"SetSequenceResolver": func() { g.SetSequenceResolver(snowflake.AtomicResolver) },
+	}
+	for name, set := range setters {
+		func() {
+			defer func() {
+				if err := recover(); err == nil {
+					t.Errorf("%s should panic after the first id", name)
+				} else if err.(string) != snowflake.ErrConfigFrozen.Error() {
+					t.Errorf("%s: the error message should be eq 「%s」, got 「%s」", name, snowflake.ErrConfigFrozen, err)
+				}
+			}()
+			set()
+		}()
+	}
+
+	g.ResetForTesting()
+	g.SetMachineID(2)
+	if sid := g.ParseID(g.ID()); sid.MachineID != 2 {
+		t.Error("ResetForTesting should unfreeze the configuration")
+	}
+}
+
+// run with -race: configuring the generator concurrently with generation must not be a data race.
+func TestGenerator_ConcurrentConfiguration(t *testing.T) {
+	g, err := snowflake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if err := g.TrySetMachineID(uint16(i)); err != nil && !errors.Is(err, snowflake.ErrConfigFrozen) {
+					t.Error(err)
+				}
+				if err := g.TrySetStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil && !errors.Is(err, snowflake.ErrConfigFrozen) {
+					t.Error(err)
+				}
+				if err := g.TrySetWorkerID(uint16(i)); err != nil && !errors.Is(err, snowflake.ErrConfigFrozen) {
+					t.Error(err)
+				}
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				id, err := g.NextID()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				sid := g.ParseID(id)
+				_ = sid.GenerateTime()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestGenerator_Getters(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	g, err := snowflake.New(snowflake.WithMachineID(42), snowflake.WithStartTime(start))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.MachineID() != 42 {
+		t.Errorf("The machineID should be 42, got %d", g.MachineID())
+	}
+	if !g.StartTime().Equal(start) {
+		t.Errorf("The start time should be %s, got %s", start, g.StartTime())
+	}
+	if g.Layout() != snowflake.DefaultLayout {
+		t.Errorf("The layout should be the default, got %s", g.Layout())
+	}
+	if g.ResolverName() != "atomic" {
+		t.Errorf("The resolver should be atomic, got %s", g.ResolverName())
+	}
+
+	g.SetSequenceResolver(snowflake.AtomicResolver)
+	if name := g.ResolverName(); name != "github.com/hedwi/go-snowflake.AtomicResolver" {
+		t.Errorf("The resolver name should be the function name, got %s", name)
+	}
+}
+
+// run with -race: the getters must be safe to call concurrently with generation.
+func TestGenerator_ConcurrentGetters(t *testing.T) {
+	g, err := snowflake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_ = g.MachineID()
+			_ = g.StartTime()
+			_ = g.Layout()
+			_ = g.ResolverName()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_ = g.ID()
+		}
+	}()
+	wg.Wait()
+}
+
+func TestGenerator_MaxBackwardTolerance(t *testing.T) {
+	g, err := snowflake.New(snowflake.WithMaxBackwardTolerance(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snowflake.MoveClockBackward(g, time.Second)
+	_, err = g.NextID()
+	if !errors.Is(err, snowflake.ErrClockMovedBackward) {
+		t.Fatalf("The error should be ErrClockMovedBackward, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "by 1s") && !strings.Contains(err.Error(), "by 999ms") {
+		t.Errorf("The error should report how far the clock moved backward, got %v", err)
+	}
+
+	g.SetMaxBackwardTolerance(100 * time.Millisecond)
+	snowflake.MoveClockBackward(g, 20*time.Millisecond)
+	start := time.Now()
+	if _, err := g.NextID(); err != nil {
+		t.Fatalf("NextID should wait within the tolerance, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("NextID should wait for the clock, waited %s", elapsed)
+	}
+
+	snowflake.MoveClockBackward(g, time.Second)
+	start = time.Now()
+	if _, err := g.NextID(); !errors.Is(err, snowflake.ErrClockMovedBackward) {
+		t.Errorf("The error should be ErrClockMovedBackward, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("NextID should fail fast beyond the tolerance, waited %s", elapsed)
+	}
+
+	defer func() {
+		if err := recover(); err == nil {
+			t.Error("A negative tolerance should panic")
+		}
+	}()
+	g.SetMaxBackwardTolerance(-time.Second)
+}
+
+// jitteryClock returns the current time moved backward by up to 1ms at random.
+type jitteryClock struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func (c *jitteryClock) Now() time.Time {
+	c.mu.Lock()
+	jitter := time.Duration(c.rnd.Int63n(int64(time.Millisecond)))
+	c.mu.Unlock()
+
+	return time.Now().Add(-jitter)
+}
+
+func TestGenerator_LastTimestampMonotonic(t *testing.T) {
+	g, err := snowflake.New(snowflake.WithClock(&jitteryClock{rnd: rand.New(rand.NewSource(1))}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	watched := make(chan error)
+	go func() {
+		var last int64
+		for {
+			select {
+			case <-done:
+				watched <- nil
+				return
+			default:
+			}
+			ts := snowflake.LastTimestamp(g)
+			if ts < last {
+				watched <- fmt.Errorf("lastTimestamp moved backward from %d to %d", last, ts)
+				return
+			}
+			last = ts
+		}
+	}()
+
+	const goroutines, n = 200, 25
+	ids := make(chan uint64, goroutines*n)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				id, err := g.NextID()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				ids <- id
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	if err := <-watched; err != nil {
+		t.Error(err)
+	}
+
+	close(ids)
+	seen := make(map[uint64]bool, goroutines*n)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("duplicate id %d", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestGenerator_BackwardWaitShared(t *testing.T) {
+	g, err := snowflake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snowflake.MoveClockBackward(g, 100*time.Millisecond)
+
+	const goroutines = 500
+	ids := make(chan uint64, goroutines)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, err := g.NextID()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ids <- id
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond || elapsed > time.Second {
+		t.Errorf("The callers should wait for the clock once, waited %s", elapsed)
+	}
+
+	seen := make(map[uint64]bool, goroutines)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("duplicate id %d", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestGenerator_BackwardWaitClose(t *testing.T) {
+	g, err := snowflake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snowflake.MoveClockBackward(g, 3*time.Second)
+
+	errs := make(chan error, 10)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := g.NextID()
+			errs <- err
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	start := time.Now()
+	if err := g.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != snowflake.ErrClosed {
+			t.Errorf("The wait should be interrupted by Close, got %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Close should interrupt the wait, waited %s", elapsed)
+	}
+}
+
+func BenchmarkGenerator_NextID(b *testing.B) {
+	g, err := snowflake.New()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < b.N; i++ {
+		_, _ = g.NextID()
+	}
+}
+
+func BenchmarkGenerator_NextIDParallel(b *testing.B) {
+	g, err := snowflake.New()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = g.NextID()
+		}
+	})
+}
+
+func TestGenerator_WaitUntilSafe(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	old, err := snowflake.New(snowflake.WithStartTime(start), snowflake.WithClock(clocktest.NewManual(now)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastKnownID := old.ID()
+
+	// the new machine's clock is 50ms behind.
+	clock := clocktest.NewManual(now.Add(-50 * time.Millisecond))
+	g, err := snowflake.New(snowflake.WithStartTime(start), snowflake.WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := g.WaitUntilSafe(ctx, lastKnownID); err != context.DeadlineExceeded {
+		t.Fatalf("WaitUntilSafe should stop when ctx is done, got %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- g.WaitUntilSafe(context.Background(), lastKnownID)
+	}()
+	clock.Advance(50 * time.Millisecond)
+
+	select {
+	case <-done:
+		t.Fatal("WaitUntilSafe should wait until the clock is strictly past the last ID")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if id := g.ID(); id <= lastKnownID {
+		t.Errorf("The ID should be greater than the last known ID, got %d after %d", id, lastKnownID)
+	}
+}
+
+func TestGenerator_NextIDContext(t *testing.T) {
+	g, err := snowflake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.NextIDContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	snowflake.MoveClockBackward(g, 3*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := g.NextIDContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("The backward wait should stop when ctx is done, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("The backward wait should stop promptly, waited %s", elapsed)
+	}
+}
+
+func TestGenerator_NextIDContext_SequenceExhausted(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	g, err := snowflake.New(
+		snowflake.WithClock(clock),
+		snowflake.WithLayout(snowflake.Layout{TimestampBits: 46, MachineBits: 16, SequenceBits: 1}),
+		snowflake.WithStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only sequence 0 and 1 are available in a tick.
+	for i := 0; i < 2; i++ {
+		if _, err := g.NextID(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := g.NextIDContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("The wait for the next tick should stop when ctx is done, got %v", err)
+	}
+}
+
+func TestGenerator_NextIDNoWait(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	g, err := snowflake.New(
+		snowflake.WithClock(clock),
+		snowflake.WithLayout(snowflake.Layout{TimestampBits: 45, MachineBits: 16, SequenceBits: 2}),
+		snowflake.WithStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sequence 0, 1, 2, 3 are available in a tick.
+	for i := 0; i < 4; i++ {
+		if _, err := g.NextIDNoWait(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := g.NextIDNoWait(); err != snowflake.ErrSequenceExhausted {
+		t.Errorf("The error should be ErrSequenceExhausted, got %v", err)
+	}
+
+	clock.Advance(time.Millisecond)
+	if _, err := g.NextIDNoWait(); err != nil {
+		t.Errorf("The next tick should have a new sequence, got %v", err)
+	}
+
+	// beyond the slew tolerance.
+	clock.Advance(-10 * time.Millisecond)
+	if _, err := g.NextIDNoWait(); !errors.Is(err, snowflake.ErrClockMovedBackward) {
+		t.Errorf("The error should be ErrClockMovedBackward instead of sleeping, got %v", err)
+	}
+}
+
+func TestGenerator_EpochExhausted(t *testing.T) {
+	// 31 timestamp bits last 2^31-1 ms, about 24.9 days.
+	layout := snowflake.Layout{TimestampBits: 31, MachineBits: 16, SequenceBits: 16}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := clocktest.NewManual(start.Add(24 * time.Hour))
+	g, err := snowflake.New(snowflake.WithLayout(layout), snowflake.WithStartTime(start), snowflake.WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	end := start.Add(1 << 31 * time.Millisecond)
+	if !g.LifetimeEnd().Equal(end) {
+		t.Errorf("LifetimeEnd should be %s, got %s", end, g.LifetimeEnd())
+	}
+	if r := g.Remaining(); r != end.Sub(clock.Now()) {
+		t.Errorf("Remaining should be %s, got %s", end.Sub(clock.Now()), r)
+	}
+	if _, err := g.NextID(); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Set(end.Add(-time.Millisecond))
+	if _, err := g.NextID(); err != nil {
+		t.Errorf("The last tick should be usable, got %v", err)
+	}
+
+	clock.Set(end)
+	if _, err := g.NextID(); !errors.Is(err, snowflake.ErrEpochExhausted) {
+		t.Errorf("The error should be ErrEpochExhausted, got %v", err)
+	}
+	if r := g.Remaining(); r != 0 {
+		t.Errorf("Remaining should be 0, got %s", r)
+	}
+
+	// the panic message is computed from the layout.
+	g, err = snowflake.New(snowflake.WithLayout(layout), snowflake.WithStartTime(start), snowflake.WithClock(clocktest.NewManual(start)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if e := recover(); e != "The maximum life cycle of the snowflake algorithm is 596h31m23.647s" {
+			t.Errorf("The panic message should report the lifetime of the layout, got %v", e)
+		}
+	}()
+	g.SetStartTime(start.Add(-30 * 24 * time.Hour))
+}
+
+func TestGenerator_LifetimeEnd_Wide(t *testing.T) {
+	// 45 timestamp bits in milliseconds last longer than a time.Duration.
+	g, err := snowflake.New(snowflake.WithLayout(snowflake.Layout{TimestampBits: 45, MachineBits: 16, SequenceBits: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.LifetimeEnd().Year() != g.StartTime().Year()+1115 {
+		t.Errorf("The lifetime should be 1115 years, got %s", g.LifetimeEnd())
+	}
+}
+
+func TestGenerator_FullSequenceRange(t *testing.T) {
+	clock := clocktest.NewManual(time.Now())
+	g, err := snowflake.New(snowflake.WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make(map[uint64]bool)
+	var first snowflake.SID
+	for i := 0; i <= int(snowflake.MaxSequence); i++ {
+		id, err := g.NextIDNoWait()
+		if err != nil {
+			t.Fatalf("The sequence 0..%d should be usable, got %v at %d", snowflake.MaxSequence, err, i)
+		}
+		sid := g.ParseID(id)
+		if i == 0 {
+			first = sid
+		}
+		if sid.Timestamp != first.Timestamp {
+			t.Fatalf("The IDs should share one millisecond, got %d and %d", sid.Timestamp, first.Timestamp)
+		}
+		ids[id] = true
+	}
+	if len(ids) != 4096 {
+		t.Errorf("A millisecond should hold 4096 unique IDs, got %d", len(ids))
+	}
+
+	if _, err := g.NextIDNoWait(); err != snowflake.ErrSequenceExhausted {
+		t.Errorf("The sequence should be exhausted after 4096 IDs, got %v", err)
+	}
+}
+
+func TestGenerator_RequireExplicitMachineID(t *testing.T) {
+	g, err := snowflake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.RequireExplicitMachineID()
+
+	if _, err := g.NextID(); !errors.Is(err, snowflake.ErrMachineIDNotSet) {
+		t.Errorf("The error should be ErrMachineIDNotSet, got %v", err)
+	}
+	// the refused calls don't freeze the configuration.
+	if err := g.TrySetMachineID(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.NextID(); err != nil {
+		t.Errorf("An explicit machineID 0 should be accepted, got %v", err)
+	}
+
+	g, _ = snowflake.New(snowflake.WithWorkerID(3))
+	g.RequireExplicitMachineID()
+	if _, err := g.NextID(); err != nil {
+		t.Errorf("The machineID set by an option should be accepted, got %v", err)
+	}
+}
+
+func TestRequireExplicitMachineID(t *testing.T) {
+	snowflake.Reset()
+	defer snowflake.Reset()
+
+	snowflake.RequireExplicitMachineID()
+	if _, err := snowflake.NextID(); !errors.Is(err, snowflake.ErrMachineIDNotSet) {
+		t.Errorf("The error should be ErrMachineIDNotSet, got %v", err)
+	}
+	snowflake.SetMachineID(5)
+	id, err := snowflake.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := snowflake.ParseID(id).MachineID; m != 5 {
+		t.Errorf("The ID should use the machineID set, got %d", m)
+	}
+}
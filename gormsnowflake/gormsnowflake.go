@@ -0,0 +1,137 @@
+// Package gormsnowflake generates the snowflake primary keys of the GORM models on create.
+// Declare the primary key as an ID and register the plugin on the database:
+//
+//	type Order struct {
+//		ID gormsnowflake.ID `gorm:"primaryKey"`
+//	}
+//
+//	err := db.Use(gormsnowflake.Plugin{Generator: g})
+//
+// The zero primary keys of type ID or snowflake.TID are filled before the BeforeCreate hooks of the models run,
+// the keys already set are kept. A snowflake.TID key is an unsigned integer for GORM, tag it autoIncrement:false,
+// GORM would make it an auto increment column otherwise. It is a separate module, so the snowflake module doesn't depend on GORM.
+package gormsnowflake
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// ID is a snowflake.TID for the GORM models, a signed BIGINT column which is not an auto increment column.
+// The IDs above math.MaxInt64 don't fit the column and are an error, as a negative number scanned into an ID.
+type ID snowflake.TID
+
+// GormDataType returns bigint, the column type of the IDs in every dialect.
+func (id ID) GormDataType() string {
+	return "bigint"
+}
+
+// Value implements the driver.Valuer interface, see snowflake.TID.
+func (id ID) Value() (driver.Value, error) {
+	return snowflake.TID(id).Value()
+}
+
+// Scan implements the sql.Scanner interface, see snowflake.TID.
+func (id *ID) Scan(src interface{}) error {
+	return (*snowflake.TID)(id).Scan(src)
+}
+
+// String returns the decimal value of the ID.
+func (id ID) String() string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// MarshalJSON encodes the ID as a JSON string, see snowflake.TID.
+func (id ID) MarshalJSON() ([]byte, error) {
+	return snowflake.TID(id).MarshalJSON()
+}
+
+// UnmarshalJSON decodes the ID from a JSON string or number, see snowflake.TID.
+func (id *ID) UnmarshalJSON(b []byte) error {
+	return (*snowflake.TID)(id).UnmarshalJSON(b)
+}
+
+// Plugin is the GORM plugin filling the zero primary keys of type ID or snowflake.TID with the IDs of Generator,
+// or of the default generator of the snowflake package when Generator is nil.
+// A failed ID generation fails the create.
+type Plugin struct {
+	Generator *snowflake.Generator
+}
+
+// Name returns the name of the plugin.
+func (p Plugin) Name() string {
+	return "gormsnowflake"
+}
+
+// Initialize registers the callback filling the primary keys before the gorm:before_create callback,
+// so the BeforeCreate hooks of the models see the IDs.
+func (p Plugin) Initialize(db *gorm.DB) error {
+	return db.Callback().Create().Before("gorm:before_create").Register("gormsnowflake:fill_id", p.fill)
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+var (
+	idType  = reflect.TypeOf(ID(0))
+	tidType = reflect.TypeOf(snowflake.TID(0))
+)
+
+// fill the zero primary keys of the records created by db, a struct or a slice of structs.
+func (p Plugin) fill(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+
+	for _, field := range db.Statement.Schema.PrimaryFields {
+		if field.FieldType != idType && field.FieldType != tidType {
+			continue
+		}
+
+		switch rv := db.Statement.ReflectValue; rv.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < rv.Len(); i++ {
+				if err := p.fillValue(db, field, reflect.Indirect(rv.Index(i))); err != nil {
+					_ = db.AddError(err)
+					return
+				}
+			}
+		case reflect.Struct:
+			if err := p.fillValue(db, field, rv); err != nil {
+				_ = db.AddError(err)
+				return
+			}
+		}
+	}
+}
+
+func (p Plugin) fillValue(db *gorm.DB, field *schema.Field, rv reflect.Value) error {
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	// 已设置的 ID 保持不变
+	if _, zero := field.ValueOf(db.Statement.Context, rv); !zero {
+		return nil
+	}
+
+	var id snowflake.TID
+	var err error
+	if p.Generator != nil {
+		id, err = p.Generator.Next()
+	} else {
+		id, err = snowflake.Next()
+	}
+	if err != nil {
+		return err
+	}
+	field.ReflectValueOf(db.Statement.Context, rv).SetUint(uint64(id))
+
+	return nil
+}
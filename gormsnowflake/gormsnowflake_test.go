@@ -0,0 +1,195 @@
+package gormsnowflake_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/gormsnowflake"
+)
+
+type order struct {
+	ID       gormsnowflake.ID `gorm:"primaryKey"`
+	ParentID *gormsnowflake.ID
+	Name     string
+	// HookID is the ID seen by BeforeCreate.
+	HookID gormsnowflake.ID `gorm:"-"`
+}
+
+func (o *order) BeforeCreate(tx *gorm.DB) error {
+	o.HookID = o.ID
+	return nil
+}
+
+type item struct {
+	ID   snowflake.TID `gorm:"primaryKey;autoIncrement:false"`
+	Name string
+}
+
+func newDB(t *testing.T, g *snowflake.Generator) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(gormsnowflake.Plugin{Generator: g}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&order{}, &item{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func newGenerator(t *testing.T) *snowflake.Generator {
+	t.Helper()
+
+	g, err := snowflake.New(snowflake.WithMachineID(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return g
+}
+
+func TestPlugin_Create(t *testing.T) {
+	g := newGenerator(t)
+	db := newDB(t, g)
+
+	orders := make([]*order, 100)
+	for i := range orders {
+		orders[i] = &order{Name: "order"}
+		if err := db.Create(orders[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+		if orders[i].HookID != orders[i].ID {
+			t.Errorf("BeforeCreate should see the ID %d, got %d", orders[i].ID, orders[i].HookID)
+		}
+	}
+
+	var stored []order
+	if err := db.Order("id").Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != len(orders) {
+		t.Fatalf("%d orders should be stored, got %d", len(orders), len(stored))
+	}
+	for i, o := range stored {
+		// 按创建顺序递增
+		if o.ID != orders[i].ID {
+			t.Fatalf("The IDs should increase in the creation order, got %d at %d, want %d", o.ID, i, orders[i].ID)
+		}
+		if i > 0 && o.ID <= stored[i-1].ID {
+			t.Fatalf("The IDs should be unique, got %d after %d", o.ID, stored[i-1].ID)
+		}
+		if sid := g.ParseID(uint64(o.ID)); sid.MachineID != 7 {
+			t.Errorf("The ID %d should come from the generator, got machineID %d", o.ID, sid.MachineID)
+		}
+	}
+	first, last := g.ParseID(uint64(stored[0].ID)), g.ParseID(uint64(stored[len(stored)-1].ID))
+	if last.GenerateTime().Before(first.GenerateTime()) {
+		t.Errorf("The IDs should be time ordered, %s is before %s", last.GenerateTime(), first.GenerateTime())
+	}
+}
+
+func TestPlugin_CreateInBatches(t *testing.T) {
+	db := newDB(t, newGenerator(t))
+
+	orders := []order{{Name: "a"}, {ID: 42, Name: "b"}, {Name: "c"}}
+	if err := db.Create(&orders).Error; err != nil {
+		t.Fatal(err)
+	}
+	if orders[1].ID != 42 {
+		t.Errorf("The ID set should be kept, got %d", orders[1].ID)
+	}
+	if orders[0].ID == 0 || orders[2].ID <= orders[0].ID {
+		t.Errorf("The zero IDs should be filled in order, got %d and %d", orders[0].ID, orders[2].ID)
+	}
+
+	items := []*item{{Name: "a"}, {ID: 43, Name: "b"}}
+	if err := db.Create(items).Error; err != nil {
+		t.Fatal(err)
+	}
+	if items[0].ID == 0 || items[1].ID != 43 {
+		t.Errorf("The TID primary keys should be filled too, got %d and %d", items[0].ID, items[1].ID)
+	}
+
+	var count int64
+	if err := db.Model(&order{}).Where("id = ?", gormsnowflake.ID(42)).Count(&count).Error; err != nil || count != 1 {
+		t.Errorf("The order 42 should be stored, got %d, %v", count, err)
+	}
+}
+
+func TestPlugin_KeepsParent(t *testing.T) {
+	db := newDB(t, newGenerator(t))
+
+	parent := order{Name: "parent"}
+	if err := db.Create(&parent).Error; err != nil {
+		t.Fatal(err)
+	}
+	child := order{Name: "child", ParentID: &parent.ID}
+	if err := db.Create(&child).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var got order
+	if err := db.First(&got, "id = ?", child.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.ParentID == nil || *got.ParentID != parent.ID {
+		t.Errorf("The parent should be %d, got %v", parent.ID, got.ParentID)
+	}
+}
+
+func TestPlugin_Error(t *testing.T) {
+	// machineID 不可用时无法生成 ID
+	g, err := snowflake.New(snowflake.WithMachineIDProvider(func(ctx context.Context) (uint16, error) {
+		return 0, errors.New("no machineID")
+	}), snowflake.WithMachineIDProviderNoWait())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close(context.Background())
+	db := newDB(t, g)
+
+	if err := db.Create(&order{Name: "order"}).Error; err == nil {
+		t.Fatal("The create should fail when the generator fails")
+	}
+	var count int64
+	if err := db.Model(&order{}).Count(&count).Error; err != nil || count != 0 {
+		t.Errorf("No order should be stored, got %d, %v", count, err)
+	}
+}
+
+func TestID_Boundary(t *testing.T) {
+	db := newDB(t, newGenerator(t))
+
+	if err := db.Create(&order{ID: math.MaxInt64, Name: "max"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var got order
+	if err := db.First(&got, "id = ?", gormsnowflake.ID(math.MaxInt64)).Error; err != nil || got.ID != math.MaxInt64 {
+		t.Errorf("math.MaxInt64 should round trip, got %d, %v", got.ID, err)
+	}
+
+	err := db.Create(&order{ID: math.MaxInt64 + 1, Name: "overflow"}).Error
+	if err == nil {
+		t.Error("The IDs above math.MaxInt64 should not be stored")
+	}
+
+	if err := db.Exec("INSERT INTO orders (id, name) VALUES (-1, 'negative')").Error; err != nil {
+		t.Fatal(err)
+	}
+	var negative order
+	if err := db.First(&negative, "name = ?", "negative").Error; err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("A negative number should not scan into an ID, got %d, %v", negative.ID, err)
+	}
+}
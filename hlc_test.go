@@ -0,0 +1,79 @@
+package snowflake_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/clocktest"
+)
+
+func TestWithHybridLogicalClock(t *testing.T) {
+	clock := clocktest.NewManual(time.Now())
+	g, err := snowflake.New(snowflake.WithClock(clock), snowflake.WithHybridLogicalClock(), snowflake.WithMaxBackwardTolerance(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := g.ParseID(g.ID())
+
+	// neither the rollback nor the tolerance stop the generation.
+	clock.Advance(-time.Minute)
+	id, err := g.NextIDNoWait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sid := g.ParseID(id); sid.Timestamp != first.Timestamp {
+		t.Errorf("The timestamp should be the last timestamp, got %d, want %d", sid.Timestamp, first.Timestamp)
+	}
+
+	if stats := g.Stats(); stats.Lead != time.Minute {
+		t.Errorf("The lead should be reported, got %s", stats.Lead)
+	}
+
+	clock.Advance(2 * time.Minute)
+	sid := g.ParseID(g.ID())
+	if sid.Timestamp != first.Timestamp+uint64(time.Minute/time.Millisecond) {
+		t.Errorf("The physical clock should be used once it is ahead, got %d", sid.Timestamp)
+	}
+	if stats := g.Stats(); stats.Lead != 0 {
+		t.Errorf("The lead should shrink once the clock catches up, got %s", stats.Lead)
+	}
+}
+
+// TestWithHybridLogicalClock_Torture generate IDs with a clock jumping randomly by up to ±1s,
+// and a sequence exhausted every 4 IDs, the IDs must be strictly increasing.
+func TestWithHybridLogicalClock_Torture(t *testing.T) {
+	clock := clocktest.NewManual(time.Now())
+	g, err := snowflake.New(
+		snowflake.WithClock(clock),
+		snowflake.WithHybridLogicalClock(),
+		snowflake.WithLayout(snowflake.Layout{TimestampBits: 45, MachineBits: 16, SequenceBits: 2}),
+		snowflake.WithStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	var last uint64
+	for i := 0; i < 100000; i++ {
+		if rnd.Intn(10) == 0 {
+			clock.Advance(time.Duration(rnd.Int63n(int64(2*time.Second))) - time.Second)
+		}
+
+		id, err := g.NextIDNoWait()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id <= last {
+			t.Fatalf("The IDs should be strictly increasing, got %d after %d", id, last)
+		}
+		last = id
+	}
+
+	if stats := g.Stats(); stats.MaxLead <= 0 {
+		t.Errorf("The max lead should be reported, got %s", stats.MaxLead)
+	}
+}
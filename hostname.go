@@ -0,0 +1,152 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// claimTimeout is the timeout of a claim in New.
+const claimTimeout = 5 * time.Second
+
+// claimTTL is how long a machineID claim lives without a renewal, the claims are renewed every claimTTL/3.
+// It can be replaced in tests.
+var claimTTL = 30 * time.Second
+
+// ErrMachineIDClaimed is returned by ClaimMachineID, and by New with HostnameMachineID,
+// when another live host already claimed the machineID.
+var ErrMachineIDClaimed = errors.New("snowflake: the machineID is claimed by another host")
+
+// hostname returns the host name, it can be replaced in tests.
+var hostname = os.Hostname
+
+// ClaimStore records which host claimed a machineID, implement it with any KV store, see NewRedisClaimStore and NewEtcdClaimStore.
+//
+// Claim must set key to owner with ttl when key doesn't exist, refresh the ttl when key already holds owner,
+// and return the owner of key, atomically.
+type ClaimStore interface {
+	Claim(ctx context.Context, key, owner string, ttl time.Duration) (string, error)
+}
+
+// MachineIDFromHostname returns a machineID hashed from the host name, for the schedulers without pod ordinals,
+// e.g. Nomad or ECS. The FNV-1a hash of the name is folded into MachineIDLength bits, the machine bits of the default layout,
+// use HostnameMachineID to fold it into the machine bits of a generator.
+//
+// The hashes of different names may collide silently, see ClaimMachineID.
+func MachineIDFromHostname() (uint16, error) {
+	return hostnameMachineID(MachineIDLength)
+}
+
+// HostnameMachineID set the machineID of the generator from the host name, with the machine bits of its layout,
+// see MachineIDFromHostname.
+//
+// With a store, New claims the machineID for the host with ClaimMachineID and returns an error wrapping ErrMachineIDClaimed
+// when another live host claimed it. The claim lives 30s and is renewed every 10s in the background until the generator is closed.
+// Once another host claimed the machineID, or the renewals failed until the claim may expire before the next one,
+// NextID returns an error wrapping ErrLeaseLost like a lost lease, unless WithLeaseLossTolerated.
+// A nil store skips the check, e.g. in an air-gapped environment.
+func HostnameMachineID(store ClaimStore) Option {
+	return func(g *Generator) error {
+		g.derivedMachineID, g.derivedBy, g.claims = hostnameMachineID, "HostnameMachineID", store
+
+		return nil
+	}
+}
+
+// ClaimMachineID claim machineID for owner, e.g. the host name, in store with ttl.
+// It returns an error wrapping ErrMachineIDClaimed when another owner holds the claim.
+// Call it again before ttl to keep the claim alive.
+func ClaimMachineID(ctx context.Context, store ClaimStore, machineID uint16, owner string, ttl time.Duration) error {
+	got, err := store.Claim(ctx, claimKey(machineID), owner, ttl)
+	if err != nil {
+		return fmt.Errorf("snowflake: claim machineID %d: %w", machineID, err)
+	}
+	if got != owner {
+		return fmt.Errorf("%w: machineID %d is claimed by %s, not %s", ErrMachineIDClaimed, machineID, got, owner)
+	}
+
+	return nil
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// claimKey returns the key of the claim of machineID in a ClaimStore.
+func claimKey(machineID uint16) string {
+	return fmt.Sprintf("snowflake:machine:%d", machineID)
+}
+
+func hostnameMachineID(bits uint8) (uint16, error) {
+	if bits == 0 || bits > 16 {
+		return 0, fmt.Errorf("snowflake: invalid machine bits %d, it must be between 1 and 16", bits)
+	}
+
+	name, err := hostname()
+	if err != nil {
+		return 0, fmt.Errorf("snowflake: hostname: %w", err)
+	}
+	if name == "" {
+		return 0, errors.New("snowflake: the host name is empty")
+	}
+
+	return hashMachineID([]byte(name), bits), nil
+}
+
+// claimMachineID claim the machineID of the generator for the host and renew the claim until the generator is closed.
+func (g *Generator) claimMachineID() error {
+	owner, err := hostname()
+	if err != nil {
+		return fmt.Errorf("snowflake: hostname: %w", err)
+	}
+	machineID, ttl := uint16(g.machineID), claimTTL
+
+	// renewed 是最后一次成功的声明发出的时间
+	renewed := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), claimTimeout)
+	defer cancel()
+	if err := ClaimMachineID(ctx, g.claims, machineID, owner, ttl); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				// 在续约之前取时间，声明的 ttl 最早从请求发出时开始计算
+				start := time.Now()
+				ctx, cancel := context.WithTimeout(context.Background(), claimTimeout)
+				err := ClaimMachineID(ctx, g.claims, machineID, owner, ttl)
+				cancel()
+				if err == nil {
+					renewed = start
+					continue
+				}
+				// 被其它主机声明，或下一次续约前声明可能过期：与租约丢失相同处理
+				if errors.Is(err, ErrMachineIDClaimed) || time.Since(renewed)+ttl/3 >= ttl {
+					g.loseClaim(err)
+					return
+				}
+			case <-g.done:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// loseClaim record the loss of the claim of the machineID like the loss of a lease, see HostnameMachineID.
+func (g *Generator) loseClaim(err error) {
+	if g.leaseLossTolerated {
+		return
+	}
+	g.leaseErr.Store(errorBox{fmt.Errorf("%w: %v", ErrLeaseLost, err)})
+	atomic.StoreInt32(&g.leaseLost, 1)
+}
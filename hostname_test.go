@@ -0,0 +1,195 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// fakeClaimKV is an in-memory redis and etcd for the claim stores, the ttls are ignored.
+// The calls fail while down is set.
+type fakeClaimKV struct {
+	mu     sync.Mutex
+	values map[string]string
+	renews int
+	down   bool
+}
+
+func newFakeClaimKV() *fakeClaimKV {
+	return &fakeClaimKV{values: make(map[string]string)}
+}
+
+// Eval runs the claim script of the redis claim store.
+func (kv *fakeClaimKV) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	if args[1].(int64) <= 0 {
+		return nil, errors.New("ERR invalid expire time in 'set' command")
+	}
+	cur, created, err := kv.PutIfAbsent(ctx, keys[0], args[0].(string), 0)
+	if err != nil || created || cur != args[0] {
+		return cur, err
+	}
+
+	return cur, kv.KeepAliveOnce(ctx, keys[0])
+}
+
+func (kv *fakeClaimKV) PutIfAbsent(ctx context.Context, key, value string, ttl time.Duration) (string, bool, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if kv.down {
+		return "", false, errors.New("connection refused")
+	}
+	if cur, ok := kv.values[key]; ok {
+		return cur, false, nil
+	}
+	kv.values[key] = value
+
+	return value, true, nil
+}
+
+func (kv *fakeClaimKV) KeepAliveOnce(ctx context.Context, key string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.renews++
+	return nil
+}
+
+func TestMachineIDFromHostname(t *testing.T) {
+	snowflake.SetHostname(t, "web-7f9c")
+
+	m, err := snowflake.MachineIDFromHostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m > snowflake.MaxMachineID {
+		t.Errorf("The machineID should fit the default layout, got %d", m)
+	}
+	if again, _ := snowflake.MachineIDFromHostname(); again != m {
+		t.Errorf("The machineID should be stable, got %d and %d", m, again)
+	}
+
+	g, err := snowflake.New(
+		snowflake.HostnameMachineID(nil),
+		snowflake.WithLayout(snowflake.Layout{TimestampBits: 45, MachineBits: 6, SequenceBits: 12}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.MachineID() > 1<<6-1 {
+		t.Errorf("The machineID should be folded into the machine bits of the layout, got %d", g.MachineID())
+	}
+}
+
+func TestHostnameMachineID_Claim(t *testing.T) {
+	stores := map[string]func(kv *fakeClaimKV) snowflake.ClaimStore{
+		"redis": func(kv *fakeClaimKV) snowflake.ClaimStore { return snowflake.NewRedisClaimStore(kv) },
+		"etcd":  func(kv *fakeClaimKV) snowflake.ClaimStore { return snowflake.NewEtcdClaimStore(kv) },
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			kv := newFakeClaimKV()
+			store := newStore(kv)
+
+			snowflake.SetHostname(t, "web-1")
+			g, err := snowflake.New(snowflake.HostnameMachineID(store))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer g.Close(context.Background())
+
+			// the same host claims again, e.g. after a restart.
+			if err := snowflake.ClaimMachineID(context.Background(), store, g.MachineID(), "web-1", time.Minute); err != nil {
+				t.Errorf("The owner should keep its claim, got %v", err)
+			}
+			if kv.renews != 1 {
+				t.Errorf("The claim of the owner should be renewed, got %d renewals", kv.renews)
+			}
+
+			// another host whose name hashes to the same machineID.
+			err = snowflake.ClaimMachineID(context.Background(), store, g.MachineID(), "web-2", time.Minute)
+			if !errors.Is(err, snowflake.ErrMachineIDClaimed) {
+				t.Errorf("The error should be ErrMachineIDClaimed, got %v", err)
+			}
+
+			kv.set(kv.key(t), "web-2")
+			if _, err := snowflake.New(snowflake.HostnameMachineID(store)); !errors.Is(err, snowflake.ErrMachineIDClaimed) {
+				t.Errorf("New should refuse a machineID claimed by another host, got %v", err)
+			}
+		})
+	}
+}
+
+func TestHostnameMachineID_ClaimLost(t *testing.T) {
+	snowflake.SetHostname(t, "web-1")
+	snowflake.SetClaimTTL(t, 60*time.Millisecond)
+
+	cases := map[string]func(kv *fakeClaimKV, key string){
+		// the renewals fail until the claim may expire.
+		"down": func(kv *fakeClaimKV, key string) {
+			kv.mu.Lock()
+			kv.down = true
+			kv.mu.Unlock()
+		},
+		// the claim expired and another host claimed the machineID.
+		"claimed": func(kv *fakeClaimKV, key string) {
+			kv.set(key, "web-2")
+		},
+	}
+	for name, lose := range cases {
+		t.Run(name, func(t *testing.T) {
+			kv := newFakeClaimKV()
+			g, err := snowflake.New(snowflake.HostnameMachineID(snowflake.NewRedisClaimStore(kv)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer g.Close(context.Background())
+
+			lose(kv, kv.key(t))
+			deadline := time.Now().Add(time.Second)
+			for {
+				_, err := g.NextID()
+				if errors.Is(err, snowflake.ErrLeaseLost) {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("NextID should fail once the claim is lost, got %v", err)
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+		})
+	}
+}
+
+// get returns the value of key.
+func (kv *fakeClaimKV) get(key string) string {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	return kv.values[key]
+}
+
+// set sets key to value.
+func (kv *fakeClaimKV) set(key, value string) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.values[key] = value
+}
+
+// key returns the only key of kv.
+func (kv *fakeClaimKV) key(t *testing.T) string {
+	t.Helper()
+
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	for k := range kv.values {
+		return k
+	}
+	t.Fatal("No key was claimed")
+	return ""
+}
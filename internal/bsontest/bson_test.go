@@ -0,0 +1,108 @@
+package bsontest_test
+
+import (
+	"math"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+type order struct {
+	ID     snowflake.TID     `bson:"_id"`
+	Parent snowflake.NullTID `bson:"parent"`
+}
+
+// TID and NullTID implement the interfaces of the driver.
+var (
+	_ bson.ValueMarshaler   = snowflake.TID(0)
+	_ bson.ValueUnmarshaler = (*snowflake.TID)(nil)
+	_ bson.ValueMarshaler   = snowflake.NullTID{}
+	_ bson.ValueUnmarshaler = (*snowflake.NullTID)(nil)
+)
+
+func TestBSON_RoundTrip(t *testing.T) {
+	orders := []order{
+		{ID: 850006245121695744, Parent: snowflake.NullTID{}},
+		{ID: math.MaxInt64, Parent: snowflake.NullTID{ID: 850006245121695744, Valid: true}},
+		{ID: 1, Parent: snowflake.NullTID{ID: 0, Valid: true}},
+	}
+	for _, o := range orders {
+		doc, err := bson.Marshal(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ := bson.Raw(doc).Lookup("_id").Type; typ != bson.TypeInt64 {
+			t.Errorf("The ID should be a BSON int64, got %s", typ)
+		}
+		if typ := bson.Raw(doc).Lookup("parent").Type; o.Parent.Valid && typ != bson.TypeInt64 || !o.Parent.Valid && typ != bson.TypeNull {
+			t.Errorf("The parent %+v should not be a BSON %s", o.Parent, typ)
+		}
+
+		var got order
+		if err := bson.Unmarshal(doc, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != o {
+			t.Errorf("The order should round trip, got %+v, want %+v", got, o)
+		}
+	}
+
+	if _, err := bson.Marshal(order{ID: math.MaxInt64 + 1}); err == nil {
+		t.Error("The IDs above math.MaxInt64 should not be encoded")
+	}
+}
+
+func TestBSON_Legacy(t *testing.T) {
+	const id = snowflake.TID(850006245121695744)
+	d128 := func(s string) bson.Decimal128 {
+		d, err := bson.ParseDecimal128(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	docs := map[string]interface{}{
+		"int64":      int64(id),
+		"string":     "850006245121695744",
+		"decimal128": d128("850006245121695744"),
+		"exponent":   d128("8.50006245121695744E+17"),
+	}
+	for name, v := range docs {
+		t.Run(name, func(t *testing.T) {
+			doc, err := bson.Marshal(bson.D{{Key: "_id", Value: v}, {Key: "parent", Value: v}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got order
+			if err := bson.Unmarshal(doc, &got); err != nil {
+				t.Fatal(err)
+			}
+			if want := (order{ID: id, Parent: snowflake.NullTID{ID: id, Valid: true}}); got != want {
+				t.Errorf("The order should be %+v, got %+v", want, got)
+			}
+		})
+	}
+
+	invalid := map[string]interface{}{
+		// float64 已经丢失了低位
+		"double":     float64(id),
+		"negative":   int64(-1),
+		"fractional": d128("8500062451216957.5"),
+		"null":       nil,
+	}
+	for name, v := range invalid {
+		t.Run(name, func(t *testing.T) {
+			doc, err := bson.Marshal(bson.D{{Key: "_id", Value: v}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got order
+			if err := bson.Unmarshal(doc, &got); err == nil {
+				t.Errorf("An error should be returned, got %+v", got)
+			}
+		})
+	}
+}
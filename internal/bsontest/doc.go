@@ -0,0 +1,3 @@
+// Package bsontest runs the round trips of the snowflake IDs through the bson package of the mongo driver v2.
+// It is a separate module, so the mongo driver is not a dependency of the snowflake module.
+package bsontest
@@ -0,0 +1,10 @@
+module github.com/hedwi/go-snowflake/internal/bsontest
+
+go 1.25.0
+
+require (
+	github.com/hedwi/go-snowflake v0.0.0
+	go.mongodb.org/mongo-driver/v2 v2.9.1
+)
+
+replace github.com/hedwi/go-snowflake => ../..
@@ -0,0 +1,186 @@
+// Package leasekeeper renews the machineID leases of the allocator packages in the background,
+// it implements snowflake.MachineIDLease.
+package leasekeeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNotOwned is returned by a renew function when the lease is owned by another process or expired.
+var ErrNotOwned = errors.New("the lease is not owned anymore")
+
+// errNoRenewal is why the lease ended when no renewal was tried before its deadline.
+var errNoRenewal = errors.New("no renewal was tried")
+
+// Lease is a machineID lease renewed every interval, or watched, until it is lost or closed.
+type Lease struct {
+	machineID uint16
+	interval  time.Duration
+	ttl       time.Duration
+	margin    time.Duration
+	renewFn   func(ctx context.Context) error
+	releaseFn func(ctx context.Context) error
+
+	// done is closed when the lease ends, err is why, stop and stopped stop the renewal.
+	mu       sync.Mutex
+	err      error
+	done     chan struct{}
+	endOnce  sync.Once
+	stopOnce sync.Once
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// Start renew the lease of machineID with renew every interval, acquired is the time before the call which acquired it.
+// The lease is lost when renew returns ErrNotOwned, or at margin before ttl elapsed since the last successful renewal
+// was called, so the generator stops before the store can expire the lease. Close stops the renewal and calls release.
+func Start(machineID uint16, acquired time.Time, interval, ttl, margin time.Duration, renew, release func(ctx context.Context) error) *Lease {
+	l := &Lease{
+		machineID: machineID,
+		interval:  interval,
+		ttl:       ttl,
+		margin:    margin,
+		renewFn:   renew,
+		releaseFn: release,
+		done:      make(chan struct{}),
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go l.renew(acquired)
+
+	return l
+}
+
+// DefaultMargin returns the margin of Start used when the allocator options leave it unset:
+// half of the renew interval, or of the time left after it when the ttl is less than twice the renew interval.
+func DefaultMargin(interval, ttl time.Duration) time.Duration {
+	if ttl-interval < interval {
+		return (ttl - interval) / 2
+	}
+
+	return interval / 2
+}
+
+// Watch keep the lease of machineID until expired is closed, for the coordinators keeping the session alive
+// themselves, e.g. zookeeper. Close stops watching and calls release.
+func Watch(machineID uint16, expired <-chan struct{}, release func(ctx context.Context) error) *Lease {
+	l := &Lease{
+		machineID: machineID,
+		releaseFn: release,
+		done:      make(chan struct{}),
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go func() {
+		defer close(l.stopped)
+
+		select {
+		case <-expired:
+			l.end(fmt.Errorf("machineID %d: the session expired: %w", l.machineID, ErrNotOwned))
+		case <-l.stop:
+		}
+	}()
+
+	return l
+}
+
+// MachineID returns the leased machineID.
+func (l *Lease) MachineID() uint16 {
+	return l.machineID
+}
+
+// Done is closed when the lease ends.
+func (l *Lease) Done() <-chan struct{} {
+	return l.done
+}
+
+// Err returns why the lease ended, it is nil while the lease is alive and after Close.
+func (l *Lease) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.err
+}
+
+// Close stops the renewal and releases the lease, it is safe to call Close more than once.
+func (l *Lease) Close(ctx context.Context) error {
+	var err error
+	l.stopOnce.Do(func() {
+		close(l.stop)
+		<-l.stopped
+
+		if e := l.releaseFn(ctx); e != nil {
+			err = fmt.Errorf("release machineID %d: %w", l.machineID, e)
+		}
+		l.end(nil)
+	})
+
+	return err
+}
+
+// end close done once, with the reason of the end.
+func (l *Lease) end(err error) {
+	l.endOnce.Do(func() {
+		l.mu.Lock()
+		l.err = err
+		l.mu.Unlock()
+		close(l.done)
+	})
+}
+
+// renew renew the lease every interval until its deadline, the deadline moves on each successful renewal.
+func (l *Lease) renew(acquired time.Time) {
+	defer close(l.stopped)
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	// 在存储的 ttl 到期之前 margin 结束租约，给生成器留出停止的时间
+	deadline := acquired.Add(l.ttl - l.margin)
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	lastErr := errNoRenewal
+	for {
+		select {
+		case <-ticker.C:
+		case <-timer.C:
+			// 续约持续失败，租约可能在下一次续约之前过期
+			l.end(fmt.Errorf("machineID %d not renewed for %s: %w", l.machineID, l.ttl-l.margin, lastErr))
+			return
+		case <-l.stop:
+			return
+		}
+
+		// 在续约之前取时间，存储的 ttl 最早从请求发出时开始计算；续约不能越过截止时间
+		start := time.Now()
+		end := start.Add(l.interval)
+		if deadline.Before(end) {
+			end = deadline
+		}
+		ctx, cancel := context.WithDeadline(context.Background(), end)
+		err := l.renewFn(ctx)
+		cancel()
+
+		switch {
+		case err == nil:
+			deadline = start.Add(l.ttl - l.margin)
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(time.Until(deadline))
+		case errors.Is(err, ErrNotOwned):
+			l.end(fmt.Errorf("machineID %d: %w", l.machineID, err))
+			return
+		default:
+			lastErr = err
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package leasekeeper_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake/internal/leasekeeper"
+)
+
+// fakeStore is a lease store whose ttl runs from the time it receives a renewal, the reply comes back after latency.
+// The renewals fail while down is set.
+type fakeStore struct {
+	ttl     time.Duration
+	latency time.Duration
+
+	mu        sync.Mutex
+	expiresAt time.Time
+	down      bool
+	owner     bool
+}
+
+func (s *fakeStore) renew(ctx context.Context) error {
+	s.mu.Lock()
+	down, owner := s.down, s.owner
+	if !down && owner {
+		s.expiresAt = time.Now().Add(s.ttl)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-time.After(s.latency):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if down {
+		return errors.New("connection refused")
+	}
+	if !owner {
+		return leasekeeper.ErrNotOwned
+	}
+	return nil
+}
+
+func (s *fakeStore) set(down, owner bool) {
+	s.mu.Lock()
+	s.down, s.owner = down, owner
+	s.mu.Unlock()
+}
+
+func (s *fakeStore) expiry() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.expiresAt
+}
+
+func release(ctx context.Context) error {
+	return nil
+}
+
+func TestStart_EndsBeforeExpiry(t *testing.T) {
+	store := &fakeStore{ttl: 150 * time.Millisecond, latency: 20 * time.Millisecond, owner: true}
+	acquired := time.Now()
+	store.expiresAt = acquired.Add(store.ttl)
+
+	l := leasekeeper.Start(1, acquired, 50*time.Millisecond, store.ttl, 25*time.Millisecond, store.renew, release)
+	defer l.Close(context.Background())
+
+	// a few renewals succeed, then the store is unreachable.
+	time.Sleep(120 * time.Millisecond)
+	store.set(true, true)
+
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Fatal("The lease should end once the renewals fail")
+	}
+	if now, expiry := time.Now(), store.expiry(); !now.Before(expiry) {
+		t.Errorf("The lease should end before the store expires it, ended %s after", now.Sub(expiry))
+	}
+	if err := l.Err(); err == nil || errors.Is(err, leasekeeper.ErrNotOwned) {
+		t.Errorf("Err should report the failed renewals, got %v", err)
+	}
+}
+
+func TestStart_NotOwned(t *testing.T) {
+	store := &fakeStore{ttl: time.Second, owner: true}
+	l := leasekeeper.Start(1, time.Now(), 10*time.Millisecond, store.ttl, 100*time.Millisecond, store.renew, release)
+	defer l.Close(context.Background())
+
+	store.set(false, false)
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Fatal("The lease should end once it is not owned")
+	}
+	if err := l.Err(); !errors.Is(err, leasekeeper.ErrNotOwned) {
+		t.Errorf("Err should wrap ErrNotOwned, got %v", err)
+	}
+}
@@ -0,0 +1,3 @@
+// Package postgrestest runs the integration tests of the postgres resolver against the database of TEST_PG_DSN.
+// It is a separate module, so the postgres driver is not a dependency of the snowflake module.
+package postgrestest
@@ -0,0 +1,10 @@
+module github.com/hedwi/go-snowflake/internal/postgrestest
+
+go 1.15
+
+require (
+	github.com/hedwi/go-snowflake v0.0.0
+	github.com/lib/pq v1.10.9
+)
+
+replace github.com/hedwi/go-snowflake => ../..
@@ -0,0 +1,80 @@
+package snowflake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// MarshalJSON returns the ID as a JSON string, e.g. "1541815603606036480".
+// JavaScript reads the JSON numbers as float64, which rounds the integers above 2^53, most snowflake IDs.
+func (id TID) MarshalJSON() ([]byte, error) {
+	b := make([]byte, 0, 22)
+	b = append(b, '"')
+	b = strconv.AppendUint(b, uint64(id), 10)
+
+	return append(b, '"'), nil
+}
+
+// UnmarshalJSON accepts the ID as a JSON string or, for the payloads written before MarshalJSON, as a JSON number.
+// Both hold an unsigned decimal integer, a fraction or an exponent is an error. null leaves the ID unchanged.
+func (id *TID) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	s := b
+	if len(b) >= 2 && b[0] == '"' && b[len(b)-1] == '"' {
+		s = b[1 : len(b)-1]
+	}
+
+	v, err := strconv.ParseUint(string(s), 10, 64)
+	if err != nil {
+		return fmt.Errorf("snowflake: invalid JSON ID %s: %w", b, err)
+	}
+	*id = TID(v)
+
+	return nil
+}
+
+// sidJSON is the JSON form of SID, the ID is a string and the parts are numbers.
+type sidJSON struct {
+	Sequence     uint64
+	MachineID    uint64
+	Timestamp    uint64
+	ID           TID
+	DatacenterID uint64
+	WorkerID     uint64
+}
+
+// MarshalJSON returns the SID as a JSON object with the field names of SID, the ID as a string, see TID.MarshalJSON.
+// The parts stay numbers, they are far below 2^53.
+func (id SID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sidJSON{
+		Sequence:     id.Sequence,
+		MachineID:    id.MachineID,
+		Timestamp:    id.Timestamp,
+		ID:           TID(id.ID),
+		DatacenterID: id.DatacenterID,
+		WorkerID:     id.WorkerID,
+	})
+}
+
+// UnmarshalJSON reads a JSON object of MarshalJSON, the ID as a string or a number.
+// GenerateTime of the SID uses the start time of the default generator.
+func (id *SID) UnmarshalJSON(b []byte) error {
+	var s sidJSON
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*id = SID{
+		Sequence:     s.Sequence,
+		MachineID:    s.MachineID,
+		Timestamp:    s.Timestamp,
+		ID:           uint64(s.ID),
+		DatacenterID: s.DatacenterID,
+		WorkerID:     s.WorkerID,
+	}
+
+	return nil
+}
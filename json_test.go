@@ -0,0 +1,90 @@
+package snowflake_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestTID_MarshalJSON(t *testing.T) {
+	// above 2^53, JavaScript would round the number to 9007199254740994.
+	id := snowflake.TID(1<<53 + 1)
+
+	b, err := json.Marshal(struct{ ID snowflake.TID }{id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `{"ID":"9007199254740993"}`; got != want {
+		t.Errorf("The ID should be a JSON string, got %s, want %s", got, want)
+	}
+
+	max := snowflake.TID(1<<64 - 1)
+	b, _ = json.Marshal(max)
+	var got snowflake.TID
+	if err := json.Unmarshal(b, &got); err != nil || got != max {
+		t.Errorf("The max uint64 should round-trip, got %d, %v", got, err)
+	}
+}
+
+func TestTID_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    snowflake.TID
+		wantErr bool
+	}{
+		{in: `"9007199254740993"`, want: 1<<53 + 1},
+		{in: `9007199254740993`, want: 1<<53 + 1},
+		{in: `"0"`, want: 0},
+		{in: `18446744073709551615`, want: 1<<64 - 1},
+		{in: `"18446744073709551616"`, wantErr: true},
+		{in: `""`, wantErr: true},
+		{in: `"-1"`, wantErr: true},
+		{in: `-1`, wantErr: true},
+		{in: `1.5`, wantErr: true},
+		{in: `1e3`, wantErr: true},
+		{in: `"abc"`, wantErr: true},
+		{in: `true`, wantErr: true},
+		{in: `{}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var got snowflake.TID
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Unmarshal(%s) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	got := snowflake.TID(7)
+	if err := json.Unmarshal([]byte(`null`), &got); err != nil || got != 7 {
+		t.Errorf("null should leave the ID unchanged, got %d, %v", got, err)
+	}
+}
+
+func TestSID_MarshalJSON(t *testing.T) {
+	sid := snowflake.SID{Sequence: 3, MachineID: 5, Timestamp: 1 << 40, ID: 1<<63 + 1, WorkerID: 5}
+
+	for _, v := range []interface{}{sid, &sid} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `{"Sequence":3,"MachineID":5,"Timestamp":1099511627776,"ID":"9223372036854775809","DatacenterID":0,"WorkerID":5}`
+		if string(b) != want {
+			t.Errorf("Marshal(%T) = %s, want %s", v, b, want)
+		}
+	}
+
+	b, _ := json.Marshal(sid)
+	var got snowflake.SID
+	if err := json.Unmarshal(b, &got); err != nil || got != sid {
+		t.Errorf("The SID should round-trip, got %+v, %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(`{"ID":42,"Sequence":1}`), &got); err != nil || got.ID != 42 || got.Sequence != 1 {
+		t.Errorf("A numeric ID should be accepted, got %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,266 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// idBits is the number of usable bits of a snowflake ID, the sign bit is never used.
+const idBits = 63
+
+// FieldOrder is the order of the snowflake ID parts, from high bits to low bits.
+type FieldOrder uint8
+
+const (
+	// OrderTimestampMachineSequence put timestamp, machineID, sequence from high to low, it is the default order.
+	OrderTimestampMachineSequence FieldOrder = iota
+	// OrderTimestampSequenceMachine put timestamp, sequence, machineID from high to low, it is used by Sonyflake.
+	OrderTimestampSequenceMachine
+	// OrderMachineTimestampSequence put machineID, timestamp, sequence from high to low,
+	// IDs of different machines interleave instead of clustering by timestamp, they are only ordered by time per machine.
+	OrderMachineTimestampSequence
+)
+
+// String returns the parts of the order from high to low, e.g. timestamp-machine-sequence.
+func (o FieldOrder) String() string {
+	switch o {
+	case OrderTimestampMachineSequence:
+		return "timestamp-machine-sequence"
+	case OrderTimestampSequenceMachine:
+		return "timestamp-sequence-machine"
+	case OrderMachineTimestampSequence:
+		return "machine-timestamp-sequence"
+	default:
+		return fmt.Sprintf("FieldOrder(%d)", uint8(o))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, the order is encoded as its String.
+func (o FieldOrder) MarshalText() ([]byte, error) {
+	if o > OrderMachineTimestampSequence {
+		return nil, fmt.Errorf("unknown field order %d", uint8(o))
+	}
+
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, it accepts the String of an order, e.g. timestamp-machine-sequence.
+func (o *FieldOrder) UnmarshalText(text []byte) error {
+	for v := OrderTimestampMachineSequence; v <= OrderMachineTimestampSequence; v++ {
+		if v.String() == string(text) {
+			*o = v
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown field order %q", text)
+}
+
+// Layout describes the bit widths of the snowflake ID parts,
+// by default from high to low: timestamp, machineID, sequence, Order can change it.
+//
+// The widths must sum to 63 so the ID always fits in a signed int64,
+// MachineBits and SequenceBits must not be greater than 16 since they are represented by uint16.
+//
+// e.g. 2000 workers need 11 machine bits, with 8 sequence bits the timestamp can use 44 bits:
+//
+//	snowflake.Layout{TimestampBits: 44, MachineBits: 11, SequenceBits: 8}
+//
+// DatacenterBits carves the high bits of the machineID as datacenterID, the remaining low bits are the workerID,
+// e.g. 4 datacenters with up to 100 workers each:
+//
+//	snowflake.Layout{TimestampBits: 42, MachineBits: 9, SequenceBits: 12, DatacenterBits: 2}
+type Layout struct {
+	TimestampBits  uint8
+	MachineBits    uint8
+	SequenceBits   uint8
+	Order          FieldOrder
+	DatacenterBits uint8
+}
+
+// DefaultLayout is the layout used by default: 42 bits timestamp, 9 bits machineID and 12 bits sequence.
+var DefaultLayout = Layout{
+	TimestampBits: TimestampLength,
+	MachineBits:   MachineIDLength,
+	SequenceBits:  SequenceLength,
+}
+
+// Validate check the layout, it returns an error when the widths do not sum to 63 or a part is out of range.
+func (l Layout) Validate() error {
+	if l.TimestampBits == 0 {
+		return errors.New("the timestamp bits cannot be zero")
+	}
+
+	if l.SequenceBits == 0 {
+		return errors.New("the sequence bits cannot be zero")
+	}
+
+	if l.MachineBits > 16 {
+		return fmt.Errorf("the machine bits cannot be greater than 16, got %d", l.MachineBits)
+	}
+
+	if l.SequenceBits > 16 {
+		return fmt.Errorf("the sequence bits cannot be greater than 16, got %d", l.SequenceBits)
+	}
+
+	if l.DatacenterBits > l.MachineBits {
+		return fmt.Errorf("the datacenter bits cannot be greater than the machine bits %d, got %d", l.MachineBits, l.DatacenterBits)
+	}
+
+	if l.Order > OrderMachineTimestampSequence {
+		return fmt.Errorf("unknown field order %s", l.Order)
+	}
+
+	if sum := int(l.TimestampBits) + int(l.MachineBits) + int(l.SequenceBits); sum != idBits {
+		return fmt.Errorf("the sum of the layout bits must be %d, got %d", idBits, sum)
+	}
+
+	return nil
+}
+
+// MaxTimestamp returns the max timestamp (millis since start time) of the layout.
+func (l Layout) MaxTimestamp() uint64 {
+	return 1<<l.TimestampBits - 1
+}
+
+// MaxMachineID returns the max machineID of the layout.
+func (l Layout) MaxMachineID() uint16 {
+	return uint16(1<<l.MachineBits - 1)
+}
+
+// WorkerBits returns the bit width of the workerID, the machine bits which are not used by the datacenterID.
+func (l Layout) WorkerBits() uint8 {
+	return l.MachineBits - l.DatacenterBits
+}
+
+// MaxDatacenterID returns the max datacenterID of the layout, it is 0 when the layout has no datacenter bits.
+func (l Layout) MaxDatacenterID() uint16 {
+	return uint16(1<<l.DatacenterBits - 1)
+}
+
+// MaxWorkerID returns the max workerID of the layout.
+func (l Layout) MaxWorkerID() uint16 {
+	return uint16(1<<l.WorkerBits() - 1)
+}
+
+// MaxSequence returns the max sequence of the layout.
+func (l Layout) MaxSequence() uint16 {
+	return uint16(1<<l.SequenceBits - 1)
+}
+
+// String returns the layout as timestamp/machine/sequence bits, e.g. 42/9/12,
+// the datacenter split is shown as datacenter+worker, e.g. 42/2+7/12,
+// the order is appended when it is not the default, e.g. 39/16/8 (timestamp-sequence-machine).
+func (l Layout) String() string {
+	machine := fmt.Sprint(l.MachineBits)
+	if l.DatacenterBits > 0 {
+		machine = fmt.Sprintf("%d+%d", l.DatacenterBits, l.WorkerBits())
+	}
+	s := fmt.Sprintf("%d/%s/%d", l.TimestampBits, machine, l.SequenceBits)
+	if l.Order != OrderTimestampMachineSequence {
+		s += " (" + l.Order.String() + ")"
+	}
+
+	return s
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// shifts returns the shift of the timestamp, machineID and sequence parts.
+func (l Layout) shifts() (timestamp, machine, sequence uint8) {
+	switch l.Order {
+	case OrderTimestampSequenceMachine:
+		return l.MachineBits + l.SequenceBits, 0, l.MachineBits
+	case OrderMachineTimestampSequence:
+		return l.SequenceBits, l.TimestampBits + l.SequenceBits, 0
+	default:
+		return l.MachineBits + l.SequenceBits, l.SequenceBits, 0
+	}
+}
+
+func (l Layout) compose(timestamp, machineID uint64, seq uint16) uint64 {
+	ts, m, sq := l.shifts()
+
+	return timestamp<<ts | machineID<<m | uint64(seq)<<sq
+}
+
+func (l Layout) parse(id uint64) SID {
+	ts, m, sq := l.shifts()
+	machineID := id >> m & uint64(l.MaxMachineID())
+
+	return SID{
+		ID:           id,
+		Sequence:     id >> sq & uint64(l.MaxSequence()),
+		MachineID:    machineID,
+		DatacenterID: machineID >> l.WorkerBits(),
+		WorkerID:     machineID & uint64(l.MaxWorkerID()),
+		Timestamp:    id >> ts & l.MaxTimestamp(),
+	}
+}
+
+// machineID compose the datacenterID and workerID into a machineID.
+func (l Layout) machineID(datacenterID, workerID uint16) uint16 {
+	return datacenterID<<l.WorkerBits() | workerID
+}
+
+// checkStartTime check the start time s against the current time now of the generator clock.
+func checkStartTime(s time.Time, l Layout, unit time.Duration, now time.Time) error {
+	s = s.UTC()
+
+	if s.IsZero() {
+		return &configError{"The start time cannot be a zero value", ErrZeroStartTime}
+	}
+
+	if s.After(now.UTC()) {
+		return &configError{"The s cannot be greater than the current millisecond", ErrFutureStartTime}
+	}
+
+	// since we check the current millisecond is greater than s, so we don't need to check the overflow.
+	df := elapsedTicks(toTicks(now, unit), s, unit)
+	if uint64(df) > l.MaxTimestamp() {
+		return &configError{"The maximum life cycle of the snowflake algorithm is " + l.lifetime(unit), ErrStartTimeTooEarly}
+	}
+
+	return nil
+}
+
+func (l Layout) checkMachineID(m uint16) error {
+	if m > l.MaxMachineID() {
+		return &configError{fmt.Sprintf("The machineID cannot be greater than %d", l.MaxMachineID()), ErrMachineIDTooLarge}
+	}
+
+	return nil
+}
+
+func (l Layout) checkDatacenterID(d uint16) error {
+	if d > l.MaxDatacenterID() {
+		return &configError{fmt.Sprintf("The datacenterID cannot be greater than %d", l.MaxDatacenterID()), ErrDatacenterIDTooLarge}
+	}
+
+	return nil
+}
+
+func (l Layout) checkWorkerID(w uint16) error {
+	if w > l.MaxWorkerID() {
+		return &configError{fmt.Sprintf("The workerID cannot be greater than %d", l.MaxWorkerID()), ErrWorkerIDTooLarge}
+	}
+
+	return nil
+}
+
+// lifetime returns the life cycle of the layout for the time unit, in years, e.g. 139 years,
+// or as a duration for the narrow layouts which last less than a year.
+func (l Layout) lifetime(unit time.Duration) string {
+	const year = 365.25 * 24 * float64(time.Hour)
+
+	d := float64(l.MaxTimestamp()) * float64(unit)
+	if d < year {
+		return time.Duration(d).String()
+	}
+
+	return fmt.Sprintf("%d years", int(math.Round(d/year)))
+}
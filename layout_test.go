@@ -0,0 +1,220 @@
+package snowflake_test
+
+import (
+	"math"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestLayout_Validate(t *testing.T) {
+	valid := []snowflake.Layout{
+		snowflake.DefaultLayout,
+		{TimestampBits: 41, MachineBits: 10, SequenceBits: 12},
+		{TimestampBits: 44, MachineBits: 11, SequenceBits: 8},
+		{TimestampBits: 39, MachineBits: 16, SequenceBits: 8},
+		{TimestampBits: 47, MachineBits: 0, SequenceBits: 16},
+		{TimestampBits: 39, MachineBits: 16, SequenceBits: 8, Order: snowflake.OrderTimestampSequenceMachine},
+	}
+	for _, l := range valid {
+		if err := l.Validate(); err != nil {
+			t.Errorf("Layout %s should be valid: %v", l, err)
+		}
+	}
+
+	invalid := []snowflake.Layout{
+		{TimestampBits: 43, MachineBits: 9, SequenceBits: 12},
+		{TimestampBits: 40, MachineBits: 9, SequenceBits: 12},
+		{TimestampBits: 0, MachineBits: 47, SequenceBits: 16},
+		{TimestampBits: 51, MachineBits: 12, SequenceBits: 0},
+		{TimestampBits: 30, MachineBits: 17, SequenceBits: 16},
+		{TimestampBits: 30, MachineBits: 16, SequenceBits: 17},
+		{TimestampBits: 42, MachineBits: 9, SequenceBits: 12, Order: 100},
+		{TimestampBits: 42, MachineBits: 9, SequenceBits: 12, DatacenterBits: 10},
+	}
+	for _, l := range invalid {
+		if err := l.Validate(); err == nil {
+			t.Errorf("Layout %s should be invalid", l)
+		}
+	}
+}
+
+func TestLayout_Max(t *testing.T) {
+	if snowflake.DefaultLayout.MaxTimestamp() != snowflake.MaxTimestamp {
+		t.Error("MaxTimestamp should be equal the package constant")
+	}
+	if snowflake.DefaultLayout.MaxMachineID() != snowflake.MaxMachineID {
+		t.Error("MaxMachineID should be equal the package constant")
+	}
+	if snowflake.DefaultLayout.MaxSequence() != snowflake.MaxSequence {
+		t.Error("MaxSequence should be equal the package constant")
+	}
+
+	l := snowflake.Layout{TimestampBits: 31, MachineBits: 16, SequenceBits: 16}
+	if l.MaxMachineID() != 65535 || l.MaxSequence() != 65535 {
+		t.Error("16 bits parts should be equal 65535")
+	}
+	if l.MaxDatacenterID() != 0 || l.MaxWorkerID() != 65535 {
+		t.Error("Without datacenter bits the workerID should use all the machine bits")
+	}
+
+	l = snowflake.Layout{TimestampBits: 42, MachineBits: 9, SequenceBits: 12, DatacenterBits: 2}
+	if l.WorkerBits() != 7 || l.MaxDatacenterID() != 3 || l.MaxWorkerID() != 127 {
+		t.Error("The machine bits should be split into 2 datacenter bits and 7 worker bits")
+	}
+	if l.String() != "42/2+7/12" {
+		t.Error("The layout string should be equal 42/2+7/12, got", l)
+	}
+}
+
+func TestGenerator_Layout(t *testing.T) {
+	layout := snowflake.Layout{TimestampBits: 44, MachineBits: 11, SequenceBits: 8}
+	g, err := snowflake.New(snowflake.WithMachineID(2000), snowflake.WithLayout(layout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Layout() != layout {
+		t.Error("The generator layout should be equal", layout)
+	}
+
+	// 8 sequence bits means the generator must roll over to the next millisecond often.
+	mp := make(map[uint64]bool)
+	for i := 0; i < 5000; i++ {
+		id, err := g.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mp[id] {
+			t.Fatal("ID should't repeat", id)
+		}
+		mp[id] = true
+
+		sid := g.ParseID(id)
+		if sid.MachineID != 2000 {
+			t.Fatal("MachineID should be equal 2000, got", sid.MachineID)
+		}
+		if sid.Sequence > uint64(layout.MaxSequence()) {
+			t.Fatal("Sequence should not be greater than", layout.MaxSequence())
+		}
+	}
+
+	sid := g.ParseID(g.ID())
+	if d := time.Since(sid.GenerateTime()); d < 0 || d > time.Second {
+		t.Error("The id generate time should be equal current time", sid.GenerateTime())
+	}
+
+	t.Run("SetMachineID", func(tt *testing.T) {
+		defer func() {
+			if err := recover(); err == nil {
+				tt.Error("Should throw a error")
+			} else if err.(string) != "The machineID cannot be greater than 2047" {
+				tt.Error("The error message should be eq 「The machineID cannot be greater than 2047」, got", err)
+			}
+		}()
+
+		g.ResetForTesting()
+		g.SetMachineID(2047)
+		g.SetMachineID(2048)
+	})
+
+	t.Run("Invalid", func(tt *testing.T) {
+		if _, err := snowflake.New(snowflake.WithLayout(snowflake.Layout{TimestampBits: 43, MachineBits: 9, SequenceBits: 12})); err == nil {
+			tt.Error("A 64 bits layout should be rejected")
+		}
+
+		// the machineID is checked against the final layout, whatever the order of the options.
+		if _, err := snowflake.New(snowflake.WithMachineID(2000)); err == nil {
+			tt.Error("The machineID should be checked against the default layout")
+		}
+		if _, err := snowflake.New(snowflake.WithMachineID(600), snowflake.WithLayout(snowflake.Layout{TimestampBits: 42, MachineBits: 10, SequenceBits: 11})); err != nil {
+			tt.Error(err)
+		}
+
+		// a small timestamp width can not hold the default start time.
+		if _, err := snowflake.New(snowflake.WithLayout(snowflake.Layout{TimestampBits: 30, MachineBits: 17, SequenceBits: 16})); err == nil {
+			tt.Error("The layout should be rejected")
+		}
+		if _, err := snowflake.New(snowflake.WithLayout(snowflake.Layout{TimestampBits: 31, MachineBits: 16, SequenceBits: 16})); err == nil {
+			tt.Error("The start time should be checked against the layout")
+		}
+	})
+}
+
+func TestLayout_Order(t *testing.T) {
+	orders := []snowflake.FieldOrder{
+		snowflake.OrderTimestampMachineSequence,
+		snowflake.OrderTimestampSequenceMachine,
+		snowflake.OrderMachineTimestampSequence,
+	}
+
+	for _, o := range orders {
+		layout := snowflake.Layout{TimestampBits: 42, MachineBits: 9, SequenceBits: 12, Order: o, DatacenterBits: 3}
+		g, err := snowflake.New(snowflake.WithLayout(layout))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// property: any id is parsed into parts which compose the same id again.
+		roundTrip := func(id uint64) bool {
+			id &= math.MaxInt64
+			sid := g.ParseID(id)
+			if sid.MachineID != sid.DatacenterID<<layout.WorkerBits()|sid.WorkerID {
+				return false
+			}
+
+			return compose(layout, sid.Timestamp, sid.MachineID, sid.Sequence) == id
+		}
+		if err := quick.Check(roundTrip, nil); err != nil {
+			t.Errorf("%s: %v", layout, err)
+		}
+
+		for m := uint16(0); m < 3; m++ {
+			g.ResetForTesting()
+			g.SetMachineID(m * 200)
+			id := g.ID()
+			sid := g.ParseID(id)
+			if sid.MachineID != uint64(m*200) {
+				t.Errorf("%s: MachineID should be equal %d, got %d", layout, m*200, sid.MachineID)
+			}
+			if d := time.Since(sid.GenerateTime()); d < 0 || d > time.Second {
+				t.Errorf("%s: the generate time should be equal current time, got %s", layout, sid.GenerateTime())
+			}
+		}
+	}
+}
+
+func TestLayout_OrderMachineTimestampSequence(t *testing.T) {
+	layout := snowflake.Layout{TimestampBits: 42, MachineBits: 9, SequenceBits: 12, Order: snowflake.OrderMachineTimestampSequence}
+	a, err := snowflake.New(snowflake.WithLayout(layout), snowflake.WithMachineID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := snowflake.New(snowflake.WithLayout(layout), snowflake.WithMachineID(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the machineID is the most significant part, so an older id of machine 2 is greater than a newer id of machine 1.
+	older := b.ID()
+	time.Sleep(2 * time.Millisecond)
+	newer := a.ID()
+	if older <= newer {
+		t.Error("The ids should be clustered by machineID")
+	}
+	if newer>>(layout.TimestampBits+layout.SequenceBits) != 1 || older>>(layout.TimestampBits+layout.SequenceBits) != 2 {
+		t.Error("The machineID should be the highest bits")
+	}
+}
+
+func compose(l snowflake.Layout, timestamp, machineID, sequence uint64) uint64 {
+	switch l.Order {
+	case snowflake.OrderTimestampSequenceMachine:
+		return timestamp<<(l.SequenceBits+l.MachineBits) | sequence<<l.MachineBits | machineID
+	case snowflake.OrderMachineTimestampSequence:
+		return machineID<<(l.TimestampBits+l.SequenceBits) | timestamp<<l.SequenceBits | sequence
+	default:
+		return timestamp<<(l.MachineBits+l.SequenceBits) | machineID<<l.SequenceBits | sequence
+	}
+}
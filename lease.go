@@ -0,0 +1,99 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrLeaseLost is returned by NextID once the machineID lease of the generator is lost, see WithMachineIDLease,
+// or once the claim of HostnameMachineID is lost.
+var ErrLeaseLost = errors.New("snowflake: the machineID lease is lost")
+
+// MachineIDLease is a machineID leased from a coordinator, e.g. redislease.Allocate, and kept alive in the background.
+type MachineIDLease interface {
+	// MachineID returns the leased machineID.
+	MachineID() uint16
+	// Done is closed when the lease ends, because the renewal failed or the lease was closed.
+	Done() <-chan struct{}
+	// Err returns why the lease ended, it is nil while the lease is alive and after Close.
+	Err() error
+	// Close stops the renewal and releases the machineID, it must be safe to call more than once.
+	Close(ctx context.Context) error
+}
+
+// WithMachineIDLease use the machineID of the lease, Close of the generator releases the lease.
+// Once the lease is lost, another process may lease the machineID, so NextID returns an error wrapping ErrLeaseLost,
+// unless WithLeaseLossTolerated.
+func WithMachineIDLease(l MachineIDLease) Option {
+	return func(g *Generator) error {
+		if l == nil {
+			return errors.New("snowflake: invalid option WithMachineIDLease: the lease cannot be nil")
+		}
+		g.lease = l
+		g.machineID, g.machineIDSet = uint64(l.MachineID()), 1
+
+		return nil
+	}
+}
+
+// WithLeaseLossTolerated keep generating IDs once the machineID lease is lost, see WithMachineIDLease,
+// or once the claim of HostnameMachineID is lost.
+// It trades uniqueness for availability: the IDs may collide with the process which leased the machineID afterwards.
+func WithLeaseLossTolerated() Option {
+	return func(g *Generator) error {
+		g.leaseLossTolerated = true
+
+		return nil
+	}
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// watchLease release the lease on Close, and record its loss until the generator is closed.
+func (g *Generator) watchLease() error {
+	l := g.lease
+	if err := g.OnClose(l.Close); err != nil {
+		return err
+	}
+	if g.leaseLossTolerated && g.standby == nil {
+		return nil
+	}
+	g.watchLeaseLoss(l)
+
+	return nil
+}
+
+// watchLeaseLoss fail over to the standby machineID once l is lost, or record the loss until the generator is closed.
+func (g *Generator) watchLeaseLoss(l MachineIDLease) {
+	go func() {
+		select {
+		case <-l.Done():
+			err := l.Err()
+			if err == nil {
+				return
+			}
+			err = fmt.Errorf("%w: %v", ErrLeaseLost, err)
+			if g.standby != nil && g.failover(err) == nil {
+				return
+			}
+			if !g.leaseLossTolerated {
+				g.leaseErr.Store(errorBox{err})
+				atomic.StoreInt32(&g.leaseLost, 1)
+			}
+		case <-g.done:
+		}
+	}()
+}
+
+// checkLease returns the loss of the lease.
+func (g *Generator) checkLease() error {
+	if atomic.LoadInt32(&g.leaseLost) == 0 {
+		return nil
+	}
+
+	return g.leaseErr.Load().(errorBox).err
+}
@@ -0,0 +1,70 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// fakeLease is a MachineIDLease lost by lose.
+type fakeLease struct {
+	machineID uint16
+	done      chan struct{}
+	err       error
+	closed    bool
+}
+
+func newFakeLease(m uint16) *fakeLease {
+	return &fakeLease{machineID: m, done: make(chan struct{})}
+}
+
+func (l *fakeLease) MachineID() uint16               { return l.machineID }
+func (l *fakeLease) Done() <-chan struct{}           { return l.done }
+func (l *fakeLease) Err() error                      { return l.err }
+func (l *fakeLease) Close(ctx context.Context) error { l.closed = true; return nil }
+
+func (l *fakeLease) lose() {
+	l.err = errors.New("renewal failed")
+	close(l.done)
+}
+
+func TestWithMachineIDLease(t *testing.T) {
+	l := newFakeLease(42)
+	g, err := snowflake.New(snowflake.WithMachineIDLease(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.MachineID() != 42 {
+		t.Errorf("The machineID should be the leased one, got %d", g.MachineID())
+	}
+
+	l.lose()
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := g.NextID()
+		if errors.Is(err, snowflake.ErrLeaseLost) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("NextID should fail once the lease is lost, got %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := g.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !l.closed {
+		t.Error("Close of the generator should close the lease")
+	}
+
+	if _, err := snowflake.New(snowflake.WithMachineIDLease(nil)); err == nil {
+		t.Error("A nil lease should be rejected")
+	}
+	if _, err := snowflake.New(snowflake.WithMachineIDLease(newFakeLease(1024))); err == nil {
+		t.Error("A leased machineID beyond the layout should be rejected")
+	}
+}
@@ -0,0 +1,148 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"sort"
+	"strings"
+)
+
+// ErrNoMAC is returned by MachineIDFromPhysicalMAC when no interface is a candidate, see MACCandidates.
+var ErrNoMAC = errors.New("snowflake: no physical interface with a MAC address")
+
+// virtualInterfaces are the name prefixes of the interfaces created by container runtimes, VMs and VPNs.
+var virtualInterfaces = []string{"lo", "docker", "veth", "br-", "virbr", "vmnet", "vboxnet", "tun", "tap", "cni", "flannel", "cali", "kube", "wg"}
+
+// MACCandidate is a network interface considered by MachineIDFromPhysicalMAC, see MACCandidates.
+type MACCandidate struct {
+	Name string
+	MAC  net.HardwareAddr
+	// Skipped is why the interface is not a candidate, e.g. "virtual", it is empty for a candidate.
+	Skipped string
+}
+
+// MachineIDFromMAC returns a machineID hashed from the MAC address of the interface ifaceName, e.g. "eth0",
+// it is stable across reboots even when the IP is assigned by DHCP. The FNV-1a hash of the MAC is folded
+// into MachineIDLength bits, the machine bits of the default layout.
+//
+// Unlike the low bits of a private IP, the hashes of different MACs may collide: with n hosts the probability
+// is about n²/2^(MachineIDLength+1), e.g. 9% for 10 hosts. Check the machineIDs of a fleet are unique, see ClaimMachineID.
+func MachineIDFromMAC(ifaceName string) (uint16, error) {
+	ifs, err := listInterfaces()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, i := range ifs {
+		if i.name != ifaceName {
+			continue
+		}
+		if len(i.hwAddr) == 0 {
+			return 0, fmt.Errorf("snowflake: the interface %s has no MAC address", ifaceName)
+		}
+
+		return hashMachineID(i.hwAddr, MachineIDLength), nil
+	}
+
+	names := make([]string, 0, len(ifs))
+	for _, i := range ifs {
+		names = append(names, i.name)
+	}
+	sort.Strings(names)
+
+	return 0, fmt.Errorf("snowflake: no interface named %s, the interfaces are %s", ifaceName, strings.Join(names, ", "))
+}
+
+// MachineIDFromPhysicalMAC is MachineIDFromMAC with the first candidate of MACCandidates, in the order of the names.
+// It returns an error wrapping ErrNoMAC when there is no candidate.
+func MachineIDFromPhysicalMAC() (uint16, error) {
+	candidates, err := MACCandidates()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, c := range candidates {
+		if c.Skipped == "" {
+			return hashMachineID(c.MAC, MachineIDLength), nil
+		}
+	}
+
+	return 0, ErrNoMAC
+}
+
+// MACCandidates list the interfaces of the host in the order of their names, with the reason why
+// MachineIDFromPhysicalMAC skips an interface: loopback, virtual (docker0, veth*...), down, no MAC,
+// or a locally administered MAC, which is usually generated for a virtual interface. It helps to debug the machineID of a host.
+func MACCandidates() ([]MACCandidate, error) {
+	ifs, err := listInterfaces()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ifs, func(i, j int) bool {
+		return ifs[i].name < ifs[j].name
+	})
+
+	candidates := make([]MACCandidate, 0, len(ifs))
+	for _, i := range ifs {
+		c := MACCandidate{Name: i.name, MAC: i.hwAddr}
+		switch {
+		case i.flags&net.FlagLoopback != 0:
+			c.Skipped = "loopback"
+		case isVirtualInterface(i.name):
+			c.Skipped = "virtual"
+		case i.flags&net.FlagUp == 0:
+			c.Skipped = "down"
+		case len(i.hwAddr) == 0:
+			c.Skipped = "no MAC"
+		case i.hwAddr[0]&0x02 != 0:
+			c.Skipped = "locally administered MAC"
+		}
+		candidates = append(candidates, c)
+	}
+
+	return candidates, nil
+}
+
+// HashMachineID fold the FNV-1a 32-bit hash of a stable host identifier, e.g. an instance ID, into bits machine bits,
+// with the hash of MachineIDFromMAC and the other hashed sources. The hash never changes across releases.
+// bits is clamped to 1-16.
+func HashMachineID(b []byte, bits uint8) uint16 {
+	switch {
+	case bits == 0:
+		bits = 1
+	case bits > 16:
+		bits = 16
+	}
+
+	return hashMachineID(b, bits)
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+func isVirtualInterface(name string) bool {
+	for _, prefix := range virtualInterfaces {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hashMachineID fold the FNV-1a hash of b into bits bits.
+// The derived machineIDs must not shift on upgrade, never change the hash.
+func hashMachineID(b []byte, bits uint8) uint16 {
+	h := fnv.New32a()
+	_, _ = h.Write(b)
+
+	var id uint32
+	for sum := h.Sum32(); sum != 0; sum >>= bits {
+		id ^= sum & (1<<bits - 1)
+	}
+
+	return uint16(id)
+}
@@ -0,0 +1,87 @@
+package snowflake_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestMachineIDFromMAC(t *testing.T) {
+	snowflake.SetInterfaces(t,
+		snowflake.TestInterface{Name: "eth0", MAC: "00:1a:2b:3c:4d:5e"},
+		snowflake.TestInterface{Name: "eth1", MAC: "00:1a:2b:3c:4d:5f"},
+		snowflake.TestInterface{Name: "ppp0"},
+	)
+
+	m0, err := snowflake.MachineIDFromMAC("eth0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m0 > snowflake.MaxMachineID {
+		t.Errorf("The machineID should fit the default layout, got %d", m0)
+	}
+	if again, _ := snowflake.MachineIDFromMAC("eth0"); again != m0 {
+		t.Errorf("The machineID should be stable, got %d and %d", m0, again)
+	}
+	if m1, _ := snowflake.MachineIDFromMAC("eth1"); m1 == m0 {
+		t.Errorf("The machineIDs of different MACs should differ, got %d", m1)
+	}
+
+	if _, err := snowflake.MachineIDFromMAC("ppp0"); err == nil || !strings.Contains(err.Error(), "no MAC") {
+		t.Errorf("The error should report the interface has no MAC, got %v", err)
+	}
+	if _, err := snowflake.MachineIDFromMAC("eth9"); err == nil || !strings.Contains(err.Error(), "eth0, eth1, ppp0") {
+		t.Errorf("The error should list the interfaces, got %v", err)
+	}
+}
+
+func TestMachineIDFromPhysicalMAC(t *testing.T) {
+	snowflake.SetInterfaces(t,
+		snowflake.TestInterface{Name: "docker0", MAC: "00:11:22:33:44:55"},
+		snowflake.TestInterface{Name: "veth1a2b", MAC: "00:11:22:33:44:56"},
+		snowflake.TestInterface{Name: "eth0", Down: true, MAC: "00:11:22:33:44:57"},
+		snowflake.TestInterface{Name: "ens4", MAC: "02:42:ac:11:00:02"},
+		snowflake.TestInterface{Name: "ens5", MAC: "00:1a:2b:3c:4d:5e"},
+	)
+
+	m, err := snowflake.MachineIDFromPhysicalMAC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := snowflake.MachineIDFromMAC("ens5"); m != want {
+		t.Errorf("The machineID should be derived from ens5, got %d, want %d", m, want)
+	}
+
+	candidates, err := snowflake.MACCandidates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range candidates {
+		got = append(got, c.Name+":"+c.Skipped)
+	}
+	want := "docker0:virtual ens4:locally administered MAC ens5: eth0:down veth1a2b:virtual"
+	if strings.Join(got, " ") != want {
+		t.Errorf("The candidates should explain the skipped interfaces, got %q", got)
+	}
+}
+
+func TestMachineIDFromPhysicalMAC_None(t *testing.T) {
+	snowflake.SetInterfaces(t, snowflake.TestInterface{Name: "docker0", MAC: "00:11:22:33:44:55"})
+
+	if _, err := snowflake.MachineIDFromPhysicalMAC(); !errors.Is(err, snowflake.ErrNoMAC) {
+		t.Errorf("The error should be ErrNoMAC, got %v", err)
+	}
+}
+
+func TestHashMachineID(t *testing.T) {
+	// the hash of the machine-id golden value of TestMachineIDFromSystemdMachineID.
+	if m := snowflake.HashMachineID([]byte("4c4c4544003957108052b4c04f384833"), 9); m != 63 {
+		t.Errorf("The hash should be stable, got %d", m)
+	}
+	if m := snowflake.HashMachineID([]byte("i-0abc123def4567890"), 0); m > 1 {
+		t.Errorf("The bits should be clamped, got %d", m)
+	}
+}
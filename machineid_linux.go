@@ -0,0 +1,25 @@
+package snowflake
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// readMachineID read the first existing machine-id file, it returns its content and path.
+func readMachineID() ([]byte, string, error) {
+	for _, path := range machineIDFiles {
+		b, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, path, fmt.Errorf("snowflake: read the machine-id: %w", err)
+		}
+
+		return b, path, nil
+	}
+
+	return nil, "", fmt.Errorf("%w: none of %s exists", ErrNoSystemdMachineID, strings.Join(machineIDFiles, ", "))
+}
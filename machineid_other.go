@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package snowflake
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// readMachineID returns ErrNoSystemdMachineID, only linux has a systemd machine-id.
+func readMachineID() ([]byte, string, error) {
+	return nil, "", fmt.Errorf("%w: the machine-id is only available on linux, not %s", ErrNoSystemdMachineID, runtime.GOOS)
+}
@@ -0,0 +1,59 @@
+package snowflake_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestMachineIDFromSystemdMachineID(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		if _, err := snowflake.MachineIDFromSystemdMachineID(); !errors.Is(err, snowflake.ErrNoSystemdMachineID) {
+			t.Errorf("The error should be ErrNoSystemdMachineID on %s, got %v", runtime.GOOS, err)
+		}
+		t.Skip("The machine-id is only available on linux")
+	}
+
+	dir := t.TempDir()
+	etc, dbus := filepath.Join(dir, "etc-machine-id"), filepath.Join(dir, "dbus-machine-id")
+	snowflake.SetMachineIDFiles(t, etc, dbus)
+
+	if _, err := snowflake.MachineIDFromSystemdMachineID(); !errors.Is(err, snowflake.ErrNoSystemdMachineID) {
+		t.Errorf("The error should be ErrNoSystemdMachineID when the files are missing, got %v", err)
+	}
+
+	// the dbus file is the fallback.
+	if err := ioutil.WriteFile(dbus, []byte("4c4c4544003957108052b4c04f384833\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := snowflake.MachineIDFromSystemdMachineID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the hash is stable across releases, this value must never change.
+	if m != 63 {
+		t.Errorf("The machineID should be 63, got %d", m)
+	}
+
+	if err := ioutil.WriteFile(etc, []byte("uninitialized\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := snowflake.MachineIDFromSystemdMachineID(); !errors.Is(err, snowflake.ErrNoSystemdMachineID) {
+		t.Errorf("The error should be ErrNoSystemdMachineID when the machine-id is not initialized, got %v", err)
+	}
+
+	if err := ioutil.WriteFile(etc, []byte("4c4c4544003957108052b4c04f384833"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	g, err := snowflake.New(snowflake.SystemdMachineID(), snowflake.WithLayout(snowflake.Layout{TimestampBits: 45, MachineBits: 6, SequenceBits: 12}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.MachineID() > 1<<6-1 {
+		t.Errorf("The machineID should be folded into the machine bits of the layout, got %d", g.MachineID())
+	}
+}
@@ -0,0 +1,127 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MachineIDAnnotation is the pod annotation holding the machineID assigned by a controller, mount it with the downward API:
+//
+//	volumes:
+//	- name: snowflake
+//	  downwardAPI:
+//	    items:
+//	    - path: machine-id
+//	      fieldRef:
+//	        fieldPath: metadata.annotations['snowflake.hedwi.io/machine-id']
+const MachineIDAnnotation = "snowflake.hedwi.io/machine-id"
+
+// ErrMachineIDChanged is reported by MachineIDFile when the machineID file changed after New, the change is rejected.
+var ErrMachineIDChanged = errors.New("snowflake: the machineID file changed, the generator keeps its machineID")
+
+// machineIDFilePoll is how often MachineIDFile re-reads the file, the kubelet updates the downward API files every minute or so.
+var machineIDFilePoll = 10 * time.Second
+
+// MachineIDFromFile returns the machineID written in the file at path, e.g. a downward API file of the MachineIDAnnotation,
+// checked against MachineIDLength bits. When the file is missing, the error matches os.ErrNotExist,
+// when the machineID exceeds the machine bits, it matches ErrMachineIDTooLarge.
+func MachineIDFromFile(path string) (uint16, error) {
+	return machineIDFromFile(path)(MachineIDLength)
+}
+
+// ParseMachineIDAnnotation parse the value of the MachineIDAnnotation and check it against bits machine bits,
+// for the controllers assigning the machineIDs to validate an annotation before patching the pod.
+func ParseMachineIDAnnotation(value string, bits uint8) (uint16, error) {
+	if bits == 0 || bits > 16 {
+		return 0, fmt.Errorf("snowflake: invalid machine bits %d, it must be between 1 and 16", bits)
+	}
+
+	v := strings.TrimSpace(value)
+	m, err := strconv.ParseUint(v, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("snowflake: %q is not a machineID", v)
+	}
+	if max := uint64(1)<<bits - 1; m > max {
+		return 0, fmt.Errorf("%w: %d is out of range, the max machineID of %d machine bits is %d", ErrMachineIDTooLarge, m, bits, max)
+	}
+
+	return uint16(m), nil
+}
+
+// MachineIDFile set the machineID of the generator from the file at path, checked against the machine bits of its layout,
+// see MachineIDFromFile.
+//
+// The machineID of a running generator never changes. With onChange, the file is re-read every 10s until the generator
+// is closed, a changed machineID is rejected and reported to onChange with an error wrapping ErrMachineIDChanged,
+// restart the process to use it. onChange is also called when the file cannot be read anymore.
+func MachineIDFile(path string, onChange func(err error)) Option {
+	return func(g *Generator) error {
+		g.derivedMachineID, g.derivedBy = machineIDFromFile(path), "MachineIDFile"
+		g.machineIDFile, g.machineIDFileHook = path, onChange
+
+		return nil
+	}
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+func machineIDFromFile(path string) func(bits uint8) (uint16, error) {
+	return func(bits uint8) (uint16, error) {
+		b, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			return 0, fmt.Errorf("snowflake: the machineID file %s is missing: %w", path, err)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("snowflake: read the machineID file: %w", err)
+		}
+
+		m, err := ParseMachineIDAnnotation(string(b), bits)
+		if err != nil {
+			return 0, fmt.Errorf("snowflake: the machineID file %s: %w", path, err)
+		}
+
+		return m, nil
+	}
+}
+
+// watchMachineIDFile re-read the machineID file until the generator is closed and report the changes to the hook.
+func (g *Generator) watchMachineIDFile() {
+	read := machineIDFromFile(g.machineIDFile)
+	machineID := uint16(g.machineID)
+	ticker := time.NewTicker(machineIDFilePoll)
+
+	go func() {
+		defer ticker.Stop()
+
+		var last string
+		for {
+			select {
+			case <-ticker.C:
+			case <-g.done:
+				return
+			}
+
+			m, err := read(g.layout.MachineBits)
+			if err == nil && m != machineID {
+				err = fmt.Errorf("%w: %s holds %d, the machineID is %d", ErrMachineIDChanged, g.machineIDFile, m, machineID)
+			}
+
+			// 同一个错误只报告一次，文件恢复后重新报告
+			msg := ""
+			if err != nil {
+				msg = err.Error()
+			}
+			if msg != last && err != nil {
+				g.machineIDFileHook(err)
+			}
+			last = msg
+		}
+	}()
+}
@@ -0,0 +1,91 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestMachineIDFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "machine-id")
+
+	if _, err := snowflake.MachineIDFromFile(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("A missing file should match os.ErrNotExist, got %v", err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("42\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := snowflake.MachineIDFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != 42 {
+		t.Errorf("The machineID should be read from the file, got %d", m)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("512"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := snowflake.MachineIDFromFile(path); !errors.Is(err, snowflake.ErrMachineIDTooLarge) || errors.Is(err, os.ErrNotExist) {
+		t.Errorf("An out of range machineID should match ErrMachineIDTooLarge, got %v", err)
+	}
+}
+
+func TestParseMachineIDAnnotation(t *testing.T) {
+	if m, err := snowflake.ParseMachineIDAnnotation(" 15 ", 4); err != nil || m != 15 {
+		t.Errorf("ParseMachineIDAnnotation(15, 4) = %d, %v", m, err)
+	}
+	if _, err := snowflake.ParseMachineIDAnnotation("16", 4); !errors.Is(err, snowflake.ErrMachineIDTooLarge) {
+		t.Errorf("16 should exceed 4 machine bits, got %v", err)
+	}
+	if _, err := snowflake.ParseMachineIDAnnotation("pod-3", 9); err == nil {
+		t.Error("A value which is not a number should be rejected")
+	}
+}
+
+func TestMachineIDFile(t *testing.T) {
+	snowflake.SetMachineIDFilePoll(t, time.Millisecond)
+	path := filepath.Join(t.TempDir(), "machine-id")
+	if err := ioutil.WriteFile(path, []byte("7"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan error, 10)
+	g, err := snowflake.New(snowflake.MachineIDFile(path, func(err error) {
+		changes <- err
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close(context.Background())
+	if g.MachineID() != 7 {
+		t.Errorf("The machineID should be read from the file, got %d", g.MachineID())
+	}
+
+	// the controller assigned another machineID, the change is rejected.
+	if err := ioutil.WriteFile(path, []byte("8"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-changes:
+		if !errors.Is(err, snowflake.ErrMachineIDChanged) {
+			t.Errorf("The change should be reported as ErrMachineIDChanged, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("The change should be reported")
+	}
+	if g.MachineID() != 7 {
+		t.Errorf("The machineID should not change at runtime, got %d", g.MachineID())
+	}
+
+	if _, err := snowflake.New(snowflake.MachineIDFile(filepath.Join(t.TempDir(), "missing"), nil)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("New should fail when the file is missing, got %v", err)
+	}
+}
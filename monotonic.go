@@ -0,0 +1,39 @@
+package snowflake
+
+import (
+	"sync"
+	"time"
+)
+
+// monotonicClock derive the current time from the monotonic reading of an anchor,
+// so the wall clock steps (e.g. by NTP) are invisible to the generator.
+type monotonicClock struct {
+	// idle is the idle period after which the clock re-anchors to the wall clock, zero means never.
+	idle time.Duration
+
+	mu     sync.Mutex
+	anchor time.Time
+	last   time.Time
+}
+
+func newMonotonicClock(idle time.Duration) *monotonicClock {
+	now := time.Now()
+
+	return &monotonicClock{idle: idle, anchor: now, last: now}
+}
+
+// Now returns the anchored wall time plus the monotonic time elapsed since the anchor.
+func (c *monotonicClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.anchor.Add(time.Since(c.anchor))
+	if c.idle > 0 && now.Sub(c.last) >= c.idle {
+		// 空闲时重新锚定，修正长期漂移
+		c.anchor = time.Now()
+		now = c.anchor
+	}
+	c.last = now
+
+	return now
+}
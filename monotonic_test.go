@@ -0,0 +1,56 @@
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestWithMonotonicClock(t *testing.T) {
+	g, err := snowflake.New(snowflake.WithMonotonicClock())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var last uint64
+	for i := 0; i < 1000; i++ {
+		id := g.ID()
+		if id <= last {
+			t.Fatalf("The IDs should increase, got %d after %d", id, last)
+		}
+		last = id
+	}
+
+	sid := g.ParseID(last)
+	if d := time.Since(sid.GenerateTime()); d < -time.Millisecond || d > 100*time.Millisecond {
+		t.Errorf("The generate time should be close to the wall clock, got %s", sid.GenerateTime())
+	}
+}
+
+func TestMonotonicClock_Reanchor(t *testing.T) {
+	c := snowflake.NewMonotonicClock(0)
+	anchor := c.Anchor()
+	time.Sleep(5 * time.Millisecond)
+	if now := c.Now(); !c.Anchor().Equal(anchor) || now.Sub(anchor) < 5*time.Millisecond {
+		t.Errorf("The clock should not re-anchor without an idle period, got %s", now)
+	}
+
+	c = snowflake.NewMonotonicClock(2 * time.Millisecond)
+	anchor = c.Anchor()
+	c.Now()
+	if !c.Anchor().Equal(anchor) {
+		t.Error("The clock should not re-anchor while busy")
+	}
+	time.Sleep(5 * time.Millisecond)
+	c.Now()
+	if c.Anchor().Equal(anchor) {
+		t.Error("The clock should re-anchor after the idle period")
+	}
+}
+
+func TestWithMonotonicClockReanchor_Invalid(t *testing.T) {
+	if _, err := snowflake.New(snowflake.WithMonotonicClockReanchor(-time.Second)); err == nil {
+		t.Error("A negative idle period should be rejected")
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !race
+// +build !race
+
+package snowflake_test
+
+// raceEnabled is true when the tests run with the race detector, sync.Pool drops items randomly then.
+const raceEnabled = false
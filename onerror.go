@@ -0,0 +1,69 @@
+package snowflake
+
+import "sync/atomic"
+
+// errorBox holds an error in an atomic.Value, which needs a consistent concrete type.
+type errorBox struct {
+	err error
+}
+
+// WithPanicOnError make ID panic with the error instead of returning 0, see Generator.SetPanicOnError.
+func WithPanicOnError() Option {
+	return func(g *Generator) error {
+		g.panicOnError = 1
+
+		return nil
+	}
+}
+
+// SetPanicOnError set whether ID panics with the error instead of returning 0.
+// A panic is louder than a 0 inserted as a primary key, NextID is unaffected.
+// This function is thread safe.
+func (g *Generator) SetPanicOnError(p bool) {
+	var v int32
+	if p {
+		v = 1
+	}
+	atomic.StoreInt32(&g.panicOnError, v)
+}
+
+// OnError register fn to be called with the error whenever ID ignores an error and returns 0.
+// Unlike the other hooks, fn is called synchronously by ID before it returns, e.g. to log with the context of the caller,
+// it is called before ID panics with WithPanicOnError.
+// This function is thread safe.
+func (g *Generator) OnError(fn func(err error)) {
+	g.errorHooksMu.Lock()
+	defer g.errorHooksMu.Unlock()
+
+	g.errorHooks = append(g.errorHooks, fn)
+}
+
+// LastError returns the last error ignored by ID, or nil when ID never failed, for quick debugging.
+// This function is thread safe.
+func (g *Generator) LastError() error {
+	if box, ok := g.lastError.Load().(errorBox); ok {
+		return box.err
+	}
+
+	return nil
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// ignoreError record the error ignored by ID, call the hooks and panic when the generator panics on error.
+func (g *Generator) ignoreError(err error) {
+	g.lastError.Store(errorBox{err})
+
+	g.errorHooksMu.Lock()
+	hooks := g.errorHooks
+	g.errorHooksMu.Unlock()
+	for _, fn := range hooks {
+		fn(err)
+	}
+
+	if atomic.LoadInt32(&g.panicOnError) == 1 {
+		must(err)
+	}
+}
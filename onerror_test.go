@@ -0,0 +1,107 @@
+package snowflake_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/clocktest"
+)
+
+var errResolverDown = errors.New("resolver down")
+
+func newFailingGenerator(t *testing.T, opts ...snowflake.Option) *snowflake.Generator {
+	t.Helper()
+
+	// the machineID is configured, so the hooks only get the errors of the resolver.
+	opts = append(opts, snowflake.WithMachineID(1), snowflake.WithSequenceResolver(func(ms int64) (uint16, error) {
+		return 0, errResolverDown
+	}))
+	g, err := snowflake.New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return g
+}
+
+func TestGenerator_OnError(t *testing.T) {
+	g := newFailingGenerator(t)
+	if err := g.LastError(); err != nil {
+		t.Errorf("The last error should be nil before ID failed, got %v", err)
+	}
+
+	var got []error
+	g.OnError(func(err error) {
+		got = append(got, err)
+	})
+
+	if id := g.ID(); id != 0 {
+		t.Errorf("ID should return 0 on error, got %d", id)
+	}
+	if len(got) != 1 || !errors.Is(got[0], errResolverDown) {
+		t.Errorf("The hook should be called with the error, got %v", got)
+	}
+	if err := g.LastError(); !errors.Is(err, errResolverDown) {
+		t.Errorf("The last error should be returned, got %v", err)
+	}
+
+	// NextID returns the error itself, the hooks are only for ID.
+	if _, err := g.NextID(); err == nil || len(got) != 1 {
+		t.Errorf("NextID should not call the hooks, got %v", got)
+	}
+}
+
+func TestWithPanicOnError(t *testing.T) {
+	g := newFailingGenerator(t, snowflake.WithPanicOnError())
+
+	called := false
+	g.OnError(func(err error) {
+		called = true
+	})
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("ID should panic on error")
+			}
+		}()
+		g.ID()
+	}()
+	if !called {
+		t.Error("The hooks should be called before the panic")
+	}
+
+	g.SetPanicOnError(false)
+	if id := g.ID(); id != 0 {
+		t.Errorf("ID should return 0 once the panic is disabled, got %d", id)
+	}
+}
+
+// TestGenerator_NeverZero generate the first IDs of the start time with machineID 0, whose first ID would be 0.
+func TestGenerator_NeverZero(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g, err := snowflake.New(snowflake.WithClock(clocktest.NewManual(now)), snowflake.WithStartTime(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		id, err := g.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id == 0 {
+			t.Fatal("A successful generation should never produce 0")
+		}
+	}
+
+	id, err := g.NextIDAt(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == 0 {
+		t.Error("A successful backfill should never produce 0")
+	}
+}
@@ -0,0 +1,229 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Option configures a Generator created by New.
+// An option validates its value eagerly and returns a descriptive error instead of panicking.
+type Option func(g *Generator) error
+
+// WithMachineID specify the machine ID, it must not be greater than the max machineID of the layout.
+func WithMachineID(m uint16) Option {
+	return func(g *Generator) error {
+		g.machineID, g.machineIDSet = uint64(m), 1
+
+		return nil
+	}
+}
+
+// WithDatacenterID specify the datacenterID part of the machine ID, see Layout.DatacenterBits.
+// It must not be greater than the max datacenterID of the layout, and cannot be used with WithMachineID.
+func WithDatacenterID(d uint16) Option {
+	return func(g *Generator) error {
+		g.datacenterID = &d
+
+		return nil
+	}
+}
+
+// WithWorkerID specify the workerID part of the machine ID, see Layout.DatacenterBits.
+// It must not be greater than the max workerID of the layout, and cannot be used with WithMachineID.
+func WithWorkerID(w uint16) Option {
+	return func(g *Generator) error {
+		g.workerID = &w
+
+		return nil
+	}
+}
+
+// WithStartTime set the start time (epoch) of the generator.
+// It must not be zero, in the future, or earlier than the max timestamp of the layout allows.
+func WithStartTime(s time.Time) Option {
+	return func(g *Generator) error {
+		if s.IsZero() {
+			return fmt.Errorf("snowflake: invalid option WithStartTime(%s): %w", s.Format(time.RFC3339), checkStartTime(s, g.layout, g.timeUnit, g.clock.Now()))
+		}
+		g.startTime = s.UTC()
+
+		return nil
+	}
+}
+
+// WithStrictMonotonic guarantee every ID is strictly greater than the previous one of the generator,
+// e.g. for the consumers assuming increasing keys per producer. The IDs are generated one at a time,
+// an ID which would not be greater, e.g. a lower sequence from a resolver not counting up, is generated again
+// from the tick after the previous ID, ahead of the clock, or NextID waits when that tick is exhausted too.
+// NextID returns ErrEpochExhausted when the guarantee would exceed the timestamp part, use NextID rather than ID,
+// which ignores the errors. It cannot be used with WithDescending.
+func WithStrictMonotonic() Option {
+	return func(g *Generator) error {
+		g.strict = true
+
+		return nil
+	}
+}
+
+// WithDescending stores MaxTimestamp - elapsed in the timestamp part instead of elapsed,
+// so IDs sort descending over time, e.g. for newest-first scans in HBase or Bigtable.
+// IDs generated in the same tick still ascend by sequence.
+// ParseID of the generator inverts the timestamp back, so SID.Timestamp and SID.GenerateTime are not affected.
+func WithDescending() Option {
+	return func(g *Generator) error {
+		g.descending = true
+
+		return nil
+	}
+}
+
+// WithLayout set the bit layout of the generator, see Layout for the rules.
+// The machineID and start time are checked against the final layout, the order of the options does not matter.
+func WithLayout(l Layout) Option {
+	return func(g *Generator) error {
+		if err := l.Validate(); err != nil {
+			return fmt.Errorf("snowflake: invalid option WithLayout(%s): %v", l, err)
+		}
+		g.layout = l
+
+		return nil
+	}
+}
+
+// WithSequenceResolver set a custom sequence resolver, the resolver must not be nil.
+func WithSequenceResolver(seq SequenceResolver) Option {
+	return func(g *Generator) error {
+		if seq == nil {
+			return errors.New("snowflake: invalid option WithSequenceResolver: the resolver cannot be nil")
+		}
+		g.resolver.Store(resolverBox{seq})
+		g.chain = nil
+
+		return nil
+	}
+}
+
+// WithResolver set a custom Resolver, it gets the context of NextIDContext and the machineID of the generator.
+// The resolver must not be nil.
+func WithResolver(r Resolver) Option {
+	return func(g *Generator) error {
+		if r == nil {
+			return errors.New("snowflake: invalid option WithResolver: the resolver cannot be nil")
+		}
+		g.resolver.Store(resolverBox{r})
+		g.chain = nil
+
+		return nil
+	}
+}
+
+// WithResolverChain set the chain as the sequence resolver, the IDs whose sequence was served by a fallback
+// of the chain use fallbackMachineID instead of the machineID of the generator, so they cannot collide
+// with the IDs of the other processes sharing the primary resolver. fallbackMachineID must be unique to the process.
+func WithResolverChain(chain *ResolverChain, fallbackMachineID uint16) Option {
+	return func(g *Generator) error {
+		if chain == nil {
+			return errors.New("snowflake: invalid option WithResolverChain: the chain cannot be nil")
+		}
+		g.resolver.Store(resolverBox{chain})
+		g.chain, g.fallbackMachineID = chain, fallbackMachineID
+
+		return nil
+	}
+}
+
+// WithTimeUnit set the tick duration of the timestamp part, a millisecond by default.
+//
+// A larger unit trades throughput for lifetime: each machine can generate at most MaxSequence IDs per tick,
+// and the generator lives 2^TimestampBits ticks. e.g. with 12 sequence bits:
+//
+//	1ms:  4,095,000 IDs per second, 42 timestamp bits last 139 years.
+//	10ms: 409,500 IDs per second, 39 timestamp bits last 174 years.
+//	1s:   4,095 IDs per second, 32 timestamp bits last 136 years.
+//
+// The generator waits for the next tick when the sequence is exhausted, and SID.GenerateTime has the granularity of a tick.
+func WithTimeUnit(unit time.Duration) Option {
+	return func(g *Generator) error {
+		if unit <= 0 {
+			return fmt.Errorf("snowflake: invalid option WithTimeUnit(%s): the time unit must be positive", unit)
+		}
+		g.timeUnit = unit
+
+		return nil
+	}
+}
+
+// WithClock set the time source of the generator, e.g. a clocktest.Manual in tests.
+// The start time is validated against this clock too.
+func WithClock(c Clock) Option {
+	return func(g *Generator) error {
+		if c == nil {
+			return errors.New("snowflake: invalid option WithClock: the clock cannot be nil")
+		}
+		g.clock = c
+
+		return nil
+	}
+}
+
+// WithBurst enable the burst mode: when the sequence of the current tick is exhausted, the generator borrows the next tick
+// instead of waiting for it, as long as the IDs run ahead of the clock by maxLead at most, e.g. 10ms.
+// Beyond maxLead NextID waits as usual. Once the clock catches up the lead shrinks naturally,
+// the IDs remain unique and increasing, they just encode times slightly in the future, see Stats.Lead.
+func WithBurst(maxLead time.Duration) Option {
+	return func(g *Generator) error {
+		if maxLead <= 0 {
+			return fmt.Errorf("snowflake: invalid option WithBurst(%s): the max lead must be positive", maxLead)
+		}
+		g.burstLead = maxLead
+
+		return nil
+	}
+}
+
+// WithMonotonicClock derive every timestamp from the monotonic clock anchored at New,
+// wall clock steps, e.g. by NTP, are invisible to the generator and the clock never moves backward.
+// The IDs drift from the wall clock by the NTP slew error at most, GenerateTime still decodes sensible wall times.
+func WithMonotonicClock() Option {
+	return WithMonotonicClockReanchor(0)
+}
+
+// WithMonotonicClockReanchor is WithMonotonicClock, it re-anchors to the wall clock when the generator was idle for idle,
+// which bounds the drift of long-running processes. If the wall clock is behind the monotonic clock at that point,
+// the backward policy applies as usual.
+func WithMonotonicClockReanchor(idle time.Duration) Option {
+	return func(g *Generator) error {
+		if idle < 0 {
+			return fmt.Errorf("snowflake: invalid option WithMonotonicClockReanchor(%s): the idle period cannot be negative", idle)
+		}
+		g.clock = newMonotonicClock(idle)
+
+		return nil
+	}
+}
+
+// WithBackwardPolicy set what NextID does when the clock moved backward, see BackwardPolicy.
+func WithBackwardPolicy(p BackwardPolicy) Option {
+	return func(g *Generator) error {
+		if p < BackwardPolicyWait || p > BackwardPolicyLogical {
+			return fmt.Errorf("snowflake: invalid option WithBackwardPolicy: unknown policy %s", p)
+		}
+		g.backwardPolicy = int32(p)
+
+		return nil
+	}
+}
+
+// WithMaxBackwardTolerance set the max clock backward the generator waits for, see Generator.SetMaxBackwardTolerance.
+// Zero means never wait, any backward movement is an error.
+func WithMaxBackwardTolerance(d time.Duration) Option {
+	return func(g *Generator) error {
+		if d < 0 {
+			return fmt.Errorf("snowflake: invalid option WithMaxBackwardTolerance(%s): the tolerance cannot be negative", d)
+		}
+		g.maxBackward = int64(d)
+
+		return nil
+	}
+}
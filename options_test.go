@@ -0,0 +1,195 @@
+package snowflake_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestNew_Options(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	g, err := snowflake.New(
+		snowflake.WithMachineID(42),
+		snowflake.WithStartTime(start),
+		snowflake.WithSequenceResolver(func(ms int64) (uint16, error) {
+			return 7, nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sid := g.ParseID(g.ID())
+	if sid.MachineID != 42 {
+		t.Error("MachineID should be equal 42")
+	}
+	if sid.Sequence != 7 {
+		t.Error("Sequence should be equal 7")
+	}
+
+	df := uint64(time.Since(start) / time.Millisecond)
+	if sid.Timestamp/1000 != df/1000 {
+		t.Error("The timestamp should be relative to the start time")
+	}
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	cases := []struct {
+		name string
+		opt  snowflake.Option
+		msg  string
+	}{
+		{"machineID too big", snowflake.WithMachineID(512), "The machineID cannot be greater than 511"},
+		{"zero start time", snowflake.WithStartTime(time.Time{}), "The start time cannot be a zero value"},
+		{"future start time", snowflake.WithStartTime(time.Now().Add(time.Hour)), "The s cannot be greater than the current millisecond"},
+		{"start time too small", snowflake.WithStartTime(time.Date(1000, 1, 1, 0, 0, 0, 0, time.UTC)), "139 years"},
+		{"nil resolver", snowflake.WithSequenceResolver(nil), "the resolver cannot be nil"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			g, err := snowflake.New(c.opt)
+			if err == nil {
+				tt.Fatal("New should return an error")
+			}
+			if g != nil {
+				tt.Error("New should not return a generator on error")
+			}
+			if !strings.Contains(err.Error(), c.msg) {
+				tt.Errorf("The error message should contain [%s], got [%s]", c.msg, err)
+			}
+		})
+	}
+}
+
+func TestWithTimeUnit(t *testing.T) {
+	cases := []struct {
+		unit   time.Duration
+		layout snowflake.Layout
+	}{
+		{time.Millisecond, snowflake.DefaultLayout},
+		{10 * time.Millisecond, snowflake.Layout{TimestampBits: 39, MachineBits: 12, SequenceBits: 12}},
+		{time.Second, snowflake.Layout{TimestampBits: 32, MachineBits: 16, SequenceBits: 15}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.unit.String(), func(tt *testing.T) {
+			start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			g, err := snowflake.New(snowflake.WithTimeUnit(c.unit), snowflake.WithLayout(c.layout), snowflake.WithStartTime(start))
+			if err != nil {
+				tt.Fatal(err)
+			}
+
+			now := time.Now()
+			sid := g.ParseID(g.ID())
+
+			elapsed := uint64(now.Sub(start) / c.unit)
+			if sid.Timestamp < elapsed || sid.Timestamp > elapsed+1 {
+				tt.Errorf("The timestamp should be counted in %s, got %d, want %d", c.unit, sid.Timestamp, elapsed)
+			}
+
+			gt := sid.GenerateTime()
+			if gt.Sub(start)%c.unit != 0 {
+				tt.Error("The generate time should have the granularity of the time unit", gt)
+			}
+			if d := now.Sub(gt); d < -c.unit || d > c.unit {
+				tt.Error("The id generate time should be equal current time", gt)
+			}
+		})
+	}
+
+	if _, err := snowflake.New(snowflake.WithTimeUnit(0)); err == nil {
+		t.Error("The time unit must be positive")
+	}
+}
+
+func TestWithTimeUnit_WaitForNextTick(t *testing.T) {
+	cases := []struct {
+		unit time.Duration
+		n    int
+	}{
+		{time.Millisecond, 20},
+		{10 * time.Millisecond, 20},
+		{time.Second, 4},
+	}
+
+	for _, c := range cases {
+		t.Run(c.unit.String(), func(tt *testing.T) {
+			// only sequence 0 and 1 are available, so every other id needs a new tick.
+			g, err := snowflake.New(
+				snowflake.WithTimeUnit(c.unit),
+				snowflake.WithLayout(snowflake.Layout{TimestampBits: 46, MachineBits: 16, SequenceBits: 1}),
+				snowflake.WithStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+			)
+			if err != nil {
+				tt.Fatal(err)
+			}
+
+			timestamps := make([]uint64, c.n)
+			for i := range timestamps {
+				sid := g.ParseID(g.ID())
+				if sid.Sequence > 1 {
+					tt.Fatal("Sequence should be 0 or 1")
+				}
+				if i > 1 && sid.Timestamp <= timestamps[i-2] {
+					tt.Fatal("The generator should wait for the next tick")
+				}
+				timestamps[i] = sid.Timestamp
+			}
+		})
+	}
+}
+
+func TestWithDescending(t *testing.T) {
+	g, err := snowflake.New(snowflake.WithDescending(), snowflake.WithMachineID(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []uint64
+	var times []time.Time
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		times = append(times, time.Now())
+		ids = append(ids, g.ID())
+	}
+
+	for i := 1; i < len(ids); i++ {
+		if ids[i] >= ids[i-1] {
+			t.Error("The later id should be smaller", ids[i-1], ids[i])
+		}
+	}
+
+	for i, id := range ids {
+		sid := g.ParseID(id)
+		if sid.MachineID != 3 {
+			t.Error("MachineID should be equal 3")
+		}
+		if d := sid.GenerateTime().Sub(times[i]); d < -time.Millisecond || d > 50*time.Millisecond {
+			t.Error("The id generate time should be equal the wall time", sid.GenerateTime(), times[i])
+		}
+	}
+
+	// the same timestamp part decodes differently without the descending mode.
+	asc, err := snowflake.New(snowflake.WithMachineID(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sid := asc.ParseID(ids[0]); sid.Timestamp == g.ParseID(ids[0]).Timestamp {
+		t.Error("The timestamp part should be inverted")
+	}
+}
+
+func TestWithMaxBackwardTolerance(t *testing.T) {
+	if _, err := snowflake.New(snowflake.WithMaxBackwardTolerance(500 * time.Millisecond)); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := snowflake.New(snowflake.WithMaxBackwardTolerance(-time.Second)); err == nil {
+		t.Error("The tolerance cannot be negative")
+	}
+}
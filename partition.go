@@ -0,0 +1,93 @@
+package snowflake
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Partition carves the machineID space statically between regions, e.g. us-east 0-169, eu-west 170-339
+// and ap-south 340-511, so the allocators of a region only lease machineIDs of its range:
+//
+//	p, err := snowflake.NewPartition("eu-west", map[string][2]uint16{
+//		"us-east":  {0, 169},
+//		"eu-west":  {170, 339},
+//		"ap-south": {340, 511},
+//	})
+//	min, max := p.Range()
+//	lease, err := filelease.Allocate(filelease.Options{Min: min, Max: max})
+//
+// The ranges are inclusive, every region must get the same ranges so RegionOf attributes the IDs consistently.
+type Partition struct {
+	region string
+	ranges []regionRange
+}
+
+// regionRange is the inclusive range of machineIDs of a region.
+type regionRange struct {
+	region   string
+	min, max uint16
+}
+
+// NewPartition create the Partition of region, ranges maps each region to its inclusive range of machineIDs.
+// It returns an error when region has no range, a range is reversed or two ranges overlap.
+func NewPartition(region string, ranges map[string][2]uint16) (*Partition, error) {
+	p := &Partition{region: region}
+	for name, r := range ranges {
+		if r[0] > r[1] {
+			return nil, fmt.Errorf("snowflake: invalid range %d-%d of region %s", r[0], r[1], name)
+		}
+		p.ranges = append(p.ranges, regionRange{region: name, min: r[0], max: r[1]})
+	}
+	if _, ok := ranges[region]; !ok {
+		return nil, fmt.Errorf("snowflake: the region %s has no range", region)
+	}
+
+	// 按起点排序后只需比较相邻的范围
+	sort.Slice(p.ranges, func(i, j int) bool {
+		return p.ranges[i].min < p.ranges[j].min
+	})
+	for i := 1; i < len(p.ranges); i++ {
+		if prev, r := p.ranges[i-1], p.ranges[i]; r.min <= prev.max {
+			return nil, fmt.Errorf("snowflake: the ranges of the regions %s %d-%d and %s %d-%d overlap",
+				prev.region, prev.min, prev.max, r.region, r.min, r.max)
+		}
+	}
+
+	return p, nil
+}
+
+// Region returns the region of the partition.
+func (p *Partition) Region() string {
+	return p.region
+}
+
+// Range returns the inclusive range of machineIDs of the region, the allocators must lease within it.
+func (p *Partition) Range() (min, max uint16) {
+	for _, r := range p.ranges {
+		if r.region == p.region {
+			return r.min, r.max
+		}
+	}
+
+	return 0, 0
+}
+
+// Contains reports whether machineID is in the range of the region.
+func (p *Partition) Contains(machineID uint16) bool {
+	min, max := p.Range()
+
+	return machineID >= min && machineID <= max
+}
+
+// RegionOf returns the region whose range contains the machineID of id, parsed with ParseID,
+// or "" when no range contains it.
+func (p *Partition) RegionOf(id uint64) string {
+	m := ParseID(id).MachineID
+	for _, r := range p.ranges {
+		if m >= uint64(r.min) && m <= uint64(r.max) {
+			return r.region
+		}
+	}
+
+	return ""
+}
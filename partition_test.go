@@ -0,0 +1,56 @@
+package snowflake_test
+
+import (
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+var regionRanges = map[string][2]uint16{
+	"us-east":  {0, 169},
+	"eu-west":  {170, 339},
+	"ap-south": {340, 511},
+}
+
+func TestNewPartition(t *testing.T) {
+	p, err := snowflake.NewPartition("eu-west", regionRanges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if min, max := p.Range(); min != 170 || max != 339 {
+		t.Errorf("The range of eu-west should be 170-339, got %d-%d", min, max)
+	}
+	if !p.Contains(170) || !p.Contains(339) || p.Contains(340) {
+		t.Error("The range should be inclusive")
+	}
+
+	for m, want := range map[uint16]string{0: "us-east", 169: "us-east", 200: "eu-west", 511: "ap-south"} {
+		g, err := snowflake.New(snowflake.WithMachineID(m))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.RegionOf(g.ID()); got != want {
+			t.Errorf("The ID of machineID %d should be attributed to %s, got %q", m, want, got)
+		}
+	}
+
+	p, _ = snowflake.NewPartition("us-east", map[string][2]uint16{"us-east": {0, 9}})
+	g, _ := snowflake.New(snowflake.WithMachineID(10))
+	if got := p.RegionOf(g.ID()); got != "" {
+		t.Errorf("An ID outside the ranges should have no region, got %q", got)
+	}
+}
+
+func TestNewPartition_Invalid(t *testing.T) {
+	cases := map[string]map[string][2]uint16{
+		"overlap":  {"us-east": {0, 170}, "eu-west": {170, 339}},
+		"nested":   {"us-east": {0, 511}, "eu-west": {170, 339}},
+		"reversed": {"us-east": {169, 0}},
+		"missing":  {"eu-west": {170, 339}},
+	}
+	for name, ranges := range cases {
+		if _, err := snowflake.NewPartition("us-east", ranges); err == nil {
+			t.Errorf("%s: the ranges should be rejected", name)
+		}
+	}
+}
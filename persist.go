@@ -0,0 +1,166 @@
+package snowflake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// State is the generator state persisted across process restarts.
+type State struct {
+	// LastTimestamp is the time the generator may have issued IDs up to,
+	// a restarted generator refuses to generate IDs until the clock is past it.
+	LastTimestamp time.Time `json:"last_timestamp"`
+}
+
+// StatePersister saves and loads the generator State, see WithStatePersister.
+type StatePersister interface {
+	// Load returns the saved state, a zero State when nothing was saved yet.
+	Load() (State, error)
+	// Save persist the state durably.
+	Save(s State) error
+}
+
+// FileStatePersister is a StatePersister which stores the state as JSON in a file,
+// the file is replaced atomically so a crash never leaves a partial state.
+type FileStatePersister struct {
+	path string
+}
+
+// NewFileStatePersister create a FileStatePersister writing to path.
+func NewFileStatePersister(path string) *FileStatePersister {
+	return &FileStatePersister{path: path}
+}
+
+// Load implements StatePersister, a missing file is a zero State.
+func (p *FileStatePersister) Load() (State, error) {
+	var s State
+
+	b, err := ioutil.ReadFile(p.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+
+	if err := json.Unmarshal(b, &s); err != nil {
+		return s, fmt.Errorf("snowflake: invalid state file %s: %w", p.path, err)
+	}
+
+	return s, nil
+}
+
+// Save implements StatePersister, it writes a temporary file, syncs it and renames it to the path.
+func (p *FileStatePersister) Save(s State) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(p.path), filepath.Base(p.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, p.path)
+}
+
+// WithStatePersister persist the generator state with p, so a restarted process never re-issues IDs,
+// even when it restarts within the same millisecond or the clock stepped backward while it was down.
+//
+// The generator reserves the time up to interval ahead and saves it before issuing IDs in it,
+// so it saves at most once per interval while generating IDs, a larger interval bounds the fsync cost
+// but a restarted generator may have to wait up to interval. Close saves the actual last timestamp.
+// At startup New loads the state, until the clock is past the saved time the backward policy applies.
+func WithStatePersister(p StatePersister, interval time.Duration) Option {
+	return func(g *Generator) error {
+		if p == nil {
+			return errors.New("snowflake: invalid option WithStatePersister: the persister cannot be nil")
+		}
+		if interval <= 0 {
+			return fmt.Errorf("snowflake: invalid option WithStatePersister: the interval must be positive, got %s", interval)
+		}
+		g.persister, g.persistInterval = p, interval
+
+		return nil
+	}
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// loadState load the persisted state, the generator treats the saved time as its last timestamp.
+func (g *Generator) loadState() error {
+	s, err := g.persister.Load()
+	if err != nil {
+		return fmt.Errorf("snowflake: cannot load the state: %w", err)
+	}
+
+	if !s.LastTimestamp.IsZero() {
+		// 重启后必须等到时钟越过已保存的时间
+		g.lastTimestamp = toTicks(s.LastTimestamp, g.timeUnit) + 1
+	}
+
+	return g.OnClose(func(ctx context.Context) error {
+		g.persistMu.Lock()
+		defer g.persistMu.Unlock()
+
+		last := atomic.LoadInt64(&g.lastTimestamp)
+		return g.persister.Save(State{LastTimestamp: g.tickTime(last)})
+	})
+}
+
+// reserve make sure the persisted state covers the tick now.
+func (g *Generator) reserve(now int64) error {
+	if now <= atomic.LoadInt64(&g.persistReserved) {
+		return nil
+	}
+
+	g.persistMu.Lock()
+	defer g.persistMu.Unlock()
+
+	if now <= g.persistReserved {
+		return nil
+	}
+
+	ticks := int64(g.persistInterval / g.timeUnit)
+	if ticks < 1 {
+		ticks = 1
+	}
+	reserved := now + ticks
+	if err := g.persister.Save(State{LastTimestamp: g.tickTime(reserved)}); err != nil {
+		return fmt.Errorf("snowflake: cannot save the state: %w", err)
+	}
+	atomic.StoreInt64(&g.persistReserved, reserved)
+
+	return nil
+}
+
+// tickTime returns the time at the start of the tick.
+func (g *Generator) tickTime(tick int64) time.Time {
+	return time.Unix(0, tick*int64(g.timeUnit)).UTC()
+}
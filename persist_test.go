@@ -0,0 +1,143 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/clocktest"
+)
+
+func newPersistedGenerator(t *testing.T, path string, clock snowflake.Clock) *snowflake.Generator {
+	t.Helper()
+
+	g, err := snowflake.New(
+		snowflake.WithClock(clock),
+		snowflake.WithMaxBackwardTolerance(0),
+		snowflake.WithStatePersister(snowflake.NewFileStatePersister(path), time.Second),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return g
+}
+
+func TestWithStatePersister_Crash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snowflake.state")
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	g := newPersistedGenerator(t, path, clock)
+	var last uint64
+	for i := 0; i < 10; i++ {
+		last = g.ID()
+		clock.Advance(100 * time.Millisecond)
+	}
+
+	// crash without Close, then restart within the reserved second.
+	clock.Advance(-500 * time.Millisecond)
+	g = newPersistedGenerator(t, path, clock)
+	if _, err := g.NextID(); !errors.Is(err, snowflake.ErrClockMovedBackward) {
+		t.Fatalf("The restarted generator should refuse to generate IDs before the persisted time, got %v", err)
+	}
+
+	clock.Advance(time.Second)
+	id, err := g.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id <= last {
+		t.Errorf("The restarted generator should not re-issue IDs, got %d after %d", id, last)
+	}
+}
+
+func TestWithStatePersister_Close(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snowflake.state")
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	g := newPersistedGenerator(t, path, clock)
+	last := g.ID()
+	if err := g.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Close saves the actual last timestamp, a restart in the next millisecond doesn't wait for the reservation.
+	clock.Advance(time.Millisecond)
+	g = newPersistedGenerator(t, path, clock)
+	id, err := g.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id <= last {
+		t.Errorf("The restarted generator should not re-issue IDs, got %d after %d", id, last)
+	}
+
+	// a restart in the same millisecond must refuse.
+	if err := g.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	g = newPersistedGenerator(t, path, clock)
+	if _, err := g.NextID(); !errors.Is(err, snowflake.ErrClockMovedBackward) {
+		t.Errorf("The restarted generator should refuse the persisted millisecond, got %v", err)
+	}
+}
+
+func TestWithStatePersister_Interval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snowflake.state")
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	p := &countingPersister{StatePersister: snowflake.NewFileStatePersister(path)}
+
+	g, err := snowflake.New(snowflake.WithClock(clock), snowflake.WithStatePersister(p, time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		g.ID()
+		clock.Advance(25 * time.Millisecond)
+	}
+
+	// 100 IDs over 2.5 seconds need 3 reservations.
+	if p.saves != 3 {
+		t.Errorf("The state should be saved once per interval, saved %d times", p.saves)
+	}
+}
+
+func TestFileStatePersister(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snowflake.state")
+	p := snowflake.NewFileStatePersister(path)
+
+	s, err := p.Load()
+	if err != nil || !s.LastTimestamp.IsZero() {
+		t.Fatalf("A missing file should be a zero state, got %+v, %v", s, err)
+	}
+
+	want := snowflake.State{LastTimestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if err := p.Save(want); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := p.Load(); err != nil || !s.LastTimestamp.Equal(want.LastTimestamp) {
+		t.Errorf("The state should round trip, got %+v, %v", s, err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := snowflake.New(snowflake.WithStatePersister(p, time.Second)); err == nil {
+		t.Error("A corrupted state should fail New")
+	}
+}
+
+type countingPersister struct {
+	snowflake.StatePersister
+	saves int
+}
+
+func (p *countingPersister) Save(s snowflake.State) error {
+	p.saves++
+	return p.StatePersister.Save(s)
+}
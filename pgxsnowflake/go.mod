@@ -0,0 +1,14 @@
+module github.com/hedwi/go-snowflake/pgxsnowflake
+
+go 1.25.0
+
+require (
+	github.com/hedwi/go-snowflake v0.0.0-20261015084624-fec5baa67a53
+	github.com/jackc/pgx/v5 v5.11.0
+)
+
+require (
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	golang.org/x/text v0.29.0 // indirect
+)
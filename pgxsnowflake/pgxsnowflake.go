@@ -0,0 +1,128 @@
+// Package pgxsnowflake maps the snowflake IDs to the BIGINT columns of postgres with pgx v5, in the binary and text formats.
+// Register the codec on every connection, e.g. with the AfterConnect hook of a pool:
+//
+//	config, err := pgxpool.ParseConfig(dsn)
+//	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+//		pgxsnowflake.Register(conn.TypeMap())
+//		return nil
+//	}
+//
+// Then snowflake.TID and snowflake.NullTID are query arguments and scan targets, []snowflake.TID maps to BIGINT[].
+// It is a separate module, so the snowflake module doesn't depend on pgx.
+package pgxsnowflake
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// Codec is the int8 codec of pgx which encodes and decodes snowflake.TID and snowflake.NullTID,
+// the other values are handled by pgtype.Int8Codec.
+//
+// The IDs above math.MaxInt64 don't fit the signed BIGINT, encoding them is an error instead of a negative number,
+// as decoding a negative number into an ID. NULL decodes into an invalid NullTID, and is an error for a TID.
+type Codec struct {
+	pgtype.Int8Codec
+}
+
+// Register replace the int8 codec of m with Codec, and make int8 the postgres type of snowflake.TID and snowflake.NullTID,
+// for the arguments whose type postgres doesn't describe, e.g. with the simple protocol.
+// It must be called before m is used, e.g. in the AfterConnect hook of a pool.
+func Register(m *pgtype.Map) {
+	int8Type := &pgtype.Type{Name: "int8", OID: pgtype.Int8OID, Codec: Codec{}}
+	m.RegisterType(int8Type)
+	m.RegisterType(&pgtype.Type{Name: "_int8", OID: pgtype.Int8ArrayOID, Codec: &pgtype.ArrayCodec{ElementType: int8Type}})
+
+	m.RegisterDefaultPgType(snowflake.TID(0), "int8")
+	m.RegisterDefaultPgType(snowflake.NullTID{}, "int8")
+	m.RegisterDefaultPgType([]snowflake.TID(nil), "_int8")
+}
+
+// PlanEncode returns the plan of the IDs, or the plan of pgtype.Int8Codec for the other values.
+func (c Codec) PlanEncode(m *pgtype.Map, oid uint32, format int16, value interface{}) pgtype.EncodePlan {
+	switch value.(type) {
+	case snowflake.TID, snowflake.NullTID:
+		if next := c.Int8Codec.PlanEncode(m, oid, format, int64(0)); next != nil {
+			return encodePlan{next}
+		}
+	}
+
+	return c.Int8Codec.PlanEncode(m, oid, format, value)
+}
+
+// PlanScan returns the plan of the IDs, or the plan of pgtype.Int8Codec for the other targets.
+func (c Codec) PlanScan(m *pgtype.Map, oid uint32, format int16, target interface{}) pgtype.ScanPlan {
+	switch target.(type) {
+	case *snowflake.TID, *snowflake.NullTID:
+		if next := c.Int8Codec.PlanScan(m, oid, format, (*int64)(nil)); next != nil {
+			return scanPlan{next}
+		}
+	}
+
+	return c.Int8Codec.PlanScan(m, oid, format, target)
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// encodePlan encode an ID as the int64 of its Value, next encodes the int64.
+type encodePlan struct {
+	next pgtype.EncodePlan
+}
+
+func (p encodePlan) Encode(value interface{}, buf []byte) ([]byte, error) {
+	var v interface{}
+	var err error
+	switch value := value.(type) {
+	case snowflake.TID:
+		v, err = value.Value()
+	case snowflake.NullTID:
+		v, err = value.Value()
+	default:
+		return nil, fmt.Errorf("pgxsnowflake: cannot encode %T", value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		// nil 表示 NULL
+		return nil, nil
+	}
+
+	return p.next.Encode(v, buf)
+}
+
+// scanPlan scan an int64 with next, then the ID with its Scan.
+type scanPlan struct {
+	next pgtype.ScanPlan
+}
+
+func (p scanPlan) Scan(src []byte, target interface{}) error {
+	switch target := target.(type) {
+	case *snowflake.TID:
+		if src == nil {
+			return errors.New("pgxsnowflake: cannot scan NULL into a TID, use a NullTID")
+		}
+		var n int64
+		if err := p.next.Scan(src, &n); err != nil {
+			return err
+		}
+		return target.Scan(n)
+	case *snowflake.NullTID:
+		if src == nil {
+			return target.Scan(nil)
+		}
+		var n int64
+		if err := p.next.Scan(src, &n); err != nil {
+			return err
+		}
+		return target.Scan(n)
+	default:
+		return fmt.Errorf("pgxsnowflake: cannot scan into %T", target)
+	}
+}
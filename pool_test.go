@@ -0,0 +1,96 @@
+package snowflake_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/clocktest"
+)
+
+func TestWithMachineIDPool(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	g, err := snowflake.New(
+		snowflake.WithClock(clock),
+		snowflake.WithLayout(snowflake.Layout{TimestampBits: 52, MachineBits: 9, SequenceBits: 2}),
+		snowflake.WithMachineIDPool([]uint16{3, 4, 5}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.MachineID() != 3 {
+		t.Errorf("MachineID should be the first machineID of the pool, got %d", g.MachineID())
+	}
+
+	// the 4 sequences of a tick are multiplied by the 3 machineIDs of the pool.
+	seen := make(map[uint64]bool)
+	machineIDs := make(map[uint64]int)
+	var tick uint64
+	for i := 0; i < 12; i++ {
+		id, err := g.NextIDNoWait()
+		if err != nil {
+			t.Fatalf("The pool should serve 12 IDs in a tick, failed at %d: %v", i, err)
+		}
+		if seen[id] {
+			t.Fatalf("Duplicate ID %d", id)
+		}
+		seen[id] = true
+		sid := g.ParseID(id)
+		if i == 0 {
+			tick = sid.Timestamp
+		}
+		if sid.Timestamp != tick {
+			t.Fatal("The IDs should be in the same tick")
+		}
+		machineIDs[sid.MachineID]++
+	}
+	if machineIDs[3] != 4 || machineIDs[4] != 4 || machineIDs[5] != 4 {
+		t.Errorf("Each machineID should serve its 4 sequences, got %v", machineIDs)
+	}
+	if _, err := g.NextIDNoWait(); !errors.Is(err, snowflake.ErrSequenceExhausted) {
+		t.Errorf("The error should be ErrSequenceExhausted once the pool is exhausted, got %v", err)
+	}
+
+	stats := g.Stats()
+	if stats.PoolRotations != 2 || len(stats.PoolSlots) != 3 || stats.PoolSlots[1] != (snowflake.PoolSlot{MachineID: 4, IDs: 4}) {
+		t.Errorf("The stats should count the usage of the pool, got %+v", stats)
+	}
+
+	// the next tick starts with the current machineID.
+	clock.Advance(time.Millisecond)
+	id, err := g.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := g.ParseID(id).MachineID; m != 5 {
+		t.Errorf("The next tick should use the current machineID, got %d", m)
+	}
+	if err := g.SwitchMachineID(6); err == nil {
+		t.Error("The machineID of a pool should not be switched")
+	}
+}
+
+func TestWithMachineIDPool_Invalid(t *testing.T) {
+	cases := map[string][]snowflake.Option{
+		"empty":       {snowflake.WithMachineIDPool(nil)},
+		"duplicated":  {snowflake.WithMachineIDPool([]uint16{1, 1})},
+		"too large":   {snowflake.WithMachineIDPool([]uint16{1, snowflake.MaxMachineID + 1})},
+		"machineID":   {snowflake.WithMachineIDPool([]uint16{1, 2}), snowflake.WithMachineID(0)},
+		"resolver":    {snowflake.WithMachineIDPool([]uint16{1, 2}), snowflake.WithSequenceResolver(snowflake.AtomicResolver)},
+		"chain":       {snowflake.WithMachineIDPool([]uint16{1, 2}), snowflake.WithResolverChain(snowflake.ChainResolver(snowflake.NewAtomicResolver()), 9)},
+		"chain first": {snowflake.WithResolverChain(snowflake.ChainResolver(snowflake.NewAtomicResolver()), 9), snowflake.WithMachineIDPool([]uint16{1, 2})},
+	}
+	for name, opts := range cases {
+		if _, err := snowflake.New(opts...); err == nil {
+			t.Errorf("%s: the pool should be rejected", name)
+		}
+	}
+
+	// the chain is reported as such, not as a custom resolver.
+	chain := snowflake.ChainResolver(snowflake.NewAtomicResolver())
+	if _, err := snowflake.New(snowflake.WithResolverChain(chain, 9), snowflake.WithMachineIDPool([]uint16{1, 2})); err == nil || !strings.Contains(err.Error(), "WithResolverChain") {
+		t.Errorf("The conflict with the chain should be reported, got %v", err)
+	}
+}
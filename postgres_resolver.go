@@ -0,0 +1,77 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// postgresTimeout is the timeout of a query of the postgres resolver.
+	postgresTimeout = 100 * time.Millisecond
+	// postgresRetention is how long the rows of the past milliseconds are kept before they are deleted.
+	postgresRetention = time.Minute
+	// defaultPostgresBlockSize is the block size of NewPostgresResolver when blockSize < 1.
+	defaultPostgresBlockSize = 64
+)
+
+const (
+	postgresCreateTable = `CREATE UNLOGGED TABLE IF NOT EXISTS snowflake_sequences (
+	machine_id integer NOT NULL,
+	ms bigint NOT NULL,
+	n integer NOT NULL,
+	PRIMARY KEY (machine_id, ms)
+)`
+	postgresIncr = `INSERT INTO snowflake_sequences (machine_id, ms, n) VALUES ($1, $2, $3)
+ON CONFLICT (machine_id, ms) DO UPDATE SET n = snowflake_sequences.n + EXCLUDED.n
+RETURNING n`
+	postgresCleanup = `DELETE FROM snowflake_sequences WHERE machine_id = $1 AND ms < $2`
+)
+
+// NewPostgresResolver create a sequence resolver which counts the sequences in the unlogged table snowflake_sequences
+// of db, keyed by machineID and millisecond, so the processes sharing a machineID get unique sequences.
+// The upsert of a millisecond row reserves a block of blockSize sequences, 64 when blockSize < 1,
+// to amortize the round trips, the sequences left in a block when the millisecond changes are discarded.
+// The rows older than a minute are deleted from time to time.
+//
+// NewPostgresResolver creates the table if needed, db must use a postgres driver, e.g. github.com/lib/pq.
+// When the database is down the resolver returns a *ResolverError matching ErrResolverUnavailable,
+// so the callers can fall back to another resolver, see ChainResolver.
+func NewPostgresResolver(db *sql.DB, machineID uint16, blockSize int) (SequenceResolver, error) {
+	if blockSize < 1 {
+		blockSize = defaultPostgresBlockSize
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*postgresTimeout)
+	defer cancel()
+	if _, err := db.ExecContext(ctx, postgresCreateTable); err != nil {
+		return nil, &ResolverError{Resolver: "postgres", Err: err}
+	}
+
+	// cleanupAt is the tick of the next cleanup, accessed atomically.
+	var cleanupAt int64
+	incr := func(ctx context.Context, ms int64, machineID uint16, n int64) (int64, error) {
+		ctx, cancel := context.WithTimeout(ctx, postgresTimeout)
+		defer cancel()
+
+		var end int64
+		if err := db.QueryRowContext(ctx, postgresIncr, machineID, ms, n).Scan(&end); err != nil {
+			return 0, &ResolverError{Resolver: "postgres", Err: err}
+		}
+
+		// ms 的单位取决于生成器，这里按毫秒估算保留时间，清理失败不影响生成
+		retention := int64(postgresRetention / time.Millisecond)
+		if next := atomic.LoadInt64(&cleanupAt); ms >= next && atomic.CompareAndSwapInt64(&cleanupAt, next, ms+retention) {
+			_, _ = db.ExecContext(ctx, postgresCleanup, machineID, ms-retention)
+		}
+
+		return end, nil
+	}
+
+	r := newStoreResolver(incr, blockSize)
+
+	return func(ms int64) (uint16, error) {
+		return r.Resolve(context.Background(), ms, machineID)
+	}, nil
+}
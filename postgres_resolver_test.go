@@ -0,0 +1,38 @@
+package snowflake_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// downDriver is a database/sql driver whose database is always down.
+type downDriver struct{}
+
+func (downDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("dial tcp 127.0.0.1:5432: connect: connection refused")
+}
+
+func init() {
+	sql.Register("snowflake-down", downDriver{})
+}
+
+func TestPostgresResolver_Unavailable(t *testing.T) {
+	db, err := sql.Open("snowflake-down", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = snowflake.NewPostgresResolver(db, 7, 64)
+	if !errors.Is(err, snowflake.ErrResolverUnavailable) {
+		t.Fatalf("The error should be ErrResolverUnavailable, got %v", err)
+	}
+	var re *snowflake.ResolverError
+	if !errors.As(err, &re) || re.Resolver != "postgres" {
+		t.Errorf("The error should be a postgres ResolverError, got %v", err)
+	}
+}
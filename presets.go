@@ -0,0 +1,46 @@
+package snowflake
+
+import "time"
+
+// TwitterLayout is the layout of the original Twitter snowflake: 41 bits timestamp, 10 bits worker and 12 bits sequence.
+var TwitterLayout = Layout{TimestampBits: 41, MachineBits: 10, SequenceBits: 12}
+
+// TwitterEpoch is the epoch of the original Twitter snowflake, 1288834974657 millis (2010-11-04 01:42:54.657 UTC).
+var TwitterEpoch = time.Unix(0, 1288834974657*int64(time.Millisecond)).UTC()
+
+// NewTwitterCompatible create a Generator which generates and parses IDs bit-for-bit compatible with the original Twitter snowflake,
+// the workerID must not be greater than 2^10-1. The extra options are applied after the preset.
+func NewTwitterCompatible(workerID uint16, opts ...Option) (*Generator, error) {
+	preset := []Option{
+		WithLayout(TwitterLayout),
+		WithStartTime(TwitterEpoch),
+		WithMachineID(workerID),
+	}
+
+	return New(append(preset, opts...)...)
+}
+
+// SonyflakeLayout is the layout of Sonyflake: 39 bits timestamp in 10 msec units, 8 bits sequence and 16 bits machineID,
+// note the sequence is above the machineID.
+var SonyflakeLayout = Layout{TimestampBits: 39, MachineBits: 16, SequenceBits: 8, Order: OrderTimestampSequenceMachine}
+
+// SonyflakeEpoch is the default start time of Sonyflake, 2014-09-01 00:00:00 UTC.
+var SonyflakeEpoch = time.Date(2014, 9, 1, 0, 0, 0, 0, time.UTC)
+
+// SonyflakeTimeUnit is the time unit of Sonyflake, 10 msec.
+const SonyflakeTimeUnit = 10 * time.Millisecond
+
+// NewSonyflakeCompatible create a Generator which generates and parses IDs compatible with Sonyflake,
+// the timestamp is counted in 10 msec units, so GenerateTime has a 10 msec granularity.
+// A Sonyflake generator can issue 256 IDs per 10 msec for each machine, and has a life cycle of 174 years.
+// The extra options are applied after the preset, e.g. WithStartTime to use the start time of an existing Sonyflake.
+func NewSonyflakeCompatible(machineID uint16, opts ...Option) (*Generator, error) {
+	preset := []Option{
+		WithLayout(SonyflakeLayout),
+		WithStartTime(SonyflakeEpoch),
+		WithTimeUnit(SonyflakeTimeUnit),
+		WithMachineID(machineID),
+	}
+
+	return New(append(preset, opts...)...)
+}
@@ -0,0 +1,167 @@
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestNewTwitterCompatible(t *testing.T) {
+	g, err := snowflake.NewTwitterCompatible(1023)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := g.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// twitter: timestamp = id >> 22, worker = id >> 12 & 0x3FF, sequence = id & 0xFFF.
+	sid := g.ParseID(id)
+	if sid.Timestamp != id>>22 || sid.MachineID != 1023 || sid.MachineID != id>>12&0x3FF || sid.Sequence != id&0xFFF {
+		t.Error("The id should be bit-for-bit compatible with twitter snowflake", sid)
+	}
+
+	ms := int64(id>>22) + 1288834974657
+	if d := time.Now().UnixNano()/1e6 - ms; d < 0 || d > 1000 {
+		t.Error("The id should use the twitter epoch")
+	}
+
+	if _, err := snowflake.NewTwitterCompatible(1024); err == nil {
+		t.Error("The workerID cannot be greater than 1023")
+	}
+}
+
+func TestNewTwitterCompatible_ParseID(t *testing.T) {
+	g, err := snowflake.NewTwitterCompatible(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		id       uint64
+		time     time.Time
+		worker   uint64
+		sequence uint64
+	}{
+		// the first millisecond of the twitter epoch.
+		{0, time.Date(2010, 11, 4, 1, 42, 54, 657e6, time.UTC), 0, 0},
+		// (1<<22) | (1<<12) | 1: one millisecond after the epoch, worker 1, sequence 1.
+		{4198401, time.Date(2010, 11, 4, 1, 42, 54, 658e6, time.UTC), 1, 1},
+		// 1000000000000000000 >> 22 = 238418579101 millis after the epoch, worker 576.
+		{1000000000000000000, time.Date(2018, 5, 25, 13, 5, 53, 758e6, time.UTC), 576, 0},
+		// the max value of every part.
+		{1<<63 - 1, time.Date(2080, 7, 10, 17, 30, 30, 208e6, time.UTC), 1023, 4095},
+	}
+
+	for _, c := range cases {
+		sid := g.ParseID(c.id)
+		if !sid.GenerateTime().Equal(c.time) {
+			t.Errorf("%d: the generate time should be equal %s, got %s", c.id, c.time, sid.GenerateTime())
+		}
+		if sid.MachineID != c.worker {
+			t.Errorf("%d: the worker should be equal %d, got %d", c.id, c.worker, sid.MachineID)
+		}
+		if sid.Sequence != c.sequence {
+			t.Errorf("%d: the sequence should be equal %d, got %d", c.id, c.sequence, sid.Sequence)
+		}
+	}
+}
+
+// TestNewTwitterCompatible_PublishedTweets parse the IDs of published tweets, their created_at has a second precision.
+func TestNewTwitterCompatible_PublishedTweets(t *testing.T) {
+	g, err := snowflake.NewTwitterCompatible(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		id        uint64
+		createdAt string
+	}{
+		// @TwitterDev, the example tweet of the API v1.1 docs.
+		{850006245121695744, "Thu Apr 06 15:24:15 +0000 2017"},
+		// @TwitterDev, the example tweet of the API v1.1 tweet object docs.
+		{1050118621198921728, "Wed Oct 10 20:19:24 +0000 2018"},
+		// @TwitterDev, the example tweet of the API v2 docs.
+		{1228393702244134912, "Fri Feb 14 19:00:55 +0000 2020"},
+	}
+
+	for _, c := range cases {
+		want, err := time.Parse(time.RubyDate, c.createdAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sid := g.ParseID(c.id)
+		if got := sid.GenerateTime().Truncate(time.Second); !got.Equal(want) {
+			t.Errorf("%d: the generate time should be %s, got %s", c.id, want, got)
+		}
+	}
+}
+
+func TestNewSonyflakeCompatible(t *testing.T) {
+	g, err := snowflake.NewSonyflakeCompatible(0xBEEF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		id, err := g.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// sonyflake: time = id >> 24, sequence = id >> 16 & 0xFF, machine = id & 0xFFFF.
+		sid := g.ParseID(id)
+		if sid.Timestamp != id>>24 || sid.Sequence != id>>16&0xFF || sid.MachineID != id&0xFFFF || sid.MachineID != 0xBEEF {
+			t.Fatal("The id should be compatible with sonyflake", sid)
+		}
+	}
+
+	now := time.Now()
+	sid := g.ParseID(g.ID())
+	elapsed := now.UnixNano()/1e7 - snowflake.SonyflakeEpoch.UnixNano()/1e7
+	if d := int64(sid.Timestamp) - elapsed; d < 0 || d > 100 {
+		t.Error("The timestamp should be counted in 10 msec units since the sonyflake epoch", sid.Timestamp, elapsed)
+	}
+
+	if d := sid.GenerateTime().Sub(now); d < -10*time.Millisecond || d > time.Second {
+		t.Error("The id generate time should be equal current time", sid.GenerateTime())
+	}
+	if sid.GenerateTime().Sub(snowflake.SonyflakeEpoch)%(10*time.Millisecond) != 0 {
+		t.Error("The generate time should have a 10 msec granularity")
+	}
+}
+
+func TestNewSonyflakeCompatible_ParseID(t *testing.T) {
+	g, err := snowflake.NewSonyflakeCompatible(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		elapsed  uint64
+		sequence uint64
+		machine  uint64
+	}{
+		{0, 0, 0},
+		{1, 1, 1},
+		{123456789, 200, 0xC0A8},
+		{1<<39 - 1, 255, 0xFFFF},
+	}
+
+	for _, c := range cases {
+		id := c.elapsed<<24 | c.sequence<<16 | c.machine
+		sid := g.ParseID(id)
+		if sid.Timestamp != c.elapsed || sid.Sequence != c.sequence || sid.MachineID != c.machine {
+			t.Errorf("%d: should be parsed as sonyflake, got %+v", id, sid)
+		}
+
+		want := snowflake.SonyflakeEpoch.Add(time.Duration(c.elapsed) * 10 * time.Millisecond)
+		if !sid.GenerateTime().Equal(want) {
+			t.Errorf("%d: the generate time should be equal %s, got %s", id, want, sid.GenerateTime())
+		}
+	}
+}
@@ -1,12 +1,56 @@
 package snowflake
 
 import (
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"net"
+	"path"
+	"sort"
 )
 
+// ErrNoPrivateIP is returned by MachineIDFromPrivateIP when no up interface has a private IPv4 address.
+var ErrNoPrivateIP = errors.New("snowflake: no private IPv4 address")
+
+// InterfaceFilter select the network interfaces searched for a private IPv4 address by name,
+// the names are matched with path.Match patterns, e.g. "eth*" or "docker*".
+// An empty Allow allows every interface, Deny takes precedence over Allow.
+type InterfaceFilter struct {
+	Allow []string
+	Deny  []string
+}
+
+// netInterface is a network interface of the host, listInterfaces can be replaced in tests.
+type netInterface struct {
+	name   string
+	flags  net.Flags
+	hwAddr net.HardwareAddr
+	addrs  []net.Addr
+}
+
+var listInterfaces = func() ([]netInterface, error) {
+	ifs, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]netInterface, 0, len(ifs))
+	for _, i := range ifs {
+		addrs, err := i.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, netInterface{name: i.Name, flags: i.Flags, hwAddr: i.HardwareAddr, addrs: addrs})
+	}
+
+	return list, nil
+}
+
 // PrivateIPToMachineID convert private ip to machine id.
 // From https://github.com/sony/sonyflake/blob/master/sonyflake.go
+//
+// Deprecated: the conversion collides, e.g. 10.21.5.211 and 10.21.4.212 are both 216, and it returns 0 on error.
+// Use MachineIDFromPrivateIP.
 func PrivateIPToMachineID() uint16 {
 	ip, err := lower16BitPrivateIP()
 	if err != nil {
@@ -16,29 +60,108 @@ func PrivateIPToMachineID() uint16 {
 	return ip
 }
 
+// MachineIDFromPrivateIP returns the low bits bits of the first private IPv4 address of the host,
+// e.g. 10.0.3.17 is 3<<8 | 17 = 785 with 10 bits, see InterfaceFilter.MachineIDFromPrivateIP.
+func MachineIDFromPrivateIP(bits uint8) (uint16, error) {
+	return InterfaceFilter{}.MachineIDFromPrivateIP(bits)
+}
+
+// MachineIDFromPrivateIP returns the low bits bits of the first private IPv4 address (10/8, 172.16/12, 192.168/16)
+// on an up, non-loopback interface allowed by f. The interfaces are searched in the order of their names,
+// so a host with several interfaces always gets the same machineID. It returns an error wrapping ErrNoPrivateIP
+// when there is no such address, e.g. on an IPv6-only host.
+//
+// The machineID is unique as long as the hosts sharing it are in a subnet of 2^bits addresses at most:
+// with 10 bits, the hosts of a /22 get unique IDs, but in a /16 10.0.0.5 and 10.0.4.5 get the same ID 5.
+func (f InterfaceFilter) MachineIDFromPrivateIP(bits uint8) (uint16, error) {
+	if bits == 0 || bits > 16 {
+		return 0, fmt.Errorf("snowflake: invalid machine bits %d, it must be between 1 and 16", bits)
+	}
+
+	ip, err := f.privateIPv4()
+	if err != nil {
+		return 0, err
+	}
+
+	return uint16(binary.BigEndian.Uint32(ip) & (1<<bits - 1)), nil
+}
+
+// AutoMachineID set the machineID of the generator from the private IPv4 address of the host,
+// with the machine bits of its layout, see MachineIDFromPrivateIP. New returns the error when there is no such address.
+func AutoMachineID() Option {
+	return InterfaceFilter{}.AutoMachineID()
+}
+
+// AutoMachineID is the AutoMachineID option searching the interfaces allowed by f only.
+func (f InterfaceFilter) AutoMachineID() Option {
+	return func(g *Generator) error {
+		g.derivedMachineID, g.derivedBy = f.MachineIDFromPrivateIP, "AutoMachineID"
+
+		return nil
+	}
+}
+
 //--------------------------------------------------------------------
 // private function defined.
 //--------------------------------------------------------------------
 
-func privateIPv4() (net.IP, error) {
-	as, err := net.InterfaceAddrs()
+func (f InterfaceFilter) allows(name string) bool {
+	for _, p := range f.Deny {
+		if ok, _ := path.Match(p, name); ok {
+			return false
+		}
+	}
+	if len(f.Allow) == 0 {
+		return true
+	}
+	for _, p := range f.Allow {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f InterfaceFilter) privateIPv4() (net.IP, error) {
+	ifs, err := listInterfaces()
 	if err != nil {
 		return nil, err
 	}
+	sort.Slice(ifs, func(i, j int) bool {
+		return ifs[i].name < ifs[j].name
+	})
 
-	for _, a := range as {
-		ipnet, ok := a.(*net.IPNet)
-		if !ok || ipnet.IP.IsLoopback() {
+	ipv4, ipv6 := false, false
+	for _, i := range ifs {
+		if i.flags&net.FlagUp == 0 || i.flags&net.FlagLoopback != 0 || !f.allows(i.name) {
 			continue
 		}
 
-		ip := ipnet.IP.To4()
-		if isPrivateIPv4(ip) {
-			return ip, nil
+		for _, a := range i.addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok || ipnet.IP.IsLoopback() {
+				continue
+			}
+
+			ip := ipnet.IP.To4()
+			switch {
+			case ip != nil:
+				ipv4 = true
+			case ipnet.IP.IsGlobalUnicast():
+				ipv6 = true
+			}
+			if isPrivateIPv4(ip) {
+				return ip, nil
+			}
 		}
 	}
 
-	return nil, errors.New("no private ip address")
+	if ipv6 && !ipv4 {
+		return nil, fmt.Errorf("%w: the host has IPv6 addresses only, set the machineID explicitly", ErrNoPrivateIP)
+	}
+
+	return nil, ErrNoPrivateIP
 }
 
 func isPrivateIPv4(ip net.IP) bool {
@@ -47,7 +170,7 @@ func isPrivateIPv4(ip net.IP) bool {
 }
 
 func lower16BitPrivateIP() (uint16, error) {
-	ip, err := privateIPv4()
+	ip, err := InterfaceFilter{}.privateIPv4()
 	if err != nil {
 		return 0, err
 	}
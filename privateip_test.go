@@ -1,14 +1,118 @@
 package snowflake_test
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/hedwi/go-snowflake"
 )
 
 func TestPrivateIPToMachineID(t *testing.T) {
+	if _, err := snowflake.MachineIDFromPrivateIP(16); err != nil {
+		t.Skipf("The host has no private IPv4 address: %v", err)
+	}
+
 	mid := snowflake.PrivateIPToMachineID()
 	if mid <= 0 {
 		t.Error("MachineID should be > 0")
 	}
 }
+
+func TestMachineIDFromPrivateIP(t *testing.T) {
+	// the interfaces are searched by name, not in the order of the host.
+	snowflake.SetInterfaces(t,
+		snowflake.TestInterface{Name: "lo", Addrs: []string{"127.0.0.1/8"}},
+		snowflake.TestInterface{Name: "eth1", Addrs: []string{"192.168.1.20/24"}},
+		snowflake.TestInterface{Name: "eth0", Addrs: []string{"fe80::1/64", "203.0.113.7/24", "10.0.3.17/16"}},
+		snowflake.TestInterface{Name: "docker0", Down: true, Addrs: []string{"172.17.0.1/16"}},
+	)
+
+	m, err := snowflake.MachineIDFromPrivateIP(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != 3<<8|17 {
+		t.Errorf("The machineID should be the low 10 bits of 10.0.3.17, got %d", m)
+	}
+
+	m, err = snowflake.MachineIDFromPrivateIP(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != 17 {
+		t.Errorf("The machineID should be the low 8 bits of 10.0.3.17, got %d", m)
+	}
+
+	for _, bits := range []uint8{0, 17} {
+		if _, err := snowflake.MachineIDFromPrivateIP(bits); err == nil {
+			t.Errorf("The bits %d should be rejected", bits)
+		}
+	}
+}
+
+func TestInterfaceFilter(t *testing.T) {
+	snowflake.SetInterfaces(t,
+		snowflake.TestInterface{Name: "docker0", Addrs: []string{"172.17.0.1/16"}},
+		snowflake.TestInterface{Name: "eth0", Addrs: []string{"10.0.3.17/16"}},
+		snowflake.TestInterface{Name: "wlan0", Addrs: []string{"192.168.1.20/24"}},
+	)
+
+	cases := []struct {
+		filter snowflake.InterfaceFilter
+		want   uint16
+	}{
+		{snowflake.InterfaceFilter{}, 1},
+		{snowflake.InterfaceFilter{Deny: []string{"docker*"}}, 3<<8 | 17},
+		{snowflake.InterfaceFilter{Allow: []string{"wlan*"}}, 1<<8 | 20},
+		{snowflake.InterfaceFilter{Allow: []string{"eth*", "wlan*"}, Deny: []string{"eth0"}}, 1<<8 | 20},
+	}
+	for _, c := range cases {
+		m, err := c.filter.MachineIDFromPrivateIP(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m != c.want {
+			t.Errorf("%+v: the machineID should be %d, got %d", c.filter, c.want, m)
+		}
+	}
+
+	_, err := snowflake.InterfaceFilter{Allow: []string{"ens*"}}.MachineIDFromPrivateIP(10)
+	if !errors.Is(err, snowflake.ErrNoPrivateIP) {
+		t.Errorf("The error should be ErrNoPrivateIP when no interface is allowed, got %v", err)
+	}
+}
+
+func TestMachineIDFromPrivateIP_IPv6Only(t *testing.T) {
+	snowflake.SetInterfaces(t,
+		snowflake.TestInterface{Name: "lo", Addrs: []string{"127.0.0.1/8", "::1/128"}},
+		snowflake.TestInterface{Name: "eth0", Addrs: []string{"2001:db8::5/64", "fe80::1/64"}},
+	)
+
+	_, err := snowflake.MachineIDFromPrivateIP(10)
+	if !errors.Is(err, snowflake.ErrNoPrivateIP) || !strings.Contains(err.Error(), "IPv6") {
+		t.Errorf("The error should explain the host is IPv6-only, got %v", err)
+	}
+}
+
+func TestAutoMachineID(t *testing.T) {
+	snowflake.SetInterfaces(t, snowflake.TestInterface{Name: "eth0", Addrs: []string{"10.0.3.17/16"}})
+
+	g, err := snowflake.New(snowflake.AutoMachineID(), snowflake.WithLayout(snowflake.Layout{TimestampBits: 43, MachineBits: 8, SequenceBits: 12}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := g.MachineID(); m != 17 {
+		t.Errorf("The machineID should use the machine bits of the layout, got %d", m)
+	}
+
+	if _, err := snowflake.New(snowflake.AutoMachineID(), snowflake.WithMachineID(1)); err == nil {
+		t.Error("AutoMachineID should not be used with WithMachineID")
+	}
+	if _, err := snowflake.New(snowflake.AutoMachineID(), snowflake.WithMachineID(0)); err == nil {
+		t.Error("AutoMachineID should not be used with WithMachineID(0)")
+	}
+	if _, err := snowflake.New(snowflake.InterfaceFilter{Allow: []string{"wlan*"}}.AutoMachineID()); !errors.Is(err, snowflake.ErrNoPrivateIP) {
+		t.Errorf("The error should be ErrNoPrivateIP, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// providerInitialBackoff and providerMaxBackoff bound the delay between the attempts of a failing MachineIDProvider.
+	providerInitialBackoff = 100 * time.Millisecond
+	providerMaxBackoff     = 10 * time.Second
+)
+
+// ErrMachineIDUnavailable is returned by NextID while the MachineIDProvider of the generator has not provided
+// the machineID yet, with WithMachineIDProviderNoWait, and by NextIDNoWait. It wraps the last error of the provider.
+var ErrMachineIDUnavailable = errors.New("snowflake: the machineID is not provided yet")
+
+// MachineIDProvider provides the machineID of a generator lazily, e.g. from a lease service which is not ready at startup.
+type MachineIDProvider func(ctx context.Context) (uint16, error)
+
+// WithMachineIDProvider resolve the machineID with p on the first NextID instead of in New, so main doesn't block
+// on a lease service. p is called by one goroutine at a time and retried with a backoff from 100ms to 10s
+// until it succeeds or the generator is closed, the machineID is then cached for the lifetime of the generator.
+//
+// The first NextID calls wait for the machineID until their context is done, or return an error wrapping
+// ErrMachineIDUnavailable with WithMachineIDProviderNoWait. MachineID returns 0 until the machineID is provided.
+func WithMachineIDProvider(p MachineIDProvider) Option {
+	return func(g *Generator) error {
+		if p == nil {
+			return errors.New("snowflake: invalid option WithMachineIDProvider: the provider cannot be nil")
+		}
+		g.provider = &machineIDProvider{fn: p, ready: make(chan struct{})}
+
+		return nil
+	}
+}
+
+// WithMachineIDProviderNoWait make NextID return an error wrapping ErrMachineIDUnavailable instead of waiting
+// for the MachineIDProvider, see WithMachineIDProvider.
+func WithMachineIDProviderNoWait() Option {
+	return func(g *Generator) error {
+		g.providerNoWait = true
+
+		return nil
+	}
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// machineIDProvider calls a MachineIDProvider once in the background, ready is closed when it succeeded.
+type machineIDProvider struct {
+	// provided is set to 1 once the machineID is provided, accessed atomically.
+	provided int32
+
+	fn    MachineIDProvider
+	once  sync.Once
+	ready chan struct{}
+
+	mu      sync.Mutex
+	lastErr error
+}
+
+// waitMachineID start the provider on the first call and wait for the machineID.
+func (g *Generator) waitMachineID(ctx context.Context, noWait bool) error {
+	p := g.provider
+	if atomic.LoadInt32(&p.provided) == 1 {
+		return nil
+	}
+	p.once.Do(func() {
+		go g.provideMachineID()
+	})
+
+	if noWait || g.providerNoWait {
+		select {
+		case <-p.ready:
+			return nil
+		default:
+			return p.unavailable()
+		}
+	}
+
+	select {
+	case <-p.ready:
+		return nil
+	case <-g.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return fmt.Errorf("%v: %w", p.unavailable(), ctx.Err())
+	}
+}
+
+// provideMachineID call the provider until it provides a valid machineID or the generator is closed.
+func (g *Generator) provideMachineID() {
+	p := g.provider
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-g.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	backoff := providerInitialBackoff
+	for {
+		m, err := p.fn(ctx)
+		if err == nil {
+			err = g.layout.checkMachineID(m)
+		}
+		if err == nil {
+			atomic.StoreUint64(&g.machineID, uint64(m))
+			atomic.StoreInt32(&g.machineIDSet, 1)
+			atomic.StoreInt32(&p.provided, 1)
+			close(p.ready)
+			return
+		}
+
+		p.mu.Lock()
+		p.lastErr = err
+		p.mu.Unlock()
+
+		// 失败后退避重试，不缓存错误
+		timer := time.NewTimer(RetryPolicy{Jitter: 0.2}.jitter(backoff))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		if backoff *= 2; backoff > providerMaxBackoff {
+			backoff = providerMaxBackoff
+		}
+	}
+}
+
+// unavailable returns ErrMachineIDUnavailable with the last error of the provider.
+func (p *machineIDProvider) unavailable() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.lastErr == nil {
+		return ErrMachineIDUnavailable
+	}
+
+	return fmt.Errorf("%w: %v", ErrMachineIDUnavailable, p.lastErr)
+}
@@ -0,0 +1,105 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestWithMachineIDProvider(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	provider := func(ctx context.Context) (uint16, error) {
+		n := atomic.AddInt32(&calls, 1)
+		<-release
+		if n == 1 {
+			// the lease service is not ready on the first attempt.
+			return 0, errors.New("lease service unavailable")
+		}
+		return 42, nil
+	}
+
+	g, err := snowflake.New(snowflake.WithMachineIDProvider(provider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close(context.Background())
+	if atomic.LoadInt32(&calls) != 0 {
+		t.Error("The provider should not be called by New")
+	}
+
+	// the concurrent first calls wait for one provider call.
+	var wg sync.WaitGroup
+	ids := make([]uint64, 10)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id, err := g.NextID()
+			if err != nil {
+				t.Error(err)
+			}
+			ids[i] = id
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("The provider should be retried once after its failure, got %d calls", n)
+	}
+	for _, id := range ids {
+		if m := g.ParseID(id).MachineID; m != 42 {
+			t.Errorf("The IDs should use the provided machineID, got %d", m)
+		}
+	}
+	if g.MachineID() != 42 {
+		t.Errorf("The provided machineID should be cached, got %d", g.MachineID())
+	}
+	if _, err := g.NextID(); err != nil || atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("The next calls should use the cached machineID, got %v after %d calls", err, calls)
+	}
+}
+
+func TestWithMachineIDProviderNoWait(t *testing.T) {
+	provider := func(ctx context.Context) (uint16, error) {
+		return 0, errors.New("lease service unavailable")
+	}
+	g, err := snowflake.New(snowflake.WithMachineIDProvider(provider), snowflake.WithMachineIDProviderNoWait())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = g.NextID()
+	if !errors.Is(err, snowflake.ErrMachineIDUnavailable) {
+		t.Errorf("The error should be ErrMachineIDUnavailable, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	g2, _ := snowflake.New(snowflake.WithMachineIDProvider(provider))
+	if _, err := g2.NextIDContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("The wait should end with the context, got %v", err)
+	}
+
+	// Close stops the retries and the waits.
+	if err := g.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := g2.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g2.NextID(); !errors.Is(err, snowflake.ErrClosed) {
+		t.Errorf("The error should be ErrClosed, got %v", err)
+	}
+
+	if _, err := snowflake.New(snowflake.WithMachineIDProvider(provider), snowflake.WithMachineID(1)); err == nil {
+		t.Error("WithMachineIDProvider should conflict with WithMachineID")
+	}
+}
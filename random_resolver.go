@@ -0,0 +1,48 @@
+package snowflake
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// randomStartResolver starts the sequence of every tick at a random offset and wraps around the max sequence.
+type randomStartResolver struct {
+	mu     sync.Mutex
+	ms     int64
+	offset uint32
+	// n is the count of sequences issued in ms.
+	n   uint32
+	max uint32
+}
+
+// NewRandomStartResolver create a sequence resolver which starts every tick at a random sequence in [0, maxSequence],
+// then counts up and wraps to 0, so the low bits of the IDs of a low traffic service are not always 0,
+// e.g. for the sharding schemes which mod the raw ID.
+//
+// maxSequence must be the max sequence of the generator layout, e.g. Layout.MaxSequence(),
+// the tick is exhausted once it issued maxSequence+1 sequences.
+func NewRandomStartResolver(maxSequence uint16) SequenceResolver {
+	return (&randomStartResolver{max: uint32(maxSequence)}).resolve
+}
+
+func (r *randomStartResolver) resolve(ms int64) (uint16, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ms < r.ms {
+		return 0, ErrSequenceExhausted
+	}
+	if ms > r.ms || r.n == 0 {
+		// 新的时间单位：随机选择起始序列号
+		r.ms, r.n = ms, 0
+		r.offset = uint32(rand.Int63n(int64(r.max) + 1))
+	}
+
+	if r.n > r.max {
+		return 0, ErrSequenceExhausted
+	}
+	seq := (r.offset + r.n) % (r.max + 1)
+	r.n++
+
+	return uint16(seq), nil
+}
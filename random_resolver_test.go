@@ -0,0 +1,49 @@
+package snowflake_test
+
+import (
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestRandomStartResolver_Distribution(t *testing.T) {
+	const buckets, perBucket = 16, 1000
+
+	// one ID per tick, the low bits of the sequences should be roughly uniform.
+	resolve := snowflake.NewRandomStartResolver(snowflake.MaxSequence)
+	counts := make([]int, buckets)
+	for ms := int64(1); ms <= buckets*perBucket; ms++ {
+		seq, err := resolve(ms)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[seq%buckets]++
+	}
+
+	for i, c := range counts {
+		if c < perBucket*8/10 || c > perBucket*12/10 {
+			t.Errorf("The low bits should be uniform, bucket %d holds %d of %d", i, c, buckets*perBucket)
+		}
+	}
+}
+
+func TestRandomStartResolver_Wraps(t *testing.T) {
+	// 4 sequences per tick, they should all be issued once whatever the offset.
+	resolve := snowflake.NewRandomStartResolver(3)
+	for ms := int64(1); ms <= 100; ms++ {
+		seen := make(map[uint16]bool)
+		for i := 0; i < 4; i++ {
+			seq, err := resolve(ms)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if seq > 3 || seen[seq] {
+				t.Fatalf("Sequence %d should be unique and within the max sequence", seq)
+			}
+			seen[seq] = true
+		}
+		if _, err := resolve(ms); err != snowflake.ErrSequenceExhausted {
+			t.Fatalf("The tick should be exhausted after 4 sequences, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package snowflake
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// rateLimiter is a token bucket implemented as a GCRA: tat is the theoretical arrival time of the next ID,
+// in nanoseconds of the monotonic clock, an ID is allowed while tat runs ahead of now by burst intervals at most.
+type rateLimiter struct {
+	// tat, limited and waited are accessed atomically.
+	tat     int64
+	limited uint64
+	waited  int64
+
+	interval int64
+	burst    int64
+}
+
+// WithMaxRate limit the generator to idsPerSecond IDs per second, with bursts of burst IDs at most,
+// e.g. to keep a runaway retry loop from consuming the sequence space shared with other processes through a resolver.
+// Beyond the rate NextID and NextIDContext wait for their turn, until the context is done,
+// and NextIDNoWait returns ErrRateLimited. The callers limited are counted in Stats.
+// The happy path costs a single atomic operation.
+func WithMaxRate(idsPerSecond float64, burst int) Option {
+	return func(g *Generator) error {
+		if idsPerSecond <= 0 || idsPerSecond > float64(time.Second) {
+			return fmt.Errorf("snowflake: invalid option WithMaxRate(%g, %d): the rate must be positive and at most 1e9 IDs per second", idsPerSecond, burst)
+		}
+		if burst <= 0 {
+			return fmt.Errorf("snowflake: invalid option WithMaxRate(%g, %d): the burst must be positive", idsPerSecond, burst)
+		}
+		g.limiter = &rateLimiter{interval: int64(float64(time.Second) / idsPerSecond), burst: int64(burst)}
+
+		return nil
+	}
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// wait take n tokens, it waits for the tokens unless noWait, or returns ErrRateLimited.
+func (l *rateLimiter) wait(ctx context.Context, g *Generator, noWait bool, n int) error {
+	for {
+		now := int64(time.Since(monoBase))
+		tat := atomic.LoadInt64(&l.tat)
+		next := tat
+		if next < now {
+			next = now
+		}
+		next += int64(n) * l.interval
+
+		// 超出突发容量的部分需要等待
+		delay := next - now - l.burst*l.interval
+		if delay > 0 && noWait {
+			atomic.AddUint64(&l.limited, 1)
+			return fmt.Errorf("%w: retry in %s", ErrRateLimited, time.Duration(delay))
+		}
+		if !atomic.CompareAndSwapInt64(&l.tat, tat, next) {
+			continue
+		}
+		if delay <= 0 {
+			return nil
+		}
+
+		atomic.AddUint64(&l.limited, 1)
+		atomic.AddInt64(&l.waited, delay)
+		g.sleep(ctx, time.Duration(delay))
+		if err := ctx.Err(); err != nil {
+			// 归还预留的令牌
+			atomic.AddInt64(&l.tat, -int64(n)*l.interval)
+			return err
+		}
+		if atomic.LoadInt32(&g.closed) == 1 {
+			return ErrClosed
+		}
+
+		return nil
+	}
+}
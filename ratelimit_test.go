@@ -0,0 +1,83 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestWithMaxRate(t *testing.T) {
+	g, err := snowflake.New(snowflake.WithMaxRate(100, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the burst is served immediately, then NextIDNoWait is limited.
+	for i := 0; i < 5; i++ {
+		if _, err := g.NextIDNoWait(); err != nil {
+			t.Fatalf("The burst should be served, got %v", err)
+		}
+	}
+	if _, err := g.NextIDNoWait(); !errors.Is(err, snowflake.ErrRateLimited) {
+		t.Fatalf("The error should be ErrRateLimited beyond the burst, got %v", err)
+	}
+
+	// NextID waits for its turn, 5 IDs at 100 IDs per second take ~50ms.
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := g.NextID(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("NextID should wait for the rate, took %s", elapsed)
+	}
+
+	if stats := g.Stats(); stats.RateLimited < 6 || stats.RateLimitWait <= 0 {
+		t.Errorf("The limited calls should be reported, got %+v", stats)
+	}
+}
+
+func TestWithMaxRate_Context(t *testing.T) {
+	g, err := snowflake.New(snowflake.WithMaxRate(1, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.NextID(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := g.NextIDContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("The wait should stop with the context, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("The wait should stop with the context, took %s", elapsed)
+	}
+}
+
+func TestWithMaxRate_Invalid(t *testing.T) {
+	for _, opt := range []snowflake.Option{snowflake.WithMaxRate(0, 1), snowflake.WithMaxRate(-1, 1), snowflake.WithMaxRate(10, 0)} {
+		if _, err := snowflake.New(opt); err == nil {
+			t.Error("The invalid rate should be rejected")
+		}
+	}
+}
+
+func BenchmarkWithMaxRate(b *testing.B) {
+	g, err := snowflake.New(snowflake.WithMaxRate(1e9, 1<<20))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = g.NextID()
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package snowflake
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// redisKeyTTL is the ttl of the sequence keys, a key is only used during its millisecond.
+	redisKeyTTL = 5 * time.Second
+	// redisTimeout is the timeout of a call to redis, including the retries.
+	redisTimeout = 50 * time.Millisecond
+	// redisRetries is the count of retries of a failed call to redis, with a doubling backoff from 1ms.
+	redisRetries = 2
+	// defaultRedisBlockSize is the block size of NewRedisBlockResolver when blockSize < 1.
+	defaultRedisBlockSize = 64
+)
+
+// RedisCmdable is the redis client used by the redis resolvers, implement it with any redis library.
+//
+// IncrBy must run INCRBY key n, and set the ttl of key when INCRBY created it, in one round trip,
+// e.g. with a pipeline (MULTI, INCRBY, PEXPIRE NX, EXEC) or a script. With go-redis:
+//
+//	type goRedis struct{ c *redis.Client }
+//
+//	func (r goRedis) IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+//		var incr *redis.IntCmd
+//		_, err := r.c.TxPipelined(ctx, func(p redis.Pipeliner) error {
+//			incr = p.IncrBy(ctx, key, n)
+//			p.PExpire(ctx, key, ttl)
+//			return nil
+//		})
+//		if err != nil {
+//			return 0, err
+//		}
+//		return incr.Val(), nil
+//	}
+type RedisCmdable interface {
+	IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error)
+}
+
+// NewRedisResolver create a Resolver which counts the sequences in redis with INCR,
+// so the processes sharing a machineID, e.g. the workers of a host, get unique sequences.
+// The key of a millisecond is snowflake:{machineID}:{ms}, machineID is the one passed to Resolve,
+// the key expires after 5s. Set it with WithResolver:
+//
+//	g, err := snowflake.New(snowflake.WithMachineID(42), snowflake.WithResolver(snowflake.NewRedisResolver(client)))
+//
+// Every ID costs a round trip to redis, about 100-500µs on a local network, use NewRedisBlockResolver
+// to amortize the round trips. A call is bounded by the context of NextIDContext and a 50ms timeout,
+// a failed call is retried twice with a backoff, then the resolver returns a *ResolverError matching ErrResolverUnavailable.
+func NewRedisResolver(client RedisCmdable) Resolver {
+	return newStoreResolver(redisIncr(client), 1)
+}
+
+// NewRedisBlockResolver create a Resolver like NewRedisResolver, which reserves blocks of blockSize sequences
+// with INCRBY, 64 when blockSize < 1, so a round trip to redis serves blockSize IDs of a millisecond.
+// The sequences left in a block when the millisecond or the machineID changes are discarded.
+func NewRedisBlockResolver(client RedisCmdable, blockSize int) Resolver {
+	if blockSize < 1 {
+		blockSize = defaultRedisBlockSize
+	}
+
+	return newStoreResolver(redisIncr(client), blockSize)
+}
+
+// redisIncr returns the incr function of the redis resolvers, see storeResolver.
+func redisIncr(client RedisCmdable) storeIncr {
+	return func(ctx context.Context, ms int64, machineID uint16, n int64) (int64, error) {
+		ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+		defer cancel()
+
+		key := fmt.Sprintf("snowflake:%d:%d", machineID, ms)
+		backoff := time.Millisecond
+		for i := 0; ; i++ {
+			end, err := client.IncrBy(ctx, key, n, redisKeyTTL)
+			if err == nil {
+				return end, nil
+			}
+			if i == redisRetries {
+				return 0, &ResolverError{Resolver: "redis", Err: err}
+			}
+
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-ctx.Done():
+				return 0, &ResolverError{Resolver: "redis", Err: err}
+			}
+		}
+	}
+}
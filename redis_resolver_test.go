@@ -0,0 +1,141 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// fakeRedis is an in-memory RedisCmdable, it fails the calls while down is set.
+type fakeRedis struct {
+	mu    sync.Mutex
+	keys  map[string]int64
+	calls int
+	down  bool
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{keys: make(map[string]int64)}
+}
+
+func (r *fakeRedis) IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls++
+	if r.down {
+		return 0, errors.New("dial tcp 127.0.0.1:6379: connect: connection refused")
+	}
+	r.keys[key] += n
+
+	return r.keys[key], nil
+}
+
+func TestRedisResolver_SharedMachineID(t *testing.T) {
+	redis := newFakeRedis()
+
+	// two processes sharing machineID 7 get unique sequences.
+	p1, p2 := snowflake.NewRedisResolver(redis), snowflake.NewRedisResolver(redis)
+	seen := make(map[uint16]bool)
+	for i := 0; i < 10; i++ {
+		for _, r := range []snowflake.Resolver{p1, p2} {
+			seq, err := r.Resolve(context.Background(), 1, 7)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if seen[seq] {
+				t.Fatalf("Sequence %d should not repeat across processes", seq)
+			}
+			seen[seq] = true
+		}
+	}
+
+	if _, ok := redis.keys["snowflake:7:1"]; !ok {
+		t.Errorf("The key should be snowflake:{machineID}:{ms}, got %v", redis.keys)
+	}
+}
+
+func TestRedisBlockResolver(t *testing.T) {
+	redis := newFakeRedis()
+	p1, p2 := snowflake.NewRedisBlockResolver(redis, 64), snowflake.NewRedisBlockResolver(redis, 64)
+
+	seen := make(map[uint16]bool)
+	for i := 0; i < 100; i++ {
+		for _, r := range []snowflake.Resolver{p1, p2} {
+			seq, err := r.Resolve(context.Background(), 1, 7)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if seen[seq] {
+				t.Fatalf("Sequence %d should not repeat across processes", seq)
+			}
+			seen[seq] = true
+		}
+	}
+
+	// 100 IDs per process need 2 blocks each.
+	if redis.calls != 4 {
+		t.Errorf("The blocks should amortize the round trips, got %d calls", redis.calls)
+	}
+}
+
+func TestRedisResolver_Unavailable(t *testing.T) {
+	redis := newFakeRedis()
+	redis.down = true
+
+	_, err := snowflake.NewRedisResolver(redis).Resolve(context.Background(), 1, 7)
+	if !errors.Is(err, snowflake.ErrResolverUnavailable) {
+		t.Fatalf("The error should be ErrResolverUnavailable, got %v", err)
+	}
+	var re *snowflake.ResolverError
+	if !errors.As(err, &re) || re.Resolver != "redis" {
+		t.Errorf("The error should be a redis ResolverError, got %v", err)
+	}
+	if redis.calls != 3 {
+		t.Errorf("The call should be retried twice, got %d calls", redis.calls)
+	}
+
+	g, err := snowflake.New(snowflake.WithMachineID(7), snowflake.WithResolver(snowflake.NewRedisResolver(redis)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.NextID(); !errors.Is(err, snowflake.ErrResolverUnavailable) {
+		t.Errorf("NextID should return the resolver error, got %v", err)
+	}
+}
+
+func TestRedisResolver_Context(t *testing.T) {
+	redis := newFakeRedis()
+	redis.down = true
+
+	// a done context stops the retries.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := snowflake.NewRedisResolver(redis).Resolve(ctx, 1, 7); !errors.Is(err, snowflake.ErrResolverUnavailable) {
+		t.Fatalf("The error should be ErrResolverUnavailable, got %v", err)
+	}
+	if redis.calls != 1 {
+		t.Errorf("The call should not be retried once the context is done, got %d calls", redis.calls)
+	}
+}
+
+func TestRedisBlockResolver_MachineID(t *testing.T) {
+	redis := newFakeRedis()
+	r := snowflake.NewRedisBlockResolver(redis, 64)
+
+	if _, err := r.Resolve(context.Background(), 1, 7); err != nil {
+		t.Fatal(err)
+	}
+	// the block of machineID 7 is not used for machineID 8.
+	seq, err := r.Resolve(context.Background(), 1, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq != 0 || redis.keys["snowflake:8:1"] != 64 {
+		t.Errorf("machineID 8 should reserve a block of its own key, got sequence %d and keys %v", seq, redis.keys)
+	}
+}
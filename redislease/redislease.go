@@ -0,0 +1,168 @@
+// Package redislease leases snowflake machineIDs from redis, so the processes get a unique machineID at startup
+// without a static assignment:
+//
+//	lease, err := redislease.Allocate(ctx, goRedis{client}, redislease.Options{})
+//	if err != nil {
+//		return err
+//	}
+//	g, err := snowflake.New(snowflake.WithMachineIDLease(lease))
+//
+// The lease is a key per machineID, snowflake:lease:{machineID}, holding the owner with a TTL,
+// it is renewed in the background and deleted by Close.
+package redislease
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/internal/leasekeeper"
+)
+
+const (
+	defaultPrefix = "snowflake:lease:"
+	defaultTTL    = 10 * time.Second
+)
+
+// ErrNoFreeMachineID is returned by Allocate when every machineID up to Options.MaxMachineID is leased.
+var ErrNoFreeMachineID = errors.New("redislease: no free machineID")
+
+// claimScript set the first free key of prefix..0 to prefix..max with SET NX PX, in one round trip,
+// so the processes starting together don't race key by key.
+const claimScript = `for i = 0, tonumber(ARGV[3]) do
+	if redis.call('SET', ARGV[1] .. i, ARGV[2], 'NX', 'PX', ARGV[4]) then
+		return i
+	end
+end
+return -1`
+
+// renewScript refresh the ttl of the key when it still holds the owner.
+const renewScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0`
+
+// releaseScript delete the key when it still holds the owner.
+const releaseScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`
+
+// Client is the redis client used by Allocate, implement it with any redis library supporting EVAL. With go-redis:
+//
+//	type goRedis struct{ c *redis.Client }
+//
+//	func (r goRedis) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+//		return r.c.Eval(ctx, script, keys, args...).Result()
+//	}
+//
+// The claim script builds the keys from the prefix, with redis cluster use a prefix with a hash tag, e.g. "{snowflake}:lease:".
+type Client interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// Options configure Allocate, the zero value uses the defaults.
+type Options struct {
+	// Prefix is the prefix of the keys, "snowflake:lease:" by default.
+	Prefix string
+	// MaxMachineID is the max machineID leased, snowflake.MaxMachineID by default.
+	MaxMachineID uint16
+	// TTL is how long a lease lives without a renewal, 10s by default.
+	TTL time.Duration
+	// RenewInterval is how often the lease is renewed, TTL/3 by default.
+	RenewInterval time.Duration
+	// SafetyMargin is how long before the TTL elapses the lease ends when the renewals fail, so the generator stops
+	// before the lease expires in redis, RenewInterval/2 by default, less when the TTL is under twice the RenewInterval.
+	SafetyMargin time.Duration
+	// Owner identifies the process in the keys, hostname:pid:random by default.
+	Owner string
+}
+
+// Allocate lease the lowest free machineID in [0, MaxMachineID] and renew it in the background until Close.
+// The free machineID is claimed by a script in one round trip, so 50 pods starting together cost 50 round trips
+// and get 50 different machineIDs.
+//
+// The lease is lost when a renewal finds the key owned by another process, or SafetyMargin before TTL elapsed
+// since the last successful renewal was sent, before redis expires the key:
+// Done is closed and Err returns why, see snowflake.WithMachineIDLease.
+func Allocate(ctx context.Context, client Client, opts Options) (snowflake.MachineIDLease, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	acquired := time.Now()
+	res, err := client.Eval(ctx, claimScript, nil, opts.Prefix, opts.Owner, int64(opts.MaxMachineID), opts.TTL.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("redislease: allocate: %w", err)
+	}
+	id, ok := res.(int64)
+	if !ok {
+		return nil, fmt.Errorf("redislease: allocate: unexpected reply %v", res)
+	}
+	if id < 0 {
+		return nil, fmt.Errorf("%w: 0-%d are leased", ErrNoFreeMachineID, opts.MaxMachineID)
+	}
+
+	key := fmt.Sprintf("%s%d", opts.Prefix, id)
+	renew := func(ctx context.Context) error {
+		res, err := client.Eval(ctx, renewScript, []string{key}, opts.Owner, opts.TTL.Milliseconds())
+		if err != nil {
+			return err
+		}
+		if n, _ := res.(int64); n != 1 {
+			return fmt.Errorf("redislease: the key %s is not owned by %s: %w", key, opts.Owner, leasekeeper.ErrNotOwned)
+		}
+		return nil
+	}
+	release := func(ctx context.Context) error {
+		_, err := client.Eval(ctx, releaseScript, []string{key}, opts.Owner)
+		return err
+	}
+
+	return leasekeeper.Start(uint16(id), acquired, opts.RenewInterval, opts.TTL, opts.SafetyMargin, renew, release), nil
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+func (o Options) withDefaults() (Options, error) {
+	if o.Prefix == "" {
+		o.Prefix = defaultPrefix
+	}
+	if o.MaxMachineID == 0 {
+		o.MaxMachineID = snowflake.MaxMachineID
+	}
+	if o.TTL == 0 {
+		o.TTL = defaultTTL
+	}
+	if o.TTL < time.Millisecond {
+		return o, fmt.Errorf("redislease: invalid TTL %s, it must be at least 1ms", o.TTL)
+	}
+	if o.RenewInterval == 0 {
+		o.RenewInterval = o.TTL / 3
+	}
+	if o.RenewInterval <= 0 || o.RenewInterval >= o.TTL {
+		return o, fmt.Errorf("redislease: invalid renew interval %s, it must be positive and less than the TTL %s", o.RenewInterval, o.TTL)
+	}
+	if o.SafetyMargin == 0 {
+		o.SafetyMargin = leasekeeper.DefaultMargin(o.RenewInterval, o.TTL)
+	}
+	if o.SafetyMargin <= 0 || o.SafetyMargin >= o.TTL-o.RenewInterval {
+		return o, fmt.Errorf("redislease: invalid safety margin %s, it must be positive and less than the TTL minus the renew interval %s", o.SafetyMargin, o.TTL-o.RenewInterval)
+	}
+	if o.Owner == "" {
+		host, _ := os.Hostname()
+		b := make([]byte, 4)
+		_, _ = rand.Read(b)
+		o.Owner = fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(b))
+	}
+
+	return o, nil
+}
@@ -0,0 +1,239 @@
+package redislease_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/redislease"
+)
+
+// fakeRedis runs the scripts of redislease on a map, one script at a time like redis, the ttls are ignored.
+type fakeRedis struct {
+	mu     sync.Mutex
+	keys   map[string]string
+	down   bool
+	renews int
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{keys: make(map[string]string)}
+}
+
+func (r *fakeRedis) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.down {
+		return nil, errors.New("connection refused")
+	}
+
+	switch {
+	case strings.Contains(script, "'NX'"):
+		prefix, owner, max := args[0].(string), args[1].(string), args[2].(int64)
+		for i := int64(0); i <= max; i++ {
+			key := fmt.Sprintf("%s%d", prefix, i)
+			if _, ok := r.keys[key]; !ok {
+				r.keys[key] = owner
+				return i, nil
+			}
+		}
+		return int64(-1), nil
+	case strings.Contains(script, "PEXPIRE"):
+		if r.keys[keys[0]] != args[0] {
+			return int64(0), nil
+		}
+		r.renews++
+		return int64(1), nil
+	case strings.Contains(script, "DEL"):
+		if r.keys[keys[0]] != args[0] {
+			return int64(0), nil
+		}
+		delete(r.keys, keys[0])
+		return int64(1), nil
+	}
+
+	return nil, fmt.Errorf("unknown script %q", script)
+}
+
+func (r *fakeRedis) set(key, value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys[key] = value
+}
+
+func (r *fakeRedis) get(key string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, ok := r.keys[key]
+	return v, ok
+}
+
+func TestAllocate_ThunderingHerd(t *testing.T) {
+	client := newFakeRedis()
+
+	// 50 pods start together, they get the 50 lowest machineIDs.
+	leases := make([]snowflake.MachineIDLease, 50)
+	var wg sync.WaitGroup
+	for i := range leases {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l, err := redislease.Allocate(context.Background(), client, redislease.Options{Owner: fmt.Sprintf("pod-%d", i)})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			leases[i] = l
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[uint16]bool)
+	for _, l := range leases {
+		if l == nil {
+			t.FailNow()
+		}
+		if seen[l.MachineID()] || l.MachineID() >= 50 {
+			t.Errorf("The machineIDs should be unique and the lowest, got %d", l.MachineID())
+		}
+		seen[l.MachineID()] = true
+	}
+
+	// a released machineID is leased again.
+	released := leases[7].MachineID()
+	if err := leases[7].Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := client.get(fmt.Sprintf("snowflake:lease:%d", released)); ok {
+		t.Error("Close should release the machineID")
+	}
+	l, err := redislease.Allocate(context.Background(), client, redislease.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.MachineID() != released {
+		t.Errorf("The lowest free machineID should be leased, got %d, want %d", l.MachineID(), released)
+	}
+
+	for _, l := range append(leases, l) {
+		_ = l.Close(context.Background())
+	}
+}
+
+func TestAllocate_NoFreeMachineID(t *testing.T) {
+	client := newFakeRedis()
+	opts := redislease.Options{MaxMachineID: 1}
+	for i := 0; i < 2; i++ {
+		l, err := redislease.Allocate(context.Background(), client, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close(context.Background())
+	}
+
+	if _, err := redislease.Allocate(context.Background(), client, opts); !errors.Is(err, redislease.ErrNoFreeMachineID) {
+		t.Errorf("The error should be ErrNoFreeMachineID, got %v", err)
+	}
+}
+
+func TestAllocate_Renew(t *testing.T) {
+	client := newFakeRedis()
+	l, err := redislease.Allocate(context.Background(), client, redislease.Options{TTL: 30 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close(context.Background())
+
+	time.Sleep(50 * time.Millisecond)
+	client.mu.Lock()
+	renews := client.renews
+	client.mu.Unlock()
+	if renews == 0 {
+		t.Error("The lease should be renewed")
+	}
+	select {
+	case <-l.Done():
+		t.Fatalf("The lease should be alive, got %v", l.Err())
+	default:
+	}
+}
+
+func TestAllocate_Lost(t *testing.T) {
+	client := newFakeRedis()
+	l, err := redislease.Allocate(context.Background(), client, redislease.Options{TTL: 30 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := snowflake.New(snowflake.WithMachineIDLease(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.NextID(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the key expired during a pause and another process leased the machineID.
+	client.set("snowflake:lease:0", "other")
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Fatal("The lost lease should be done")
+	}
+	if l.Err() == nil {
+		t.Error("The lost lease should report why")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := g.NextID()
+		if errors.Is(err, snowflake.ErrLeaseLost) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("The generator should stop once the lease is lost, got %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Close of the generator closes the lease, it doesn't release the key of the other process.
+	if err := g.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if owner, _ := client.get("snowflake:lease:0"); owner != "other" {
+		t.Errorf("The key of the other process should be kept, got %q", owner)
+	}
+}
+
+func TestAllocate_Unreachable(t *testing.T) {
+	client := newFakeRedis()
+	l, err := redislease.Allocate(context.Background(), client, redislease.Options{TTL: 30 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := snowflake.New(snowflake.WithMachineIDLease(l), snowflake.WithLeaseLossTolerated())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close(context.Background())
+
+	client.mu.Lock()
+	client.down = true
+	client.mu.Unlock()
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Fatal("The lease should be lost once no renewal succeeded for the TTL")
+	}
+
+	if _, err := g.NextID(); err != nil {
+		t.Errorf("The generator should keep generating with WithLeaseLossTolerated, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package snowflake
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// registry holds the named generators, e.g. one generator per entity type.
+var registry = struct {
+	sync.RWMutex
+	generators map[string]*Generator
+}{generators: make(map[string]*Generator)}
+
+// Register register a generator by name, so it can be used everywhere with Get.
+// It returns an error when the name is already registered or the generator is nil.
+// This function is thread safe.
+func Register(name string, g *Generator) error {
+	if g == nil {
+		return fmt.Errorf("snowflake: cannot register a nil generator as %q", name)
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.generators[name]; ok {
+		return fmt.Errorf("snowflake: generator %q is already registered", name)
+	}
+	registry.generators[name] = g
+
+	return nil
+}
+
+// Get returns the generator registered by name, or nil when the name is not registered.
+// This function is thread safe.
+func Get(name string) *Generator {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	return registry.generators[name]
+}
+
+// MustGet returns the generator registered by name, it will panic when the name is not registered.
+// It is intended for init-time wiring.
+func MustGet(name string) *Generator {
+	g := Get(name)
+	if g == nil {
+		panic(fmt.Sprintf("snowflake: generator %q is not registered", name))
+	}
+
+	return g
+}
+
+// Names returns the sorted names of all the registered generators, e.g. for a debug dump.
+// This function is thread safe.
+func Names() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	names := make([]string, 0, len(registry.generators))
+	for name := range registry.generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,84 @@
+package snowflake_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestRegister(t *testing.T) {
+	orders, err := snowflake.New(snowflake.WithMachineID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := snowflake.Register("test.orders", orders); err != nil {
+		t.Fatal(err)
+	}
+	if err := snowflake.Register("test.orders", orders); err == nil {
+		t.Error("Should throw a error when the name is already registered")
+	}
+	if err := snowflake.Register("test.nil", nil); err == nil {
+		t.Error("Should throw a error when the generator is nil")
+	}
+
+	if snowflake.Get("test.orders") != orders {
+		t.Error("Get should return the registered generator")
+	}
+	if snowflake.Get("test.missing") != nil {
+		t.Error("Get should return nil when the name is not registered")
+	}
+
+	sid := orders.ParseID(snowflake.MustGet("test.orders").ID())
+	if sid.MachineID != 1 {
+		t.Error("MachineID should be equal 1")
+	}
+
+	func() {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Error("MustGet should panic when the name is not registered")
+			}
+		}()
+		snowflake.MustGet("test.missing")
+	}()
+}
+
+func TestRegister_Concurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			g, err := snowflake.New()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			name := fmt.Sprintf("test.concurrent.%02d", i)
+			if err := snowflake.Register(name, g); err != nil {
+				t.Error(err)
+			}
+			if snowflake.Get(name) != g {
+				t.Error("Get should return the registered generator")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	n := 0
+	names := snowflake.Names()
+	for i, name := range names {
+		if i > 0 && names[i-1] >= name {
+			t.Error("Names should be sorted")
+		}
+		if len(name) > 16 && name[:16] == "test.concurrent." {
+			n++
+		}
+	}
+	if n != 50 {
+		t.Error("Names should enumerate all the registered generators")
+	}
+}
@@ -0,0 +1,30 @@
+package snowflake
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReserveBlock claim n consecutive IDs in one call and returns the first one, the block is first, first+1 ... first+n-1,
+// e.g. a bulk insert computes the references of the child rows before hitting the database.
+// NextID never issues the IDs of the block again.
+//
+// The block shares the timestamp and machineID of its IDs, so it never spans ticks: when the sequence left
+// in the current tick is less than n, ReserveBlock waits for the next tick, like NextID on exhaustion.
+// n must be between 1 and MaxSequence+1 of the layout, and the generator must use its own atomic resolver,
+// the custom resolvers cannot claim consecutive sequences. The sequence must be the low bits of the layout:
+// with OrderTimestampSequenceMachine, e.g. NewSonyflakeCompatible, first+1 is the ID of another machineID.
+// This function is thread safe.
+func (g *Generator) ReserveBlock(n int) (uint64, error) {
+	if max := int(g.layout.MaxSequence()) + 1; n <= 0 || n > max {
+		return 0, fmt.Errorf("snowflake: invalid block size %d, it must be between 1 and %d for layout %s", n, max, g.layout)
+	}
+	if g.layout.Order == OrderTimestampSequenceMachine {
+		return 0, fmt.Errorf("snowflake: ReserveBlock needs the sequence in the low bits, got layout %s", g.layout)
+	}
+	if _, ok := g.resolver.Load().(resolverBox); ok || g.chain != nil || g.fallback != nil {
+		return 0, fmt.Errorf("snowflake: ReserveBlock needs the atomic resolver of the generator, got %s", g.ResolverName())
+	}
+
+	return g.nextBlock(context.Background(), false, n)
+}
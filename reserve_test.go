@@ -0,0 +1,114 @@
+package snowflake_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/clocktest"
+)
+
+func TestGenerator_ReserveBlock(t *testing.T) {
+	clock := clocktest.NewManual(time.Now())
+	clock.AutoAdvance(time.Millisecond)
+	g, err := snowflake.New(
+		snowflake.WithClock(clock),
+		snowflake.WithLayout(snowflake.Layout{TimestampBits: 45, MachineBits: 16, SequenceBits: 2}),
+		snowflake.WithStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 4 IDs per tick, the block of 4 doesn't fit in the tick of the first ID, it takes the whole next tick.
+	id := g.ID()
+	first, err := g.ReserveBlock(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first <= id {
+		t.Errorf("The block should come after the last ID, got %d after %d", first, id)
+	}
+	start := g.ParseID(first)
+	if start.Sequence != 0 {
+		t.Errorf("The block should start the tick, got %+v", start)
+	}
+	if end := g.ParseID(first + 3); end.Timestamp != start.Timestamp || end.Sequence != 3 {
+		t.Errorf("The block should be consecutive in one tick, got %+v", end)
+	}
+
+	if next := g.ID(); next <= first+3 {
+		t.Errorf("NextID should not issue the IDs of the block, got %d", next)
+	}
+
+	for _, n := range []int{0, -1, 5} {
+		if _, err := g.ReserveBlock(n); err == nil {
+			t.Errorf("The block size %d should be rejected", n)
+		}
+	}
+}
+
+func TestGenerator_ReserveBlock_Concurrent(t *testing.T) {
+	g, err := snowflake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	seen := make(map[uint64]bool)
+	add := func(id uint64) {
+		mu.Lock()
+		defer mu.Unlock()
+		if seen[id] {
+			t.Errorf("The ID %d should be unique", id)
+		}
+		seen[id] = true
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if i%2 == 0 {
+					add(g.ID())
+					continue
+				}
+				first, err := g.ReserveBlock(10)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				for k := uint64(0); k < 10; k++ {
+					add(first + k)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestGenerator_ReserveBlock_CustomResolver(t *testing.T) {
+	g, err := snowflake.New(snowflake.WithSequenceResolver(snowflake.NewAtomicResolver()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.ReserveBlock(10); err == nil {
+		t.Error("A custom resolver cannot claim consecutive sequences")
+	}
+}
+
+func TestGenerator_ReserveBlock_Sonyflake(t *testing.T) {
+	g, err := snowflake.NewSonyflakeCompatible(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the machineID is in the low bits, first+1 would be an ID of machineID 8.
+	if first, err := g.ReserveBlock(2); err == nil {
+		t.Errorf("ReserveBlock should fail when the sequence is not in the low bits, got %d", first)
+	}
+}
@@ -0,0 +1,35 @@
+package snowflake
+
+import "context"
+
+// Resolver is a sequence resolver which gets the context of NextIDContext and the machineID of the generator,
+// e.g. a distributed resolver builds its key from the machineID and bounds its round trip with ctx.
+// It follows the contract of SequenceResolver, set it with WithResolver.
+//
+// A SequenceResolver is a Resolver ignoring ctx and machineID, so the existing resolvers keep working.
+// To migrate a SequenceResolver, implement Resolve and replace WithSequenceResolver(f) with WithResolver(r):
+//
+//	type redisResolver struct{ c RedisCmdable }
+//
+//	func (r redisResolver) Resolve(ctx context.Context, ms int64, machineID uint16) (uint16, error) {
+//		n, err := r.c.IncrBy(ctx, fmt.Sprintf("snowflake:%d:%d", machineID, ms), 1, 5*time.Second)
+//		...
+//	}
+type Resolver interface {
+	Resolve(ctx context.Context, ms int64, machineID uint16) (uint16, error)
+}
+
+// Resolve call f with ms, ctx and machineID are ignored.
+func (f SequenceResolver) Resolve(ctx context.Context, ms int64, machineID uint16) (uint16, error) {
+	return f(ms)
+}
+
+// Resolve is the atomic resolver as a Resolver, ctx and machineID are ignored.
+func (r *atomicResolver) Resolve(ctx context.Context, ms int64, machineID uint16) (uint16, error) {
+	return r.resolve(ms)
+}
+
+// resolverBox holds the Resolver of a generator, atomic.Value needs a consistent concrete type.
+type resolverBox struct {
+	r Resolver
+}
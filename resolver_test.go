@@ -0,0 +1,62 @@
+package snowflake_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+type ctxKey struct{}
+
+// sequenceResolver adapts r to a SequenceResolver with machineID 0.
+func sequenceResolver(r snowflake.Resolver) snowflake.SequenceResolver {
+	return func(ms int64) (uint16, error) {
+		return r.Resolve(context.Background(), ms, 0)
+	}
+}
+
+// recordingResolver records the context value and machineID it gets.
+type recordingResolver struct {
+	value     interface{}
+	machineID uint16
+	resolve   snowflake.SequenceResolver
+}
+
+func (r *recordingResolver) Resolve(ctx context.Context, ms int64, machineID uint16) (uint16, error) {
+	r.value, r.machineID = ctx.Value(ctxKey{}), machineID
+	return r.resolve(ms)
+}
+
+func TestWithResolver(t *testing.T) {
+	r := &recordingResolver{resolve: snowflake.NewAtomicResolver()}
+	g, err := snowflake.New(snowflake.WithMachineID(42), snowflake.WithResolver(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request-1")
+	if _, err := g.NextIDContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if r.value != "request-1" || r.machineID != 42 {
+		t.Errorf("The resolver should get the context and the machineID, got %v and %d", r.value, r.machineID)
+	}
+	if name := g.ResolverName(); name != "*snowflake_test.recordingResolver" {
+		t.Errorf("ResolverName should return the type of the resolver, got %s", name)
+	}
+
+	if _, err := snowflake.New(snowflake.WithResolver(nil)); err == nil {
+		t.Error("A nil resolver should be rejected")
+	}
+}
+
+func TestSequenceResolver_Resolve(t *testing.T) {
+	var r snowflake.Resolver = snowflake.SequenceResolver(snowflake.NewAtomicResolver())
+
+	for i := 0; i < 3; i++ {
+		if seq, err := r.Resolve(context.Background(), 1, 42); err != nil || int(seq) != i {
+			t.Fatalf("The adapter should call the func, got %d, %v", seq, err)
+		}
+	}
+}
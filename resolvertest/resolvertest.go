@@ -0,0 +1,163 @@
+// Package resolvertest provides conformance tests for snowflake.SequenceResolver implementations,
+// they turn the contract documented on snowflake.SequenceResolver into executable documentation.
+// Call TestResolver from a test of the custom resolver:
+//
+//	func TestRedisResolver(t *testing.T) {
+//		resolvertest.TestResolver(t, func() snowflake.SequenceResolver {
+//			return newRedisResolver(t)
+//		})
+//	}
+package resolvertest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// ms is the tick used by the tests, far from the zero value a resolver may start with.
+const ms = 1 << 40
+
+// Option changes the checks of TestResolver for the resolvers with a looser contract.
+type Option func(*config)
+
+type config struct {
+	blocks bool
+}
+
+// WithBlocks declares that the resolver hands out the sequences in blocks which may be discarded unused,
+// e.g. snowflake.BlockLeaseResolver, whose blocks cached in a sync.Pool can be dropped.
+// The sequence space of a new tick must not be empty, but its size may differ from the previous tick.
+func WithBlocks() Option {
+	return func(c *config) {
+		c.blocks = true
+	}
+}
+
+// TestResolver check the contract of snowflake.SequenceResolver: the sequences of a tick are unique,
+// the exhaustion is signaled with snowflake.ErrSequenceExhausted, a new tick gets a fresh sequence space
+// of the same size, an older tick is exhausted and the resolver is safe for concurrent use.
+// newResolver must return a resolver with a fresh state for every subtest.
+func TestResolver(t *testing.T, newResolver func() snowflake.SequenceResolver, opts ...Option) {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	t.Run("Unique", func(t *testing.T) {
+		resolve := newResolver()
+		if _, err := drain(resolve, ms); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Exhausted", func(t *testing.T) {
+		resolve := newResolver()
+		if _, err := drain(resolve, ms); err != nil {
+			t.Fatal(err)
+		}
+
+		// the exhaustion keeps being signaled, a sequence is never issued twice.
+		for i := 0; i < 3; i++ {
+			if _, err := resolve(ms); !errors.Is(err, snowflake.ErrSequenceExhausted) {
+				t.Fatalf("The error should be ErrSequenceExhausted once the tick is exhausted, got %v", err)
+			}
+		}
+	})
+
+	t.Run("ResetsOnNewTick", func(t *testing.T) {
+		resolve := newResolver()
+		first, err := drain(resolve, ms)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		next, err := drain(resolve, ms+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next != first && !c.blocks {
+			t.Errorf("The new tick should have a fresh sequence space of %d sequences, got %d", first, next)
+		}
+	})
+
+	t.Run("OlderTick", func(t *testing.T) {
+		resolve := newResolver()
+		if _, err := resolve(ms); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := resolve(ms - 1); !errors.Is(err, snowflake.ErrSequenceExhausted) {
+			t.Errorf("The error should be ErrSequenceExhausted for an older tick, got %v", err)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		const goroutines, n = 8, 1000
+
+		resolve := newResolver()
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		seen := make(map[uint16]bool)
+		errs := make(chan error, goroutines)
+		for i := 0; i < goroutines; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < n; j++ {
+					seq, err := resolve(ms)
+					if errors.Is(err, snowflake.ErrSequenceExhausted) {
+						return
+					}
+					if err != nil {
+						errs <- err
+						return
+					}
+
+					mu.Lock()
+					dup := seen[seq]
+					seen[seq] = true
+					mu.Unlock()
+					if dup {
+						errs <- errors.New("resolvertest: a sequence was returned twice")
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Error(err)
+		}
+	})
+}
+
+// drain resolve the sequences of ms until it is exhausted, it returns the count of sequences,
+// or an error when a sequence repeats or the resolver fails.
+func drain(resolve snowflake.SequenceResolver, ms int64) (int, error) {
+	seen := make(map[uint16]bool)
+	for i := 0; i <= math.MaxUint16+1; i++ {
+		seq, err := resolve(ms)
+		if errors.Is(err, snowflake.ErrSequenceExhausted) {
+			if len(seen) == 0 {
+				return 0, errors.New("resolvertest: a new tick should not be exhausted")
+			}
+			return len(seen), nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if seen[seq] {
+			return 0, fmt.Errorf("resolvertest: sequence %d should not repeat in a tick", seq)
+		}
+		seen[seq] = true
+	}
+
+	return 0, errors.New("resolvertest: the exhaustion should be signaled with ErrSequenceExhausted")
+}
@@ -0,0 +1,85 @@
+package resolvertest_test
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/resolvertest"
+)
+
+// sequenceResolver adapts r to a SequenceResolver with machineID 1.
+func sequenceResolver(r snowflake.Resolver) snowflake.SequenceResolver {
+	return func(ms int64) (uint16, error) {
+		return r.Resolve(context.Background(), ms, 1)
+	}
+}
+
+func TestAtomicResolver(t *testing.T) {
+	resolvertest.TestResolver(t, snowflake.NewAtomicResolver)
+}
+
+func TestShardedResolver(t *testing.T) {
+	resolvertest.TestResolver(t, func() snowflake.SequenceResolver {
+		return snowflake.NewShardedResolver(8, snowflake.MaxSequence)
+	})
+}
+
+func TestRandomStartResolver(t *testing.T) {
+	resolvertest.TestResolver(t, func() snowflake.SequenceResolver {
+		return snowflake.NewRandomStartResolver(snowflake.MaxSequence)
+	})
+}
+
+func TestBlockLeaseResolver(t *testing.T) {
+	resolvertest.TestResolver(t, func() snowflake.SequenceResolver {
+		return sequenceResolver(snowflake.NewBlockLeaseResolver(32))
+	}, resolvertest.WithBlocks())
+}
+
+// memRedis is an in-memory snowflake.RedisCmdable.
+type memRedis struct {
+	mu   sync.Mutex
+	keys map[string]int64
+}
+
+func (r *memRedis) IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys[key] += n
+
+	return r.keys[key], nil
+}
+
+func TestRedisResolver(t *testing.T) {
+	resolvertest.TestResolver(t, func() snowflake.SequenceResolver {
+		return sequenceResolver(snowflake.NewRedisResolver(&memRedis{keys: make(map[string]int64)}))
+	})
+}
+
+func TestRedisBlockResolver(t *testing.T) {
+	resolvertest.TestResolver(t, func() snowflake.SequenceResolver {
+		return sequenceResolver(snowflake.NewRedisBlockResolver(&memRedis{keys: make(map[string]int64)}, 64))
+	})
+}
+
+func TestSharedMemoryResolver(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "freebsd" {
+		t.Skip("The shared memory resolver is not supported on", runtime.GOOS)
+	}
+
+	resolvertest.TestResolver(t, func() snowflake.SequenceResolver {
+		r, err := snowflake.NewSharedMemoryResolver(filepath.Join(t.TempDir(), "snowflake"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = r.Close() })
+
+		return sequenceResolver(r)
+	})
+}
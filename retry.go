@@ -0,0 +1,119 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy is the exponential backoff of NextIDRetry, the zero values use the defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the total count of attempts, 3 by default.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, 1ms by default.
+	InitialBackoff time.Duration
+	// MaxBackoff caps a delay, 100ms by default.
+	MaxBackoff time.Duration
+	// Multiplier grows the delay after every retry, 2 by default.
+	Multiplier float64
+	// Jitter randomizes each delay by ±Jitter of it, between 0 and 1, 0.2 by default.
+	// A negative Jitter disables it, the delays are exact.
+	Jitter float64
+	// MaxDelay caps the cumulative delay of the retries, 1s by default.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy used for the zero RetryPolicy fields.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     100 * time.Millisecond,
+	Multiplier:     2,
+	Jitter:         0.2,
+	MaxDelay:       time.Second,
+}
+
+// NextIDRetry use NextIDContext to generate snowflake id, and retries the retryable errors with the backoff of p,
+// e.g. the transient failures of the resolvers backed by redis or postgres.
+// An error is retryable when it, or an error it wraps, has a Temporary() bool method returning true,
+// as *ResolverError does, the other errors are returned at once.
+//
+// The retries stop when ctx is done, or when the attempts or the cumulative delay of p are exhausted,
+// the returned error then reports the count of attempts and wraps the last error.
+// This function is thread safe.
+func (g *Generator) NextIDRetry(ctx context.Context, p RetryPolicy) (uint64, error) {
+	p = p.withDefaults()
+
+	backoff := p.InitialBackoff
+	var waited time.Duration
+	for attempt := 1; ; attempt++ {
+		id, err := g.NextIDContext(ctx)
+		if err == nil || !isTemporary(err) {
+			return id, err
+		}
+
+		d := p.jitter(backoff)
+		if attempt >= p.MaxAttempts || waited+d > p.MaxDelay {
+			return 0, fmt.Errorf("snowflake: NextID failed after %d attempts: %w", attempt, err)
+		}
+
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, fmt.Errorf("snowflake: NextID failed after %d attempts: %w", attempt, err)
+		}
+		waited += d
+
+		backoff = time.Duration(float64(backoff) * p.Multiplier)
+		if backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// Temporary reports true, the store of the resolver may come back, see NextIDRetry.
+func (e *ResolverError) Temporary() bool {
+	return true
+}
+
+// isTemporary returns true when err, or an error it wraps, is temporary.
+func isTemporary(err error) bool {
+	var t interface{ Temporary() bool }
+
+	return errors.As(err, &t) && t.Temporary()
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultRetryPolicy.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = DefaultRetryPolicy.Multiplier
+	}
+	if p.Jitter < 0 {
+		// 负数表示关闭抖动
+		p.Jitter = 0
+	} else if p.Jitter == 0 || p.Jitter > 1 {
+		p.Jitter = DefaultRetryPolicy.Jitter
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultRetryPolicy.MaxDelay
+	}
+
+	return p
+}
+
+// jitter randomize d by ±Jitter of it.
+func (p RetryPolicy) jitter(d time.Duration) time.Duration {
+	return d + time.Duration((rand.Float64()*2-1)*p.Jitter*float64(d))
+}
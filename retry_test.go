@@ -0,0 +1,129 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// failingResolver fails the first n calls with err.
+func failingResolver(n int, err error) (snowflake.SequenceResolver, *int) {
+	calls := 0
+	resolve := snowflake.NewAtomicResolver()
+
+	return func(ms int64) (uint16, error) {
+		calls++
+		if calls <= n {
+			return 0, err
+		}
+		return resolve(ms)
+	}, &calls
+}
+
+func TestNextIDRetry(t *testing.T) {
+	transient := &snowflake.ResolverError{Resolver: "redis", Err: errors.New("i/o timeout")}
+
+	t.Run("transient", func(t *testing.T) {
+		resolve, calls := failingResolver(2, transient)
+		g, err := snowflake.New(snowflake.WithSequenceResolver(resolve))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := g.NextIDRetry(context.Background(), snowflake.RetryPolicy{}); err != nil {
+			t.Fatalf("The transient errors should be retried, got %v", err)
+		}
+		if *calls != 3 {
+			t.Errorf("NextIDRetry should make 3 attempts, got %d", *calls)
+		}
+	})
+
+	t.Run("attempts", func(t *testing.T) {
+		resolve, calls := failingResolver(10, transient)
+		g, err := snowflake.New(snowflake.WithSequenceResolver(resolve))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = g.NextIDRetry(context.Background(), snowflake.RetryPolicy{MaxAttempts: 4})
+		if !errors.Is(err, snowflake.ErrResolverUnavailable) || !strings.Contains(err.Error(), "after 4 attempts") {
+			t.Errorf("The error should report the attempts and wrap the last error, got %v", err)
+		}
+		if *calls != 4 {
+			t.Errorf("The attempts should be capped, got %d", *calls)
+		}
+	})
+
+	t.Run("permanent", func(t *testing.T) {
+		permanent := errors.New("permission denied")
+		resolve, calls := failingResolver(10, permanent)
+		g, err := snowflake.New(snowflake.WithSequenceResolver(resolve))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := g.NextIDRetry(context.Background(), snowflake.RetryPolicy{}); err != permanent {
+			t.Errorf("A permanent error should be returned as is, got %v", err)
+		}
+		if *calls != 1 {
+			t.Errorf("A permanent error should not be retried, got %d calls", *calls)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		resolve, _ := failingResolver(1000, transient)
+		g, err := snowflake.New(snowflake.WithSequenceResolver(resolve))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err = g.NextIDRetry(ctx, snowflake.RetryPolicy{MaxAttempts: 1000, InitialBackoff: 5 * time.Millisecond, MaxDelay: time.Minute})
+		if !errors.Is(err, snowflake.ErrResolverUnavailable) {
+			t.Errorf("The last error should be returned, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("The retries should stop at the deadline, took %s", elapsed)
+		}
+	})
+
+	t.Run("max delay", func(t *testing.T) {
+		resolve, calls := failingResolver(1000, transient)
+		g, err := snowflake.New(snowflake.WithSequenceResolver(resolve))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 2ms + 4ms + 8ms fit in 20ms (with the jitter), 16ms more does not.
+		_, err = g.NextIDRetry(context.Background(), snowflake.RetryPolicy{MaxAttempts: 1000, InitialBackoff: 2 * time.Millisecond, MaxDelay: 20 * time.Millisecond})
+		if err == nil {
+			t.Fatal("NextIDRetry should fail")
+		}
+		if *calls < 3 || *calls > 5 {
+			t.Errorf("The cumulative delay should be capped, got %d attempts", *calls)
+		}
+	})
+
+	t.Run("no jitter", func(t *testing.T) {
+		resolve, calls := failingResolver(1000, transient)
+		g, err := snowflake.New(snowflake.WithSequenceResolver(resolve))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// without the jitter 2ms + 4ms + 8ms fit exactly in 14ms, 16ms more does not.
+		_, err = g.NextIDRetry(context.Background(), snowflake.RetryPolicy{MaxAttempts: 1000, InitialBackoff: 2 * time.Millisecond, MaxDelay: 14 * time.Millisecond, Jitter: -1})
+		if err == nil {
+			t.Fatal("NextIDRetry should fail")
+		}
+		if *calls != 4 {
+			t.Errorf("The delays should not be randomized, got %d attempts", *calls)
+		}
+	})
+}
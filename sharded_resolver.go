@@ -0,0 +1,100 @@
+package snowflake
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// shard is a stripe of the sharded resolver, padded to a cache line so the shards don't share one.
+type shard struct {
+	mu sync.Mutex
+	ms int64
+	n  uint32
+	_  [64 - 8 - 8 - 4]byte
+}
+
+// shardedResolver stripes the sequence across shards, shard i issues the sequences i, i+N, i+2N... of a tick,
+// so the shards never issue the same sequence and the callers on different shards don't contend.
+type shardedResolver struct {
+	// latest is the latest tick seen by any shard, accessed atomically, keep it as the first field
+	// to guarantee 64-bit alignment on 32-bit platforms.
+	latest int64
+	shards []shard
+	max    uint16
+
+	// hints caches the shard index of the callers, sync.Pool keeps a cache per P,
+	// so the goroutines running on a P keep using the same shard.
+	hints    sync.Pool
+	nextHint uint32
+}
+
+// NewShardedResolver create a sequence resolver which stripes its counter across shards,
+// it scales better than the atomic resolver when many goroutines generate IDs at the same time.
+// shards < 1 uses runtime.GOMAXPROCS(0) shards.
+//
+// maxSequence must be the max sequence of the generator layout, e.g. Layout.MaxSequence(),
+// a shard exhausted up to it borrows the sequences of the other shards before the tick is reported exhausted.
+// The sequences of a tick are unique but not issued in order.
+func NewShardedResolver(shards int, maxSequence uint16) SequenceResolver {
+	if shards < 1 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+	if shards > int(maxSequence)+1 {
+		shards = int(maxSequence) + 1
+	}
+
+	r := &shardedResolver{shards: make([]shard, shards), max: maxSequence}
+	r.hints.New = func() interface{} {
+		hint := atomic.AddUint32(&r.nextHint, 1) % uint32(len(r.shards))
+		return &hint
+	}
+
+	return r.resolve
+}
+
+func (r *shardedResolver) resolve(ms int64) (uint16, error) {
+	for {
+		latest := atomic.LoadInt64(&r.latest)
+		if ms < latest {
+			return 0, ErrSequenceExhausted
+		}
+		if ms == latest || atomic.CompareAndSwapInt64(&r.latest, latest, ms) {
+			break
+		}
+	}
+
+	hint := r.hints.Get().(*uint32)
+	defer r.hints.Put(hint)
+
+	// 从提示的分片开始，分片耗尽后借用其它分片
+	for i := 0; i < len(r.shards); i++ {
+		idx := (int(*hint) + i) % len(r.shards)
+		if seq, ok := r.shards[idx].next(ms, idx, len(r.shards), r.max); ok {
+			return seq, nil
+		}
+	}
+
+	return 0, ErrSequenceExhausted
+}
+
+// next returns the next sequence of ms issued by the shard idx of n shards, false when the shard is exhausted.
+func (s *shard) next(ms int64, idx, n int, max uint16) (uint16, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ms < s.ms {
+		return 0, false
+	}
+	if ms > s.ms {
+		s.ms, s.n = ms, 0
+	}
+
+	seq := uint64(s.n)*uint64(n) + uint64(idx)
+	if seq > uint64(max) {
+		return 0, false
+	}
+	s.n++
+
+	return uint16(seq), true
+}
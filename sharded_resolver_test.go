@@ -0,0 +1,95 @@
+package snowflake_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestShardedResolver_FullRange(t *testing.T) {
+	// the shards borrow from each other, so the whole sequence space of a tick is used.
+	resolve := snowflake.NewShardedResolver(8, snowflake.MaxSequence)
+
+	seen := make(map[uint16]bool)
+	for {
+		seq, err := resolve(1)
+		if err == snowflake.ErrSequenceExhausted {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seq > snowflake.MaxSequence || seen[seq] {
+			t.Fatalf("Sequence %d should be unique and within the max sequence", seq)
+		}
+		seen[seq] = true
+	}
+
+	if len(seen) != int(snowflake.MaxSequence)+1 {
+		t.Errorf("A tick should hold %d sequences, got %d", snowflake.MaxSequence+1, len(seen))
+	}
+}
+
+func TestShardedResolver_Generator(t *testing.T) {
+	g, err := snowflake.New(snowflake.WithSequenceResolver(snowflake.NewShardedResolver(0, snowflake.MaxSequence)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const goroutines, n = 64, 2000
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	ids := make(map[uint64]bool, goroutines*n)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				id := g.ID()
+				mu.Lock()
+				if ids[id] {
+					t.Errorf("ID %d should not repeat", id)
+				}
+				ids[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkResolverContention(b *testing.B) {
+	resolvers := []struct {
+		name string
+		new  func() snowflake.SequenceResolver
+	}{
+		{"atomic", snowflake.NewAtomicResolver},
+		{"sharded", func() snowflake.SequenceResolver { return snowflake.NewShardedResolver(0, snowflake.MaxSequence) }},
+		{"block-lease", func() snowflake.SequenceResolver { return sequenceResolver(snowflake.NewBlockLeaseResolver(32)) }},
+	}
+
+	for _, r := range resolvers {
+		for _, goroutines := range []int{1, 8, 64, 256} {
+			b.Run(fmt.Sprintf("%s/%d", r.name, goroutines), func(b *testing.B) {
+				resolve := r.new()
+
+				var wg sync.WaitGroup
+				b.ResetTimer()
+				for i := 0; i < goroutines; i++ {
+					wg.Add(1)
+					go func(n int) {
+						defer wg.Done()
+						for j := 0; j < n; j++ {
+							_, _ = resolve(time.Now().UnixNano() / int64(time.Millisecond))
+						}
+					}(b.N/goroutines + 1)
+				}
+				wg.Wait()
+			})
+		}
+	}
+}
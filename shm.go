@@ -0,0 +1,126 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	// shmMagic marks a shared memory resolver file, it is the first word of the file.
+	shmMagic = 0x534e4f57464c4b31 // SNOWFLK1
+	// shmSize is the size of the file, the magic word and the state word.
+	shmSize = 16
+	// shmSeqBits is the width of the sequence count in the state word, the tick uses the other bits.
+	shmSeqBits = 17
+	// shmMaxTick is the max tick the state word can hold.
+	shmMaxTick = 1<<(64-shmSeqBits) - 1
+)
+
+var errSharedMemoryUnsupported = errors.New("snowflake: the shared memory resolver is not supported on this platform")
+
+// SharedMemoryResolver is a sequence resolver whose state lives in a memory mapped file,
+// so the processes of a host mapping the same file get unique sequences, see NewSharedMemoryResolver.
+type SharedMemoryResolver struct {
+	// mu guards the mapping, Close waits for the running Resolve calls before unmapping.
+	mu     sync.RWMutex
+	data   []byte
+	state  *uint64
+	closed bool
+}
+
+// NewSharedMemoryResolver create a sequence resolver backed by the file at path, e.g. /dev/shm/snowflake-42,
+// the processes of a host sharing a machineID must use the same file, then they get unique sequences
+// without a network hop: the file holds (tick, count) in one word updated with an atomic compare and swap.
+//
+// The first process creates the file, the others map it. The file can stay after all the processes exit,
+// a tick older than the current one, e.g. left by a crashed process, is simply superseded, so the file never
+// needs a cleanup; delete it only when no process uses it. It must be on a local file system, preferably a tmpfs.
+// When the clock of the host moved backward since the file was written, the sequences are exhausted until it catches up.
+//
+// It is supported on linux, darwin and freebsd, call Close to unmap the file once the generator is closed.
+func NewSharedMemoryResolver(path string) (*SharedMemoryResolver, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() < shmSize {
+		// 截断到相同大小是幂等的，并发创建的进程不会互相覆盖
+		if err := f.Truncate(shmSize); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := mapFile(f, shmSize)
+	if err != nil {
+		return nil, err
+	}
+
+	magic := (*uint64)(unsafe.Pointer(&data[0]))
+	if !atomic.CompareAndSwapUint64(magic, 0, shmMagic) && atomic.LoadUint64(magic) != shmMagic {
+		_ = unmapFile(data)
+		return nil, fmt.Errorf("snowflake: %s is not a shared memory resolver file", path)
+	}
+
+	return &SharedMemoryResolver{data: data, state: (*uint64)(unsafe.Pointer(&data[8]))}, nil
+}
+
+// Resolve is r as a Resolver, ctx and machineID are ignored, it returns ErrClosed once r is closed.
+// This function is thread safe.
+func (r *SharedMemoryResolver) Resolve(ctx context.Context, ms int64, machineID uint16) (uint16, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return 0, ErrClosed
+	}
+	if ms < 0 || ms > shmMaxTick {
+		return 0, fmt.Errorf("snowflake: the tick %d exceeds the shared memory resolver", ms)
+	}
+
+	for {
+		old := atomic.LoadUint64(r.state)
+		tick, n := int64(old>>shmSeqBits), old&(1<<shmSeqBits-1)
+
+		var state uint64
+		switch {
+		case ms < tick:
+			return 0, ErrSequenceExhausted
+		case ms > tick:
+			n, state = 0, uint64(ms)<<shmSeqBits|1
+		case n > math.MaxUint16:
+			return 0, ErrSequenceExhausted
+		default:
+			state = old + 1
+		}
+
+		if atomic.CompareAndSwapUint64(r.state, old, state) {
+			return uint16(n), nil
+		}
+	}
+}
+
+// Close unmap the file, the file itself is kept for the other processes.
+// This function is thread safe.
+func (r *SharedMemoryResolver) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	return unmapFile(r.data)
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package snowflake
+
+import "os"
+
+// mapFile returns errSharedMemoryUnsupported, the cross-process atomics need a shared mapping.
+func mapFile(f *os.File, size int) ([]byte, error) {
+	return nil, errSharedMemoryUnsupported
+}
+
+func unmapFile(data []byte) error {
+	return errSharedMemoryUnsupported
+}
@@ -0,0 +1,150 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package snowflake_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// shmChildEnv is set for the child processes of TestSharedMemoryResolver_Processes, it is the path of the shared file.
+const shmChildEnv = "SNOWFLAKE_SHM_CHILD"
+
+// shmBaseTick is the first tick the child processes resolve.
+const shmBaseTick = 1 << 40
+
+func TestSharedMemoryResolver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snowflake")
+	r1, err := snowflake.NewSharedMemoryResolver(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r1.Close()
+	r2, err := snowflake.NewSharedMemoryResolver(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		r := r1
+		if i%2 == 1 {
+			r = r2
+		}
+		if seq, err := r.Resolve(context.Background(), 1, 0); err != nil || int(seq) != i {
+			t.Fatalf("The mappings should share the sequence, got %d, %v, want %d", seq, err, i)
+		}
+	}
+	if _, err := r2.Resolve(context.Background(), 0, 0); err != snowflake.ErrSequenceExhausted {
+		t.Errorf("A past tick should be exhausted, got %v", err)
+	}
+
+	if err := r2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r2.Resolve(context.Background(), 2, 0); err != snowflake.ErrClosed {
+		t.Errorf("The error should be ErrClosed, got %v", err)
+	}
+
+	// the state left by a closed (or crashed) process is superseded by a new tick.
+	r3, err := snowflake.NewSharedMemoryResolver(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r3.Close()
+	if seq, err := r3.Resolve(context.Background(), 2, 0); err != nil || seq != 0 {
+		t.Errorf("A new tick should restart the sequence, got %d, %v", seq, err)
+	}
+
+	other := filepath.Join(t.TempDir(), "other")
+	if err := ioutil.WriteFile(other, []byte("not a snowflake file"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := snowflake.NewSharedMemoryResolver(other); err == nil {
+		t.Error("A foreign file should be rejected")
+	}
+}
+
+// TestSharedMemoryResolver_Processes spawns child processes sharing a file, the (tick, sequence) pairs must be unique.
+func TestSharedMemoryResolver_Processes(t *testing.T) {
+	if path := os.Getenv(shmChildEnv); path != "" {
+		shmChild(t, path)
+		return
+	}
+
+	const children = 4
+
+	path := filepath.Join(t.TempDir(), "snowflake")
+	var cmds []*exec.Cmd
+	var outs []*bytes.Buffer
+	for i := 0; i < children; i++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestSharedMemoryResolver_Processes$")
+		cmd.Env = append(os.Environ(), shmChildEnv+"="+path)
+		out := &bytes.Buffer{}
+		cmd.Stdout = out
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, cmd)
+		outs = append(outs, out)
+	}
+
+	seen := make(map[string]bool)
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("The child process %d failed: %v", i, err)
+		}
+
+		s := bufio.NewScanner(outs[i])
+		for s.Scan() {
+			var tick int64
+			var seq uint16
+			if _, err := fmt.Sscanf(s.Text(), "seq %d %d", &tick, &seq); err != nil {
+				continue
+			}
+			key := s.Text()
+			if seen[key] {
+				t.Fatalf("The pair %d/%d should be unique across processes", tick, seq)
+			}
+			seen[key] = true
+		}
+	}
+
+	if len(seen) == 0 {
+		t.Fatal("The child processes should resolve sequences")
+	}
+}
+
+// shmChild resolve sequences of 100 ticks, and print them to stdout.
+func shmChild(t *testing.T, path string) {
+	r, err := snowflake.NewSharedMemoryResolver(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	for tick := int64(shmBaseTick); tick < shmBaseTick+100; tick++ {
+		for i := 0; i < 200; i++ {
+			seq, err := r.Resolve(context.Background(), tick, 0)
+			if err == snowflake.ErrSequenceExhausted {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			fmt.Fprintf(w, "seq %d %d\n", tick, seq)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package snowflake
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultShutdownTimeout is how long ReleaseOnShutdown and CloseOnShutdown wait for the release by default.
+const defaultShutdownTimeout = 5 * time.Second
+
+// defaultShutdownSignals are the signals handled when none is given, SIGTERM is sent by the orchestrators on a deploy.
+var defaultShutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// ShutdownOptions configure ReleaseOnShutdown and CloseOnShutdown, the zero value uses the defaults.
+type ShutdownOptions struct {
+	// Timeout is how long the release waits at most, 5s by default, so an unreachable store never delays the shutdown.
+	Timeout time.Duration
+	// Signals are the signals handled, SIGINT and SIGTERM by default.
+	Signals []os.Signal
+	// Reraise sends the signal again to the process once released, so a process which doesn't handle the signal
+	// exits as it would have without the handler. Leave it unset when the application handles the signal itself,
+	// e.g. with signal.NotifyContext, it would get the signal twice: a process cannot tell whether another
+	// signal.Notify subscriber exists. On windows, where a process cannot signal itself, it is ignored.
+	Reraise bool
+}
+
+// ReleaseOnShutdown release lease as soon as the process receives one of the signals of opts, SIGINT and SIGTERM by default,
+// so a rolling deploy frees the machineID at once instead of after the TTL of the lease.
+// The signal is consumed: the application handles it as well to shut down, unless opts.Reraise.
+//
+// It is safe to Close the lease explicitly as well, e.g. with the generator: the handler stops once the lease ends.
+// Call stop to stop handling the signals without releasing the lease.
+func ReleaseOnShutdown(lease MachineIDLease, opts ShutdownOptions) (stop func()) {
+	return onShutdown(lease.Done(), lease.Close, opts)
+}
+
+// CloseOnShutdown close the generator as soon as the process receives one of the signals of opts, SIGINT and SIGTERM by default,
+// which releases its machineID lease and flushes its persisted state, see Close and ReleaseOnShutdown.
+// This function is thread safe.
+func (g *Generator) CloseOnShutdown(opts ShutdownOptions) (stop func()) {
+	return onShutdown(g.done, g.Close, opts)
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// onShutdown call release on the first of the signals, until done is closed or stop is called.
+func onShutdown(done <-chan struct{}, release func(ctx context.Context) error, opts ShutdownOptions) func() {
+	timeout, signals := opts.Timeout, opts.Signals
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	if len(signals) == 0 {
+		signals = defaultShutdownSignals
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, signals...)
+	quit := make(chan struct{})
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			signal.Stop(sigs)
+			close(quit)
+		})
+	}
+
+	go func() {
+		select {
+		case sig := <-sigs:
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			released := make(chan struct{})
+			go func() {
+				_ = release(ctx)
+				close(released)
+			}()
+			// 存储不可达时最多等待 timeout
+			select {
+			case <-released:
+			case <-ctx.Done():
+			}
+			cancel()
+
+			// 停止处理；需要时重新发送信号，没有其它处理者的进程按原来的方式退出
+			stop()
+			if !opts.Reraise {
+				return
+			}
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				_ = p.Signal(sig)
+			}
+		case <-done:
+			stop()
+		case <-quit:
+		}
+	}()
+
+	return stop
+}
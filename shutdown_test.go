@@ -0,0 +1,130 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package snowflake_test
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// releaseLease records its release, which blocks until unblock is closed.
+type releaseLease struct {
+	done     chan struct{}
+	released chan struct{}
+	unblock  chan struct{}
+	once     sync.Once
+}
+
+func newReleaseLease(blocking bool) *releaseLease {
+	l := &releaseLease{done: make(chan struct{}), released: make(chan struct{}), unblock: make(chan struct{})}
+	if !blocking {
+		close(l.unblock)
+	}
+	return l
+}
+
+func (l *releaseLease) MachineID() uint16     { return 7 }
+func (l *releaseLease) Done() <-chan struct{} { return l.done }
+func (l *releaseLease) Err() error            { return nil }
+func (l *releaseLease) Close(ctx context.Context) error {
+	l.once.Do(func() { close(l.released) })
+	// an unreachable store ignores ctx.
+	<-l.unblock
+	return nil
+}
+
+// absorbSignal keep the test process alive when sig is sent again, it returns the channel receiving sig.
+func absorbSignal(t *testing.T, sig os.Signal) chan os.Signal {
+	ch := make(chan os.Signal, 4)
+	signal.Notify(ch, sig)
+	t.Cleanup(func() { signal.Stop(ch) })
+	return ch
+}
+
+func receive(t *testing.T, ch chan os.Signal, within time.Duration) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(within):
+		t.Fatal("The signal should be received")
+	}
+}
+
+func TestReleaseOnShutdown(t *testing.T) {
+	absorbed := absorbSignal(t, syscall.SIGUSR1)
+	l := newReleaseLease(false)
+	stop := snowflake.ReleaseOnShutdown(l, snowflake.ShutdownOptions{Timeout: time.Second, Signals: []os.Signal{syscall.SIGUSR1}, Reraise: true})
+	defer stop()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-l.released:
+	case <-time.After(time.Second):
+		t.Fatal("The lease should be released on the signal")
+	}
+	// the signal is received, then sent again once the lease is released.
+	receive(t, absorbed, time.Second)
+	receive(t, absorbed, time.Second)
+}
+
+func TestReleaseOnShutdown_Timeout(t *testing.T) {
+	absorbed := absorbSignal(t, syscall.SIGUSR2)
+	l := newReleaseLease(true)
+	defer close(l.unblock)
+	snowflake.ReleaseOnShutdown(l, snowflake.ShutdownOptions{Timeout: 20 * time.Millisecond, Signals: []os.Signal{syscall.SIGUSR2}, Reraise: true})
+
+	start := time.Now()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, absorbed, time.Second)
+	// the unreachable store doesn't delay the shutdown beyond the timeout.
+	receive(t, absorbed, time.Second)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("The shutdown should not wait for the release beyond the timeout, waited %s", elapsed)
+	}
+}
+
+func TestGenerator_CloseOnShutdown(t *testing.T) {
+	absorbed := absorbSignal(t, syscall.SIGUSR1)
+	l := newReleaseLease(false)
+	g, err := snowflake.New(snowflake.WithMachineIDLease(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := g.CloseOnShutdown(snowflake.ShutdownOptions{Timeout: time.Second, Signals: []os.Signal{syscall.SIGUSR1}})
+	defer stop()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, absorbed, time.Second)
+	select {
+	case <-l.released:
+	case <-time.After(time.Second):
+		t.Fatal("Close of the generator should release the lease")
+	}
+	// the signal is not sent again by default, the application handles it.
+	select {
+	case <-absorbed:
+		t.Error("The signal should not be sent again without Reraise")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if _, err := g.NextID(); err != snowflake.ErrClosed {
+		t.Errorf("The generator should be closed, got %v", err)
+	}
+	// the explicit Close after the signal is a no-op.
+	if err := g.Close(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,99 +1,121 @@
 package snowflake
 
 import (
-	"errors"
+	"context"
+	"math"
 	"sync/atomic"
 	"time"
 )
 
 // These constants are the bit lengths of snowflake ID parts.
 const (
-	TimestampLength uint8  = 43
+	TimestampLength uint8  = 42
 	MachineIDLength uint8  = 9
 	SequenceLength  uint8  = 12
 	MaxSequence     uint16 = 1<<SequenceLength - 1
 	MaxTimestamp    uint64 = 1<<TimestampLength - 1
 	MaxMachineID    uint16 = 1<<MachineIDLength - 1
-
-	machineIDMoveLength = SequenceLength
-	timestampMoveLength = MachineIDLength + SequenceLength
 )
 
-// SequenceResolver the snowflake sequence resolver.
+// SequenceResolver the snowflake sequence resolver, ms is the current tick of the generator, a millisecond by default.
 //
 // When you want to use the snowflake algorithm to generate unique ID, You must ensure: The sequence-number generated in the same millisecond of the same node is unique.
 // Based on this, we create this interface provide following resolver:
 //
 //	AtomicResolver : base sync/atomic (by default).
+//
+// A resolver needing the context of NextIDContext or the machineID implements Resolver instead.
+//
+// The contract of a resolver, checked by resolvertest.TestResolver:
+//
+//   - it is safe for concurrent use, the concurrent calls with the same ms get different sequences;
+//   - the sequences of a ms are unique, a ms greater than any ms seen before gets a fresh sequence space,
+//     the atomic resolver restarts from 0 and counts up, other resolvers may issue the sequences in any order;
+//   - it returns ErrSequenceExhausted once the sequence of ms is exhausted, and for a ms older than the latest one,
+//     the generator then moves to the next tick;
+//   - any other error is returned by NextID as is.
+//
+// A sequence greater than the max sequence of the layout is handled as exhausted too,
+// so a resolver can count up to 2^16-1 regardless of the layout.
 type SequenceResolver func(ms int64) (uint16, error)
 
 // default start time is 2008-11-10 23:00:00 UTC, why ? In the playground the time begins at 2009-11-10 23:00:00 UTC.
 // It can run on golang playground.
-// default machineID is 0
+// default machineID is random, picked by the first ID, see ErrUnmanagedMachineID
 // default resolver is AtomicResolver
 var (
-	resolver      SequenceResolver
-	machineID     uint64 = 0
-	startTime            = time.Date(2008, 11, 10, 23, 0, 0, 0, time.UTC)
-	lastTimestamp int64  = 0 // 👈 新增：记录上一次生成 ID 的毫秒时间（相对于 Unix）
+	defaultStartTime = time.Date(2008, 11, 10, 23, 0, 0, 0, time.UTC)
+	defaultGenerator = newGenerator()
 )
 
 // ID use ID to generate snowflake id, and it will ignore error. if you want error info, you need use NextID method.
+// ID returns 0 on error, a successful generation never produces 0, see OnError, LastError and SetPanicOnError.
 // This function is thread safe.
 func ID() uint64 {
-	id, _ := NextID()
-	return id
+	return defaultGenerator.ID()
 }
 
-// NextID use NextID to generate snowflake id and return an error.
-// This function is thread safe.
 // NextID use NextID to generate snowflake id and return an error.
 // This function is thread safe.
 func NextID() (uint64, error) {
-	now := currentMillis()
-	last := atomic.LoadInt64(&lastTimestamp)
-
-	// ⏰ 时钟回拨检测
-	if now < last {
-		backward := last - now
-		// 🛡️ 最大容忍回拨：5000 毫秒（5秒）
-		if backward > 5000 {
-			return 0, errors.New("clock moved backward too much (>5s), refusing to generate ID")
-		}
-		// 在容忍范围内，等待时间追上
-		time.Sleep(time.Duration(backward) * time.Millisecond)
-		now = currentMillis()
-	}
+	return defaultGenerator.NextID()
+}
 
-	// 获取序列号
-	seqResolver := callSequenceResolver()
-	seq, err := seqResolver(now)
-	if err != nil {
-		return 0, err
-	}
+// NextIDContext use NextIDContext to generate snowflake id, the waits for the clock return ctx.Err() once ctx is done.
+// This function is thread safe.
+func NextIDContext(ctx context.Context) (uint64, error) {
+	return defaultGenerator.NextIDContext(ctx)
+}
 
-	// 序列号溢出：等待下一毫秒
-	for seq >= MaxSequence {
-		now = waitForNextMillis(now)
-		seq, err = seqResolver(now)
-		if err != nil {
-			return 0, err
-		}
-	}
+// NextIDNoWait use NextIDNoWait to generate snowflake id without waiting for the clock,
+// see Generator.NextIDNoWait for the errors, the callers must handle the retry themselves.
+// This function is thread safe.
+func NextIDNoWait() (uint64, error) {
+	return defaultGenerator.NextIDNoWait()
+}
 
-	// 更新 lastTimestamp（必须在生成 ID 前完成）
-	atomic.StoreInt64(&lastTimestamp, now)
+// NextIDRetry use NextIDRetry to generate snowflake id, retrying the retryable errors, see Generator.NextIDRetry.
+// This function is thread safe.
+func NextIDRetry(ctx context.Context, p RetryPolicy) (uint64, error) {
+	return defaultGenerator.NextIDRetry(ctx, p)
+}
 
-	// 计算相对于 startTime 的偏移
-	df := elapsedTime(now, startTime)
-	if df < 0 || uint64(df) > MaxTimestamp {
-		return 0, errors.New("the maximum life cycle of the snowflake algorithm is 2^43-1(millis), please check start-time")
-	}
+// NextIDAt use NextIDAt to generate snowflake id at the historical time t, see Generator.NextIDAt.
+// This function is thread safe.
+func NextIDAt(t time.Time) (uint64, error) {
+	return defaultGenerator.NextIDAt(t)
+}
 
-	id := (uint64(df) << uint64(timestampMoveLength)) |
-		(machineID << uint64(machineIDMoveLength)) |
-		uint64(seq)
-	return id, nil
+// IDsAt use IDsAt to generate n snowflake ids at the historical time t, see Generator.IDsAt.
+// This function is thread safe.
+func IDsAt(t time.Time, n int) ([]uint64, error) {
+	return defaultGenerator.IDsAt(t, n)
+}
+
+// ForgetBackfill release the backfill state of the default generator before t, see Generator.ForgetBackfill.
+// This function is thread safe.
+func ForgetBackfill(t time.Time) {
+	defaultGenerator.ForgetBackfill(t)
+}
+
+// ReserveBlock claim n consecutive IDs of the default generator and returns the first one, see Generator.ReserveBlock.
+// This function is thread safe.
+func ReserveBlock(n int) (uint64, error) {
+	return defaultGenerator.ReserveBlock(n)
+}
+
+// NextInt64 use NextInt64 to generate snowflake id as int64 and return an error,
+// the id is never negative so it can be stored as a BIGINT or a Java long.
+// This function is thread safe.
+func NextInt64() (int64, error) {
+	return defaultGenerator.NextInt64()
+}
+
+// TrySetStartTime set the start time for snowflake algorithm, it returns an error instead of panicking,
+// see Generator.TrySetStartTime for the errors.
+// This function is thread-unsafe, recommended you call him in the main function.
+func TrySetStartTime(s time.Time) error {
+	return defaultGenerator.TrySetStartTime(s)
 }
 
 // SetStartTime set the start time for snowflake algorithm.
@@ -102,44 +124,157 @@ func NextID() (uint64, error) {
 //
 //	s IsZero
 //	s > current millisecond,
-//	current millisecond - s > 2^41(69 years).
+//	current millisecond - s > 2^42-1(139 years),
+//	an ID has already been generated.
 //
 // This function is thread-unsafe, recommended you call him in the main function.
 func SetStartTime(s time.Time) {
-	s = s.UTC()
+	defaultGenerator.SetStartTime(s)
+}
 
-	if s.IsZero() {
-		panic("The start time cannot be a zero value")
-	}
+// TrySetMachineID specify the machine ID, it returns an error instead of panicking,
+// see Generator.TrySetMachineID for the errors.
+// This function is thread-unsafe, recommended you call him in the main function.
+func TrySetMachineID(m uint16) error {
+	return defaultGenerator.TrySetMachineID(m)
+}
 
-	if s.After(time.Now().UTC()) {
-		panic("The s cannot be greater than the current millisecond")
-	}
+// SetMachineID specify the machine ID. It will panic when machined > max limit for 2^9-1,
+// or an ID has already been generated.
+// This function is thread-unsafe, recommended you call him in the main function.
+func SetMachineID(m uint16) {
+	defaultGenerator.SetMachineID(m)
+}
 
-	// since we check the current millisecond is greater than s, so we don't need to check the overflow.
-	df := elapsedTime(currentMillis(), s)
-	if uint64(df) > MaxTimestamp {
-		panic("The maximum life cycle of the snowflake algorithm is 279 years")
-	}
+// RequireExplicitMachineID make NextID of the default generator return ErrMachineIDNotSet until SetMachineID is called,
+// see Generator.RequireExplicitMachineID. Call it first in main, the replicas which forgot SetMachineID
+// would use random machineIDs, which may collide.
+// This function is thread safe.
+func RequireExplicitMachineID() {
+	defaultGenerator.RequireExplicitMachineID()
+}
 
-	startTime = s
+// SetMaxBackwardTolerance set the max clock backward the default generator waits for, see Generator.SetMaxBackwardTolerance.
+// This function is thread safe.
+func SetMaxBackwardTolerance(d time.Duration) {
+	defaultGenerator.SetMaxBackwardTolerance(d)
 }
 
-// SetMachineID specify the machine ID. It will panic when machined > max limit for 2^9-1.
+// SetBackwardPolicy set what the default generator does when the clock moved backward, see BackwardPolicy.
+// This function is thread safe.
+func SetBackwardPolicy(p BackwardPolicy) {
+	defaultGenerator.SetBackwardPolicy(p)
+}
+
+// SetClock set the time source of the default generator, see Generator.SetClock.
+// It will panic when an ID has already been generated.
 // This function is thread-unsafe, recommended you call him in the main function.
-func SetMachineID(m uint16) {
-	if m > MaxMachineID {
-		panic("The machineID cannot be greater than 511")
-	}
-	machineID = uint64(m)
+func SetClock(c Clock) {
+	defaultGenerator.SetClock(c)
 }
 
 // SetSequenceResolver set a custom sequence resolver.
+// It will panic when an ID has already been generated.
 // This function is thread-unsafe, recommended you call him in the main function.
 func SetSequenceResolver(seq SequenceResolver) {
-	if seq != nil {
-		resolver = seq
-	}
+	defaultGenerator.SetSequenceResolver(seq)
+}
+
+// ResetForTesting unfreeze the configuration so the setters can be called again after IDs were generated.
+// It is an escape hatch for tests and will panic when it is not called from a test binary.
+func ResetForTesting() {
+	defaultGenerator.ResetForTesting()
+}
+
+// MachineID returns the machine ID of the default generator.
+// This function is thread safe.
+func MachineID() uint16 {
+	return defaultGenerator.MachineID()
+}
+
+// StartTime returns the start time of the default generator.
+// This function is thread safe.
+func StartTime() time.Time {
+	return defaultGenerator.StartTime()
+}
+
+// LifetimeEnd returns the time the timestamp part of the default generator runs out, see Generator.LifetimeEnd.
+// This function is thread safe.
+func LifetimeEnd() time.Time {
+	return defaultGenerator.LifetimeEnd()
+}
+
+// Remaining returns how long the default generator can still generate IDs, see Generator.Remaining.
+// This function is thread safe.
+func Remaining() time.Duration {
+	return defaultGenerator.Remaining()
+}
+
+// ResolverName returns the name of the sequence resolver of the default generator, see Generator.ResolverName.
+// This function is thread safe.
+func ResolverName() string {
+	return defaultGenerator.ResolverName()
+}
+
+// Default returns the default generator used by the package-level functions, e.g. Default().Layout().
+func Default() *Generator {
+	return defaultGenerator
+}
+
+// Reset restore the default generator to the package defaults: start time, no machineID, atomic resolver and DefaultLayout,
+// it is meant for tests which must not leak the state between cases.
+// The previous default generator is closed, which stops its background goroutines and releases its machineID lease,
+// the generators returned by Default before Reset return ErrClosed.
+// This function is thread-unsafe, don't call him while IDs are generated.
+func Reset() {
+	old := defaultGenerator
+	defaultGenerator = newGenerator()
+	_ = old.Close(context.Background())
+}
+
+// WaitUntilSafe block until the clock is strictly past the timestamp of lastKnownID, see Generator.WaitUntilSafe.
+// This function is thread safe.
+func WaitUntilSafe(ctx context.Context, lastKnownID uint64) error {
+	return defaultGenerator.WaitUntilSafe(ctx, lastKnownID)
+}
+
+// OnClockEvent register fn to be called when the default generator detects a clock anomaly, see Generator.OnClockEvent.
+// This function is thread safe.
+func OnClockEvent(fn func(ev ClockEvent)) {
+	defaultGenerator.OnClockEvent(fn)
+}
+
+// OnSequenceExhausted register fn to be called when the default generator waited for the next tick,
+// see Generator.OnSequenceExhausted.
+// This function is thread safe.
+func OnSequenceExhausted(fn func(ms int64, waited time.Duration)) {
+	defaultGenerator.OnSequenceExhausted(fn)
+}
+
+// OnHighUtilization register fn to be called when a tick of the default generator used more than threshold
+// of its sequence space, see Generator.OnHighUtilization.
+// This function is thread safe.
+func OnHighUtilization(threshold float64, fn func(ms int64, used uint16)) {
+	defaultGenerator.OnHighUtilization(threshold, fn)
+}
+
+// OnError register fn to be called with the error whenever ID of the default generator ignores an error,
+// see Generator.OnError.
+// This function is thread safe.
+func OnError(fn func(err error)) {
+	defaultGenerator.OnError(fn)
+}
+
+// LastError returns the last error ignored by ID of the default generator, or nil.
+// This function is thread safe.
+func LastError() error {
+	return defaultGenerator.LastError()
+}
+
+// SetPanicOnError set whether ID of the default generator panics with the error instead of returning 0.
+// This function is thread safe.
+func SetPanicOnError(p bool) {
+	defaultGenerator.SetPanicOnError(p)
 }
 
 // SID snowflake id
@@ -148,56 +283,48 @@ type SID struct {
 	MachineID uint64
 	Timestamp uint64
 	ID        uint64
+
+	// DatacenterID and WorkerID are the two parts of MachineID split by Layout.DatacenterBits,
+	// without datacenter bits DatacenterID is 0 and WorkerID equals MachineID.
+	DatacenterID uint64
+	WorkerID     uint64
+
+	// epoch and timeUnit come from the generator which parsed the id, zero timeUnit means the default generator.
+	epoch    int64
+	timeUnit time.Duration
 }
 
 // GenerateTime snowflake generate at, return a UTC time.
 func (id *SID) GenerateTime() time.Time {
-	ms := startTime.UTC().UnixNano()/1e6 + int64(id.Timestamp)
+	epoch, unit := id.epoch, id.timeUnit
+	if unit == 0 {
+		epoch, unit = atomic.LoadInt64(&defaultGenerator.epoch), defaultGenerator.timeUnit
+	}
+	ticks := epoch + int64(id.Timestamp)
 
-	return time.Unix(0, ms*int64(time.Millisecond)).UTC()
+	return time.Unix(0, ticks*int64(unit)).UTC()
 }
 
-// ParseID parse snowflake it to SID struct.
+// ParseID parse snowflake it to SID struct with the layout of the default generator.
 func ParseID(id uint64) SID {
-	t := id >> uint64(SequenceLength+MachineIDLength)
-	sequence := id & uint64(MaxSequence)
-	mID := (id & (uint64(MaxMachineID) << SequenceLength)) >> SequenceLength
-
-	return SID{
-		ID:        id,
-		Sequence:  sequence,
-		MachineID: mID,
-		Timestamp: t,
-	}
+	return defaultGenerator.layout.parse(id)
+}
+
+// ParseInt64 parse snowflake id stored as int64 to SID struct with the layout of the default generator.
+// A negative value is not a valid snowflake id, its sign bit is ignored.
+func ParseInt64(id int64) SID {
+	return ParseID(uint64(id) & math.MaxInt64)
 }
 
 //--------------------------------------------------------------------
 // private function defined.
 //--------------------------------------------------------------------
 
-func waitForNextMillis(last int64) int64 {
-	for {
-		now := currentMillis()
-		if now > last {
-			return now
-		}
-		// 避免 CPU 空转，微小休眠
-		time.Sleep(1 * time.Nanosecond)
-	}
-}
-func callSequenceResolver() SequenceResolver {
-	if resolver == nil {
-		return AtomicResolver
-	}
-
-	return resolver
-}
-
-func elapsedTime(noms int64, s time.Time) int64 {
-	return noms - s.UTC().UnixNano()/1e6
+func elapsedTicks(now int64, s time.Time, unit time.Duration) int64 {
+	return now - toTicks(s, unit)
 }
 
-// currentMillis get current millisecond.
-func currentMillis() int64 {
-	return time.Now().UTC().UnixNano() / 1e6
+// toTicks convert t to the number of time units since the unix epoch.
+func toTicks(t time.Time, unit time.Duration) int64 {
+	return t.UTC().UnixNano() / int64(unit)
 }
@@ -1,7 +1,9 @@
 package snowflake_test
 
 import (
+	"context"
 	"errors"
+	"math"
 	"sync"
 	"testing"
 	"time"
@@ -60,6 +62,8 @@ func TestID_bitch(t *testing.T) {
 }
 
 func TestSetStartTime(t *testing.T) {
+	snowflake.ResetForTesting()
+
 	t.Run("A nil time", func(tt *testing.T) {
 		defer func() {
 			if e := recover(); e == nil {
@@ -88,11 +92,11 @@ func TestSetStartTime(t *testing.T) {
 		defer func() {
 			if e := recover(); e == nil {
 				tt.Error("Should throw a error when starttime is too small")
-			} else if e.(string) != "The maximum life cycle of the snowflake algorithm is 279 years" {
-				tt.Error("The error message should equal [The maximum life cycle of the snowflake algorithm is 279 years]")
+			} else if e.(string) != "The maximum life cycle of the snowflake algorithm is 139 years" {
+				tt.Error("The error message should equal [The maximum life cycle of the snowflake algorithm is 139 years]")
 			}
 		}()
-		// Set a time that would exceed 43-bit timestamp limit (279 years)
+		// Set a time that would exceed 42-bit timestamp limit (139 years)
 		// Use a very early date that Go supports
 		time := time.Date(1000, 1, 1, 1, 0, 0, 0, time.UTC)
 		snowflake.SetStartTime(time)
@@ -118,6 +122,7 @@ func TestSetStartTime(t *testing.T) {
 	})
 
 	t.Run("Basic", func(tt *testing.T) {
+		snowflake.ResetForTesting()
 		date := time.Date(2002, 1, 1, 1, 0, 0, 0, time.UTC)
 		snowflake.SetStartTime(date)
 
@@ -137,8 +142,8 @@ func TestSetStartTime(t *testing.T) {
 func TestSetMachineID(t *testing.T) {
 	// first test,
 	sid := snowflake.ParseID(snowflake.ID())
-	if sid.MachineID != 0 {
-		t.Error("MachineID should be equal 0")
+	if sid.MachineID != uint64(snowflake.MachineID()) || !snowflake.Default().Stats().UnmanagedMachineID {
+		t.Error("MachineID should be the random machineID picked without configuration")
 	}
 
 	t.Run("No Panic", func(tt *testing.T) {
@@ -148,6 +153,7 @@ func TestSetMachineID(t *testing.T) {
 			}
 		}()
 
+		snowflake.ResetForTesting()
 		snowflake.SetMachineID(1)
 		id := snowflake.ID()
 		sid := snowflake.ParseID(id)
@@ -166,9 +172,11 @@ func TestSetMachineID(t *testing.T) {
 			}
 		}()
 
+		snowflake.ResetForTesting()
 		snowflake.SetMachineID(512)
 	})
 
+	snowflake.ResetForTesting()
 	snowflake.SetMachineID(100)
 	sid = snowflake.ParseID(snowflake.ID())
 	if sid.MachineID != 100 {
@@ -177,6 +185,7 @@ func TestSetMachineID(t *testing.T) {
 }
 
 func TestSetSequenceResolver(t *testing.T) {
+	snowflake.ResetForTesting()
 	snowflake.SetSequenceResolver(func(c int64) (uint16, error) {
 		return 100, nil
 	})
@@ -209,6 +218,7 @@ func TestNextID(t *testing.T) {
 		return
 	}
 
+	snowflake.ResetForTesting()
 	snowflake.SetSequenceResolver(func(ms int64) (uint16, error) {
 		return 0, errors.New("test error")
 	})
@@ -242,6 +252,7 @@ func TestParseID(t *testing.T) {
 }
 
 func TestSID_GenerateTime(t *testing.T) {
+	snowflake.ResetForTesting()
 	snowflake.SetSequenceResolver(snowflake.AtomicResolver)
 	a, e := snowflake.NextID()
 	if e != nil {
@@ -255,3 +266,179 @@ func TestSID_GenerateTime(t *testing.T) {
 		t.Error("The id generate time should be equal current time")
 	}
 }
+
+func TestNextInt64(t *testing.T) {
+	id, err := snowflake.NextInt64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id <= 0 {
+		t.Error("The snowflake should't <= 0.")
+	}
+
+	sid := snowflake.ParseInt64(id)
+	if sid.ID != uint64(id) {
+		t.Error("ParseInt64 should be equal ParseID")
+	}
+	if snowflake.ParseInt64(-id).ID != uint64(-id)&math.MaxInt64 {
+		t.Error("The sign bit should be ignored")
+	}
+}
+
+func TestNextInt64_NearEndOfLife(t *testing.T) {
+	// the timestamp is close to its max value, so the highest timestamp bit (bit 62) is set.
+	layout := snowflake.Layout{TimestampBits: 39, MachineBits: 12, SequenceBits: 12}
+	start := time.Now().Add(-time.Duration(layout.MaxTimestamp())*time.Millisecond + time.Hour)
+	g, err := snowflake.New(snowflake.WithLayout(layout), snowflake.WithStartTime(start), snowflake.WithMachineID(4095))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10000; i++ {
+		id, err := g.NextInt64()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id < 0 || id>>62 != 1 {
+			t.Fatal("The id should use bit 62 but not bit 63", id)
+		}
+	}
+}
+
+func TestSignBitUnused(t *testing.T) {
+	layouts := []snowflake.Layout{
+		snowflake.DefaultLayout,
+		snowflake.TwitterLayout,
+		snowflake.SonyflakeLayout,
+		{TimestampBits: 44, MachineBits: 11, SequenceBits: 8, DatacenterBits: 3},
+		{TimestampBits: 31, MachineBits: 16, SequenceBits: 16},
+	}
+
+	for _, l := range layouts {
+		g, err := snowflake.New(snowflake.WithLayout(l), snowflake.WithStartTime(time.Now().Add(-time.Hour)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// bit 63 belongs to no part, and bits 0-62 belong to exactly one part.
+		if sid := g.ParseID(1 << 63); sid.Timestamp != 0 || sid.MachineID != 0 || sid.Sequence != 0 {
+			t.Errorf("%s: the sign bit should not be used, got %+v", l, sid)
+		}
+		sid := g.ParseID(math.MaxInt64)
+		if sid.Timestamp != l.MaxTimestamp() || sid.MachineID != uint64(l.MaxMachineID()) || sid.Sequence != uint64(l.MaxSequence()) {
+			t.Errorf("%s: bits 0-62 should be used, got %+v", l, sid)
+		}
+	}
+}
+
+func TestTrySetStartTime(t *testing.T) {
+	snowflake.ResetForTesting()
+
+	cases := []struct {
+		name string
+		s    time.Time
+		err  error
+	}{
+		{"zero", time.Time{}, snowflake.ErrZeroStartTime},
+		{"future", time.Now().Add(time.Hour), snowflake.ErrFutureStartTime},
+		{"too early", time.Date(1000, 1, 1, 1, 0, 0, 0, time.UTC), snowflake.ErrStartTimeTooEarly},
+	}
+	for _, c := range cases {
+		err := snowflake.TrySetStartTime(c.s)
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s: the error should be %v, got %v", c.name, c.err, err)
+		}
+		for _, other := range cases {
+			if other.err != c.err && errors.Is(err, other.err) {
+				t.Errorf("%s: the errors should be distinct", c.name)
+			}
+		}
+	}
+
+	if err := snowflake.TrySetStartTime(time.Date(2008, 11, 10, 23, 0, 0, 0, time.UTC)); err != nil {
+		t.Error(err)
+	}
+
+	snowflake.ID()
+	if err := snowflake.TrySetStartTime(time.Date(2008, 11, 10, 23, 0, 0, 0, time.UTC)); !errors.Is(err, snowflake.ErrConfigFrozen) {
+		t.Error("The error should be ErrConfigFrozen, got", err)
+	}
+}
+
+func TestTrySetMachineID(t *testing.T) {
+	snowflake.ResetForTesting()
+
+	if err := snowflake.TrySetMachineID(512); !errors.Is(err, snowflake.ErrMachineIDTooLarge) {
+		t.Error("The error should be ErrMachineIDTooLarge, got", err)
+	} else if err.Error() != "The machineID cannot be greater than 511" {
+		t.Error("The error message should be eq 「The machineID cannot be greater than 511」")
+	}
+
+	if err := snowflake.TrySetMachineID(0); err != nil {
+		t.Error(err)
+	}
+
+	snowflake.ID()
+	if err := snowflake.TrySetMachineID(1); !errors.Is(err, snowflake.ErrConfigFrozen) {
+		t.Error("The error should be ErrConfigFrozen, got", err)
+	}
+
+	// the option errors wrap the same sentinel errors.
+	if _, err := snowflake.New(snowflake.WithMachineID(512)); !errors.Is(err, snowflake.ErrMachineIDTooLarge) {
+		t.Error("The error should be ErrMachineIDTooLarge, got", err)
+	}
+	if _, err := snowflake.New(snowflake.WithStartTime(time.Time{})); !errors.Is(err, snowflake.ErrZeroStartTime) {
+		t.Error("The error should be ErrZeroStartTime, got", err)
+	}
+}
+
+func TestReset(t *testing.T) {
+	tests := []uint16{1, 2, 3}
+	for _, m := range tests {
+		snowflake.Reset()
+		if snowflake.MachineID() != 0 {
+			t.Fatalf("Reset should restore the machineID, got %d", snowflake.MachineID())
+		}
+
+		snowflake.SetMachineID(m)
+		snowflake.SetSequenceResolver(snowflake.AtomicResolver)
+		_ = snowflake.ID()
+
+		if snowflake.MachineID() != m {
+			t.Errorf("The machineID should be %d, got %d", m, snowflake.MachineID())
+		}
+	}
+
+	snowflake.Reset()
+	if !snowflake.StartTime().Equal(time.Date(2008, 11, 10, 23, 0, 0, 0, time.UTC)) {
+		t.Errorf("Reset should restore the start time, got %s", snowflake.StartTime())
+	}
+	if snowflake.ResolverName() != "atomic" {
+		t.Errorf("Reset should restore the resolver, got %s", snowflake.ResolverName())
+	}
+	if snowflake.Default().Layout() != snowflake.DefaultLayout {
+		t.Errorf("Reset should restore the layout, got %s", snowflake.Default().Layout())
+	}
+}
+
+func TestReset_Close(t *testing.T) {
+	defer snowflake.Reset()
+
+	old := snowflake.Default()
+	closed := false
+	_ = old.OnClose(func(ctx context.Context) error {
+		closed = true
+		return nil
+	})
+
+	snowflake.Reset()
+	if !closed {
+		t.Error("Reset should close the previous default generator")
+	}
+	if _, err := old.NextID(); !errors.Is(err, snowflake.ErrClosed) {
+		t.Errorf("The previous default generator should return ErrClosed, got %v", err)
+	}
+	if snowflake.Default() == old {
+		t.Error("Reset should replace the default generator")
+	}
+}
@@ -0,0 +1,88 @@
+package snowflake
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Value returns the ID as an int64 for the BIGINT columns, see driver.Valuer.
+// The IDs above math.MaxInt64 are an error instead of negative numbers, the default layout never generates them.
+func (id TID) Value() (driver.Value, error) {
+	if id > math.MaxInt64 {
+		return nil, fmt.Errorf("snowflake: the ID %d overflows the int64 of BIGINT", uint64(id))
+	}
+
+	return int64(id), nil
+}
+
+// Scan reads the ID of an int64, uint64, decimal string or []byte column, see sql.Scanner.
+// NULL and the negative numbers are an error, scan the nullable columns into a NullTID.
+func (id *TID) Scan(src interface{}) error {
+	var v uint64
+	switch src := src.(type) {
+	case int64:
+		if src < 0 {
+			return fmt.Errorf("snowflake: cannot scan the negative ID %d", src)
+		}
+		v = uint64(src)
+	case uint64:
+		v = src
+	case string:
+		return id.scanString(src)
+	case []byte:
+		return id.scanString(string(src))
+	case nil:
+		return errors.New("snowflake: cannot scan NULL into a TID, use a NullTID")
+	default:
+		return fmt.Errorf("snowflake: cannot scan %T into a TID", src)
+	}
+	*id = TID(v)
+
+	return nil
+}
+
+// NullTID is a TID which may be NULL, as sql.NullInt64.
+type NullTID struct {
+	ID    TID
+	Valid bool // Valid is true if ID is not NULL
+}
+
+// Value returns nil for NULL, otherwise the value of the ID, see TID.Value.
+func (n NullTID) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+
+	return n.ID.Value()
+}
+
+// Scan reads NULL or an ID, see TID.Scan.
+func (n *NullTID) Scan(src interface{}) error {
+	if src == nil {
+		n.ID, n.Valid = 0, false
+		return nil
+	}
+	if err := n.ID.Scan(src); err != nil {
+		return err
+	}
+	n.Valid = true
+
+	return nil
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+func (id *TID) scanString(s string) error {
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("snowflake: cannot scan the ID %q: %w", s, err)
+	}
+	*id = TID(v)
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package snowflake_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// stubDriver is a database/sql driver whose queries return one row holding the value of the query,
+// and whose statements record their arguments.
+type stubDriver struct {
+	mu     sync.Mutex
+	values map[string]driver.Value
+	args   []driver.Value
+}
+
+var stub = &stubDriver{values: map[string]driver.Value{}}
+
+func init() {
+	sql.Register("snowflake-stub", stub)
+}
+
+func (d *stubDriver) Open(name string) (driver.Conn, error) { return stubConn{d}, nil }
+
+type stubConn struct{ d *stubDriver }
+
+func (c stubConn) Prepare(query string) (driver.Stmt, error) { return stubStmt{c.d, query}, nil }
+func (c stubConn) Close() error                              { return nil }
+func (c stubConn) Begin() (driver.Tx, error)                 { return nil, errors.New("stub: no transactions") }
+
+type stubStmt struct {
+	d     *stubDriver
+	query string
+}
+
+func (s stubStmt) Close() error  { return nil }
+func (s stubStmt) NumInput() int { return -1 }
+
+func (s stubStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.args = args
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s stubStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	return &stubRows{v: s.d.values[s.query]}, nil
+}
+
+type stubRows struct {
+	v    driver.Value
+	done bool
+}
+
+func (r *stubRows) Columns() []string { return []string{"id"} }
+func (r *stubRows) Close() error      { return nil }
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.v
+
+	return nil
+}
+
+func openStub(t *testing.T, values map[string]driver.Value) *sql.DB {
+	t.Helper()
+
+	stub.mu.Lock()
+	stub.values, stub.args = values, nil
+	stub.mu.Unlock()
+	db, err := sql.Open("snowflake-stub", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestTID_Scan(t *testing.T) {
+	db := openStub(t, map[string]driver.Value{
+		"int64":    int64(math.MaxInt64),
+		"uint64":   uint64(math.MaxUint64),
+		"string":   "1541815603606036480",
+		"bytes":    []byte("42"),
+		"null":     nil,
+		"negative": int64(-1),
+		"junk":     "12a",
+		"float":    1.5,
+	})
+
+	tests := []struct {
+		query   string
+		want    snowflake.TID
+		wantErr bool
+	}{
+		{query: "int64", want: math.MaxInt64},
+		{query: "uint64", want: math.MaxUint64},
+		{query: "string", want: 1541815603606036480},
+		{query: "bytes", want: 42},
+		{query: "null", wantErr: true},
+		{query: "negative", wantErr: true},
+		{query: "junk", wantErr: true},
+		{query: "float", wantErr: true},
+	}
+	for _, tt := range tests {
+		var got snowflake.TID
+		err := db.QueryRow(tt.query).Scan(&got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Scan of %s error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Scan of %s = %d, want %d", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestNullTID_Scan(t *testing.T) {
+	db := openStub(t, map[string]driver.Value{"null": nil, "int64": int64(42)})
+
+	n := snowflake.NullTID{ID: 7, Valid: true}
+	if err := db.QueryRow("null").Scan(&n); err != nil || n.Valid || n.ID != 0 {
+		t.Errorf("NULL should scan into an invalid NullTID, got %+v, %v", n, err)
+	}
+	if err := db.QueryRow("int64").Scan(&n); err != nil || !n.Valid || n.ID != 42 {
+		t.Errorf("The ID should scan into a valid NullTID, got %+v, %v", n, err)
+	}
+}
+
+func TestTID_Value(t *testing.T) {
+	db := openStub(t, nil)
+
+	if _, err := db.Exec("insert", snowflake.TID(math.MaxInt64), snowflake.NullTID{}, snowflake.NullTID{ID: 1, Valid: true}); err != nil {
+		t.Fatal(err)
+	}
+	stub.mu.Lock()
+	args := stub.args
+	stub.mu.Unlock()
+	if len(args) != 3 || args[0] != int64(math.MaxInt64) || args[1] != nil || args[2] != int64(1) {
+		t.Errorf("The IDs should be int64 arguments, got %#v", args)
+	}
+
+	if _, err := db.Exec("insert", snowflake.TID(math.MaxInt64+1)); err == nil {
+		t.Error("An ID above math.MaxInt64 should be rejected")
+	}
+}
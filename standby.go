@@ -0,0 +1,90 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// standbyMachineID is the state of WithStandbyMachineID.
+type standbyMachineID struct {
+	// failovers is the counter of Stats, active is 1 while the generator uses the standby machineID, accessed atomically.
+	failovers uint64
+	active    int32
+
+	id uint16
+}
+
+// WithStandbyMachineID pre-register a standby machineID: once the machineID lease is lost, see WithMachineIDLease,
+// or a duplicate of the machineID is detected, see WithDuplicateDetection, the generator switches to id
+// with the fence of SwitchMachineID instead of halting.
+// The standby machineID must be reserved for the process, e.g. leased or configured, like the primary one.
+//
+// The failover is reported to the OnMachineIDChange hooks with the loss as Reason, and counted by Stats.
+// The generator stays on the standby machineID until RestorePrimary, it never switches back by itself to avoid flapping.
+// A second loss or duplicate while on the standby machineID cannot fail over, NextID returns an error wrapping ErrLeaseLost,
+// or ErrDuplicateMachineID with WithHaltOnDuplicate.
+func WithStandbyMachineID(id uint16) Option {
+	return func(g *Generator) error {
+		g.standby = &standbyMachineID{id: id}
+
+		return nil
+	}
+}
+
+// RestorePrimary switch the generator from the standby machineID back to the machineID of l, leased again
+// after the failover, with the fence of SwitchMachineID. The standby machineID is then armed for the loss of l,
+// and Close of the generator releases l.
+// This function is thread safe.
+func (g *Generator) RestorePrimary(l MachineIDLease) error {
+	s := g.standby
+	if s == nil {
+		return errors.New("snowflake: no standby machineID, see WithStandbyMachineID")
+	}
+	if l == nil {
+		return errors.New("snowflake: the lease cannot be nil")
+	}
+	if l.MachineID() == s.id {
+		return fmt.Errorf("snowflake: invalid lease of machineID %d: it must differ from the standby machineID", s.id)
+	}
+	if !atomic.CompareAndSwapInt32(&s.active, 1, 0) {
+		return errors.New("snowflake: the generator doesn't use the standby machineID")
+	}
+
+	ev, err := g.switchMachineID(l.MachineID())
+	if err != nil {
+		atomic.StoreInt32(&s.active, 1)
+		return err
+	}
+	g.emitMachineIDEvent(ev)
+	if err := g.OnClose(l.Close); err != nil {
+		return err
+	}
+	g.watchLeaseLoss(l)
+
+	return nil
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// failover switch to the standby machineID after reason, the loss of the lease or a duplicate, unless the generator already uses it.
+func (g *Generator) failover(reason error) error {
+	s := g.standby
+	if !atomic.CompareAndSwapInt32(&s.active, 0, 1) {
+		return errors.New("snowflake: the generator already uses the standby machineID")
+	}
+
+	// 切换到备用 machineID，失败时恢复状态
+	ev, err := g.switchMachineID(s.id)
+	if err != nil {
+		atomic.StoreInt32(&s.active, 0)
+		return err
+	}
+	atomic.AddUint64(&s.failovers, 1)
+	ev.Reason = reason
+	g.emitMachineIDEvent(ev)
+
+	return nil
+}
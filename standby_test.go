@@ -0,0 +1,87 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestWithStandbyMachineID(t *testing.T) {
+	l := newFakeLease(1)
+	g, err := snowflake.New(snowflake.WithMachineIDLease(l), snowflake.WithStandbyMachineID(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close(context.Background())
+
+	events := make(chan snowflake.MachineIDEvent, 2)
+	g.OnMachineIDChange(func(ev snowflake.MachineIDEvent) {
+		events <- ev
+	})
+	if _, err := g.NextID(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the lost lease fails over to the standby machineID instead of halting.
+	l.lose()
+	select {
+	case ev := <-events:
+		if ev.Old != 1 || ev.New != 2 || !errors.Is(ev.Reason, snowflake.ErrLeaseLost) {
+			t.Errorf("Unexpected failover event %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("The failover should be reported")
+	}
+	id, err := g.NextID()
+	if err != nil {
+		t.Fatalf("NextID should use the standby machineID, got %v", err)
+	}
+	if m := g.ParseID(id).MachineID; m != 2 {
+		t.Errorf("The IDs should use the standby machineID, got %d", m)
+	}
+	if stats := g.Stats(); !stats.OnStandby || stats.Failovers != 1 {
+		t.Errorf("The failover should be counted, got %+v", stats)
+	}
+
+	// the generator switches back only by RestorePrimary.
+	if err := g.RestorePrimary(newFakeLease(2)); err == nil {
+		t.Error("The lease of the standby machineID should be rejected")
+	}
+	primary := newFakeLease(1)
+	if err := g.RestorePrimary(primary); err != nil {
+		t.Fatal(err)
+	}
+	if g.MachineID() != 1 || g.Stats().OnStandby {
+		t.Errorf("The primary machineID should be restored, got %d", g.MachineID())
+	}
+	if ev := <-events; ev.New != 1 || ev.Reason != nil {
+		t.Errorf("Unexpected restore event %+v", ev)
+	}
+	if err := g.RestorePrimary(primary); err == nil {
+		t.Error("RestorePrimary should fail when the generator doesn't use the standby machineID")
+	}
+
+	// the standby machineID is armed again for the restored lease.
+	primary.lose()
+	<-events
+	if g.MachineID() != 2 || g.Stats().Failovers != 2 {
+		t.Errorf("The loss of the restored lease should fail over, got %d", g.MachineID())
+	}
+}
+
+func TestWithStandbyMachineID_Invalid(t *testing.T) {
+	if _, err := snowflake.New(snowflake.WithMachineID(3), snowflake.WithStandbyMachineID(3)); err == nil {
+		t.Error("The standby machineID should differ from the machineID")
+	}
+	if _, err := snowflake.New(snowflake.WithStandbyMachineID(snowflake.MaxMachineID + 1)); !errors.Is(err, snowflake.ErrMachineIDTooLarge) {
+		t.Errorf("The error should be ErrMachineIDTooLarge, got %v", err)
+	}
+
+	g, _ := snowflake.New(snowflake.WithMachineID(1))
+	if err := g.RestorePrimary(newFakeLease(1)); err == nil {
+		t.Error("RestorePrimary should fail without a standby machineID")
+	}
+}
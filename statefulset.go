@@ -0,0 +1,67 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrNoOrdinal is returned by MachineIDFromStatefulSetOrdinal when the pod name doesn't end with -N.
+var ErrNoOrdinal = errors.New("snowflake: the pod name has no StatefulSet ordinal")
+
+// MachineIDFromStatefulSetOrdinal returns offset plus the ordinal of a StatefulSet pod, e.g. 107 for myapp-7 with offset 100,
+// the pods of a StatefulSet get unique machineIDs without any coordination. An empty podName reads the HOSTNAME
+// environment variable, which kubernetes sets to the pod name.
+//
+// The offset lets several StatefulSets share the machineIDs: set A uses offset 0 for 0-99 and set B uses offset 100
+// for 100-199, keep the replicas of a set below the gap. The machineID must fit MachineIDLength bits,
+// use StatefulSetOrdinal to check it against the machine bits of a generator.
+func MachineIDFromStatefulSetOrdinal(podName string, offset uint16) (uint16, error) {
+	return statefulSetOrdinal(podName, offset)(MachineIDLength)
+}
+
+// StatefulSetOrdinal set the machineID of the generator from the ordinal of a StatefulSet pod,
+// checked against the machine bits of its layout, see MachineIDFromStatefulSetOrdinal.
+func StatefulSetOrdinal(podName string, offset uint16) Option {
+	return func(g *Generator) error {
+		g.derivedMachineID, g.derivedBy = statefulSetOrdinal(podName, offset), "StatefulSetOrdinal"
+
+		return nil
+	}
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+func statefulSetOrdinal(podName string, offset uint16) func(bits uint8) (uint16, error) {
+	return func(bits uint8) (uint16, error) {
+		if bits == 0 || bits > 16 {
+			return 0, fmt.Errorf("snowflake: invalid machine bits %d, it must be between 1 and 16", bits)
+		}
+
+		name := podName
+		if name == "" {
+			name = os.Getenv("HOSTNAME")
+		}
+		if name == "" {
+			return 0, fmt.Errorf("%w: the HOSTNAME environment variable is empty", ErrNoOrdinal)
+		}
+
+		i := strings.LastIndexByte(name, '-')
+		ordinal, err := strconv.ParseUint(name[i+1:], 10, 16)
+		if i <= 0 || err != nil {
+			return 0, fmt.Errorf("%w: %q doesn't match the <statefulset>-<ordinal> pattern", ErrNoOrdinal, name)
+		}
+
+		max := uint64(1)<<bits - 1
+		if id := ordinal + uint64(offset); id > max {
+			return 0, fmt.Errorf("snowflake: the ordinal %d of %s plus the offset %d is %d, it exceeds the max machineID %d of %d bits",
+				ordinal, name, offset, id, max, bits)
+		}
+
+		return uint16(ordinal) + offset, nil
+	}
+}
@@ -0,0 +1,60 @@
+package snowflake_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestMachineIDFromStatefulSetOrdinal(t *testing.T) {
+	setenv(t, "HOSTNAME", "myapp-7")
+
+	tests := []struct {
+		podName string
+		offset  uint16
+		want    uint16
+		err     error
+	}{
+		{"", 0, 7, nil},
+		{"orders-api-12", 0, 12, nil},
+		{"orders-api-12", 100, 112, nil},
+		{"myapp-0", 511, 511, nil},
+		{"myapp-1", 511, 0, errors.New("exceeds")},
+		{"myapp", 0, 0, snowflake.ErrNoOrdinal},
+		{"myapp-", 0, 0, snowflake.ErrNoOrdinal},
+		{"myapp-7f4c9", 0, 0, snowflake.ErrNoOrdinal},
+		{"-7", 0, 0, snowflake.ErrNoOrdinal},
+	}
+	for _, tt := range tests {
+		got, err := snowflake.MachineIDFromStatefulSetOrdinal(tt.podName, tt.offset)
+		switch {
+		case tt.err == nil && err != nil:
+			t.Errorf("MachineIDFromStatefulSetOrdinal(%q, %d) error: %v", tt.podName, tt.offset, err)
+		case tt.err != nil && err == nil:
+			t.Errorf("MachineIDFromStatefulSetOrdinal(%q, %d) should fail, got %d", tt.podName, tt.offset, got)
+		case tt.err == snowflake.ErrNoOrdinal && !errors.Is(err, snowflake.ErrNoOrdinal):
+			t.Errorf("MachineIDFromStatefulSetOrdinal(%q, %d) error should be ErrNoOrdinal, got %v", tt.podName, tt.offset, err)
+		case tt.err == nil && got != tt.want:
+			t.Errorf("MachineIDFromStatefulSetOrdinal(%q, %d) = %d, want %d", tt.podName, tt.offset, got, tt.want)
+		}
+	}
+}
+
+func TestStatefulSetOrdinal(t *testing.T) {
+	g, err := snowflake.New(snowflake.StatefulSetOrdinal("myapp-300", 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.MachineID() != 500 {
+		t.Errorf("The machineID should be the ordinal plus the offset, got %d", g.MachineID())
+	}
+
+	layout := snowflake.Layout{TimestampBits: 41, MachineBits: 4, SequenceBits: 18}
+	if _, err := snowflake.New(snowflake.WithLayout(layout), snowflake.StatefulSetOrdinal("myapp-16", 0)); err == nil {
+		t.Error("An ordinal beyond the machine bits of the layout should be rejected")
+	}
+	if _, err := snowflake.New(snowflake.WithMachineID(1), snowflake.StatefulSetOrdinal("myapp-1", 0)); err == nil {
+		t.Error("StatefulSetOrdinal should conflict with WithMachineID")
+	}
+}
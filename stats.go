@@ -0,0 +1,107 @@
+package snowflake
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the generator counters.
+type Stats struct {
+	// AbsorbedBackward is the number of backward movements absorbed within the slew tolerance.
+	AbsorbedBackward uint64
+	// AbsorbedDrift is the total backward movement absorbed within the slew tolerance.
+	AbsorbedDrift time.Duration
+
+	// Lead is how far the last ID runs ahead of the clock, with a logical clock or an HLC.
+	Lead time.Duration
+	// MaxLead is the max lead of an ID so far.
+	MaxLead time.Duration
+
+	// ResolverDegraded is true while the fallback resolver serves, see WithResolverFallback.
+	ResolverDegraded bool
+	// ResolverFallbacks is the number of switches to the fallback resolver, ResolverRecoveries the number of switches back.
+	ResolverFallbacks  uint64
+	ResolverRecoveries uint64
+	// FallbackServed is the number of sequences served by the fallback resolver.
+	FallbackServed uint64
+
+	// RateLimited is the number of calls delayed or rejected by WithMaxRate, RateLimitWait is the total delay.
+	RateLimited   uint64
+	RateLimitWait time.Duration
+
+	// OnStandby is true while the generator uses the standby machineID, Failovers is the number of failovers to it,
+	// see WithStandbyMachineID.
+	OnStandby bool
+	Failovers uint64
+
+	// UnmanagedMachineID is true while the generator uses the random machineID picked because none was configured,
+	// see ErrUnmanagedMachineID.
+	UnmanagedMachineID bool
+
+	// PoolSlots is the usage of each machineID of the pool, PoolRotations the number of rotations to the next machineID,
+	// see WithMachineIDPool.
+	PoolSlots     []PoolSlot
+	PoolRotations uint64
+}
+
+// Stats returns a snapshot of the generator counters.
+// This function is thread safe.
+func (g *Generator) Stats() Stats {
+	stats := Stats{
+		AbsorbedBackward: atomic.LoadUint64(&g.absorbedBackward),
+		AbsorbedDrift:    time.Duration(atomic.LoadInt64(&g.absorbedDrift)),
+		Lead:             g.lead(),
+		MaxLead:          time.Duration(atomic.LoadInt64(&g.maxLead)) * g.timeUnit,
+
+		UnmanagedMachineID: atomic.LoadInt32(&g.unmanaged) == 1,
+	}
+	if f := g.fallback; f != nil {
+		stats.ResolverDegraded = atomic.LoadInt32(&f.active) == 1
+		stats.ResolverFallbacks = atomic.LoadUint64(&f.fallbacks)
+		stats.ResolverRecoveries = atomic.LoadUint64(&f.recoveries)
+		stats.FallbackServed = atomic.LoadUint64(&f.served)
+	}
+
+	if l := g.limiter; l != nil {
+		stats.RateLimited = atomic.LoadUint64(&l.limited)
+		stats.RateLimitWait = time.Duration(atomic.LoadInt64(&l.waited))
+	}
+
+	if s := g.standby; s != nil {
+		stats.OnStandby = atomic.LoadInt32(&s.active) == 1
+		stats.Failovers = atomic.LoadUint64(&s.failovers)
+	}
+
+	if p := g.pool; p != nil {
+		stats.PoolRotations = atomic.LoadUint64(&p.rotations)
+		for _, s := range p.slots {
+			stats.PoolSlots = append(stats.PoolSlots, PoolSlot{MachineID: s.id, IDs: atomic.LoadUint64(&s.used)})
+		}
+	}
+
+	return stats
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// lead returns how far the last ID runs ahead of the clock.
+func (g *Generator) lead() time.Duration {
+	lead := atomic.LoadInt64(&g.lastTimestamp) - g.currentTicks()
+	if lead < 0 {
+		return 0
+	}
+
+	return time.Duration(lead) * g.timeUnit
+}
+
+// recordLead record the lead of an ID in ticks.
+func (g *Generator) recordLead(lead int64) {
+	for {
+		max := atomic.LoadInt64(&g.maxLead)
+		if lead <= max || atomic.CompareAndSwapInt64(&g.maxLead, max, lead) {
+			return
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package snowflake
+
+import (
+	"context"
+	"math"
+	"sync"
+)
+
+// storeResolver is a sequence resolver counting the sequences in an external store shared by several processes,
+// e.g. redis or postgres. incr adds n to the counter of ms and machineID in the store and returns the new value,
+// so [value-n, value) is a block of sequences reserved by this process.
+type storeResolver struct {
+	incr      storeIncr
+	blockSize uint32
+
+	// mu guards the latest tick and machineID, and the block [next, end) of them reserved by this process.
+	mu        sync.Mutex
+	ms        int64
+	machineID uint16
+	next, end uint32
+}
+
+// storeIncr is the counter of a store, see storeResolver.
+type storeIncr func(ctx context.Context, ms int64, machineID uint16, n int64) (int64, error)
+
+func newStoreResolver(incr storeIncr, blockSize int) *storeResolver {
+	if blockSize > math.MaxUint16+1 {
+		blockSize = math.MaxUint16 + 1
+	}
+
+	return &storeResolver{incr: incr, blockSize: uint32(blockSize)}
+}
+
+// Resolve is r as a Resolver, ctx bounds the round trips to the store.
+func (r *storeResolver) Resolve(ctx context.Context, ms int64, machineID uint16) (uint16, error) {
+	if r.blockSize == 1 {
+		// 每个 ID 一次往返，不持有锁，并发的调用者同时访问存储
+		r.mu.Lock()
+		ok := r.advance(ms, machineID)
+		r.mu.Unlock()
+		if !ok {
+			return 0, ErrSequenceExhausted
+		}
+
+		n, err := r.incr(ctx, ms, machineID, 1)
+		if err != nil {
+			return 0, err
+		}
+		if n-1 > math.MaxUint16 {
+			return 0, ErrSequenceExhausted
+		}
+
+		return uint16(n - 1), nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.advance(ms, machineID) {
+		return 0, ErrSequenceExhausted
+	}
+	if r.next >= r.end {
+		end, err := r.incr(ctx, ms, machineID, int64(r.blockSize))
+		if err != nil {
+			return 0, err
+		}
+		if end-int64(r.blockSize) > math.MaxUint16 {
+			return 0, ErrSequenceExhausted
+		}
+		if end > math.MaxUint16+1 {
+			end = math.MaxUint16 + 1
+		}
+		r.next, r.end = uint32(end-int64(r.blockSize)), uint32(end)
+	}
+
+	seq := r.next
+	r.next++
+
+	return uint16(seq), nil
+}
+
+// advance move the latest tick to ms, the block of the past tick or of another machineID is discarded.
+// It returns false when ms is older than the latest tick.
+func (r *storeResolver) advance(ms int64, machineID uint16) bool {
+	if ms < r.ms {
+		return false
+	}
+	if ms > r.ms || machineID != r.machineID {
+		// machineID 切换后块属于旧的计数器，不能继续使用
+		r.ms, r.machineID, r.next, r.end = ms, machineID, 0, 0
+	}
+
+	return true
+}
@@ -0,0 +1,101 @@
+package snowflake_test
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/clocktest"
+)
+
+// TestWithStrictMonotonic_Property generate IDs with a clock jumping by up to ±2ms and a resolver
+// starting every tick at a random sequence, the IDs must be strictly increasing.
+func TestWithStrictMonotonic_Property(t *testing.T) {
+	n := 1000000
+	if testing.Short() {
+		n = 100000
+	}
+
+	clock := clocktest.NewManual(time.Now())
+	layout := snowflake.Layout{TimestampBits: 45, MachineBits: 12, SequenceBits: 6}
+	g, err := snowflake.New(
+		snowflake.WithClock(clock),
+		snowflake.WithStrictMonotonic(),
+		snowflake.WithBackwardPolicy(snowflake.BackwardPolicyLogical),
+		snowflake.WithSequenceResolver(snowflake.NewRandomStartResolver(layout.MaxSequence())),
+		snowflake.WithLayout(layout),
+		snowflake.WithStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	var last uint64
+	for i := 0; i < n; i++ {
+		if rnd.Intn(10) == 0 {
+			clock.Advance(time.Duration(rnd.Int63n(int64(4*time.Millisecond))) - 2*time.Millisecond)
+		}
+
+		id, err := g.NextIDNoWait()
+		if err == snowflake.ErrSequenceExhausted {
+			clock.Advance(time.Millisecond)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id <= last {
+			t.Fatalf("The IDs should be strictly increasing, got %d after %d", id, last)
+		}
+		last = id
+	}
+}
+
+func TestWithStrictMonotonic_Concurrent(t *testing.T) {
+	g, err := snowflake.New(
+		snowflake.WithStrictMonotonic(),
+		snowflake.WithSequenceResolver(snowflake.NewShardedResolver(4, snowflake.MaxSequence)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const goroutines, n = 8, 5000
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var ids []uint64
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				// the lock keeps the IDs in the order they were issued.
+				mu.Lock()
+				id, err := g.NextID()
+				ids = append(ids, id)
+				mu.Unlock()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Fatalf("The IDs should be strictly increasing, got %d after %d", ids[i], ids[i-1])
+		}
+	}
+}
+
+func TestWithStrictMonotonic_Descending(t *testing.T) {
+	if _, err := snowflake.New(snowflake.WithStrictMonotonic(), snowflake.WithDescending()); err == nil {
+		t.Error("WithStrictMonotonic should not be used with WithDescending")
+	}
+}
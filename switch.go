@@ -0,0 +1,132 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// machineIDEventQueue is the number of machineID events queued for the hooks, the events are dropped when it is full.
+const machineIDEventQueue = 16
+
+// MachineIDEvent is a change of the machineID by SwitchMachineID, see OnMachineIDChange.
+type MachineIDEvent struct {
+	// Old and New are the machineIDs before and after the switch.
+	Old, New uint16
+	// Reason is the loss of the lease or the duplicate which triggered a failover to the standby machineID,
+	// see WithStandbyMachineID, it is nil for the switches by SwitchMachineID and RestorePrimary.
+	Reason error
+	// Fenced is how long the generation was blocked waiting for the clock to pass the last ID.
+	Fenced time.Duration
+	// Time is the generator clock time of the switch.
+	Time time.Time
+}
+
+// SwitchMachineID change the machineID of a running generator, e.g. when a lease allocator lost its machineID
+// and leased another one. Unlike TrySetMachineID it can be called after IDs were generated:
+// it blocks the generation, waits until the clock is at least one tick past the last ID, swaps the machineID
+// and resumes, so no ID of the new machineID reuses a (timestamp, sequence) pair generated before the switch.
+//
+// It returns ErrMachineIDTooLarge when newID > the max machineID of the layout, ErrClosed when the generator is closed,
+// or an error wrapping ErrClockMovedBackward when the clock is behind the last ID by more than WithMaxBackwardTolerance.
+// The hooks registered with OnMachineIDChange are called after the switch.
+// This function is thread safe.
+func (g *Generator) SwitchMachineID(newID uint16) error {
+	ev, err := g.switchMachineID(newID)
+	if err != nil {
+		return err
+	}
+	g.emitMachineIDEvent(ev)
+
+	return nil
+}
+
+// OnMachineIDChange register fn to be called when the machineID changed, by SwitchMachineID or a standby failover.
+// The hooks are called by a goroutine of the generator like the OnClockEvent hooks, they never block ID generation.
+// This function is thread safe.
+func (g *Generator) OnMachineIDChange(fn func(ev MachineIDEvent)) {
+	g.machineIDHooksMu.Lock()
+	defer g.machineIDHooksMu.Unlock()
+
+	g.machineIDHooks = append(g.machineIDHooks, fn)
+	if g.machineIDEvents == nil {
+		g.machineIDEvents = make(chan MachineIDEvent, machineIDEventQueue)
+		go g.dispatchMachineIDEvents(g.machineIDEvents)
+	}
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// switchMachineID fence and switch the machineID to newID, it returns the event of the switch for the hooks.
+func (g *Generator) switchMachineID(newID uint16) (MachineIDEvent, error) {
+	if err := g.layout.checkMachineID(newID); err != nil {
+		return MachineIDEvent{}, err
+	}
+	if g.pool != nil {
+		return MachineIDEvent{}, errors.New("snowflake: the machineID of a generator with a machineID pool cannot be switched")
+	}
+	if g.fallback != nil && newID == g.fallback.machineID {
+		return MachineIDEvent{}, fmt.Errorf("snowflake: invalid machineID %d: it must differ from the fallback machineID", newID)
+	}
+
+	g.switchMu.Lock()
+	defer g.switchMu.Unlock()
+
+	if atomic.LoadInt32(&g.closed) == 1 {
+		return MachineIDEvent{}, ErrClosed
+	}
+
+	start := time.Now()
+	last := atomic.LoadInt64(&g.lastTimestamp)
+	if last != 0 {
+		// 时钟落后太多时不等待
+		tolerance := time.Duration(atomic.LoadInt64(&g.maxBackward))
+		if backward := time.Duration(last-g.currentTicks()) * g.timeUnit; backward > tolerance {
+			return MachineIDEvent{}, fmt.Errorf("%w by %s (tolerance %s), refusing to switch the machineID", ErrClockMovedBackward, backward, tolerance)
+		}
+		// 栅栏：等待时钟越过最后一个 ID 的时间单位
+		if _, err := g.waitForNextTick(context.Background(), last); err != nil {
+			return MachineIDEvent{}, err
+		}
+	}
+
+	old := uint16(atomic.SwapUint64(&g.machineID, uint64(newID)))
+	atomic.StoreInt32(&g.machineIDSet, 1)
+	atomic.StoreInt32(&g.unmanaged, 0)
+
+	return MachineIDEvent{Old: old, New: newID, Fenced: time.Since(start), Time: g.clock.Now()}, nil
+}
+
+// emitMachineIDEvent queue the event for the hooks, it never blocks.
+func (g *Generator) emitMachineIDEvent(ev MachineIDEvent) {
+	g.machineIDHooksMu.Lock()
+	events := g.machineIDEvents
+	g.machineIDHooksMu.Unlock()
+
+	select {
+	case events <- ev:
+	default:
+		// 队列已满或没有钩子，丢弃事件
+	}
+}
+
+func (g *Generator) dispatchMachineIDEvents(events chan MachineIDEvent) {
+	for {
+		select {
+		case ev := <-events:
+			g.machineIDHooksMu.Lock()
+			hooks := g.machineIDHooks
+			g.machineIDHooksMu.Unlock()
+
+			for _, fn := range hooks {
+				fn(ev)
+			}
+		case <-g.done:
+			return
+		}
+	}
+}
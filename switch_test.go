@@ -0,0 +1,102 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/clocktest"
+)
+
+func TestGenerator_SwitchMachineID(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	g, err := snowflake.New(snowflake.WithClock(clock), snowflake.WithMachineID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close(context.Background())
+
+	events := make(chan snowflake.MachineIDEvent, 1)
+	g.OnMachineIDChange(func(ev snowflake.MachineIDEvent) {
+		events <- ev
+	})
+
+	last, err := g.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	switched := make(chan error, 1)
+	go func() {
+		switched <- g.SwitchMachineID(2)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// the generation is blocked until the clock passes the last ID.
+	generated := make(chan uint64, 1)
+	go func() {
+		id, _ := g.NextID()
+		generated <- id
+	}()
+	select {
+	case err := <-switched:
+		t.Fatalf("SwitchMachineID should wait for the clock, got %v", err)
+	case <-generated:
+		t.Fatal("NextID should be blocked during the switch")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(time.Millisecond)
+	if err := <-switched; err != nil {
+		t.Fatal(err)
+	}
+	id := <-generated
+	sid, lastSID := g.ParseID(id), g.ParseID(last)
+	if sid.MachineID != 2 {
+		t.Errorf("The IDs should use the new machineID after the switch, got %d", sid.MachineID)
+	}
+	if sid.Timestamp <= lastSID.Timestamp {
+		t.Errorf("The IDs of the new machineID should be after the last ID, got %d <= %d", sid.Timestamp, lastSID.Timestamp)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Old != 1 || ev.New != 2 || ev.Fenced <= 0 {
+			t.Errorf("Unexpected event %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Error("The hook should be called after the switch")
+	}
+}
+
+func TestGenerator_SwitchMachineID_Errors(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	g, err := snowflake.New(snowflake.WithClock(clock), snowflake.WithMaxBackwardTolerance(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.SwitchMachineID(snowflake.MaxMachineID + 1); !errors.Is(err, snowflake.ErrMachineIDTooLarge) {
+		t.Errorf("The error should be ErrMachineIDTooLarge, got %v", err)
+	}
+
+	// a switch before the first ID doesn't wait.
+	if err := g.SwitchMachineID(3); err != nil || g.MachineID() != 3 {
+		t.Errorf("The machineID should be switched, got %d, %v", g.MachineID(), err)
+	}
+
+	if _, err := g.NextID(); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(-2 * time.Second)
+	if err := g.SwitchMachineID(4); !errors.Is(err, snowflake.ErrClockMovedBackward) {
+		t.Errorf("The error should be ErrClockMovedBackward, got %v", err)
+	}
+
+	_ = g.Close(context.Background())
+	if err := g.SwitchMachineID(4); !errors.Is(err, snowflake.ErrClosed) {
+		t.Errorf("The error should be ErrClosed, got %v", err)
+	}
+}
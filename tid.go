@@ -0,0 +1,113 @@
+package snowflake
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Base62TextPrefix is the prefix of the base62 IDs accepted by TID.UnmarshalText, e.g. "b62:1nXUa2Y6RLE".
+const Base62TextPrefix = "b62:"
+
+// TID is a typed snowflake ID, a uint64 which prints as its decimal value and decodes its parts.
+// It converts from and to uint64 for free: TID(id), uint64(tid).
+//
+// The type is not named ID, the package function ID generates the IDs of the default generator.
+// The accessors decode the ID with the layout and start time of the default generator,
+// use Generator.ParseID for the IDs of a generator with another configuration.
+type TID uint64
+
+// String returns the decimal value of the ID.
+func (id TID) String() string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// Time returns the generation time of the ID, a UTC time.
+func (id TID) Time() time.Time {
+	sid := ParseID(uint64(id))
+
+	return sid.GenerateTime()
+}
+
+// Machine returns the machineID of the ID.
+func (id TID) Machine() uint16 {
+	return uint16(ParseID(uint64(id)).MachineID)
+}
+
+// Seq returns the sequence of the ID.
+func (id TID) Seq() uint16 {
+	return uint16(ParseID(uint64(id)).Sequence)
+}
+
+// MarshalText returns the decimal value of the ID, e.g. for the IDs used as JSON map keys or in YAML.
+func (id TID) MarshalText() ([]byte, error) {
+	return id.AppendText(nil)
+}
+
+// AppendText appends the decimal value of the ID to b and returns the extended buffer, see encoding.TextAppender.
+// It doesn't allocate when b has the capacity.
+func (id TID) AppendText(b []byte) ([]byte, error) {
+	return strconv.AppendUint(b, uint64(id), 10), nil
+}
+
+// UnmarshalText parse the decimal value of the ID, or its base62 value with the Base62TextPrefix.
+// It returns an error wrapping ErrEmptyString, ErrInvalidCharacter, ErrStringTooLong or ErrValueOverflow.
+func (id *TID) UnmarshalText(b []byte) error {
+	s := string(b)
+	var v uint64
+	var err error
+	if strings.HasPrefix(s, Base62TextPrefix) {
+		v, err = ParseBase62(s[len(Base62TextPrefix):])
+	} else {
+		v, err = decimal.Decode(s)
+	}
+	if err != nil {
+		return err
+	}
+	*id = TID(v)
+
+	return nil
+}
+
+// MarshalBinary returns the 8 big-endian bytes of the ID, see Bytes.
+func (id TID) MarshalBinary() ([]byte, error) {
+	return id.AppendBinary(make([]byte, 0, 8))
+}
+
+// AppendBinary appends the 8 big-endian bytes of the ID to b and returns the extended buffer,
+// see encoding.BinaryAppender. It doesn't allocate when b has the capacity.
+func (id TID) AppendBinary(b []byte) ([]byte, error) {
+	v := Bytes(uint64(id))
+
+	return append(b, v[:]...), nil
+}
+
+// UnmarshalBinary reads the 8 big-endian bytes of MarshalBinary, see FromBytes.
+func (id *TID) UnmarshalBinary(b []byte) error {
+	v, err := FromBytes(b)
+	if err != nil {
+		return err
+	}
+	*id = TID(v)
+
+	return nil
+}
+
+// Next is NextID returning a TID.
+// This function is thread safe.
+func (g *Generator) Next() (TID, error) {
+	id, err := g.NextID()
+
+	return TID(id), err
+}
+
+// Next is NextID returning a TID, see Generator.Next.
+// This function is thread safe.
+func Next() (TID, error) {
+	return defaultGenerator.Next()
+}
+
+// String returns the decimal value of the ID.
+func (id SID) String() string {
+	return strconv.FormatUint(id.ID, 10)
+}
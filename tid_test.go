@@ -0,0 +1,156 @@
+package snowflake_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestTID(t *testing.T) {
+	snowflake.Reset()
+	defer snowflake.Reset()
+	snowflake.SetMachineID(9)
+
+	id, err := snowflake.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sid := snowflake.ParseID(uint64(id))
+
+	if got := fmt.Sprint(id); got != fmt.Sprint(uint64(id)) {
+		t.Errorf("The TID should print as its decimal value, got %s", got)
+	}
+	if id.Machine() != 9 || uint64(id.Seq()) != sid.Sequence {
+		t.Errorf("The accessors should decode the ID, got machineID %d and sequence %d", id.Machine(), id.Seq())
+	}
+	if d := time.Since(id.Time()); d < 0 || d > time.Second || !id.Time().Equal(sid.GenerateTime()) {
+		t.Errorf("Time should be the generation time, got %s", id.Time())
+	}
+
+	if got := fmt.Sprint(sid); got != id.String() {
+		t.Errorf("The SID should print as its decimal ID, got %s", got)
+	}
+	if got := fmt.Sprintf("%v", &sid); got != id.String() {
+		t.Errorf("A *SID should print as its decimal ID, got %s", got)
+	}
+
+	g, _ := snowflake.New(snowflake.WithMachineID(3))
+	tid, err := g.Next()
+	if err != nil || g.ParseID(uint64(tid)).MachineID != 3 {
+		t.Errorf("Generator.Next should return the ID of the generator, got %d, %v", tid, err)
+	}
+}
+
+func TestTID_UnmarshalText(t *testing.T) {
+	id := snowflake.TID(1<<63 + 12345)
+	b62 := snowflake.Base62TextPrefix + snowflake.FormatBase62(uint64(id))
+
+	tests := []struct {
+		in      string
+		want    snowflake.TID
+		wantErr error
+	}{
+		{in: id.String(), want: id},
+		{in: b62, want: id},
+		{in: "0", want: 0},
+		{in: "", wantErr: snowflake.ErrEmptyString},
+		{in: snowflake.Base62TextPrefix, wantErr: snowflake.ErrEmptyString},
+		{in: "12a", wantErr: snowflake.ErrInvalidCharacter},
+		{in: "-1", wantErr: snowflake.ErrInvalidCharacter},
+		{in: " 1", wantErr: snowflake.ErrInvalidCharacter},
+		{in: "b62:abc!", wantErr: snowflake.ErrInvalidCharacter},
+		{in: "18446744073709551616", wantErr: snowflake.ErrValueOverflow},
+		{in: "184467440737095516150", wantErr: snowflake.ErrStringTooLong},
+	}
+	for _, tt := range tests {
+		var got snowflake.TID
+		err := got.UnmarshalText([]byte(tt.in))
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("UnmarshalText(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("UnmarshalText(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTID_MapKey(t *testing.T) {
+	m := map[snowflake.TID]string{1<<63 + 1: "a", 42: "b"}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `{"42":"b","9223372036854775809":"a"}`; got != want {
+		t.Errorf("The IDs should be decimal keys, got %s, want %s", got, want)
+	}
+
+	var got map[snowflake.TID]string
+	if err := json.Unmarshal([]byte(`{"42":"b","9223372036854775809":"a","b62:G":"c"}`), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[1<<63+1] != "a" || got[42] != "b" || got[16] != "c" {
+		t.Errorf("The keys should be parsed, got %v", got)
+	}
+	if err := json.Unmarshal([]byte(`{"junk":"a"}`), &got); !errors.Is(err, snowflake.ErrInvalidCharacter) {
+		t.Errorf("A junk key should fail, got %v", err)
+	}
+}
+
+func TestTID_Append(t *testing.T) {
+	id := snowflake.TID(1<<63 + 1)
+	buf := make([]byte, 0, 64)
+
+	b, _ := id.AppendText(append(buf, "id="...))
+	if got := string(b); got != "id=9223372036854775809" {
+		t.Errorf("AppendText should append the decimal value, got %s", got)
+	}
+	b, _ = id.AppendBinary(buf[:0])
+	if v, err := snowflake.FromBytes(b); err != nil || snowflake.TID(v) != id {
+		t.Errorf("AppendBinary should append the 8 bytes, got %v", b)
+	}
+	b = snowflake.AppendBase62(buf[:0], uint64(id))
+	if got := string(b); got != snowflake.FormatBase62(uint64(id)) {
+		t.Errorf("AppendBase62 should append the base62 value, got %s", got)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		buf, _ = id.AppendText(buf[:0])
+		buf, _ = id.AppendBinary(buf[:0])
+		buf = snowflake.AppendBase62(buf[:0], uint64(id))
+	})
+	if allocs != 0 {
+		t.Errorf("The appenders should not allocate with a large enough buffer, got %v allocs", allocs)
+	}
+}
+
+func BenchmarkTID_AppendText(b *testing.B) {
+	id := snowflake.TID(1<<63 + 1)
+	buf := make([]byte, 0, 20)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _ = id.AppendText(buf[:0])
+	}
+}
+
+func BenchmarkTID_AppendBinary(b *testing.B) {
+	id := snowflake.TID(1<<63 + 1)
+	buf := make([]byte, 0, 8)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _ = id.AppendBinary(buf[:0])
+	}
+}
+
+func BenchmarkAppendBase62(b *testing.B) {
+	buf := make([]byte, 0, 11)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = snowflake.AppendBase62(buf[:0], uint64(i))
+	}
+}
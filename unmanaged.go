@@ -0,0 +1,66 @@
+package snowflake
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrUnmanagedMachineID is the warning passed to the OnError hooks when the first ID is generated without a machineID
+// configured: the generator uses a random machineID instead of 0, see Stats.UnmanagedMachineID.
+// Unlike the errors ignored by ID, it is passed to the hooks by a goroutine of the generator, so a hook may call NextID,
+// and it is not logged when no hook is registered.
+var ErrUnmanagedMachineID = errors.New("snowflake: no machineID is configured, using a random machineID")
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// pickUnmanagedMachineID pick a random machineID once for a generator without a machineID configured.
+// The processes which never configure the machineID rarely collide, instead of always sharing the machineID 0:
+// two of them collide with the probability 1/2^MachineBits, n of them about n²/2^(MachineBits+1).
+func (g *Generator) pickUnmanagedMachineID() {
+	g.unmanagedOnce.Do(func() {
+		m, err := g.randomMachineID()
+		if err != nil {
+			// 随机数不可用时保留 machineID 0
+			m = uint16(atomic.LoadUint64(&g.machineID))
+		}
+		atomic.StoreUint64(&g.machineID, uint64(m))
+		atomic.StoreInt32(&g.unmanaged, 1)
+
+		warning := fmt.Errorf("%w %d, configure the machineID of each process to avoid duplicate IDs", ErrUnmanagedMachineID, m)
+		g.errorHooksMu.Lock()
+		hooks := g.errorHooks
+		g.errorHooksMu.Unlock()
+		if len(hooks) == 0 {
+			return
+		}
+		// 在 sync.Once 之外异步调用，钩子中调用 NextID 不会死锁
+		go func() {
+			for _, fn := range hooks {
+				fn(warning)
+			}
+		}()
+	})
+}
+
+// randomMachineID returns a random machineID of the layout, other than the fallback and standby machineIDs.
+func (g *Generator) randomMachineID() (uint16, error) {
+	b := make([]byte, 2)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return 0, err
+		}
+		m := binary.BigEndian.Uint16(b) & g.layout.MaxMachineID()
+		if g.chain != nil && m == g.fallbackMachineID ||
+			g.fallback != nil && m == g.fallback.machineID ||
+			g.standby != nil && m == g.standby.id {
+			continue
+		}
+
+		return m, nil
+	}
+}
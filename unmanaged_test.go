@@ -0,0 +1,75 @@
+package snowflake_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+func TestGenerator_UnmanagedMachineID(t *testing.T) {
+	g, err := snowflake.New(snowflake.WithResolverFallback(snowflake.AtomicResolver, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings := make(chan error, 8)
+	g.OnError(func(err error) {
+		// a hook generating an ID doesn't deadlock the first NextID.
+		if _, err := g.NextID(); err != nil {
+			t.Error(err)
+		}
+		warnings <- err
+	})
+
+	// the concurrent first calls share the random machineID.
+	var wg sync.WaitGroup
+	machineIDs := make([]uint64, 8)
+	for i := range machineIDs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id, err := g.NextID()
+			if err != nil {
+				t.Error(err)
+			}
+			machineIDs[i] = g.ParseID(id).MachineID
+		}(i)
+	}
+	wg.Wait()
+	for _, m := range machineIDs {
+		if m != uint64(g.MachineID()) || m == 7 {
+			t.Errorf("The IDs should use one random machineID other than the fallback one, got %d", m)
+		}
+	}
+	select {
+	case err := <-warnings:
+		if !errors.Is(err, snowflake.ErrUnmanagedMachineID) {
+			t.Errorf("The warning should wrap ErrUnmanagedMachineID, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("The random machineID should be reported to the hooks")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n := len(warnings); n != 0 {
+		t.Errorf("The random machineID should be reported once, got %d more warnings", n)
+	}
+	if !g.Stats().UnmanagedMachineID {
+		t.Error("The machineID should be recorded as unmanaged")
+	}
+
+	// an explicit machineID 0 is kept.
+	g, _ = snowflake.New(snowflake.WithMachineID(0))
+	id, _ := g.NextID()
+	if g.ParseID(id).MachineID != 0 || g.Stats().UnmanagedMachineID {
+		t.Error("An explicit machineID should override the random one")
+	}
+
+	// strict mode refuses to generate instead.
+	g, _ = snowflake.New()
+	g.RequireExplicitMachineID()
+	if _, err := g.NextID(); !errors.Is(err, snowflake.ErrMachineIDNotSet) || g.Stats().UnmanagedMachineID {
+		t.Errorf("The error should be ErrMachineIDNotSet, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package snowflake
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// WaitStrategy is how the generator waits for the next tick when the sequence of the current tick is exhausted.
+type WaitStrategy uint8
+
+const (
+	// WaitHybrid sleep until shortly before the next tick, then spin, it is the default on most platforms.
+	// It is timer-resolution aware: it only sleeps when the remaining duration exceeds the timer granularity,
+	// so on coarse-timer platforms (e.g. windows, ~15.6ms) it spins instead of overshooting the next tick.
+	WaitHybrid WaitStrategy = iota
+	// WaitSpin spin with runtime.Gosched until the next tick, it has the best throughput and burns a CPU.
+	WaitSpin
+	// WaitSleep sleep the remaining duration of the current tick, it uses the least CPU
+	// but the scheduler latency is lost from the next tick. Use it on coarse-timer platforms to prefer lower CPU over throughput.
+	WaitSleep
+)
+
+// spinThreshold is the remaining duration below which WaitHybrid spins instead of sleeping,
+// it covers the usual scheduler latency of time.Sleep.
+const spinThreshold = 200 * time.Microsecond
+
+// String returns the name of the strategy, e.g. hybrid.
+func (s WaitStrategy) String() string {
+	switch s {
+	case WaitHybrid:
+		return "hybrid"
+	case WaitSpin:
+		return "spin"
+	case WaitSleep:
+		return "sleep"
+	default:
+		return fmt.Sprintf("WaitStrategy(%d)", uint8(s))
+	}
+}
+
+// WithTimerGranularity set the timer granularity used by WaitHybrid and the clock backward wait instead of measuring it,
+// e.g. 15600*time.Microsecond for the default windows timer. The waits shorter than d spin instead of sleeping.
+func WithTimerGranularity(d time.Duration) Option {
+	return func(g *Generator) error {
+		if d < 0 {
+			return fmt.Errorf("snowflake: invalid option WithTimerGranularity(%s): the granularity cannot be negative", d)
+		}
+		g.timerGranularity = d
+
+		return nil
+	}
+}
+
+// WithWaitStrategy set how the generator waits for the next tick on sequence exhaustion,
+// the default depends on the platform, see WaitHybrid.
+func WithWaitStrategy(s WaitStrategy) Option {
+	return func(g *Generator) error {
+		if s > WaitSleep {
+			return fmt.Errorf("snowflake: invalid option WithWaitStrategy: unknown strategy %s", s)
+		}
+		g.waitStrategy = s
+
+		return nil
+	}
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+// waitForNextTick wait until the current tick is greater than last, or ctx is done.
+func (g *Generator) waitForNextTick(ctx context.Context, last int64) (int64, error) {
+	next := time.Unix(0, (last+1)*int64(g.timeUnit))
+	for {
+		now := g.clock.Now()
+		if tick := toTicks(now, g.timeUnit); tick > last {
+			return tick, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		// 根据策略等待下一个时间单位
+		remaining := next.Sub(now)
+		switch {
+		case g.waitStrategy == WaitSleep:
+			time.Sleep(remaining)
+		case g.waitStrategy == WaitHybrid && remaining > g.spinThreshold():
+			time.Sleep(remaining - g.spinThreshold())
+		default:
+			runtime.Gosched()
+		}
+	}
+}
+
+// sleep wait d, or until ctx or the generator is done. Unless the strategy is WaitSleep,
+// a wait shorter than the timer granularity spins until the deadline instead of overshooting it.
+func (g *Generator) sleep(ctx context.Context, d time.Duration) {
+	if g.waitStrategy != WaitSleep && d <= g.spinThreshold() {
+		deadline := time.Now().Add(d)
+		for time.Now().Before(deadline) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-g.done:
+				return
+			default:
+				runtime.Gosched()
+			}
+		}
+		return
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-g.done:
+	case <-ctx.Done():
+	}
+}
+
+// spinThreshold returns the remaining duration below which the generator spins instead of sleeping.
+func (g *Generator) spinThreshold() time.Duration {
+	gran := g.timerGranularity
+	if gran == 0 {
+		gran = measuredTimerGranularity()
+	}
+	if gran < spinThreshold {
+		return spinThreshold
+	}
+
+	return gran
+}
+
+var (
+	timerGranularityOnce sync.Once
+	timerGranularity     time.Duration
+)
+
+// measuredTimerGranularity measure the actual duration of a short sleep once per process,
+// it is about 100µs on linux and up to 15.6ms on windows.
+func measuredTimerGranularity() time.Duration {
+	timerGranularityOnce.Do(func() {
+		for i := 0; i < 3; i++ {
+			start := time.Now()
+			time.Sleep(50 * time.Microsecond)
+			if d := time.Since(start); timerGranularity == 0 || d < timerGranularity {
+				timerGranularity = d
+			}
+		}
+	})
+
+	return timerGranularity
+}
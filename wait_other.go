@@ -0,0 +1,7 @@
+//go:build !windows
+// +build !windows
+
+package snowflake
+
+// defaultWaitStrategy is WaitHybrid, sleeping is precise enough to wake up shortly before the next tick.
+const defaultWaitStrategy = WaitHybrid
@@ -0,0 +1,97 @@
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+var waitStrategies = []snowflake.WaitStrategy{snowflake.WaitHybrid, snowflake.WaitSpin, snowflake.WaitSleep}
+
+func TestWithWaitStrategy(t *testing.T) {
+	for _, s := range waitStrategies {
+		t.Run(s.String(), func(t *testing.T) {
+			// only sequence 0 and 1 are available, so every other id needs a new tick.
+			g, err := snowflake.New(
+				snowflake.WithWaitStrategy(s),
+				snowflake.WithLayout(snowflake.Layout{TimestampBits: 46, MachineBits: 16, SequenceBits: 1}),
+				snowflake.WithStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			timestamps := make([]uint64, 20)
+			for i := range timestamps {
+				sid := g.ParseID(g.ID())
+				if i > 1 && sid.Timestamp <= timestamps[i-2] {
+					t.Fatal("The generator should wait for the next tick")
+				}
+				timestamps[i] = sid.Timestamp
+			}
+		})
+	}
+
+	if _, err := snowflake.New(snowflake.WithWaitStrategy(42)); err == nil {
+		t.Error("An unknown strategy should be rejected")
+	}
+}
+
+// BenchmarkWaitStrategy saturate the sequence and report the achieved IDs per second of each strategy.
+func BenchmarkWaitStrategy(b *testing.B) {
+	for _, s := range waitStrategies {
+		b.Run(s.String(), func(b *testing.B) {
+			g, err := snowflake.New(snowflake.WithWaitStrategy(s))
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				_, _ = g.NextID()
+			}
+			b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "ids/s")
+		})
+	}
+}
+
+func TestWithTimerGranularity(t *testing.T) {
+	g, err := snowflake.New(snowflake.WithTimerGranularity(15600 * time.Microsecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := snowflake.SpinThreshold(g); d != 15600*time.Microsecond {
+		t.Errorf("The configured granularity should be used, got %s", d)
+	}
+
+	g, err = snowflake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := snowflake.SpinThreshold(g); d < 200*time.Microsecond {
+		t.Errorf("The measured granularity should not be below the default spin threshold, got %s", d)
+	}
+
+	if _, err := snowflake.New(snowflake.WithTimerGranularity(-time.Millisecond)); err == nil {
+		t.Error("A negative granularity should be rejected")
+	}
+}
+
+func TestBackwardWait_Spin(t *testing.T) {
+	// a 1ms backward wait is below the granularity, so it spins until the deadline instead of sleeping.
+	g, err := snowflake.New(snowflake.WithTimerGranularity(15600 * time.Microsecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.ID()
+
+	snowflake.MoveClockBackward(g, time.Millisecond)
+	start := time.Now()
+	if _, err := g.NextID(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("The short backward wait should not overshoot, waited %s", elapsed)
+	}
+}
@@ -0,0 +1,4 @@
+package snowflake
+
+// defaultWaitStrategy is WaitSpin on windows, its timer granularity makes sleeping overshoot the next tick.
+const defaultWaitStrategy = WaitSpin
@@ -0,0 +1,37 @@
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+)
+
+// BenchmarkNextTick_Windows needs a new tick for every other ID, with the ~15.6ms windows timer
+// WaitSleep overshoots every tick while WaitSpin and the timer-resolution aware WaitHybrid keep up with the clock.
+func BenchmarkNextTick_Windows(b *testing.B) {
+	for _, s := range waitStrategies {
+		b.Run(s.String(), func(b *testing.B) {
+			g, err := snowflake.New(
+				snowflake.WithWaitStrategy(s),
+				snowflake.WithLayout(snowflake.Layout{TimestampBits: 46, MachineBits: 16, SequenceBits: 1}),
+				snowflake.WithStartTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+			)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				_, _ = g.NextID()
+			}
+			b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "ids/s")
+		})
+	}
+}
+
+func TestDefaultWaitStrategy_Windows(t *testing.T) {
+	if snowflake.DefaultWaitStrategy != snowflake.WaitSpin {
+		t.Errorf("The default strategy should spin on windows, got %s", snowflake.DefaultWaitStrategy)
+	}
+}
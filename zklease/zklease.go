@@ -0,0 +1,172 @@
+// Package zklease leases snowflake machineIDs from zookeeper, so the processes get a unique machineID at startup
+// without a static assignment:
+//
+//	lease, err := zklease.Allocate(ctx, zkClient{conn, expired}, zklease.Options{})
+//	if err != nil {
+//		return err
+//	}
+//	g, err := snowflake.New(snowflake.WithMachineIDLease(lease))
+//
+// The lease is an ephemeral sequential node under /snowflake/machines, its sequence modulo MaxMachineID+1
+// is the machineID. The node lives as long as the zookeeper session, it is deleted by Close.
+package zklease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/internal/leasekeeper"
+)
+
+const (
+	defaultPath = "/snowflake/machines"
+	nodePrefix  = "m-"
+)
+
+// ErrTooManyParticipants is returned by Allocate when there are more nodes than machineIDs,
+// some of the processes would share a machineID.
+var ErrTooManyParticipants = errors.New("zklease: more participants than machineIDs")
+
+// ErrMachineIDCollision is returned by Allocate when every new node collided with the machineID of an older node.
+var ErrMachineIDCollision = errors.New("zklease: machineID collision")
+
+// Client is the zookeeper client used by Allocate, implement it with github.com/go-zookeeper/zk.
+// Expired is closed when the session expired, the ephemeral node is deleted by zookeeper then:
+//
+//	type zkClient struct {
+//		conn    *zk.Conn
+//		expired chan struct{} // closed on zk.StateExpired, watching the events of zk.Connect
+//	}
+//
+//	func (c zkClient) CreateEphemeralSequential(ctx context.Context, path string, data []byte) (string, error) {
+//		return c.conn.Create(path, data, zk.FlagEphemeral|zk.FlagSequence, zk.WorldACL(zk.PermAll))
+//	}
+//
+//	func (c zkClient) Children(ctx context.Context, path string) ([]string, error) {
+//		children, _, err := c.conn.Children(path)
+//		return children, err
+//	}
+//
+//	func (c zkClient) Delete(ctx context.Context, path string) error {
+//		return c.conn.Delete(path, -1)
+//	}
+//
+//	func (c zkClient) Expired() <-chan struct{} { return c.expired }
+//
+// The parent node, Options.Path, must exist.
+type Client interface {
+	CreateEphemeralSequential(ctx context.Context, path string, data []byte) (string, error)
+	Children(ctx context.Context, path string) ([]string, error)
+	Delete(ctx context.Context, path string) error
+	Expired() <-chan struct{}
+}
+
+// Options configure Allocate, the zero value uses the defaults.
+type Options struct {
+	// Path is the parent of the nodes, "/snowflake/machines" by default.
+	Path string
+	// MaxMachineID is the max machineID leased, snowflake.MaxMachineID by default.
+	MaxMachineID uint16
+	// Owner is the data of the node, the host name by default.
+	Owner string
+}
+
+// Allocate create an ephemeral sequential node and lease the machineID of its sequence, until Close.
+// When an older node has the same machineID, the sequences wrapped around the machineIDs, the node is created
+// again with the next sequence. Allocate fails fast with ErrTooManyParticipants when there are more nodes
+// than machineIDs.
+//
+// The lease is lost when the zookeeper session expired: Done is closed and Err returns why,
+// see snowflake.WithMachineIDLease.
+func Allocate(ctx context.Context, client Client, opts Options) (snowflake.MachineIDLease, error) {
+	opts = opts.withDefaults()
+	slots := int64(opts.MaxMachineID) + 1
+
+	for attempt := int64(0); attempt < slots; attempt++ {
+		node, err := client.CreateEphemeralSequential(ctx, path.Join(opts.Path, nodePrefix), []byte(opts.Owner))
+		if err != nil {
+			return nil, fmt.Errorf("zklease: create a node: %w", err)
+		}
+		release := func(ctx context.Context) error {
+			return client.Delete(ctx, node)
+		}
+
+		id, err := opts.machineID(ctx, client, path.Base(node), slots)
+		if err == nil {
+			return leasekeeper.Watch(uint16(id), client.Expired(), release), nil
+		}
+
+		_ = release(ctx)
+		if !errors.Is(err, ErrMachineIDCollision) {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %d attempts", ErrMachineIDCollision, slots)
+}
+
+//--------------------------------------------------------------------
+// private function defined.
+//--------------------------------------------------------------------
+
+func (o Options) withDefaults() Options {
+	if o.Path == "" {
+		o.Path = defaultPath
+	}
+	if o.MaxMachineID == 0 {
+		o.MaxMachineID = snowflake.MaxMachineID
+	}
+	if o.Owner == "" {
+		o.Owner, _ = os.Hostname()
+	}
+
+	return o
+}
+
+// machineID returns the machineID of node, or ErrMachineIDCollision when an older node has the same one.
+func (o Options) machineID(ctx context.Context, client Client, node string, slots int64) (int64, error) {
+	seq, ok := sequence(node)
+	if !ok {
+		return 0, fmt.Errorf("zklease: the node %s has no sequence", node)
+	}
+
+	children, err := client.Children(ctx, o.Path)
+	if err != nil {
+		return 0, fmt.Errorf("zklease: list the nodes: %w", err)
+	}
+	var nodes int64
+	for _, c := range children {
+		if _, ok := sequence(c); ok {
+			nodes++
+		}
+	}
+	if nodes > slots {
+		return 0, fmt.Errorf("%w: %d nodes under %s for %d machineIDs", ErrTooManyParticipants, nodes, o.Path, slots)
+	}
+
+	id := seq % slots
+	for _, c := range children {
+		// 序号更小的节点先持有这个 ID
+		if s, ok := sequence(c); ok && s < seq && s%slots == id {
+			return 0, fmt.Errorf("%w: machineID %d of %s is leased by %s", ErrMachineIDCollision, id, node, c)
+		}
+	}
+
+	return id, nil
+}
+
+// sequence returns the sequence suffix of a node created by Allocate, e.g. 12 for m-0000000012.
+func sequence(node string) (int64, bool) {
+	if !strings.HasPrefix(node, nodePrefix) {
+		return 0, false
+	}
+	seq, err := strconv.ParseInt(strings.TrimPrefix(node, nodePrefix), 10, 64)
+
+	return seq, err == nil && seq >= 0
+}
@@ -0,0 +1,144 @@
+package zklease_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hedwi/go-snowflake"
+	"github.com/hedwi/go-snowflake/zklease"
+)
+
+// fakeZK keeps the ephemeral sequential nodes of one session in a map.
+type fakeZK struct {
+	mu      sync.Mutex
+	nodes   map[string]string
+	seq     int
+	expired chan struct{}
+}
+
+func newFakeZK() *fakeZK {
+	return &fakeZK{nodes: make(map[string]string), expired: make(chan struct{})}
+}
+
+func (z *fakeZK) CreateEphemeralSequential(ctx context.Context, p string, data []byte) (string, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	node := fmt.Sprintf("%s%010d", p, z.seq)
+	z.seq++
+	z.nodes[node] = string(data)
+	return node, nil
+}
+
+func (z *fakeZK) Children(ctx context.Context, p string) ([]string, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	var children []string
+	for n := range z.nodes {
+		if path.Dir(n) == p {
+			children = append(children, path.Base(n))
+		}
+	}
+	return children, nil
+}
+
+func (z *fakeZK) Delete(ctx context.Context, p string) error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	delete(z.nodes, p)
+	return nil
+}
+
+func (z *fakeZK) Expired() <-chan struct{} {
+	return z.expired
+}
+
+func (z *fakeZK) count() int {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	return len(z.nodes)
+}
+
+func TestAllocate(t *testing.T) {
+	client := newFakeZK()
+	opts := zklease.Options{MaxMachineID: 3}
+
+	leases := make([]snowflake.MachineIDLease, 4)
+	for i := range leases {
+		l, err := zklease.Allocate(context.Background(), client, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.MachineID() != uint16(i) {
+			t.Errorf("The machineID should be the sequence, got %d, want %d", l.MachineID(), i)
+		}
+		leases[i] = l
+	}
+
+	// the sequence wraps around the 4 machineIDs, the node of sequence 5 collides with machineID 1 and is created again.
+	if err := leases[2].Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	l, err := zklease.Allocate(context.Background(), client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.MachineID() != 2 {
+		t.Errorf("The released machineID should be leased, got %d", l.MachineID())
+	}
+	if n := client.count(); n != 4 {
+		t.Errorf("The colliding nodes should be deleted, got %d nodes", n)
+	}
+
+	// the 5th participant fails fast.
+	if _, err := zklease.Allocate(context.Background(), client, opts); !errors.Is(err, zklease.ErrTooManyParticipants) {
+		t.Errorf("The error should be ErrTooManyParticipants, got %v", err)
+	}
+	if n := client.count(); n != 4 {
+		t.Errorf("The node of a failed allocation should be deleted, got %d nodes", n)
+	}
+}
+
+func TestAllocate_Expired(t *testing.T) {
+	client := newFakeZK()
+	l, err := zklease.Allocate(context.Background(), client, zklease.Options{Owner: "pod-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := snowflake.New(snowflake.WithMachineIDLease(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close(context.Background())
+
+	close(client.expired)
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Fatal("The lease should be lost once the session expired")
+	}
+	if err := l.Err(); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("The lost lease should report the expiry, got %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := g.NextID()
+		if errors.Is(err, snowflake.ErrLeaseLost) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("The generator should stop once the lease is lost, got %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}